@@ -0,0 +1,267 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+const sampleMessageJSON = `{
+	"id": "msg_1",
+	"type": "message",
+	"role": "assistant",
+	"model": "claude-sonnet-4-5",
+	"content": [
+		{"type": "text", "text": "Let me look that up."},
+		{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {"q": "cats"}}
+	],
+	"stop_reason": "tool_use",
+	"usage": {"input_tokens": 10, "output_tokens": 5}
+}`
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) types.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(
+		client.WithAPIKey("test-key"),
+		client.WithBaseURL(server.URL),
+	)
+}
+
+func TestRawChat(t *testing.T) {
+	var received MessagesRequest
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("X-Api-Key") != "test-key" || r.Header.Get("Anthropic-Version") != APIVersion {
+			t.Errorf("missing auth or version headers: %v", r.Header)
+		}
+		if r.Header.Get("Traceparent") != "00-trace" {
+			t.Errorf("missing context headers: %v", r.Header)
+		}
+		if err := json.UnmarshalRead(r.Body, &received); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Write([]byte(sampleMessageJSON))
+	})
+
+	ctx := types.WithRequestHeaders(context.Background(), http.Header{"traceparent": {"00-trace"}})
+	resp, err := c.Chat(ctx, &types.ChatParams{
+		Model:        "claude-sonnet-4-5",
+		SystemPrompt: "Be helpful.",
+		Messages:     []types.Message{types.NewUserMessage(types.WithText("Find cats"))},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if received.System != "Be helpful." || received.Messages[0].Content[0].Text != "Find cats" {
+		t.Errorf("unexpected request body: %+v", received)
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" {
+		t.Errorf("expected finish reason tool_calls, got %q", choice.FinishReason)
+	}
+	if choice.Message.TextContent() != "Let me look that up." {
+		t.Errorf("unexpected text: %q", choice.Message.TextContent())
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments["q"] != "cats" {
+		t.Errorf("unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+	if resp.Usage.TotalTokens != 15 {
+		t.Errorf("expected 15 total tokens, got %d", resp.Usage.TotalTokens)
+	}
+	if raw, _ := resp.Extra[types.ExtraRaw].(jsontext.Value); string(raw) != sampleMessageJSON {
+		t.Errorf("expected the raw response in Extra, got %q", raw)
+	}
+	if resp.Extra["stop_reason"] != "tool_use" {
+		t.Errorf("expected stop_reason tool_use in Extra, got %v", resp.Extra["stop_reason"])
+	}
+}
+
+func TestRawChatNativeResponseFormat(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"id": "msg_2", "type": "message", "role": "assistant", "model": "claude-sonnet-4-5",
+			"content": [
+				{"type": "text", "text": "Here you go."},
+				{"type": "tool_use", "id": "toolu_2", "name": "_output", "input": {"name": "Alice"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 1, "output_tokens": 1}
+		}`))
+	})
+
+	resp, err := c.Chat(context.Background(), &types.ChatParams{
+		Model:    "claude-sonnet-4-5",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("Who?"))},
+		ResponseFormat: types.ResponseFormat{
+			Mode: types.ResponseFormatModeNative,
+			Schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"name": map[string]any{"type": "string"}},
+				"required":   []any{"name"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := resp.Choices[0].StructuredContent; got != `{"name":"Alice"}` {
+		t.Errorf("unexpected structured content: %s", got)
+	}
+	if len(resp.Choices[0].Message.ToolCalls) != 0 {
+		t.Errorf("expected output tool call to be removed")
+	}
+}
+
+func TestRawChatAPIError(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: required"}}`))
+	})
+
+	_, err := c.Chat(context.Background(), &types.ChatParams{Model: "claude-sonnet-4-5"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Type != "invalid_request_error" {
+		t.Errorf("unexpected api error: %+v", apiErr)
+	}
+}
+
+func TestRawChatRetriesOverloaded(t *testing.T) {
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	var attempts atomic.Int32
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(529)
+			w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+			return
+		}
+		w.Write([]byte(sampleMessageJSON))
+	})
+
+	if _, err := c.Chat(context.Background(), &types.ChatParams{Model: "claude-sonnet-4-5"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts.Load() != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts.Load())
+	}
+}
+
+const nativeStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_2","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Here you go."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_2","name":"_output","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"name\": "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Alice\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":5}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+func TestRawChatStreamNativeResponseFormat(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(nativeStream))
+	})
+	native := types.ResponseFormat{
+		Mode: types.ResponseFormatModeNative,
+		Schema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"name": map[string]any{"type": "string"}},
+			"required":   []any{"name"},
+		},
+	}
+
+	var text string
+	resp, err := types.StreamWithHandler(context.Background(), c, &types.ChatParams{
+		Model:          "claude-sonnet-4-5",
+		Messages:       []types.Message{types.NewUserMessage(types.WithText("Who?"))},
+		ResponseFormat: native,
+	}, func(chunk *types.StreamChunk) error {
+		if d := chunk.Choices[0].Delta; d != nil {
+			text += d.Content
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if text != `{"name": "Alice"}` {
+		t.Errorf("expected the output tool's input streamed as text, got %q", text)
+	}
+	choice := resp.Choices[0]
+	if choice.StructuredContent != `{"name": "Alice"}` || len(choice.Message.ToolCalls) != 0 || choice.FinishReason != "stop" {
+		t.Errorf("unexpected native stream result: %q, %+v, finish %q", choice.StructuredContent, choice.Message.ToolCalls, choice.FinishReason)
+	}
+}
+
+func TestRawChatStream(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var request MessagesRequest
+		if err := json.UnmarshalRead(r.Body, &request); err != nil || !request.Stream {
+			t.Errorf("expected stream request, got %+v (err %v)", request, err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sampleStream))
+	})
+
+	var text string
+	resp, err := types.StreamWithHandler(context.Background(), c, &types.ChatParams{Model: "claude-sonnet-4-5"},
+		func(chunk *types.StreamChunk) error {
+			if d := chunk.Choices[0].Delta; d != nil {
+				text += d.Content
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if text != "Checking weather." {
+		t.Errorf("unexpected streamed text: %q", text)
+	}
+	if resp.Choices[0].Message.ToolCalls[0].Function.Name != "weather" {
+		t.Errorf("unexpected accumulated message: %+v", resp.Choices[0].Message)
+	}
+}
@@ -0,0 +1,63 @@
+package anthropic
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrUnsupportedMessageRole indicates that a message role is not supported by the adapter.
+	ErrUnsupportedMessageRole = errors.New("anthropic chat: unsupported message role")
+
+	// ErrUnsupportedUserContentPart indicates that a user message includes content the adapter cannot convert.
+	ErrUnsupportedUserContentPart = errors.New("anthropic chat: unsupported content part for user message")
+
+	// ErrUnsupportedAssistantContentPart indicates that an assistant message includes unsupported content.
+	ErrUnsupportedAssistantContentPart = errors.New("anthropic chat: unsupported content part for assistant message")
+
+	// ErrUnsupportedToolContentPart indicates that a tool result message includes unsupported content.
+	ErrUnsupportedToolContentPart = errors.New("anthropic chat: unsupported content part for tool message")
+
+	// ErrMissingToolCallID indicates that a tool result message is missing the required ToolCallID,
+	// or that an assistant message contains a tool call with an empty ID.
+	ErrMissingToolCallID = errors.New("anthropic chat: tool message missing ToolCallID")
+
+	// ErrMultipleChoicesNotSupported is returned when ChatParams.N > 1; the API returns a single choice.
+	ErrMultipleChoicesNotSupported = errors.New("anthropic chat: n > 1 is not supported")
+
+	// ErrEmbeddingsNotSupported is returned by RawEmbed; Anthropic does not offer an embeddings API.
+	ErrEmbeddingsNotSupported = errors.New("anthropic: embeddings are not supported")
+)
+
+// APIError is returned when the Messages API responds with a non-2xx status or an error event.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+
+	// RetryDelay is the Retry-After the API sent with the error, zero if none.
+	RetryDelay time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("anthropic api error: %s: %s", e.Type, e.Message)
+	}
+	return fmt.Sprintf("anthropic api error (status %d): %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// retryable reports whether the request may succeed if sent again.
+func (e *APIError) retryable() bool {
+	return e.StatusCode == 429 || e.StatusCode == 529 || e.StatusCode >= 500
+}
+
+// Transient reports whether the request may succeed if sent again, see types.TransientError.
+func (e *APIError) Transient() bool {
+	return e.retryable()
+}
+
+// RetryAfter returns the delay the API asked for before retrying, zero if none.
+func (e *APIError) RetryAfter() time.Duration {
+	return e.RetryDelay
+}
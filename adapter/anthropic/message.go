@@ -0,0 +1,205 @@
+package anthropic
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ToMessageParams converts unified messages to Anthropic message parameters.
+// Tool result messages become tool_result blocks in a user turn, and consecutive
+// turns with the same role are merged because the API requires alternating roles.
+func ToMessageParams(messages []types.Message) ([]MessageParam, error) {
+	result := make([]MessageParam, 0, len(messages))
+
+	for _, message := range messages {
+		var (
+			param MessageParam
+			err   error
+		)
+
+		switch message.Role {
+		case types.RoleUser:
+			param, err = toUserMessage(&message)
+			if err != nil {
+				return nil, fmt.Errorf("error converting message to user message: %w", err)
+			}
+		case types.RoleAssistant:
+			param, err = toAssistantMessage(&message)
+			if err != nil {
+				return nil, fmt.Errorf("error converting message to assistant message: %w", err)
+			}
+		case types.RoleTool:
+			param, err = toToolResultMessage(&message)
+			if err != nil {
+				return nil, fmt.Errorf("error converting message to tool result: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedMessageRole, message.Role)
+		}
+
+		if cc := ToCacheControl(message.CacheControl); cc != nil && len(param.Content) > 0 {
+			param.Content[len(param.Content)-1].CacheControl = cc
+		}
+
+		if n := len(result); n > 0 && result[n-1].Role == param.Role {
+			result[n-1].Content = append(result[n-1].Content, param.Content...)
+			continue
+		}
+		result = append(result, param)
+	}
+
+	return result, nil
+}
+
+// toUserMessage converts a user message to an Anthropic user turn
+func toUserMessage(message *types.Message) (MessageParam, error) {
+	content := make([]ContentBlock, 0, len(message.ContentPart))
+
+	for _, contentPart := range message.ContentPart {
+		block, ok := toContentBlock(contentPart)
+		if !ok {
+			return MessageParam{}, &types.UnsupportedContentError{Part: contentPart, Err: ErrUnsupportedUserContentPart}
+		}
+		if block != nil {
+			content = append(content, *block)
+		}
+	}
+
+	return MessageParam{Role: roleUser, Content: content}, nil
+}
+
+// toAssistantMessage converts an assistant message with content and tool calls to an Anthropic assistant turn
+func toAssistantMessage(message *types.Message) (MessageParam, error) {
+	content := make([]ContentBlock, 0, len(message.ContentPart)+len(message.ToolCalls))
+
+	for _, contentPart := range message.ContentPart {
+		switch part := contentPart.(type) {
+		case *types.ContentPartText:
+			if part.Text != "" {
+				content = append(content, ContentBlock{Type: blockTypeText, Text: part.Text, CacheControl: ToCacheControl(part.CacheControl)})
+			}
+		case *types.ContentPartReasoning:
+			// Extended thinking is sent back as received; reasoning from other providers,
+			// which has no signature, is not
+			switch {
+			case part.Redacted:
+				content = append(content, ContentBlock{Type: blockTypeRedacted, Data: part.Text})
+			case part.Signature != "":
+				content = append(content, ContentBlock{Type: blockTypeThinking, Thinking: part.Text, Signature: part.Signature})
+			}
+		default:
+			return MessageParam{}, &types.UnsupportedContentError{Part: part, Err: ErrUnsupportedAssistantContentPart}
+		}
+	}
+
+	for _, toolCall := range message.ToolCalls {
+		if toolCall.ID == "" {
+			return MessageParam{}, fmt.Errorf("%w: tool call %q has empty ID", ErrMissingToolCallID, toolCall.Function.Name)
+		}
+		input := toolCall.Function.Arguments
+		if input == nil {
+			input = map[string]any{}
+		}
+		content = append(content, ContentBlock{
+			Type:  blockTypeToolUse,
+			ID:    toolCall.ID,
+			Name:  toolCall.Function.Name,
+			Input: input,
+		})
+	}
+
+	return MessageParam{Role: roleAssistant, Content: content}, nil
+}
+
+// toToolResultMessage converts a tool result message to a user turn holding a tool_result block
+func toToolResultMessage(message *types.Message) (MessageParam, error) {
+	if message.ToolCallID == nil {
+		return MessageParam{}, ErrMissingToolCallID
+	}
+
+	content := make([]ContentBlock, 0, len(message.ContentPart))
+	for _, contentPart := range message.ContentPart {
+		block, ok := toContentBlock(contentPart)
+		if !ok {
+			return MessageParam{}, &types.UnsupportedContentError{Part: contentPart, Err: ErrUnsupportedToolContentPart}
+		}
+		if block != nil {
+			content = append(content, *block)
+		}
+	}
+
+	return MessageParam{
+		Role: roleUser,
+		Content: []ContentBlock{{
+			Type:      blockTypeToolResult,
+			ToolUseID: *message.ToolCallID,
+			Content:   content,
+		}},
+	}, nil
+}
+
+// toContentBlock converts text, image and document parts. Empty text yields a nil block,
+// since the API rejects empty text blocks; ok is false for unsupported parts.
+func toContentBlock(contentPart types.ContentPart) (block *ContentBlock, ok bool) {
+	switch part := contentPart.(type) {
+	case *types.ContentPartText:
+		if part.Text == "" {
+			return nil, true
+		}
+		return &ContentBlock{Type: blockTypeText, Text: part.Text, CacheControl: ToCacheControl(part.CacheControl)}, true
+	case *types.ContentPartImage:
+		mediaType := part.MIMEType
+		if mediaType == "" {
+			mediaType = "image/png"
+		}
+		return &ContentBlock{
+			Type:   blockTypeImage,
+			Source: &ImageSource{Type: "base64", MediaType: mediaType, Data: part.Data},
+		}, true
+	case *types.ContentPartImageURL:
+		return &ContentBlock{
+			Type:   blockTypeImage,
+			Source: &ImageSource{Type: "url", URL: part.URL},
+		}, true
+	case *types.ContentPartDocument:
+		return toDocumentBlock(part)
+	default:
+		return nil, false
+	}
+}
+
+// SupportsDocument reports whether the Messages API reads document: PDFs, and text documents
+// sent inline.
+func (c *Client) SupportsDocument(document *types.ContentPartDocument) bool {
+	_, ok := toDocumentBlock(document)
+	return ok
+}
+
+// toDocumentBlock converts a PDF or inline text document to a document block; ok is false
+// for other documents. Text is sent decoded, as plain text.
+func toDocumentBlock(document *types.ContentPartDocument) (block *ContentBlock, ok bool) {
+	mediaType := document.MIMEType
+	if mediaType == "" {
+		mediaType = "application/pdf"
+	}
+
+	var source *ImageSource
+	switch {
+	case mediaType == "application/pdf" && document.Data != "":
+		source = &ImageSource{Type: "base64", MediaType: mediaType, Data: document.Data}
+	case mediaType == "application/pdf" && document.URL != "":
+		source = &ImageSource{Type: "url", URL: document.URL}
+	case strings.HasPrefix(mediaType, "text/") && document.Data != "":
+		text, err := base64.StdEncoding.DecodeString(document.Data)
+		if err != nil {
+			return nil, false
+		}
+		source = &ImageSource{Type: "text", MediaType: "text/plain", Data: string(text)}
+	default:
+		return nil, false
+	}
+	return &ContentBlock{Type: blockTypeDocument, Source: source, Title: document.Title, EnableCitations: document.Citations}, true
+}
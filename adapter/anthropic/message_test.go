@@ -0,0 +1,299 @@
+package anthropic
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+func TestToMessageParamsToolRoundTrip(t *testing.T) {
+	callA, callB := "toolu_a", "toolu_b"
+	messages := []types.Message{
+		types.NewUserMessage(
+			types.WithText("What's in this image and the weather?"),
+			func(m *types.Message) {
+				m.ContentPart = append(m.ContentPart,
+					&types.ContentPartImage{Data: "aGVsbG8=", MIMEType: "image/jpeg"},
+					types.NewContentPartImageURL("https://example.com/cat.png"),
+				)
+			},
+		),
+		types.NewAssistantMessage(
+			types.WithText("Let me check."),
+			types.WithToolCalls(
+				types.ToolCall{ID: callA, Function: types.ToolFunction{Name: "weather", Arguments: map[string]any{"city": "Paris"}}},
+				types.ToolCall{ID: callB, Function: types.ToolFunction{Name: "time"}},
+			),
+		),
+		types.NewToolMessage(types.WithText("sunny"), types.WithToolCallID(callA)),
+		types.NewToolMessage(types.WithText("noon"), types.WithToolCallID(callB)),
+	}
+
+	params, err := ToMessageParams(messages)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(params) != 3 {
+		t.Fatalf("expected 3 turns (tool results merged), got %d", len(params))
+	}
+
+	user := params[0]
+	if user.Role != roleUser || len(user.Content) != 3 {
+		t.Fatalf("unexpected user turn: %+v", user)
+	}
+	if src := user.Content[1].Source; src.Type != "base64" || src.MediaType != "image/jpeg" {
+		t.Errorf("unexpected base64 image source: %+v", src)
+	}
+	if src := user.Content[2].Source; src.Type != "url" || src.URL != "https://example.com/cat.png" {
+		t.Errorf("unexpected url image source: %+v", src)
+	}
+
+	assistant := params[1]
+	if assistant.Role != roleAssistant || len(assistant.Content) != 3 {
+		t.Fatalf("unexpected assistant turn: %+v", assistant)
+	}
+	if use := assistant.Content[1]; use.Type != blockTypeToolUse || use.ID != callA || use.Input["city"] != "Paris" {
+		t.Errorf("unexpected tool_use block: %+v", use)
+	}
+	if use := assistant.Content[2]; use.Input == nil {
+		t.Errorf("expected empty input object for tool call without arguments")
+	}
+
+	results := params[2]
+	if results.Role != roleUser || len(results.Content) != 2 {
+		t.Fatalf("unexpected tool result turn: %+v", results)
+	}
+	if r := results.Content[1]; r.Type != blockTypeToolResult || r.ToolUseID != callB || r.Content[0].Text != "noon" {
+		t.Errorf("unexpected tool_result block: %+v", r)
+	}
+}
+
+func TestToMessageParamsErrors(t *testing.T) {
+	if _, err := ToMessageParams([]types.Message{{Role: "unknown"}}); !errors.Is(err, ErrUnsupportedMessageRole) {
+		t.Errorf("expected ErrUnsupportedMessageRole, got %v", err)
+	}
+
+	if _, err := ToMessageParams([]types.Message{types.NewToolMessage(types.WithText("x"))}); !errors.Is(err, ErrMissingToolCallID) {
+		t.Errorf("expected ErrMissingToolCallID, got %v", err)
+	}
+
+	refusal := types.Message{Role: types.RoleAssistant, ContentPart: []types.ContentPart{types.NewContentPartRefusal("no")}}
+	if _, err := ToMessageParams([]types.Message{refusal}); !errors.Is(err, ErrUnsupportedAssistantContentPart) {
+		t.Errorf("expected ErrUnsupportedAssistantContentPart, got %v", err)
+	}
+
+	file := types.NewUserMessage(types.WithFile(types.NewContentPartFileID("file-1")))
+	_, err := ToMessageParams([]types.Message{file})
+	var unsupported *types.UnsupportedContentError
+	if !errors.As(err, &unsupported) || !errors.Is(err, ErrUnsupportedUserContentPart) {
+		t.Errorf("expected UnsupportedContentError wrapping ErrUnsupportedUserContentPart, got %v", err)
+	}
+}
+
+func TestToMessagesRequest(t *testing.T) {
+	maxTokens := 256
+	params := &types.ChatParams{
+		Model:        "claude-sonnet-4-5",
+		SystemPrompt: "Be brief.",
+		Messages:     []types.Message{types.NewUserMessage(types.WithText("hi"))},
+		MaxTokens:    &maxTokens,
+		Stop:         []string{"END"},
+		Tools: []types.ToolDefinition{
+			{Name: "lookup", Description: "Looks up", InputSchema: map[string]any{"type": "object"}},
+		},
+		ToolChoice: types.ToolChoiceRequired(),
+	}
+
+	request, err := ToMessagesRequest(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if request.System != "Be brief." || request.MaxTokens != 256 || request.StopSequences[0] != "END" {
+		t.Errorf("unexpected request: %+v", request)
+	}
+	if len(request.Tools) != 1 || request.Tools[0].Name != "lookup" {
+		t.Errorf("unexpected tools: %+v", request.Tools)
+	}
+	if request.ToolChoice == nil || request.ToolChoice.Type != "any" {
+		t.Errorf("expected required tool choice to map to any, got %+v", request.ToolChoice)
+	}
+
+	types.WithParallelToolCalls(false)(params)
+	params.ToolChoice = nil
+	request, err = ToMessagesRequest(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request.ToolChoice == nil || request.ToolChoice.Type != "auto" || !request.ToolChoice.DisableParallelToolUse {
+		t.Errorf("expected auto tool choice with parallel tool use disabled, got %+v", request.ToolChoice)
+	}
+
+	params.MaxTokens = nil
+	params.ResponseFormat = types.ResponseFormat{Mode: types.ResponseFormatModeNative, Schema: map[string]any{"type": "object"}}
+	request, err = ToMessagesRequest(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request.MaxTokens != DefaultMaxTokens {
+		t.Errorf("expected default max tokens, got %d", request.MaxTokens)
+	}
+	if len(request.Tools) != 2 || request.Tools[1].Name != types.OutputToolName {
+		t.Errorf("expected native mode to add the output tool, got %+v", request.Tools)
+	}
+	if len(params.Tools) != 1 {
+		t.Errorf("expected caller's tools to be untouched, got %d", len(params.Tools))
+	}
+}
+
+func TestToMessagesRequestCacheControl(t *testing.T) {
+	params := &types.ChatParams{
+		Model:        "claude-sonnet-4-5",
+		SystemPrompt: "Be brief.",
+		Messages: []types.Message{
+			types.NewUserMessage(types.WithText("a long document"), types.WithCacheControl(types.CacheControl{TTL: "1h"})),
+			{Role: types.RoleAssistant, ContentPart: []types.ContentPart{&types.ContentPartText{Text: "noted", CacheControl: &types.CacheControl{}}}},
+		},
+		Tools: []types.ToolDefinition{
+			{Name: "lookup", InputSchema: map[string]any{"type": "object"}},
+			{Name: "search", InputSchema: map[string]any{"type": "object"}},
+		},
+	}
+	types.WithSystemCacheControl(types.CacheControl{})(params)
+	types.WithToolsCacheControl(types.CacheControl{})(params)
+
+	request, err := ToMessagesRequest(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request.Tools[0].CacheControl != nil || request.Tools[1].CacheControl == nil || request.Tools[1].CacheControl.Type != "ephemeral" {
+		t.Errorf("expected only the last tool to be cached, got %+v", request.Tools)
+	}
+	if cc := request.Messages[0].Content[0].CacheControl; cc == nil || cc.TTL != "1h" {
+		t.Errorf("expected the cached message to end with a cache breakpoint, got %+v", cc)
+	}
+	if request.Messages[1].Content[0].CacheControl == nil {
+		t.Error("expected the cached text part to carry a cache breakpoint")
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var wire struct {
+		Model  string         `json:"model"`
+		System []ContentBlock `json:"system"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		t.Fatalf("unmarshal failed: %v\n%s", err, data)
+	}
+	if wire.Model != "claude-sonnet-4-5" || len(wire.System) != 1 || wire.System[0].Text != "Be brief." || wire.System[0].CacheControl == nil {
+		t.Errorf("expected the system prompt as a cached text block, got %s", data)
+	}
+}
+
+func TestThinkingRoundTrip(t *testing.T) {
+	resp := FromMessagesResponse(&MessagesResponse{
+		ID:   "msg_1",
+		Role: roleAssistant,
+		Content: []ContentBlock{
+			{Type: blockTypeThinking, Thinking: "The user wants cats.", Signature: "sig-1"},
+			{Type: blockTypeRedacted, Data: "encrypted"},
+			{Type: blockTypeToolUse, ID: "toolu_1", Name: "lookup", Input: map[string]any{"q": "cats"}},
+		},
+		StopReason: "tool_use",
+	})
+	message := resp.Choices[0].Message
+	if got := message.ReasoningContent(); got != "The user wants cats." {
+		t.Errorf("expected the thinking as reasoning, got %q", got)
+	}
+
+	// Reasoning from other providers has no signature and is dropped
+	message.ContentPart = append(message.ContentPart, types.NewContentPartReasoning("unsigned"))
+	params, err := ToMessageParams([]types.Message{*message})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content := params[0].Content
+	if len(content) != 3 {
+		t.Fatalf("expected thinking, redacted thinking and tool use blocks, got %+v", content)
+	}
+	if b := content[0]; b.Type != blockTypeThinking || b.Thinking != "The user wants cats." || b.Signature != "sig-1" {
+		t.Errorf("unexpected thinking block: %+v", b)
+	}
+	if b := content[1]; b.Type != blockTypeRedacted || b.Data != "encrypted" {
+		t.Errorf("unexpected redacted thinking block: %+v", b)
+	}
+}
+
+func TestToMessageParamsDocuments(t *testing.T) {
+	messages := []types.Message{types.NewUserMessage(
+		types.WithDocument(&types.ContentPartDocument{Data: "JVBERi0=", Title: "Q3 report"}),
+		types.WithDocument(types.NewContentPartDocumentURL("https://example.com/a.pdf")),
+		types.WithDocument(types.NewContentPartDocumentData("YSxiCjEsMg==", "text/csv", "data.csv")),
+	)}
+	params, err := ToMessageParams(messages)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content := params[0].Content
+	if b := content[0]; b.Type != blockTypeDocument || b.Title != "Q3 report" || *b.Source != (ImageSource{Type: "base64", MediaType: "application/pdf", Data: "JVBERi0="}) {
+		t.Errorf("unexpected PDF block: %+v %+v", b, b.Source)
+	}
+	if b := content[1]; b.Type != blockTypeDocument || *b.Source != (ImageSource{Type: "url", URL: "https://example.com/a.pdf"}) {
+		t.Errorf("unexpected PDF URL block: %+v %+v", b, b.Source)
+	}
+	if b := content[2]; b.Type != blockTypeDocument || *b.Source != (ImageSource{Type: "text", MediaType: "text/plain", Data: "a,b\n1,2"}) {
+		t.Errorf("unexpected text document block: %+v %+v", b, b.Source)
+	}
+
+	image := types.NewContentPartDocumentData("iVBORw0=", "image/png", "chart.png")
+	if (&Client{}).SupportsDocument(image) {
+		t.Error("expected images sent as documents to be unsupported")
+	}
+	if _, err := ToMessageParams([]types.Message{types.NewUserMessage(types.WithDocument(image))}); err == nil {
+		t.Error("expected an error for an unsupported document")
+	}
+}
+
+func TestCitations(t *testing.T) {
+	request, err := ToMessagesRequest(&types.ChatParams{
+		Model:    "claude-sonnet-4-5",
+		Messages: []types.Message{types.NewUserMessage(types.WithDocument(&types.ContentPartDocument{Data: "JVBERi0=", Citations: true}))},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"citations":{"enabled":true}`) {
+		t.Errorf("expected citations to be enabled on the document, got %s", data)
+	}
+
+	var response MessagesResponse
+	if err := json.Unmarshal([]byte(`{"id": "msg_1", "role": "assistant", "content": [
+		{"type": "text", "text": "Über "},
+		{"type": "text", "text": "revenue grew.", "citations": [
+			{"type": "page_location", "cited_text": "Revenue grew 12%.", "document_index": 1, "document_title": "Q3 report", "start_page_number": 2, "end_page_number": 3}
+		]},
+		{"type": "text", "text": " Sunny.", "citations": [
+			{"type": "web_search_result_location", "cited_text": "Sunny all week", "url": "https://weather.example", "title": "Forecast"}
+		]}
+	], "stop_reason": "end_turn"}`), &response); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	want := []types.Annotation{
+		{Type: types.AnnotationDocumentCitation, StartIndex: 5, EndIndex: 18, Title: "Q3 report", DocumentIndex: 1, CitedText: "Revenue grew 12%."},
+		{Type: types.AnnotationURLCitation, StartIndex: 18, EndIndex: 25, URL: "https://weather.example", Title: "Forecast", CitedText: "Sunny all week"},
+	}
+	if got := FromMessagesResponse(&response).Choices[0].Message.Annotations; !reflect.DeepEqual(got, want) {
+		t.Errorf("Annotations = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,23 @@
+package anthropic
+
+import "github.com/KennyKeni/elysia/types"
+
+// AdapterName identifies this adapter in model validation errors.
+const AdapterName = "anthropic"
+
+func init() {
+	types.RegisterModels(AdapterName, SupportedModels())
+}
+
+// SupportedModels returns the Claude model names known to this adapter.
+func SupportedModels() []string {
+	return []string{
+		"claude-opus-4-1",
+		"claude-opus-4-0",
+		"claude-sonnet-4-5",
+		"claude-sonnet-4-0",
+		"claude-3-7-sonnet-latest",
+		"claude-3-5-haiku-latest",
+		"claude-haiku-4-5",
+	}
+}
@@ -0,0 +1,254 @@
+package anthropic
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// DefaultMaxTokens is sent when ChatParams.MaxTokens is unset; the Messages API requires max_tokens.
+const DefaultMaxTokens = 4096
+
+// MessagesRequest is the request body of the Messages API.
+type MessagesRequest struct {
+	Model         string           `json:"model"`
+	MaxTokens     int              `json:"max_tokens"`
+	System        string           `json:"system,omitempty"`
+	Messages      []MessageParam   `json:"messages"`
+	Tools         []ToolParam      `json:"tools,omitempty"`
+	ToolChoice    *ToolChoiceParam `json:"tool_choice,omitempty"`
+	Temperature   *float64         `json:"temperature,omitempty"`
+	TopP          *float64         `json:"top_p,omitempty"`
+	TopK          *int             `json:"top_k,omitempty"`
+	StopSequences []string         `json:"stop_sequences,omitempty"`
+	Stream        bool             `json:"stream,omitzero"`
+
+	// SystemCacheControl caches the prefix ending with System, which is then sent as a
+	// text block (nil = sent as a string)
+	SystemCacheControl *CacheControlParam `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler, sending System as a text block when it is cached.
+func (r MessagesRequest) MarshalJSON() ([]byte, error) {
+	type plain MessagesRequest
+	if r.SystemCacheControl == nil || r.System == "" {
+		return json.Marshal(plain(r))
+	}
+	return json.Marshal(struct {
+		plain
+		System []ContentBlock `json:"system"`
+	}{plain(r), []ContentBlock{{Type: blockTypeText, Text: r.System, CacheControl: r.SystemCacheControl}}})
+}
+
+// MessageParam is a single conversation turn. Anthropic only has user and assistant turns;
+// tool results are sent as tool_result blocks inside user turns.
+type MessageParam struct {
+	Role    string         `json:"role"`
+	Content []ContentBlock `json:"content"`
+}
+
+// ContentBlock is a content block in a request or response. Only the fields relevant to Type are set.
+type ContentBlock struct {
+	Type      string         `json:"type"`
+	Text      string         `json:"text,omitempty"`
+	Source    *ImageSource   `json:"source,omitempty"`
+	ID        string         `json:"id,omitempty"`
+	Name      string         `json:"name,omitempty"`
+	Input     map[string]any `json:"input,omitzero"`
+	ToolUseID string         `json:"tool_use_id,omitempty"`
+	Content   []ContentBlock `json:"content,omitempty"`
+	IsError   bool           `json:"is_error,omitzero"`
+	Thinking  string         `json:"thinking,omitempty"`
+	Signature string         `json:"signature,omitempty"`
+	Data      string         `json:"data,omitempty"` // Encrypted reasoning of redacted_thinking blocks
+	Title     string         `json:"title,omitempty"`
+	Citations []Citation     `json:"citations,omitempty"` // Sources of response text blocks
+
+	// EnableCitations asks the model to cite the document block, sending citations as
+	// {"enabled": true}
+	EnableCitations bool `json:"-"`
+
+	CacheControl *CacheControlParam `json:"cache_control,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, sending the citations setting of document blocks.
+func (b ContentBlock) MarshalJSON() ([]byte, error) {
+	type plain ContentBlock
+	if !b.EnableCitations {
+		return json.Marshal(plain(b))
+	}
+	return json.Marshal(struct {
+		plain
+		Citations map[string]bool `json:"citations"`
+	}{plain(b), map[string]bool{"enabled": true}})
+}
+
+// Citation is a source cited by a text block of a response: a passage of a document of the
+// request (char_location, page_location, content_block_location) or a web search result
+// (web_search_result_location).
+type Citation struct {
+	Type          string `json:"type"`
+	CitedText     string `json:"cited_text"`
+	DocumentIndex int    `json:"document_index"`
+	DocumentTitle string `json:"document_title"`
+	URL           string `json:"url"`
+	Title         string `json:"title"`
+}
+
+// CacheControlParam marks the end of a cached prompt prefix.
+type CacheControlParam struct {
+	Type string `json:"type"` // Always "ephemeral"
+	TTL  string `json:"ttl,omitempty"`
+}
+
+// ImageSource is the source of an image or document block: inline base64 data, a URL, or
+// plain text for text documents.
+type ImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// ToolParam describes a tool the model may call.
+type ToolParam struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+
+	CacheControl *CacheControlParam `json:"cache_control,omitempty"`
+}
+
+// ToolChoiceParam controls how the model uses tools.
+type ToolChoiceParam struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitzero"`
+}
+
+const (
+	blockTypeText       = "text"
+	blockTypeImage      = "image"
+	blockTypeToolUse    = "tool_use"
+	blockTypeToolResult = "tool_result"
+	blockTypeDocument   = "document"
+	blockTypeThinking   = "thinking"
+	blockTypeRedacted   = "redacted_thinking"
+
+	roleUser      = "user"
+	roleAssistant = "assistant"
+)
+
+// ToMessagesRequest converts unified chat params to a Messages API request.
+//
+// Anthropic has no native JSON schema response format, so ResponseFormatModeNative is served
+// by offering the hidden output tool; its call is turned back into text by FromMessagesResponse.
+func ToMessagesRequest(chatParams *types.ChatParams) (*MessagesRequest, error) {
+	if chatParams == nil {
+		return nil, errors.New("nil chatParams")
+	}
+	if chatParams.N != nil && *chatParams.N > 1 {
+		return nil, ErrMultipleChoicesNotSupported
+	}
+
+	request := &MessagesRequest{
+		Model:         chatParams.Model,
+		MaxTokens:     DefaultMaxTokens,
+		System:        chatParams.SystemPrompt,
+		Temperature:   chatParams.Temperature,
+		TopP:          chatParams.TopP,
+		TopK:          chatParams.TopK,
+		StopSequences: chatParams.Stop,
+
+		SystemCacheControl: ToCacheControl(chatParams.SystemCacheControl),
+	}
+
+	if chatParams.MaxTokens != nil {
+		request.MaxTokens = *chatParams.MaxTokens
+	}
+
+	messages, err := ToMessageParams(chatParams.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("ToMessageParams failed: %w", err)
+	}
+	request.Messages = messages
+
+	toolDefinitions := chatParams.Tools
+	rf := chatParams.ResponseFormat
+	if rf.Mode == types.ResponseFormatModeNative && rf.Schema != nil {
+		toolDefinitions = append(toolDefinitions[:len(toolDefinitions):len(toolDefinitions)], types.BuildOutputToolDefinition(rf))
+	}
+
+	if len(toolDefinitions) > 0 {
+		tools, err := ToToolParams(toolDefinitions)
+		if err != nil {
+			return nil, fmt.Errorf("ToToolParams failed: %w", err)
+		}
+		request.Tools = tools
+		if chatParams.ToolsCacheControl != nil {
+			request.Tools[len(tools)-1].CacheControl = ToCacheControl(chatParams.ToolsCacheControl)
+		}
+
+		if chatParams.ToolChoice != nil {
+			request.ToolChoice = ToToolChoice(chatParams.ToolChoice)
+		}
+
+		// Anthropic expresses this on the tool choice, which "none" does not accept
+		if parallel := chatParams.ParallelToolCalls; parallel != nil && !*parallel {
+			if request.ToolChoice == nil {
+				request.ToolChoice = ToToolChoice(nil)
+			}
+			if request.ToolChoice.Type != "none" {
+				request.ToolChoice.DisableParallelToolUse = true
+			}
+		}
+	}
+
+	return request, nil
+}
+
+// ToToolParams converts unified tool definitions to Anthropic tool parameters.
+func ToToolParams(toolDefinitions []types.ToolDefinition) ([]ToolParam, error) {
+	result := make([]ToolParam, 0, len(toolDefinitions))
+
+	for _, definition := range toolDefinitions {
+		if definition.InputSchema == nil {
+			return nil, fmt.Errorf("tool %s has nil input schema", definition.Name)
+		}
+		result = append(result, ToolParam{
+			Name:        definition.Name,
+			Description: definition.Description,
+			InputSchema: definition.InputSchema,
+		})
+	}
+
+	return result, nil
+}
+
+// ToCacheControl converts a unified cache control to an Anthropic cache control; nil stays nil.
+func ToCacheControl(cc *types.CacheControl) *CacheControlParam {
+	if cc == nil {
+		return nil
+	}
+	return &CacheControlParam{Type: "ephemeral", TTL: cc.TTL}
+}
+
+// ToToolChoice converts unified ToolChoice to an Anthropic tool choice.
+func ToToolChoice(toolChoice *types.ToolChoice) *ToolChoiceParam {
+	if toolChoice == nil {
+		return &ToolChoiceParam{Type: "auto"}
+	}
+
+	switch toolChoice.Mode {
+	case types.ToolChoiceModeNone:
+		return &ToolChoiceParam{Type: "none"}
+	case types.ToolChoiceModeRequired:
+		return &ToolChoiceParam{Type: "any"}
+	case types.ToolChoiceModeTool:
+		return &ToolChoiceParam{Type: "tool", Name: toolChoice.Name}
+	default:
+		return &ToolChoiceParam{Type: "auto"}
+	}
+}
@@ -0,0 +1,174 @@
+package anthropic
+
+import (
+	"encoding/json/v2"
+	"unicode/utf8"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// MessagesResponse is the response body of the Messages API.
+type MessagesResponse struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	Role       string         `json:"role"`
+	Model      string         `json:"model"`
+	Content    []ContentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      Usage          `json:"usage"`
+
+	StopSequence string `json:"stop_sequence"` // The stop sequence that ended the response
+}
+
+// Usage reports token counts for a request.
+type Usage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+}
+
+// FromMessagesResponse converts a Messages API response to the unified types.ChatResponse
+func FromMessagesResponse(response *MessagesResponse) *types.ChatResponse {
+	if response == nil {
+		return nil
+	}
+
+	return &types.ChatResponse{
+		ID:    response.ID,
+		Model: response.Model,
+		Choices: []types.Choice{{
+			Index:        0,
+			Message:      fromContentBlocks(response.Content),
+			FinishReason: FromStopReason(response.StopReason),
+		}},
+		Usage: FromUsage(&response.Usage),
+		Extra: fromResponseExtra(response),
+	}
+}
+
+// fromResponseExtra returns the fields of a response that types.ChatResponse has no place
+// for: the stop reason before mapping, and the stop sequence that was hit.
+func fromResponseExtra(response *MessagesResponse) map[string]any {
+	extra := map[string]any{"stop_reason": response.StopReason}
+	if response.StopSequence != "" {
+		extra["stop_sequence"] = response.StopSequence
+	}
+	return extra
+}
+
+// fromContentBlocks converts response content blocks to an assistant message
+func fromContentBlocks(blocks []ContentBlock) *types.Message {
+	message := &types.Message{
+		Role:        types.RoleAssistant,
+		ContentPart: make([]types.ContentPart, 0),
+		ToolCalls:   make([]types.ToolCall, 0),
+	}
+
+	textLen := 0 // Characters of text so far, where the citations of the next block start
+	for _, block := range blocks {
+		switch block.Type {
+		case blockTypeText:
+			message.ContentPart = append(message.ContentPart, types.NewContentPartText(block.Text))
+			end := textLen + utf8.RuneCountInString(block.Text)
+			for _, citation := range block.Citations {
+				message.Annotations = append(message.Annotations, FromCitation(citation, textLen, end))
+			}
+			textLen = end
+		case blockTypeThinking:
+			message.ContentPart = append(message.ContentPart, &types.ContentPartReasoning{Text: block.Thinking, Signature: block.Signature})
+		case blockTypeRedacted:
+			message.ContentPart = append(message.ContentPart, types.NewContentPartRedactedReasoning(block.Data))
+		case blockTypeToolUse:
+			args := block.Input
+			if args == nil {
+				args = map[string]any{}
+			}
+			message.ToolCalls = append(message.ToolCalls, types.ToolCall{
+				ID: block.ID,
+				Function: types.ToolFunction{
+					Name:      block.Name,
+					Arguments: args,
+				},
+			})
+		}
+	}
+
+	return message
+}
+
+// FromCitation converts a citation of the text block spanning the characters start to end
+// of the message text to a types.Annotation.
+func FromCitation(citation Citation, start, end int) types.Annotation {
+	if citation.Type == "web_search_result_location" {
+		return types.Annotation{
+			Type:       types.AnnotationURLCitation,
+			StartIndex: start,
+			EndIndex:   end,
+			URL:        citation.URL,
+			Title:      citation.Title,
+			CitedText:  citation.CitedText,
+		}
+	}
+	return types.Annotation{
+		Type:          types.AnnotationDocumentCitation,
+		StartIndex:    start,
+		EndIndex:      end,
+		Title:         citation.DocumentTitle,
+		DocumentIndex: citation.DocumentIndex,
+		CitedText:     citation.CitedText,
+	}
+}
+
+// FromStopReason maps Anthropic stop reasons to the OpenAI-style finish reasons used across adapters.
+func FromStopReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence", "pause_turn":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return reason
+	}
+}
+
+// FromUsage converts Anthropic usage to types.Usage. Anthropic counts cache reads and writes
+// separately from input_tokens; they are folded into PromptTokens to match other providers.
+func FromUsage(usage *Usage) *types.Usage {
+	if usage == nil {
+		return nil
+	}
+
+	prompt := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+	return &types.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      prompt + usage.OutputTokens,
+		PromptTokensDetails: types.PromptTokensDetails{
+			CachedTokens: usage.CacheReadInputTokens,
+		},
+	}
+}
+
+// unwrapNativeOutput replaces the message content with the arguments of a call to the hidden
+// output tool, so native-mode extraction reads the structured output as text. It reports
+// whether such a call was found.
+func unwrapNativeOutput(message *types.Message, outputTool string) (bool, error) {
+	for i, call := range message.ToolCalls {
+		if call.Function.Name != outputTool {
+			continue
+		}
+		content, err := json.Marshal(call.Function.Arguments)
+		if err != nil {
+			return false, err
+		}
+		message.ToolCalls = append(message.ToolCalls[:i], message.ToolCalls[i+1:]...)
+		message.ContentPart = []types.ContentPart{types.NewContentPartText(string(content))}
+		return true, nil
+	}
+	return false, nil
+}
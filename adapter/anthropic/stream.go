@@ -0,0 +1,274 @@
+package anthropic
+
+import (
+	"bufio"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// StreamEvent is a server-sent event of a streaming Messages API response.
+// Only the fields relevant to Type are set.
+type StreamEvent struct {
+	Type         string            `json:"type"`
+	Message      *MessagesResponse `json:"message,omitempty"`
+	Index        int               `json:"index"`
+	ContentBlock *ContentBlock     `json:"content_block,omitempty"`
+	Delta        *StreamDelta      `json:"delta,omitempty"`
+	Usage        *Usage            `json:"usage,omitempty"`
+	Error        *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// StreamDelta carries the incremental payload of content_block_delta and message_delta events.
+type StreamDelta struct {
+	Type        string    `json:"type"`
+	Text        string    `json:"text"`
+	PartialJSON string    `json:"partial_json"`
+	Thinking    string    `json:"thinking"`
+	Signature   string    `json:"signature"`
+	Citation    *Citation `json:"citation"`
+	StopReason  string    `json:"stop_reason"`
+
+	StopSequence string `json:"stop_sequence"`
+}
+
+// chatStream turns Messages API events into unified stream chunks.
+// Anthropic indexes content blocks across text and tool_use; tool calls are
+// renumbered so ToolCallDelta.Index counts tool calls only. The input of the
+// Native mode output tool is streamed as text instead, replacing the text before
+// it as in RawChat; that text is held back until the message ends without it.
+type chatStream struct {
+	reader *bufio.Reader
+	body   io.ReadCloser
+
+	outputTool  string // Name of the Native mode output tool ("" = none)
+	outputBlock int    // Index of the output tool's block (-1 = not started)
+	outputJSON  bool   // The output tool's input was streamed
+	heldText    strings.Builder
+
+	id          string
+	model       string
+	usage       Usage // Input counts from message_start
+	toolIndexes map[int]int
+
+	textLen    int                // Characters of text streamed so far
+	blockStart map[int]int        // Characters of text before each text block
+	citations  map[int][]Citation // Citations of each text block, sent when it stops
+}
+
+func newChatStream(body io.ReadCloser, outputTool string) *types.Stream {
+	s := &chatStream{
+		reader:      bufio.NewReader(body),
+		body:        body,
+		outputTool:  outputTool,
+		outputBlock: -1,
+		toolIndexes: make(map[int]int),
+		blockStart:  make(map[int]int),
+		citations:   make(map[int][]Citation),
+	}
+	return types.NewStream(s.next, s)
+}
+
+func (s *chatStream) next() (*types.StreamChunk, error) {
+	for {
+		data, err := s.readEvent()
+		if err != nil {
+			return nil, err
+		}
+
+		var event StreamEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, fmt.Errorf("anthropic stream: decode event: %w", err)
+		}
+
+		chunk, err := s.toChunk(&event)
+		if err != nil {
+			return nil, err
+		}
+		if chunk != nil {
+			if chunk.Extra == nil {
+				chunk.Extra = make(map[string]any)
+			}
+			chunk.Extra[types.ExtraRaw] = jsontext.Value(data)
+			return chunk, nil
+		}
+		if event.Type == "message_stop" {
+			return nil, io.EOF
+		}
+	}
+}
+
+// toChunk converts an event to a chunk; events without a unified equivalent return nil.
+func (s *chatStream) toChunk(event *StreamEvent) (*types.StreamChunk, error) {
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			s.id = event.Message.ID
+			s.model = event.Message.Model
+			s.usage = event.Message.Usage
+		}
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Role: types.RoleAssistant}}), nil
+
+	case "content_block_start":
+		if event.ContentBlock != nil && event.ContentBlock.Type == blockTypeText {
+			s.blockStart[event.Index] = s.textLen
+			return nil, nil
+		}
+		if event.ContentBlock != nil && event.ContentBlock.Type == blockTypeRedacted {
+			return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{RedactedReasoning: event.ContentBlock.Data}}), nil
+		}
+		if event.ContentBlock == nil || event.ContentBlock.Type != blockTypeToolUse {
+			return nil, nil
+		}
+		if s.outputTool != "" && event.ContentBlock.Name == s.outputTool && s.outputBlock < 0 {
+			s.outputBlock = event.Index
+			s.heldText.Reset()
+			return nil, nil
+		}
+		toolIndex := len(s.toolIndexes)
+		s.toolIndexes[event.Index] = toolIndex
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{
+			ToolCalls: []types.ToolCallDelta{{
+				Index:        toolIndex,
+				ID:           event.ContentBlock.ID,
+				FunctionName: event.ContentBlock.Name,
+			}},
+		}}), nil
+
+	case "content_block_delta":
+		if event.Delta == nil {
+			return nil, nil
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			s.textLen += utf8.RuneCountInString(event.Delta.Text)
+			if s.outputTool != "" {
+				if s.outputBlock < 0 {
+					s.heldText.WriteString(event.Delta.Text)
+				}
+				return nil, nil
+			}
+			return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Content: event.Delta.Text}}), nil
+		case "citations_delta":
+			if event.Delta.Citation != nil {
+				s.citations[event.Index] = append(s.citations[event.Index], *event.Delta.Citation)
+			}
+			return nil, nil
+		case "thinking_delta":
+			return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Reasoning: event.Delta.Thinking}}), nil
+		case "signature_delta":
+			return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{ReasoningSignature: event.Delta.Signature}}), nil
+		case "input_json_delta":
+			if event.Index == s.outputBlock {
+				if event.Delta.PartialJSON == "" {
+					return nil, nil
+				}
+				s.outputJSON = true
+				return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Content: event.Delta.PartialJSON}}), nil
+			}
+			toolIndex, ok := s.toolIndexes[event.Index]
+			if !ok || event.Delta.PartialJSON == "" {
+				return nil, nil
+			}
+			return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{
+				ToolCalls: []types.ToolCallDelta{{Index: toolIndex, Arguments: event.Delta.PartialJSON}},
+			}}), nil
+		}
+		return nil, nil
+
+	case "message_delta":
+		chunk := s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Content: s.heldText.String()}})
+		s.heldText.Reset()
+		if event.Delta != nil {
+			chunk.Choices[0].FinishReason = FromStopReason(event.Delta.StopReason)
+			if s.outputBlock >= 0 && event.Delta.StopReason == "tool_use" {
+				chunk.Choices[0].FinishReason = "stop" // Only the output tool was called
+			}
+			chunk.Extra = fromResponseExtra(&MessagesResponse{StopReason: event.Delta.StopReason, StopSequence: event.Delta.StopSequence})
+		}
+		if event.Usage != nil {
+			usage := s.usage
+			usage.OutputTokens = event.Usage.OutputTokens
+			chunk.Usage = FromUsage(&usage)
+		}
+		return chunk, nil
+
+	case "error":
+		apiErr := &APIError{Type: "error"}
+		if event.Error != nil {
+			apiErr.Type = event.Error.Type
+			apiErr.Message = event.Error.Message
+		}
+		return nil, apiErr
+
+	case "content_block_stop":
+		if event.Index == s.outputBlock && !s.outputJSON {
+			// The output tool was called without input
+			return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Content: "{}"}}), nil
+		}
+		citations := s.citations[event.Index]
+		if len(citations) == 0 {
+			return nil, nil
+		}
+		delete(s.citations, event.Index)
+		delta := &types.MessageDelta{}
+		for _, citation := range citations {
+			delta.Annotations = append(delta.Annotations, FromCitation(citation, s.blockStart[event.Index], s.textLen))
+		}
+		return s.chunk(types.StreamChoice{Delta: delta}), nil
+	}
+
+	// ping, message_stop
+	return nil, nil
+}
+
+func (s *chatStream) chunk(choice types.StreamChoice) *types.StreamChunk {
+	return &types.StreamChunk{
+		ID:      s.id,
+		Model:   s.model,
+		Choices: []types.StreamChoice{choice},
+	}
+}
+
+// readEvent returns the data payload of the next server-sent event.
+func (s *chatStream) readEvent() ([]byte, error) {
+	var data strings.Builder
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			if errors.Is(err, io.EOF) && data.Len() > 0 {
+				return []byte(data.String()), nil
+			}
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if data.Len() > 0 {
+				return []byte(data.String()), nil
+			}
+			continue
+		}
+
+		if payload, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(payload, " "))
+		}
+		// event:, id: and comment lines are ignored; the JSON payload carries its own type
+	}
+}
+
+func (s *chatStream) Close() error {
+	return s.body.Close()
+}
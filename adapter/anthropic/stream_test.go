@@ -0,0 +1,251 @@
+package anthropic
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+const sampleStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"usage":{"input_tokens":4,"cache_read_input_tokens":8,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"weather."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\": "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+func TestChatStream(t *testing.T) {
+	stream := newChatStream(io.NopCloser(strings.NewReader(sampleStream)), "")
+	defer stream.Close()
+
+	acc := types.NewMessageAccumulator()
+	var finishReason string
+	var usage *types.Usage
+	for stream.Next() {
+		chunk := stream.Chunk()
+		if chunk.ID != "msg_1" || chunk.Model != "claude-sonnet-4-5" {
+			t.Errorf("unexpected chunk metadata: %+v", chunk)
+		}
+		for _, choice := range chunk.Choices {
+			acc.Update(choice.Delta)
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+
+	msg, err := acc.Message()
+	if err != nil {
+		t.Fatalf("unexpected accumulator error: %v", err)
+	}
+	if msg.Role != types.RoleAssistant || msg.TextContent() != "Checking weather." {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID != "toolu_1" || msg.ToolCalls[0].Function.Arguments["city"] != "Paris" {
+		t.Errorf("unexpected tool calls: %+v", msg.ToolCalls)
+	}
+	if msg.ToolCalls[0].Function.Name != "weather" {
+		t.Errorf("unexpected tool name: %q", msg.ToolCalls[0].Function.Name)
+	}
+	if finishReason != "tool_calls" {
+		t.Errorf("expected finish reason tool_calls, got %q", finishReason)
+	}
+	if usage == nil || usage.PromptTokens != 12 || usage.CompletionTokens != 20 || usage.TotalTokens != 32 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+	if usage != nil && usage.PromptTokensDetails.CachedTokens != 8 {
+		t.Errorf("expected 8 cached tokens, got %+v", usage.PromptTokensDetails)
+	}
+}
+
+const thinkingStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"usage":{"input_tokens":4,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Two plus "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"two."}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-1"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"encrypted"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: content_block_start
+data: {"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"4"}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+func TestChatStreamNativeWithoutOutputTool(t *testing.T) {
+	// Text is held back until the message ends without the output tool, then kept
+	stream := newChatStream(io.NopCloser(strings.NewReader(sampleStream)), "_output")
+	defer stream.Close()
+
+	acc := types.NewMessageAccumulator()
+	var finishReason string
+	for stream.Next() {
+		choice := stream.Chunk().Choices[0]
+		acc.Update(choice.Delta)
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+		}
+	}
+	msg, err := acc.Message()
+	if err != nil || msg.TextContent() != "Checking weather." || msg.ToolCalls[0].Function.Name != "weather" {
+		t.Errorf("expected text and tool calls kept without the output tool, got %+v (err %v)", msg, err)
+	}
+	if finishReason != "tool_calls" {
+		t.Errorf("expected the tool_calls finish reason, got %q", finishReason)
+	}
+}
+
+func TestChatStreamThinking(t *testing.T) {
+	stream := newChatStream(io.NopCloser(strings.NewReader(thinkingStream)), "")
+	defer stream.Close()
+
+	acc := types.NewMessageAccumulator()
+	for stream.Next() {
+		for _, choice := range stream.Chunk().Choices {
+			acc.Update(choice.Delta)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+
+	msg, err := acc.Message()
+	if err != nil {
+		t.Fatalf("unexpected accumulator error: %v", err)
+	}
+	if len(msg.ContentPart) != 3 || msg.TextContent() != "4" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if r, ok := msg.ContentPart[0].(*types.ContentPartReasoning); !ok || r.Text != "Two plus two." || r.Signature != "sig-1" {
+		t.Errorf("unexpected thinking: %+v", msg.ContentPart[0])
+	}
+	if r, ok := msg.ContentPart[1].(*types.ContentPartReasoning); !ok || !r.Redacted || r.Text != "encrypted" {
+		t.Errorf("unexpected redacted thinking: %+v", msg.ContentPart[1])
+	}
+}
+
+const citationStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"usage":{"input_tokens":4,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Per the report, "}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"citations_delta","citation":{"type":"char_location","cited_text":"Revenue grew 12%.","document_index":0,"document_title":"Q3","start_char_index":0,"end_char_index":17}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"revenue grew."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+func TestChatStreamCitations(t *testing.T) {
+	stream := newChatStream(io.NopCloser(strings.NewReader(citationStream)), "")
+	defer stream.Close()
+
+	acc := types.NewMessageAccumulator()
+	for stream.Next() {
+		for _, choice := range stream.Chunk().Choices {
+			acc.Update(choice.Delta)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+
+	msg, err := acc.Message()
+	if err != nil {
+		t.Fatalf("unexpected accumulator error: %v", err)
+	}
+	want := []types.Annotation{{Type: types.AnnotationDocumentCitation, StartIndex: 16, EndIndex: 29, Title: "Q3", CitedText: "Revenue grew 12%."}}
+	if !reflect.DeepEqual(msg.Annotations, want) {
+		t.Errorf("Annotations = %+v, want %+v", msg.Annotations, want)
+	}
+}
+
+func TestChatStreamErrorEvent(t *testing.T) {
+	body := "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"
+	stream := newChatStream(io.NopCloser(strings.NewReader(body)), "")
+
+	if stream.Next() {
+		t.Fatal("expected no chunks")
+	}
+	apiErr, ok := stream.Err().(*APIError)
+	if !ok || apiErr.Type != "overloaded_error" {
+		t.Fatalf("expected overloaded APIError, got %v", stream.Err())
+	}
+}
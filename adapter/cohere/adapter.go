@@ -0,0 +1,250 @@
+package cohere
+
+import (
+	"bytes"
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+// DefaultBaseURL is the Cohere API endpoint used when no base URL is configured.
+const DefaultBaseURL = "https://api.cohere.com"
+
+// retryBaseDelay is the first backoff delay between retries; it doubles on each attempt.
+var retryBaseDelay = 500 * time.Millisecond
+
+// Client calls the Cohere v2 API and implements the unified chat interface
+type Client struct {
+	httpClient        *http.Client
+	baseURL           string
+	apiKey            string
+	headers           http.Header
+	maxRetries        int
+	perAttemptTimeout time.Duration
+	strictModels      bool
+}
+
+// NewClient creates a new Cohere client wrapped with ResponseFormat handling
+func NewClient(opts ...client.Option) types.Client {
+	cfg := client.NewConfig(opts...)
+	return types.NewClient(newRawClient(opts...), types.WithLogger(cfg.Logger), types.WithLogContent(cfg.LogContent), types.WithDocumentConverter(cfg.DocumentConverter), types.WithChunkTimeout(cfg.ChunkTimeout))
+}
+
+// newRawClient creates the raw Cohere client (internal)
+func newRawClient(opts ...client.Option) *Client {
+	cfg := client.DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c := &Client{
+		baseURL:           DefaultBaseURL,
+		apiKey:            cfg.APIKey,
+		headers:           cfg.Headers,
+		maxRetries:        cfg.MaxRetries,
+		perAttemptTimeout: cfg.PerAttemptTimeout,
+		strictModels:      cfg.StrictModelValidation,
+	}
+
+	if cfg.BaseURL != nil {
+		c.baseURL = strings.TrimSuffix(*cfg.BaseURL, "/")
+	}
+
+	// Http Client, only used if it isn't nil
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	// Total timeout is set on HTTP client
+	if cfg.TotalTimeout > 0 {
+		timed := *httpClient
+		timed.Timeout = cfg.TotalTimeout
+		httpClient = &timed
+	}
+	c.httpClient = httpClient
+
+	return c
+}
+
+// RawChat performs a non-streaming v2 Chat request
+func (c *Client) RawChat(ctx context.Context, params *types.ChatParams) (*types.ChatResponse, error) {
+	request, err := c.toChatRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.post(ctx, "/v2/chat", request, true)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("cohere chat: read response: %w", err)
+	}
+	var response ChatResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("cohere chat: decode response: %w", err)
+	}
+
+	chatResponse := FromChatResponse(&response)
+	chatResponse.Extra[types.ExtraRaw] = jsontext.Value(data)
+	return chatResponse, nil
+}
+
+// RawChatStream performs a streaming v2 Chat request and returns an iterator over chunks.
+func (c *Client) RawChatStream(ctx context.Context, params *types.ChatParams) (*types.Stream, error) {
+	request, err := c.toChatRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	request.Stream = true
+
+	body, err := c.post(ctx, "/v2/chat", request, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChatStream(body), nil
+}
+
+// RawEmbed performs a v2 Embed request
+func (c *Client) RawEmbed(ctx context.Context, params *types.EmbeddingParams) (*types.EmbeddingResponse, error) {
+	request, err := ToEmbedRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.post(ctx, "/v2/embed", request, true)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var response EmbedResponse
+	if err := json.UnmarshalRead(body, &response); err != nil {
+		return nil, fmt.Errorf("cohere embed: decode response: %w", err)
+	}
+
+	return FromEmbedResponse(params.Model, &response), nil
+}
+
+func (c *Client) toChatRequest(params *types.ChatParams) (*ChatRequest, error) {
+	if c.strictModels && params != nil {
+		if err := types.ValidateModel(AdapterName, params.Model); err != nil {
+			return nil, err
+		}
+	}
+	return ToChatRequest(params)
+}
+
+// post sends a JSON request to path, retrying rate limits and server errors.
+// On success the caller owns the returned body. When readBody is set the body is buffered
+// within the attempt so the per-attempt timeout covers it; streams are returned unread.
+func (c *Client) post(ctx context.Context, path string, request any, readBody bool) (io.ReadCloser, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("cohere: encode request: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		body, err := c.send(ctx, path, payload, readBody)
+		if err == nil {
+			return body, nil
+		}
+
+		var apiErr *APIError
+		if attempt >= c.maxRetries || ctx.Err() != nil || (errors.As(err, &apiErr) && !apiErr.retryable()) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryBaseDelay << attempt):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, path string, payload []byte, readBody bool) (io.ReadCloser, error) {
+	if readBody && c.perAttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.perAttemptTimeout)
+		defer cancel()
+	}
+
+	resp, err := c.do(ctx, path, payload)
+	if err != nil {
+		return nil, err
+	}
+	if !readBody {
+		return resp.Body, nil
+	}
+
+	// Read the body inside the attempt so the per-attempt timeout covers it
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// do sends a single request and converts non-2xx responses to *APIError
+func (c *Client) do(ctx context.Context, path string, payload []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	for key, values := range types.RequestHeaders(ctx) {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, decodeAPIError(resp)
+	}
+
+	return resp, nil
+}
+
+func decodeAPIError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode, RetryDelay: types.RetryAfter(resp.Header)}
+
+	var envelope struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.Message != "" {
+		apiErr.Message = envelope.Message
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+
+	return apiErr
+}
@@ -0,0 +1,128 @@
+package cohere
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestRawChat(t *testing.T) {
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/chat" || r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{
+			"id": "chat_1",
+			"finish_reason": "TOOL_CALL",
+			"message": {
+				"role": "assistant",
+				"tool_plan": "I will search for cats.",
+				"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "search", "arguments": "{\"q\":\"cats\"}"}}]
+			},
+			"usage": {"tokens": {"input_tokens": 12, "output_tokens": 8}}
+		}`))
+	})
+
+	c := NewClient(client.WithAPIKey("test-key"), client.WithBaseURL(url))
+	resp, err := c.Chat(context.Background(), &types.ChatParams{
+		Model:    "command-a-03-2025",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("Find cats"))},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || choice.Message.TextContent() != "I will search for cats." {
+		t.Errorf("unexpected choice: %+v", choice)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments["q"] != "cats" {
+		t.Errorf("unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+	if resp.Usage.TotalTokens != 20 {
+		t.Errorf("expected 20 total tokens, got %d", resp.Usage.TotalTokens)
+	}
+	if resp.Extra["finish_reason"] != "TOOL_CALL" || resp.Extra[types.ExtraRaw] == nil {
+		t.Errorf("expected the raw response and finish reason in Extra, got %v", resp.Extra)
+	}
+}
+
+func TestRawChatAPIError(t *testing.T) {
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"invalid api token"}`))
+	})
+
+	c := NewClient(client.WithBaseURL(url))
+	_, err := c.Chat(context.Background(), &types.ChatParams{Model: "command-a-03-2025"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "invalid api token" {
+		t.Fatalf("expected unauthorized APIError, got %v", err)
+	}
+}
+
+func TestRawEmbed(t *testing.T) {
+	var received EmbedRequest
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.UnmarshalRead(r.Body, &received); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{"id":"emb_1","embeddings":{"float":[[0.1,0.2],[0.3,0.4]]},"meta":{"billed_units":{"input_tokens":4}}}`))
+	})
+
+	c := NewClient(client.WithBaseURL(url))
+	resp, err := c.Embed(context.Background(), types.NewEmbeddingParams(
+		types.WithEmbeddingModel("embed-v4.0"),
+		types.WithInput([]string{"a", "b"}),
+		WithInputType(InputTypeClustering),
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if received.InputType != InputTypeClustering || received.EmbeddingTypes[0] != "float" {
+		t.Errorf("unexpected request: %+v", received)
+	}
+	if len(resp.Embeddings) != 2 || resp.Embeddings[1].Index != 1 || resp.Embeddings[1].Vector[1] != 0.4 {
+		t.Errorf("unexpected embeddings: %+v", resp.Embeddings)
+	}
+	if resp.Model != "embed-v4.0" || resp.Usage.PromptTokens != 4 {
+		t.Errorf("unexpected response metadata: %+v", resp)
+	}
+}
+
+func TestRerank(t *testing.T) {
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/rerank" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"id":"rr_1","results":[{"index":1,"relevance_score":0.9},{"index":0,"relevance_score":0.1}]}`))
+	})
+
+	topN := 2
+	resp, err := NewReranker(client.WithBaseURL(url)).Rerank(context.Background(), &RerankParams{
+		Model:     "rerank-v3.5",
+		Query:     "capital of France",
+		Documents: []string{"Berlin is in Germany", "Paris is the capital of France"},
+		TopN:      &topN,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].Index != 1 || resp.Results[0].RelevanceScore != 0.9 {
+		t.Errorf("unexpected results: %+v", resp.Results)
+	}
+}
@@ -0,0 +1,107 @@
+package cohere
+
+import (
+	"errors"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// InputType tells Cohere embedding models how the embeddings will be used.
+type InputType string
+
+const (
+	InputTypeSearchDocument InputType = "search_document"
+	InputTypeSearchQuery    InputType = "search_query"
+	InputTypeClassification InputType = "classification"
+	InputTypeClustering     InputType = "clustering"
+)
+
+// extraInputType is the EmbeddingParams.Extra key read for the input type.
+const extraInputType = "input_type"
+
+// WithInputType sets the Cohere embedding input type. Requests without one use InputTypeSearchDocument.
+func WithInputType(inputType InputType) types.EmbeddingParamsOption {
+	return func(e *types.EmbeddingParams) {
+		if e.Extra == nil {
+			e.Extra = make(map[string]any)
+		}
+		e.Extra[extraInputType] = inputType
+	}
+}
+
+// EmbedRequest is the request body of the v2 Embed API.
+type EmbedRequest struct {
+	Model           string    `json:"model"`
+	Texts           []string  `json:"texts"`
+	InputType       InputType `json:"input_type"`
+	EmbeddingTypes  []string  `json:"embedding_types"`
+	OutputDimension *int      `json:"output_dimension,omitempty"`
+}
+
+// EmbedResponse is the response body of the v2 Embed API.
+type EmbedResponse struct {
+	ID         string `json:"id"`
+	Embeddings struct {
+		Float [][]float64 `json:"float"`
+	} `json:"embeddings"`
+	Meta *Meta `json:"meta,omitempty"`
+}
+
+// Meta carries billing information for embed and rerank responses.
+type Meta struct {
+	BilledUnits *struct {
+		InputTokens float64 `json:"input_tokens"`
+		SearchUnits float64 `json:"search_units"`
+	} `json:"billed_units,omitempty"`
+}
+
+// ToEmbedRequest converts unified embedding params to a v2 Embed request.
+// Only float embeddings are requested, since types.Embedding holds float vectors.
+func ToEmbedRequest(embeddingParams *types.EmbeddingParams) (*EmbedRequest, error) {
+	if embeddingParams == nil {
+		return nil, errors.New("nil embeddingParams")
+	}
+	if embeddingParams.EncodingFormat != nil && *embeddingParams.EncodingFormat != types.EncodingFormatFloat {
+		return nil, errors.New("cohere embed: only float encoding is supported")
+	}
+
+	request := &EmbedRequest{
+		Model:           embeddingParams.Model,
+		Texts:           embeddingParams.Input,
+		InputType:       InputTypeSearchDocument,
+		EmbeddingTypes:  []string{"float"},
+		OutputDimension: embeddingParams.Dimensions,
+	}
+
+	switch v := embeddingParams.Extra[extraInputType].(type) {
+	case InputType:
+		request.InputType = v
+	case string:
+		request.InputType = InputType(v)
+	}
+
+	return request, nil
+}
+
+// FromEmbedResponse converts a v2 Embed response to the unified types.EmbeddingResponse
+func FromEmbedResponse(model string, response *EmbedResponse) *types.EmbeddingResponse {
+	if response == nil {
+		return nil
+	}
+
+	embeddings := make([]types.Embedding, len(response.Embeddings.Float))
+	for i, vector := range response.Embeddings.Float {
+		embeddings[i] = types.Embedding{Index: int64(i), Vector: vector, Object: "embedding"}
+	}
+
+	result := &types.EmbeddingResponse{
+		Model:      model,
+		Embeddings: embeddings,
+	}
+	if response.Meta != nil && response.Meta.BilledUnits != nil {
+		tokens := int64(response.Meta.BilledUnits.InputTokens)
+		result.Usage = &types.Usage{PromptTokens: tokens, TotalTokens: tokens}
+	}
+
+	return result
+}
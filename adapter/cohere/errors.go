@@ -0,0 +1,56 @@
+package cohere
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrUnsupportedMessageRole indicates that a message role is not supported by the adapter.
+	ErrUnsupportedMessageRole = errors.New("cohere chat: unsupported message role")
+
+	// ErrUnsupportedUserContentPart indicates that a user message includes content the adapter cannot convert.
+	ErrUnsupportedUserContentPart = errors.New("cohere chat: unsupported content part for user message")
+
+	// ErrUnsupportedAssistantContentPart indicates that an assistant message includes unsupported content.
+	ErrUnsupportedAssistantContentPart = errors.New("cohere chat: unsupported content part for assistant message")
+
+	// ErrUnsupportedToolContentPart indicates that a tool result message includes unsupported content.
+	ErrUnsupportedToolContentPart = errors.New("cohere chat: unsupported content part for tool message")
+
+	// ErrMissingToolCallID indicates that a tool result message is missing the required ToolCallID,
+	// or that an assistant message contains a tool call with an empty ID.
+	ErrMissingToolCallID = errors.New("cohere chat: tool message missing ToolCallID")
+
+	// ErrMultipleChoicesNotSupported is returned when ChatParams.N > 1; the API returns a single choice.
+	ErrMultipleChoicesNotSupported = errors.New("cohere chat: n > 1 is not supported")
+)
+
+// APIError is returned when the Cohere API responds with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	Message    string
+
+	// RetryDelay is the Retry-After the API sent with the error, zero if none.
+	RetryDelay time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("cohere api error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// retryable reports whether the request may succeed if sent again.
+func (e *APIError) retryable() bool {
+	return e.StatusCode == 429 || e.StatusCode >= 500
+}
+
+// Transient reports whether the request may succeed if sent again, see types.TransientError.
+func (e *APIError) Transient() bool {
+	return e.retryable()
+}
+
+// RetryAfter returns the delay the API asked for before retrying, zero if none.
+func (e *APIError) RetryAfter() time.Duration {
+	return e.RetryDelay
+}
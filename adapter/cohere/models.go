@@ -0,0 +1,22 @@
+package cohere
+
+import "github.com/KennyKeni/elysia/types"
+
+// AdapterName identifies this adapter in model validation errors.
+const AdapterName = "cohere"
+
+func init() {
+	types.RegisterModels(AdapterName, SupportedModels())
+}
+
+// SupportedModels returns the Cohere chat model names known to this adapter.
+func SupportedModels() []string {
+	return []string{
+		"command-a-03-2025",
+		"command-r7b-12-2024",
+		"command-r-plus-08-2024",
+		"command-r-08-2024",
+		"command-r-plus",
+		"command-r",
+	}
+}
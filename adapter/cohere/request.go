@@ -0,0 +1,301 @@
+package cohere
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ChatRequest is the request body of the v2 Chat API.
+type ChatRequest struct {
+	Model            string              `json:"model"`
+	Messages         []ChatMessage       `json:"messages"`
+	Tools            []Tool              `json:"tools,omitempty"`
+	ToolChoice       string              `json:"tool_choice,omitempty"`
+	MaxTokens        *int                `json:"max_tokens,omitempty"`
+	Temperature      *float64            `json:"temperature,omitempty"`
+	P                *float64            `json:"p,omitempty"`
+	K                *int                `json:"k,omitempty"`
+	StopSequences    []string            `json:"stop_sequences,omitempty"`
+	Seed             *int64              `json:"seed,omitempty"`
+	FrequencyPenalty *float64            `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64            `json:"presence_penalty,omitempty"`
+	ResponseFormat   *ResponseFormatSpec `json:"response_format,omitempty"`
+	Stream           bool                `json:"stream,omitzero"`
+}
+
+// ChatMessage is a message in a v2 Chat request or response.
+// Content is a string for system and tool messages and a []ContentItem otherwise.
+type ChatMessage struct {
+	Role       string     `json:"role"`
+	Content    any        `json:"content,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolPlan   string     `json:"tool_plan,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// ContentItem is a text or image item of message content.
+type ContentItem struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL references an image by URL or data URL.
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// ToolCall is a function call requested by the model. Arguments is a JSON string.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction is the name and JSON arguments of a tool call.
+type ToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// Tool describes a function the model may call.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is the definition of a callable function.
+type ToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// ResponseFormatSpec requests JSON output, optionally constrained by a schema.
+type ResponseFormatSpec struct {
+	Type       string         `json:"type"`
+	JSONSchema map[string]any `json:"json_schema,omitempty"`
+}
+
+const (
+	roleSystem    = "system"
+	roleUser      = "user"
+	roleAssistant = "assistant"
+	roleTool      = "tool"
+)
+
+// ToChatRequest converts unified chat params to a v2 Chat request.
+//
+// Cohere's tool_choice only supports REQUIRED and NONE, so ToolChoiceModeTool is sent as
+// REQUIRED with the tool list narrowed to the chosen tool.
+func ToChatRequest(chatParams *types.ChatParams) (*ChatRequest, error) {
+	if chatParams == nil {
+		return nil, errors.New("nil chatParams")
+	}
+	if chatParams.N != nil && *chatParams.N > 1 {
+		return nil, ErrMultipleChoicesNotSupported
+	}
+
+	request := &ChatRequest{
+		Model:            chatParams.Model,
+		MaxTokens:        chatParams.MaxTokens,
+		Temperature:      chatParams.Temperature,
+		P:                chatParams.TopP,
+		K:                chatParams.TopK,
+		StopSequences:    chatParams.Stop,
+		Seed:             chatParams.Seed,
+		FrequencyPenalty: chatParams.FrequencyPenalty,
+		PresencePenalty:  chatParams.PresencePenalty,
+	}
+
+	messages, err := ToChatMessages(chatParams.SystemPrompt, chatParams.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("ToChatMessages failed: %w", err)
+	}
+	request.Messages = messages
+
+	if len(chatParams.Tools) > 0 {
+		toolDefinitions := chatParams.Tools
+		if tc := chatParams.ToolChoice; tc != nil {
+			switch tc.Mode {
+			case types.ToolChoiceModeRequired:
+				request.ToolChoice = "REQUIRED"
+			case types.ToolChoiceModeNone:
+				request.ToolChoice = "NONE"
+			case types.ToolChoiceModeTool:
+				request.ToolChoice = "REQUIRED"
+				toolDefinitions = nil
+				for _, def := range chatParams.Tools {
+					if def.Name == tc.Name {
+						toolDefinitions = append(toolDefinitions, def)
+					}
+				}
+			}
+		}
+
+		tools, err := ToTools(toolDefinitions)
+		if err != nil {
+			return nil, fmt.Errorf("ToTools failed: %w", err)
+		}
+		request.Tools = tools
+	}
+
+	rf := chatParams.ResponseFormat
+	if rf.Mode == types.ResponseFormatModeNative && rf.Schema != nil {
+		request.ResponseFormat = &ResponseFormatSpec{Type: "json_object", JSONSchema: rf.Schema}
+	}
+	if rf.Mode == types.ResponseFormatModeJSON {
+		request.ResponseFormat = &ResponseFormatSpec{Type: "json_object"}
+	}
+
+	return request, nil
+}
+
+// ToTools converts unified tool definitions to Cohere tools.
+func ToTools(toolDefinitions []types.ToolDefinition) ([]Tool, error) {
+	result := make([]Tool, 0, len(toolDefinitions))
+
+	for _, definition := range toolDefinitions {
+		if definition.InputSchema == nil {
+			return nil, fmt.Errorf("tool %s has nil input schema", definition.Name)
+		}
+		result = append(result, Tool{
+			Type: "function",
+			Function: ToolFunction{
+				Name:        definition.Name,
+				Description: definition.Description,
+				Parameters:  definition.InputSchema,
+			},
+		})
+	}
+
+	return result, nil
+}
+
+// ToChatMessages converts unified messages to Cohere chat messages, prepending the system prompt.
+func ToChatMessages(systemPrompt string, messages []types.Message) ([]ChatMessage, error) {
+	result := make([]ChatMessage, 0, len(messages)+1)
+
+	if systemPrompt != "" {
+		result = append(result, ChatMessage{Role: roleSystem, Content: systemPrompt})
+	}
+
+	for _, message := range messages {
+		switch message.Role {
+		case types.RoleUser:
+			userMessage, err := toUserMessage(&message)
+			if err != nil {
+				return nil, fmt.Errorf("error converting message to user message: %w", err)
+			}
+			result = append(result, userMessage)
+		case types.RoleAssistant:
+			assistantMessage, err := toAssistantMessage(&message)
+			if err != nil {
+				return nil, fmt.Errorf("error converting message to assistant message: %w", err)
+			}
+			result = append(result, assistantMessage)
+		case types.RoleTool:
+			toolMessage, err := toToolMessage(&message)
+			if err != nil {
+				return nil, fmt.Errorf("error converting message to tool message: %w", err)
+			}
+			result = append(result, toolMessage)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedMessageRole, message.Role)
+		}
+	}
+
+	return result, nil
+}
+
+// toUserMessage converts a user message to a Cohere user message
+func toUserMessage(message *types.Message) (ChatMessage, error) {
+	content := make([]ContentItem, 0, len(message.ContentPart))
+
+	for _, contentPart := range message.ContentPart {
+		switch part := contentPart.(type) {
+		case *types.ContentPartText:
+			content = append(content, ContentItem{Type: "text", Text: part.Text})
+		case *types.ContentPartImage:
+			mimeType := part.MIMEType
+			if mimeType == "" {
+				mimeType = "image/png"
+			}
+			content = append(content, ContentItem{
+				Type:     "image_url",
+				ImageURL: &ImageURL{URL: fmt.Sprintf("data:%s;base64,%s", mimeType, part.Data)},
+			})
+		case *types.ContentPartImageURL:
+			content = append(content, ContentItem{Type: "image_url", ImageURL: &ImageURL{URL: part.URL}})
+		default:
+			return ChatMessage{}, &types.UnsupportedContentError{Part: part, Err: ErrUnsupportedUserContentPart}
+		}
+	}
+
+	return ChatMessage{Role: roleUser, Content: content}, nil
+}
+
+// toAssistantMessage converts an assistant message to a Cohere assistant message.
+// When tool calls are present the text is sent as the tool plan, mirroring how Cohere returns it.
+func toAssistantMessage(message *types.Message) (ChatMessage, error) {
+	for _, contentPart := range message.ContentPart {
+		switch contentPart.(type) {
+		case *types.ContentPartText, *types.ContentPartReasoning:
+			// Reasoning is not sent back; TextContent below ignores it
+		default:
+			return ChatMessage{}, &types.UnsupportedContentError{Part: contentPart, Err: ErrUnsupportedAssistantContentPart}
+		}
+	}
+	text := message.TextContent()
+
+	if len(message.ToolCalls) == 0 {
+		return ChatMessage{Role: roleAssistant, Content: []ContentItem{{Type: "text", Text: text}}}, nil
+	}
+
+	toolCalls := make([]ToolCall, 0, len(message.ToolCalls))
+	for _, toolCall := range message.ToolCalls {
+		if toolCall.ID == "" {
+			return ChatMessage{}, fmt.Errorf("%w: tool call %q has empty ID", ErrMissingToolCallID, toolCall.Function.Name)
+		}
+		args := toolCall.Function.Arguments
+		if args == nil {
+			args = map[string]any{}
+		}
+		argsJSON, err := json.Marshal(args)
+		if err != nil {
+			return ChatMessage{}, fmt.Errorf("failed to marshal tool call arguments: %w", err)
+		}
+		toolCalls = append(toolCalls, ToolCall{
+			ID:   toolCall.ID,
+			Type: "function",
+			Function: ToolCallFunction{
+				Name:      toolCall.Function.Name,
+				Arguments: string(argsJSON),
+			},
+		})
+	}
+
+	return ChatMessage{Role: roleAssistant, ToolCalls: toolCalls, ToolPlan: text}, nil
+}
+
+// toToolMessage converts a tool result message to a Cohere tool message
+func toToolMessage(message *types.Message) (ChatMessage, error) {
+	for _, contentPart := range message.ContentPart {
+		if _, ok := contentPart.(*types.ContentPartText); !ok {
+			return ChatMessage{}, &types.UnsupportedContentError{Part: contentPart, Err: ErrUnsupportedToolContentPart}
+		}
+	}
+
+	if message.ToolCallID == nil {
+		return ChatMessage{}, ErrMissingToolCallID
+	}
+
+	return ChatMessage{
+		Role:       roleTool,
+		Content:    message.TextContent(),
+		ToolCallID: *message.ToolCallID,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package cohere
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+func TestToChatRequest(t *testing.T) {
+	callID := "call_1"
+	params := &types.ChatParams{
+		Model:        "command-a-03-2025",
+		SystemPrompt: "Be brief.",
+		Messages: []types.Message{
+			types.NewUserMessage(types.WithText("Weather in Paris?")),
+			types.NewAssistantMessage(
+				types.WithText("I will look up the weather."),
+				types.WithToolCalls(types.ToolCall{ID: callID, Function: types.ToolFunction{Name: "weather", Arguments: map[string]any{"city": "Paris"}}}),
+			),
+			types.NewToolMessage(types.WithText("sunny"), types.WithToolCallID(callID)),
+		},
+		Tools: []types.ToolDefinition{
+			{Name: "weather", InputSchema: map[string]any{"type": "object"}},
+			{Name: "time", InputSchema: map[string]any{"type": "object"}},
+		},
+		ToolChoice: types.ToolChoiceToolWithName("weather"),
+	}
+	types.WithSeed(42)(params)
+
+	request, err := ToChatRequest(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if request.Seed == nil || *request.Seed != 42 {
+		t.Errorf("expected seed 42, got %v", request.Seed)
+	}
+
+	if len(request.Messages) != 4 || request.Messages[0].Role != roleSystem || request.Messages[0].Content != "Be brief." {
+		t.Fatalf("unexpected messages: %+v", request.Messages)
+	}
+
+	assistant := request.Messages[2]
+	if assistant.ToolPlan != "I will look up the weather." || assistant.Content != nil {
+		t.Errorf("expected text to become the tool plan, got %+v", assistant)
+	}
+	if call := assistant.ToolCalls[0]; call.ID != callID || call.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+
+	tool := request.Messages[3]
+	if tool.Role != roleTool || tool.ToolCallID != callID || tool.Content != "sunny" {
+		t.Errorf("unexpected tool message: %+v", tool)
+	}
+
+	if request.ToolChoice != "REQUIRED" || len(request.Tools) != 1 || request.Tools[0].Function.Name != "weather" {
+		t.Errorf("expected forced tool to narrow tools and require a call, got %q %+v", request.ToolChoice, request.Tools)
+	}
+}
+
+func TestToChatRequestNativeResponseFormat(t *testing.T) {
+	schema := map[string]any{"type": "object"}
+	request, err := ToChatRequest(&types.ChatParams{
+		Model:          "command-a-03-2025",
+		ResponseFormat: types.ResponseFormat{Mode: types.ResponseFormatModeNative, Schema: schema},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request.ResponseFormat == nil || request.ResponseFormat.Type != "json_object" || request.ResponseFormat.JSONSchema["type"] != "object" {
+		t.Errorf("unexpected response format: %+v", request.ResponseFormat)
+	}
+}
+
+func TestToChatMessagesErrors(t *testing.T) {
+	if _, err := ToChatMessages("", []types.Message{{Role: "unknown"}}); !errors.Is(err, ErrUnsupportedMessageRole) {
+		t.Errorf("expected ErrUnsupportedMessageRole, got %v", err)
+	}
+	if _, err := ToChatMessages("", []types.Message{types.NewToolMessage(types.WithText("x"))}); !errors.Is(err, ErrMissingToolCallID) {
+		t.Errorf("expected ErrMissingToolCallID, got %v", err)
+	}
+
+	file := types.NewUserMessage(types.WithFile(types.NewContentPartFileData("JVBERi0=", "report.pdf")))
+	var unsupported *types.UnsupportedContentError
+	if _, err := ToChatMessages("", []types.Message{file}); !errors.As(err, &unsupported) {
+		t.Errorf("expected UnsupportedContentError, got %v", err)
+	}
+}
+
+func TestToEmbedRequest(t *testing.T) {
+	params := types.NewEmbeddingParams(
+		types.WithEmbeddingModel("embed-v4.0"),
+		types.WithStringInput("hello"),
+		WithInputType(InputTypeSearchQuery),
+	)
+
+	request, err := ToEmbedRequest(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request.InputType != InputTypeSearchQuery || request.Texts[0] != "hello" {
+		t.Errorf("unexpected request: %+v", request)
+	}
+
+	request, _ = ToEmbedRequest(types.NewEmbeddingParams(types.WithStringInput("doc")))
+	if request.InputType != InputTypeSearchDocument {
+		t.Errorf("expected default input type search_document, got %q", request.InputType)
+	}
+
+	base64 := types.EncodingFormatBase64
+	if _, err := ToEmbedRequest(&types.EmbeddingParams{EncodingFormat: &base64}); err == nil {
+		t.Error("expected error for base64 encoding")
+	}
+}
@@ -0,0 +1,72 @@
+package cohere
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/client"
+)
+
+// RerankParams are the parameters of a rerank request.
+type RerankParams struct {
+	Model     string
+	Query     string
+	Documents []string
+	TopN      *int // Number of results to return (nil = all documents)
+}
+
+// RerankResult is a document's position in the input and its relevance to the query.
+type RerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+}
+
+// RerankResponse holds results ordered from most to least relevant.
+type RerankResponse struct {
+	ID      string         `json:"id"`
+	Results []RerankResult `json:"results"`
+	Meta    *Meta          `json:"meta,omitempty"`
+}
+
+type rerankRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      *int     `json:"top_n,omitempty"`
+}
+
+// Reranker orders documents by relevance to a query using the v2 Rerank API.
+type Reranker struct {
+	c *Client
+}
+
+// NewReranker creates a rerank client; it accepts the same options as NewClient.
+func NewReranker(opts ...client.Option) *Reranker {
+	return &Reranker{c: newRawClient(opts...)}
+}
+
+// Rerank scores params.Documents against params.Query.
+func (r *Reranker) Rerank(ctx context.Context, params *RerankParams) (*RerankResponse, error) {
+	if params == nil {
+		return nil, errors.New("nil rerankParams")
+	}
+
+	body, err := r.c.post(ctx, "/v2/rerank", rerankRequest{
+		Model:     params.Model,
+		Query:     params.Query,
+		Documents: params.Documents,
+		TopN:      params.TopN,
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var response RerankResponse
+	if err := json.UnmarshalRead(body, &response); err != nil {
+		return nil, fmt.Errorf("cohere rerank: decode response: %w", err)
+	}
+	return &response, nil
+}
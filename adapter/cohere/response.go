@@ -0,0 +1,121 @@
+package cohere
+
+import (
+	"strings"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ChatResponse is the response body of the v2 Chat API.
+type ChatResponse struct {
+	ID           string          `json:"id"`
+	FinishReason string          `json:"finish_reason"`
+	Message      ResponseMessage `json:"message"`
+	Usage        *Usage          `json:"usage,omitempty"`
+}
+
+// ResponseMessage is the assistant message of a chat response.
+type ResponseMessage struct {
+	Role      string        `json:"role"`
+	Content   []ContentItem `json:"content"`
+	ToolPlan  string        `json:"tool_plan"`
+	ToolCalls []ToolCall    `json:"tool_calls"`
+}
+
+// Usage reports billed and actual token counts.
+type Usage struct {
+	BilledUnits *TokenCounts `json:"billed_units,omitempty"`
+	Tokens      *TokenCounts `json:"tokens,omitempty"`
+}
+
+// TokenCounts holds input and output token counts.
+type TokenCounts struct {
+	InputTokens  float64 `json:"input_tokens"`
+	OutputTokens float64 `json:"output_tokens"`
+}
+
+// FromChatResponse converts a v2 Chat response to the unified types.ChatResponse
+func FromChatResponse(response *ChatResponse) *types.ChatResponse {
+	if response == nil {
+		return nil
+	}
+
+	return &types.ChatResponse{
+		ID: response.ID,
+		Choices: []types.Choice{{
+			Index:        0,
+			Message:      fromResponseMessage(&response.Message),
+			FinishReason: FromFinishReason(response.FinishReason),
+		}},
+		Usage: FromUsage(response.Usage),
+		Extra: map[string]any{"finish_reason": response.FinishReason},
+	}
+}
+
+// fromResponseMessage converts a Cohere assistant message. The tool plan becomes the
+// text content when the model calls tools without other text.
+func fromResponseMessage(msg *ResponseMessage) *types.Message {
+	message := &types.Message{
+		Role:        types.RoleAssistant,
+		ContentPart: make([]types.ContentPart, 0),
+		ToolCalls:   make([]types.ToolCall, 0),
+	}
+
+	var text strings.Builder
+	for _, item := range msg.Content {
+		if item.Type == "text" {
+			text.WriteString(item.Text)
+		}
+	}
+	if text.Len() == 0 && msg.ToolPlan != "" {
+		text.WriteString(msg.ToolPlan)
+	}
+	if text.Len() > 0 {
+		message.ContentPart = append(message.ContentPart, types.NewContentPartText(text.String()))
+	}
+
+	for _, toolCall := range msg.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, types.ToolCall{
+			ID:       toolCall.ID,
+			Function: types.NewToolFunction(toolCall.Function.Name, toolCall.Function.Arguments),
+		})
+	}
+
+	return message
+}
+
+// FromFinishReason maps Cohere finish reasons to the OpenAI-style finish reasons used across adapters.
+func FromFinishReason(reason string) string {
+	switch reason {
+	case "COMPLETE", "STOP_SEQUENCE":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "TOOL_CALL":
+		return "tool_calls"
+	default:
+		return strings.ToLower(reason)
+	}
+}
+
+// FromUsage converts Cohere usage to types.Usage, preferring actual over billed token counts
+func FromUsage(usage *Usage) *types.Usage {
+	if usage == nil {
+		return nil
+	}
+
+	counts := usage.Tokens
+	if counts == nil {
+		counts = usage.BilledUnits
+	}
+	if counts == nil {
+		return nil
+	}
+
+	input, output := int64(counts.InputTokens), int64(counts.OutputTokens)
+	return &types.Usage{
+		PromptTokens:     input,
+		CompletionTokens: output,
+		TotalTokens:      input + output,
+	}
+}
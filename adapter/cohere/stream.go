@@ -0,0 +1,165 @@
+package cohere
+
+import (
+	"bufio"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// StreamEvent is a server-sent event of a streaming v2 Chat response.
+type StreamEvent struct {
+	Type  string       `json:"type"`
+	ID    string       `json:"id"`
+	Index int          `json:"index"`
+	Delta *StreamDelta `json:"delta,omitempty"`
+}
+
+// StreamDelta carries the incremental payload of an event.
+type StreamDelta struct {
+	Message *struct {
+		Role string `json:"role"`
+		// Content and ToolCalls are arrays in message-start and single objects in deltas
+		Content   jsontext.Value `json:"content,omitempty"`
+		ToolPlan  string         `json:"tool_plan"`
+		ToolCalls jsontext.Value `json:"tool_calls,omitempty"`
+	} `json:"message,omitempty"`
+	FinishReason string `json:"finish_reason"`
+	Usage        *Usage `json:"usage,omitempty"`
+}
+
+// chatStream turns v2 Chat events into unified stream chunks.
+type chatStream struct {
+	reader *bufio.Reader
+	body   io.ReadCloser
+	id     string
+}
+
+func newChatStream(body io.ReadCloser) *types.Stream {
+	s := &chatStream{reader: bufio.NewReader(body), body: body}
+	return types.NewStream(s.next, s)
+}
+
+func (s *chatStream) next() (*types.StreamChunk, error) {
+	for {
+		data, err := s.readEvent()
+		if err != nil {
+			return nil, err
+		}
+
+		var event StreamEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, fmt.Errorf("cohere stream: decode event: %w", err)
+		}
+
+		if chunk := s.toChunk(&event); chunk != nil {
+			if chunk.Extra == nil {
+				chunk.Extra = make(map[string]any)
+			}
+			chunk.Extra[types.ExtraRaw] = jsontext.Value(data)
+			return chunk, nil
+		}
+		if event.Type == "message-end" {
+			return nil, io.EOF
+		}
+	}
+}
+
+// toChunk converts an event to a chunk; events without a unified equivalent return nil.
+func (s *chatStream) toChunk(event *StreamEvent) *types.StreamChunk {
+	if event.Type == "message-start" {
+		s.id = event.ID
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Role: types.RoleAssistant}})
+	}
+
+	delta := event.Delta
+	if delta == nil {
+		return nil
+	}
+
+	switch event.Type {
+	case "content-delta":
+		var content ContentItem
+		if delta.Message == nil || json.Unmarshal(delta.Message.Content, &content) != nil {
+			return nil
+		}
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Content: content.Text}})
+
+	case "tool-plan-delta":
+		if delta.Message == nil || delta.Message.ToolPlan == "" {
+			return nil
+		}
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Content: delta.Message.ToolPlan}})
+
+	case "tool-call-start", "tool-call-delta":
+		var call ToolCall
+		if delta.Message == nil || json.Unmarshal(delta.Message.ToolCalls, &call) != nil {
+			return nil
+		}
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{
+			ToolCalls: []types.ToolCallDelta{{
+				Index:        event.Index,
+				ID:           call.ID,
+				FunctionName: call.Function.Name,
+				Arguments:    call.Function.Arguments,
+			}},
+		}})
+
+	case "message-end":
+		chunk := s.chunk(types.StreamChoice{
+			Delta:        &types.MessageDelta{},
+			FinishReason: FromFinishReason(delta.FinishReason),
+		})
+		chunk.Usage = FromUsage(delta.Usage)
+		chunk.Extra = map[string]any{"finish_reason": delta.FinishReason}
+		return chunk
+	}
+
+	// content-start, content-end, tool-call-end, citation events
+	return nil
+}
+
+func (s *chatStream) chunk(choice types.StreamChoice) *types.StreamChunk {
+	return &types.StreamChunk{
+		ID:      s.id,
+		Choices: []types.StreamChoice{choice},
+	}
+}
+
+// readEvent returns the data payload of the next server-sent event.
+func (s *chatStream) readEvent() ([]byte, error) {
+	var data strings.Builder
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			if errors.Is(err, io.EOF) && data.Len() > 0 {
+				return []byte(data.String()), nil
+			}
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if data.Len() > 0 {
+				return []byte(data.String()), nil
+			}
+			continue
+		}
+
+		if payload, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(payload, " "))
+		}
+	}
+}
+
+func (s *chatStream) Close() error {
+	return s.body.Close()
+}
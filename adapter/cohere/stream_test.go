@@ -0,0 +1,79 @@
+package cohere
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+const sampleStream = `event: message-start
+data: {"id":"chat_1","type":"message-start","delta":{"message":{"role":"assistant","content":[],"tool_plan":"","tool_calls":[],"citations":[]}}}
+
+event: tool-plan-delta
+data: {"type":"tool-plan-delta","delta":{"message":{"tool_plan":"I will check "}}}
+
+event: tool-plan-delta
+data: {"type":"tool-plan-delta","delta":{"message":{"tool_plan":"the weather."}}}
+
+event: tool-call-start
+data: {"type":"tool-call-start","index":0,"delta":{"message":{"tool_calls":{"id":"call_1","type":"function","function":{"name":"weather","arguments":""}}}}}
+
+event: tool-call-delta
+data: {"type":"tool-call-delta","index":0,"delta":{"message":{"tool_calls":{"function":{"arguments":"{\"city\":"}}}}}
+
+event: tool-call-delta
+data: {"type":"tool-call-delta","index":0,"delta":{"message":{"tool_calls":{"function":{"arguments":"\"Paris\"}"}}}}}
+
+event: tool-call-end
+data: {"type":"tool-call-end","index":0}
+
+event: message-end
+data: {"type":"message-end","delta":{"finish_reason":"TOOL_CALL","usage":{"billed_units":{"input_tokens":10,"output_tokens":5},"tokens":{"input_tokens":100,"output_tokens":20}}}}
+
+`
+
+func TestChatStream(t *testing.T) {
+	stream := newChatStream(io.NopCloser(strings.NewReader(sampleStream)))
+	defer stream.Close()
+
+	acc := types.NewMessageAccumulator()
+	var finishReason string
+	var usage *types.Usage
+	for stream.Next() {
+		chunk := stream.Chunk()
+		if chunk.ID != "chat_1" {
+			t.Errorf("unexpected chunk ID: %q", chunk.ID)
+		}
+		for _, choice := range chunk.Choices {
+			acc.Update(choice.Delta)
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+
+	msg, err := acc.Message()
+	if err != nil {
+		t.Fatalf("unexpected accumulator error: %v", err)
+	}
+	if msg.TextContent() != "I will check the weather." {
+		t.Errorf("unexpected text: %q", msg.TextContent())
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Name != "weather" || msg.ToolCalls[0].Function.Arguments["city"] != "Paris" {
+		t.Errorf("unexpected tool calls: %+v", msg.ToolCalls)
+	}
+	if finishReason != "tool_calls" {
+		t.Errorf("expected finish reason tool_calls, got %q", finishReason)
+	}
+	if usage == nil || usage.PromptTokens != 100 || usage.CompletionTokens != 20 {
+		t.Errorf("expected actual token usage, got %+v", usage)
+	}
+}
@@ -0,0 +1,81 @@
+// Package deepseek adapts the DeepSeek API, which follows the OpenAI Chat Completions format.
+//
+// deepseek-reasoner returns its chain of thought in reasoning_content; it is surfaced as a
+// types.ContentPartReasoning ahead of the answer (or as MessageDelta.Reasoning when streaming)
+// and is never sent back, as the API rejects it in input messages.
+package deepseek
+
+import (
+	"context"
+
+	"github.com/KennyKeni/elysia/adapter/openai"
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+// DefaultBaseURL is the DeepSeek API endpoint used when no base URL is configured.
+const DefaultBaseURL = "https://api.deepseek.com"
+
+// Client calls the DeepSeek API and implements the unified chat interface
+type Client struct {
+	raw          types.RawClient
+	strictModels bool
+}
+
+// NewClient creates a new DeepSeek client wrapped with ResponseFormat handling
+func NewClient(opts ...client.Option) types.Client {
+	cfg := client.NewConfig(opts...)
+	return types.NewClient(newRawClient(opts...), types.WithLogger(cfg.Logger), types.WithLogContent(cfg.LogContent), types.WithDocumentConverter(cfg.DocumentConverter), types.WithChunkTimeout(cfg.ChunkTimeout))
+}
+
+// newRawClient creates the raw DeepSeek client (internal)
+func newRawClient(opts ...client.Option) *Client {
+	cfg := client.DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	openaiOpts := append([]client.Option{client.WithBaseURL(DefaultBaseURL)}, opts...)
+	// Models are validated here against the DeepSeek list, not the OpenAI one
+	openaiOpts = append(openaiOpts, func(c *client.Config) { c.StrictModelValidation = false })
+
+	return &Client{
+		raw:          openai.NewRawClient(openaiOpts...),
+		strictModels: cfg.StrictModelValidation,
+	}
+}
+
+// SupportsResponseFormat reports whether mode is supported. DeepSeek only offers untyped JSON
+// output (JSON mode), so the Native response format falls back to Tool mode.
+func (c *Client) SupportsResponseFormat(mode types.ResponseFormatMode) bool {
+	return mode != types.ResponseFormatModeNative
+}
+
+// RawChat performs a non-streaming chat completion request
+func (c *Client) RawChat(ctx context.Context, params *types.ChatParams) (*types.ChatResponse, error) {
+	if err := c.validateModel(params); err != nil {
+		return nil, err
+	}
+	return c.raw.RawChat(ctx, params)
+}
+
+// RawChatStream performs a streaming chat completion request and returns an iterator over chunks.
+func (c *Client) RawChatStream(ctx context.Context, params *types.ChatParams) (*types.Stream, error) {
+	if err := c.validateModel(params); err != nil {
+		return nil, err
+	}
+	return c.raw.RawChatStream(ctx, params)
+}
+
+// RawEmbed is not supported by DeepSeek
+func (c *Client) RawEmbed(ctx context.Context, params *types.EmbeddingParams) (*types.EmbeddingResponse, error) {
+	return nil, ErrEmbeddingsNotSupported
+}
+
+// validateModel checks the requested model against SupportedModels when strict validation is enabled
+func (c *Client) validateModel(params *types.ChatParams) error {
+	if !c.strictModels || params == nil {
+		return nil
+	}
+	return types.ValidateModel(AdapterName, params.Model)
+}
@@ -0,0 +1,143 @@
+package deepseek
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KennyKeni/elysia/adapter/openai"
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+const reasonerCompletion = `{"id":"c1","object":"chat.completion","created":1,"model":"deepseek-reasoner",
+"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant",
+"reasoning_content":"9.11 < 9.8 because 0.11 < 0.8.","content":"9.8 is larger."}}]}`
+
+const reasonerStream = `data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"deepseek-reasoner","choices":[{"index":0,"delta":{"role":"assistant","content":null,"reasoning_content":"Compare "}}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"deepseek-reasoner","choices":[{"index":0,"delta":{"content":null,"reasoning_content":"decimals."}}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"deepseek-reasoner","choices":[{"index":0,"delta":{"content":"9.8","reasoning_content":null},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+
+func newTestServer(t *testing.T, contentType, response string) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		requests = append(requests, body)
+		w.Header().Set("Content-Type", contentType)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestChatReasoningContent(t *testing.T) {
+	server, requests := newTestServer(t, "application/json", reasonerCompletion)
+	c := NewClient(client.WithBaseURL(server.URL), client.WithAPIKey("test"))
+
+	history := []types.Message{
+		types.NewUserMessage(types.WithText("Which is larger?")),
+		{Role: types.RoleAssistant, ContentPart: []types.ContentPart{
+			types.NewContentPartReasoning("earlier thoughts"),
+			types.NewContentPartText("earlier answer"),
+		}},
+		types.NewUserMessage(types.WithText("9.11 or 9.8?")),
+	}
+	resp, err := c.Chat(t.Context(), &types.ChatParams{Model: ModelReasoner, Messages: history})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	msg := resp.Choices[0].Message
+	if got := msg.ReasoningContent(); got != "9.11 < 9.8 because 0.11 < 0.8." {
+		t.Errorf("reasoning = %q", got)
+	}
+	if got := msg.TextContent(); got != "9.8 is larger." {
+		t.Errorf("text = %q", got)
+	}
+
+	// Reasoning from history must not be sent back
+	sent, _ := json.Marshal((*requests)[0]["messages"])
+	if strings.Contains(string(sent), "earlier thoughts") {
+		t.Errorf("reasoning was sent back: %s", sent)
+	}
+}
+
+func TestChatStreamReasoningContent(t *testing.T) {
+	server, _ := newTestServer(t, "text/event-stream", reasonerStream)
+	c := NewClient(client.WithBaseURL(server.URL))
+
+	var reasoning strings.Builder
+	resp, err := types.StreamWithHandler(t.Context(), c, &types.ChatParams{
+		Model:    ModelReasoner,
+		Messages: []types.Message{types.NewUserMessage(types.WithText("9.11 or 9.8?"))},
+	}, func(chunk *types.StreamChunk) error {
+		for _, choice := range chunk.Choices {
+			if choice.Delta != nil {
+				reasoning.WriteString(choice.Delta.Reasoning)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+
+	if reasoning.String() != "Compare decimals." {
+		t.Errorf("streamed reasoning = %q", reasoning.String())
+	}
+	msg := resp.Choices[0].Message
+	if msg.ReasoningContent() != "Compare decimals." || msg.TextContent() != "9.8" {
+		t.Errorf("unexpected message: reasoning %q, text %q", msg.ReasoningContent(), msg.TextContent())
+	}
+}
+
+func TestStrictModelValidation(t *testing.T) {
+	server, _ := newTestServer(t, "application/json", reasonerCompletion)
+	c := NewClient(client.WithBaseURL(server.URL), client.WithStrictModelValidation())
+
+	params := &types.ChatParams{Model: ModelReasoner, Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))}}
+	if _, err := c.Chat(t.Context(), params); err != nil {
+		t.Fatalf("DeepSeek model rejected: %v", err)
+	}
+
+	params.Model = "gpt-4o"
+	var unknown *types.ErrUnknownModel
+	if _, err := c.Chat(t.Context(), params); !errors.As(err, &unknown) || unknown.Adapter != AdapterName {
+		t.Fatalf("expected ErrUnknownModel for %s, got %v", AdapterName, err)
+	}
+}
+
+func TestGlobalOpenAIModelValidation(t *testing.T) {
+	openai.SetStrictModelValidation(true)
+	defer openai.SetStrictModelValidation(false)
+
+	server, _ := newTestServer(t, "application/json", reasonerCompletion)
+	c := NewClient(client.WithBaseURL(server.URL), client.WithAPIKey("test"))
+	params := &types.ChatParams{Model: ModelReasoner, Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))}}
+	if _, err := c.Chat(t.Context(), params); err != nil {
+		t.Fatalf("DeepSeek model rejected by the OpenAI model list: %v", err)
+	}
+}
+
+func TestNativeResponseFormatFallsBackToTool(t *testing.T) {
+	raw := newRawClient()
+	rf := types.ResponseFormat{Mode: types.ResponseFormatModeNative, Schema: map[string]any{"type": "object"}}
+	if got := types.EffectiveResponseFormat(raw, rf).Mode; got != types.ResponseFormatModeTool {
+		t.Errorf("mode = %q, want tool", got)
+	}
+}
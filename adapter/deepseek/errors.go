@@ -0,0 +1,8 @@
+package deepseek
+
+import "errors"
+
+var (
+	// ErrEmbeddingsNotSupported is returned by RawEmbed; DeepSeek does not offer an embeddings API.
+	ErrEmbeddingsNotSupported = errors.New("deepseek: embeddings are not supported")
+)
@@ -0,0 +1,23 @@
+package deepseek
+
+import "github.com/KennyKeni/elysia/types"
+
+// AdapterName identifies this adapter in model validation errors.
+const AdapterName = "deepseek"
+
+const (
+	// ModelChat is the general-purpose chat model.
+	ModelChat = "deepseek-chat"
+
+	// ModelReasoner returns its chain of thought as reasoning_content alongside the answer.
+	ModelReasoner = "deepseek-reasoner"
+)
+
+func init() {
+	types.RegisterModels(AdapterName, SupportedModels())
+}
+
+// SupportedModels returns the DeepSeek model names known to this adapter.
+func SupportedModels() []string {
+	return []string{ModelChat, ModelReasoner}
+}
@@ -26,6 +26,7 @@ func NewTool(mcpTool mcp.Tool, session *mcp.ClientSession) (*types.Tool, error)
 			Name:        mcpTool.Name,
 			Description: mcpTool.Description,
 			InputSchema: inputSchema,
+			Metadata:    toolMetadata(mcpTool.Annotations),
 		},
 		Execute: func(ctx context.Context, args map[string]any) (*types.ToolResult, error) {
 			callResult, err := session.CallTool(ctx, &mcp.CallToolParams{
@@ -46,6 +47,31 @@ func NewTool(mcpTool mcp.Tool, session *mcp.ClientSession) (*types.Tool, error)
 	}, nil
 }
 
+// toolMetadata converts MCP tool annotations to ToolDefinition metadata.
+// Keys use the MCP wire names (e.g. "readOnlyHint"). Returns nil when the
+// tool has no annotations.
+func toolMetadata(annotations *mcp.ToolAnnotations) map[string]any {
+	if annotations == nil {
+		return nil
+	}
+
+	metadata := map[string]any{
+		"readOnlyHint":   annotations.ReadOnlyHint,
+		"idempotentHint": annotations.IdempotentHint,
+	}
+	if annotations.Title != "" {
+		metadata["title"] = annotations.Title
+	}
+	if annotations.DestructiveHint != nil {
+		metadata["destructiveHint"] = *annotations.DestructiveHint
+	}
+	if annotations.OpenWorldHint != nil {
+		metadata["openWorldHint"] = *annotations.OpenWorldHint
+	}
+
+	return metadata
+}
+
 // convertResult converts an MCP CallToolResult to types.ToolResult
 func convertResult(callResult *mcp.CallToolResult) *types.ToolResult {
 	result := &types.ToolResult{
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/KennyKeni/elysia/agent"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestNewToolAnnotationsMetadata(t *testing.T) {
+	destructive := false
+	mcpTool := mcp.Tool{
+		Name:        "lookup",
+		Description: "Looks something up",
+		InputSchema: map[string]any{"type": "object"},
+		Annotations: &mcp.ToolAnnotations{
+			Title:           "Lookup",
+			ReadOnlyHint:    true,
+			DestructiveHint: &destructive,
+		},
+	}
+
+	tool, err := NewTool(mcpTool, nil)
+	if err != nil {
+		t.Fatalf("NewTool returned error: %v", err)
+	}
+
+	want := map[string]any{
+		"title":           "Lookup",
+		"readOnlyHint":    true,
+		"idempotentHint":  false,
+		"destructiveHint": false,
+	}
+	for k, v := range want {
+		if tool.Metadata[k] != v {
+			t.Fatalf("expected metadata %q=%v, got %v", k, v, tool.Metadata[k])
+		}
+	}
+	if _, ok := tool.Metadata["openWorldHint"]; ok {
+		t.Fatalf("expected openWorldHint to be omitted when unset")
+	}
+
+	wrapped := agent.WrapTool[struct{}](tool)
+	if wrapped.Metadata["readOnlyHint"] != true {
+		t.Fatalf("expected metadata to survive WrapTool, got %#v", wrapped.Metadata)
+	}
+}
+
+func TestNewToolWithoutAnnotations(t *testing.T) {
+	tool, err := NewTool(mcp.Tool{Name: "plain", InputSchema: map[string]any{"type": "object"}}, nil)
+	if err != nil {
+		t.Fatalf("NewTool returned error: %v", err)
+	}
+
+	if tool.Metadata != nil {
+		t.Fatalf("expected nil metadata, got %#v", tool.Metadata)
+	}
+}
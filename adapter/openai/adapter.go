@@ -12,12 +12,27 @@ import (
 
 // Client wraps the OpenAI SDK client and implements the unified chat interface
 type Client struct {
-	client openai.Client
+	client       openai.Client
+	tracker      *connTracker
+	strictModels bool
+	ownModels    bool          // Serves models other than OpenAI's, so SetStrictModelValidation is ignored
+	azure        *azureRouting // Per-request deployment routing (nil = regular OpenAI)
 }
 
 // NewClient creates a new OpenAI client wrapped with ResponseFormat handling
 func NewClient(opts ...client.Option) types.Client {
-	return types.NewClient(newRawClient(opts...))
+	cfg := client.NewConfig(opts...)
+	return types.NewClient(newRawClient(opts...), types.WithLogger(cfg.Logger), types.WithLogContent(cfg.LogContent), types.WithDocumentConverter(cfg.DocumentConverter), types.WithChunkTimeout(cfg.ChunkTimeout))
+}
+
+// NewRawClient creates the unwrapped OpenAI client for adapters that layer their own handling
+// over the OpenAI wire format, such as openaicompat. Most callers want NewClient. As such
+// adapters serve other models, SetStrictModelValidation does not apply to the client; models
+// are only validated with client.WithStrictModelValidation.
+func NewRawClient(opts ...client.Option) *Client {
+	c := newRawClient(opts...)
+	c.ownModels = true
+	return c
 }
 
 // newRawClient creates the raw OpenAI client (internal)
@@ -27,10 +42,12 @@ func newRawClient(opts ...client.Option) *Client {
 		opt(&cfg)
 	}
 
-	openaiOpts := translateConfig(cfg)
+	openaiOpts, tracker := translateConfig(cfg)
 
 	return &Client{
-		client: openai.NewClient(openaiOpts...),
+		client:       openai.NewClient(openaiOpts...),
+		tracker:      tracker,
+		strictModels: cfg.StrictModelValidation,
 	}
 }
 
@@ -39,7 +56,7 @@ func NewClientFromOpenAI(c openai.Client) types.Client {
 	return types.NewClient(&Client{client: c})
 }
 
-func translateConfig(cfg client.Config) []option.RequestOption {
+func translateConfig(cfg client.Config) ([]option.RequestOption, *connTracker) {
 	var opts []option.RequestOption
 
 	// API Key
@@ -73,6 +90,16 @@ func translateConfig(cfg client.Config) []option.RequestOption {
 		httpClient.Timeout = cfg.TotalTimeout
 	}
 
+	// Wrap the transport to record the negotiated protocol
+	transport := httpClient.Transport
+	if cfg.HTTP2 {
+		transport = configureHTTP2(transport)
+	}
+	tracker := newConnTracker(transport)
+	trackedClient := *httpClient
+	trackedClient.Transport = tracker
+	httpClient = &trackedClient
+
 	// Set HTTP Client
 	opts = append(opts, option.WithHTTPClient(httpClient))
 
@@ -84,13 +111,66 @@ func translateConfig(cfg client.Config) []option.RequestOption {
 		}
 	}
 
-	return opts
+	// Headers of the request's context, see types.WithRequestHeaders
+	opts = append(opts, option.WithMiddleware(contextHeadersMiddleware))
+
+	// Token provider overrides the API key's Authorization header on every request
+	if cfg.TokenProvider != nil {
+		opts = append(opts, option.WithMiddleware(bearerTokenMiddleware(cfg.TokenProvider)))
+	}
+
+	return opts, tracker
+}
+
+// contextHeadersMiddleware adds the headers set on the request's context with
+// types.WithRequestHeaders
+func contextHeadersMiddleware(r *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	for key, values := range types.RequestHeaders(r.Context()) {
+		r.Header[key] = values
+	}
+	return next(r)
+}
+
+// ConnectionInfo reports details about the most recent connection used by the client
+func (c *Client) ConnectionInfo() ConnectionInfo {
+	if c.tracker == nil {
+		return ConnectionInfo{}
+	}
+	return c.tracker.info()
+}
+
+// SupportsHTTP2 reports whether the client's transport is configured to negotiate HTTP/2
+func (c *Client) SupportsHTTP2() bool {
+	if c.tracker == nil {
+		return SupportsHTTP2()
+	}
+	return transportSupportsHTTP2(c.tracker.base)
 }
 
-// Potentially add per-request options
+// validateModel checks the requested model against SupportedModels when strict validation is
+// enabled for the client, or for all OpenAI clients with SetStrictModelValidation
+func (c *Client) validateModel(params *types.ChatParams) error {
+	strict := c.strictModels || (!c.ownModels && strictModelValidation.Load())
+	if !strict || params == nil {
+		return nil
+	}
+	return types.ValidateModel(AdapterName, params.Model)
+}
+
+// requestOptions returns per-request options for model
+func (c *Client) requestOptions(model string) []option.RequestOption {
+	if c.azure == nil {
+		return nil
+	}
+	return []option.RequestOption{option.WithBaseURL(c.azure.deploymentURL(model))}
+}
 
 // RawChat performs a non-streaming chat completion request
 func (c *Client) RawChat(ctx context.Context, params *types.ChatParams) (*types.ChatResponse, error) {
+	if err := c.validateModel(params); err != nil {
+		return nil, err
+	}
+
 	// Convert unified params to OpenAI params
 	openaiParams, err := ToChatCompletionParams(params)
 	if err != nil {
@@ -98,9 +178,9 @@ func (c *Client) RawChat(ctx context.Context, params *types.ChatParams) (*types.
 	}
 
 	// Call OpenAI SDK
-	completion, err := c.client.Chat.Completions.New(ctx, openaiParams)
+	completion, err := c.client.Chat.Completions.New(ctx, openaiParams, c.requestOptions(params.Model)...)
 	if err != nil {
-		return nil, err
+		return nil, wrapAPIError(err)
 	}
 
 	if err := validateChatCompletion(completion); err != nil {
@@ -113,12 +193,16 @@ func (c *Client) RawChat(ctx context.Context, params *types.ChatParams) (*types.
 
 // RawChatStream performs a streaming chat completion request and returns an iterator over chunks.
 func (c *Client) RawChatStream(ctx context.Context, params *types.ChatParams) (*types.Stream, error) {
+	if err := c.validateModel(params); err != nil {
+		return nil, err
+	}
+
 	openaiParams, err := ToChatCompletionParams(params)
 	if err != nil {
 		return nil, err
 	}
 
-	stream := c.client.Chat.Completions.NewStreaming(ctx, openaiParams)
+	stream := c.client.Chat.Completions.NewStreaming(ctx, openaiParams, c.requestOptions(params.Model)...)
 	return newChatStream(stream), nil
 }
 
@@ -131,7 +215,7 @@ func (c *Client) RawEmbed(ctx context.Context, params *types.EmbeddingParams) (*
 	}
 
 	// Call OpenAI SDK
-	embedding, err := c.client.Embeddings.New(ctx, openaiParams)
+	embedding, err := c.client.Embeddings.New(ctx, openaiParams, c.requestOptions(params.Model)...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,142 @@
+package openai
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+	"github.com/openai/openai-go/v3"
+)
+
+// NewTranscriber creates a client for the OpenAI transcription endpoint; it accepts the same options as NewClient.
+func NewTranscriber(opts ...client.Option) types.Transcriber {
+	return newRawClient(opts...)
+}
+
+// NewSpeaker creates a client for the OpenAI speech endpoint; it accepts the same options as NewClient.
+func NewSpeaker(opts ...client.Option) types.Speaker {
+	return newRawClient(opts...)
+}
+
+// Transcribe converts params.Audio to text, e.g. with gpt-4o-transcribe or whisper-1
+func (c *Client) Transcribe(ctx context.Context, params *types.TranscriptionParams) (*types.TranscriptionResponse, error) {
+	openaiParams, err := ToTranscriptionParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.client.Audio.Transcriptions.New(ctx, openaiParams, c.requestOptions(params.Model)...)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, ErrNilTranscription
+	}
+
+	return FromTranscriptionResponse(response), nil
+}
+
+// Speak synthesizes params.Input, e.g. with gpt-4o-mini-tts. The audio is streamed as it is generated.
+func (c *Client) Speak(ctx context.Context, params *types.SpeechParams) (*types.SpeechResponse, error) {
+	openaiParams, err := ToSpeechParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.client.Audio.Speech.New(ctx, openaiParams, c.requestOptions(params.Model)...)
+	if err != nil {
+		return nil, err
+	}
+
+	format := params.Format
+	if format == "" {
+		format = types.AudioFormatMP3
+	}
+	return &types.SpeechResponse{Audio: response.Body, Format: format}, nil
+}
+
+func ToTranscriptionParams(transcriptionParams *types.TranscriptionParams) (openai.AudioTranscriptionNewParams, error) {
+	if transcriptionParams == nil {
+		return openai.AudioTranscriptionNewParams{}, errors.New("nil transcriptionParams")
+	}
+	if transcriptionParams.Audio == nil {
+		return openai.AudioTranscriptionNewParams{}, errors.New("nil transcription audio")
+	}
+
+	// An empty name falls back to the reader's Name, e.g. for an *os.File
+	filename := transcriptionParams.Filename
+	if _, named := transcriptionParams.Audio.(interface{ Name() string }); filename == "" && !named {
+		filename = "audio.wav"
+	}
+
+	request := openai.AudioTranscriptionNewParams{
+		File:  openai.File(transcriptionParams.Audio, filename, ""),
+		Model: openai.AudioModel(transcriptionParams.Model),
+	}
+	if transcriptionParams.Language != "" {
+		request.Language = openai.String(transcriptionParams.Language)
+	}
+	if transcriptionParams.Prompt != "" {
+		request.Prompt = openai.String(transcriptionParams.Prompt)
+	}
+	if len(transcriptionParams.Extra) > 0 {
+		request.SetExtraFields(transcriptionParams.Extra)
+	}
+
+	return request, nil
+}
+
+// FromTranscriptionResponse converts a transcription to the unified types.TranscriptionResponse.
+// Usage is only set when the model reports token usage.
+func FromTranscriptionResponse(response *openai.AudioTranscriptionNewResponseUnion) *types.TranscriptionResponse {
+	if response == nil {
+		return nil
+	}
+
+	result := &types.TranscriptionResponse{
+		Text:     response.Text,
+		Language: response.Language,
+		Duration: response.Duration,
+		Extra:    make(map[string]any),
+	}
+
+	switch usage := response.Usage; usage.Type {
+	case "tokens":
+		result.Usage = &types.Usage{
+			PromptTokens:     usage.InputTokens,
+			CompletionTokens: usage.OutputTokens,
+			TotalTokens:      usage.TotalTokens,
+		}
+	case "duration":
+		if result.Duration == 0 {
+			result.Duration = usage.Seconds
+		}
+	}
+
+	return result
+}
+
+func ToSpeechParams(speechParams *types.SpeechParams) (openai.AudioSpeechNewParams, error) {
+	if speechParams == nil {
+		return openai.AudioSpeechNewParams{}, errors.New("nil speechParams")
+	}
+
+	request := openai.AudioSpeechNewParams{
+		Input:          speechParams.Input,
+		Model:          openai.SpeechModel(speechParams.Model),
+		Voice:          openai.AudioSpeechNewParamsVoice(speechParams.Voice),
+		ResponseFormat: openai.AudioSpeechNewParamsResponseFormat(speechParams.Format),
+	}
+	if speechParams.Instructions != "" {
+		request.Instructions = openai.String(speechParams.Instructions)
+	}
+	if speechParams.Speed != nil {
+		request.Speed = openai.Float(*speechParams.Speed)
+	}
+	if len(speechParams.Extra) > 0 {
+		request.SetExtraFields(speechParams.Extra)
+	}
+
+	return request, nil
+}
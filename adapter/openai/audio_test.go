@@ -0,0 +1,87 @@
+package openai
+
+import (
+	json "encoding/json/v2"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+func TestTranscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/transcriptions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("read file: %v", err)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		if header.Filename != "question.mp3" || string(data) != "ID3" || r.FormValue("language") != "en" {
+			t.Errorf("unexpected upload %q %q language %q", header.Filename, data, r.FormValue("language"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"text": "What time is it?", "usage": {"type": "tokens", "input_tokens": 12, "output_tokens": 6, "total_tokens": 18}}`)
+	}))
+	defer server.Close()
+
+	transcriber := NewTranscriber(client.WithBaseURL(server.URL), client.WithAPIKey("test"), client.WithMaxRetries(0))
+	resp, err := transcriber.Transcribe(t.Context(), &types.TranscriptionParams{
+		Model:    "gpt-4o-transcribe",
+		Audio:    strings.NewReader("ID3"),
+		Filename: "question.mp3",
+		Language: "en",
+	})
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+
+	if resp.Text != "What time is it?" {
+		t.Errorf("text = %q", resp.Text)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 18 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestSpeak(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/speech" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		_, _ = io.WriteString(w, "RIFF")
+	}))
+	defer server.Close()
+
+	speaker := NewSpeaker(client.WithBaseURL(server.URL), client.WithAPIKey("test"), client.WithMaxRetries(0))
+	resp, err := speaker.Speak(t.Context(), &types.SpeechParams{
+		Model:  "gpt-4o-mini-tts",
+		Input:  "It is noon.",
+		Voice:  "alloy",
+		Format: types.AudioFormatWAV,
+	})
+	if err != nil {
+		t.Fatalf("Speak failed: %v", err)
+	}
+	defer resp.Audio.Close()
+
+	audio, _ := io.ReadAll(resp.Audio)
+	if string(audio) != "RIFF" || resp.Format != types.AudioFormatWAV {
+		t.Errorf("unexpected audio %q format %q", audio, resp.Format)
+	}
+	if body["voice"] != "alloy" || body["response_format"] != "wav" || body["input"] != "It is noon." {
+		t.Errorf("unexpected request body: %v", body)
+	}
+}
@@ -0,0 +1,93 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when client.WithAPIVersion is not set.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// azureRouting maps models to Azure OpenAI deployment URLs.
+type azureRouting struct {
+	endpoint    string // https://<resource>.openai.azure.com/openai
+	deployments map[string]string
+}
+
+// deploymentURL returns the base URL for requests to model's deployment.
+func (r *azureRouting) deploymentURL(model string) string {
+	deployment := model
+	if d, ok := r.deployments[model]; ok {
+		deployment = d
+	}
+	return r.endpoint + "/deployments/" + url.PathEscape(deployment) + "/"
+}
+
+// NewAzureClient creates a client for an Azure OpenAI resource, e.g. https://my-resource.openai.azure.com.
+//
+// Requests are routed to /openai/deployments/{deployment}, where the deployment is looked up
+// from client.WithDeployment and defaults to the model name. Authenticate with client.WithAPIKey
+// (sent as the api-key header) or client.WithTokenProvider for Microsoft Entra ID tokens.
+func NewAzureClient(endpoint string, opts ...client.Option) types.Client {
+	cfg := client.NewConfig(opts...)
+	return types.NewClient(newAzureRawClient(endpoint, opts...), types.WithLogger(cfg.Logger), types.WithLogContent(cfg.LogContent), types.WithDocumentConverter(cfg.DocumentConverter), types.WithChunkTimeout(cfg.ChunkTimeout))
+}
+
+// newAzureRawClient creates the raw Azure OpenAI client (internal)
+func newAzureRawClient(endpoint string, opts ...client.Option) *Client {
+	cfg := client.DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	apiVersion := cfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = DefaultAzureAPIVersion
+	}
+
+	// Azure expects the key in the api-key header, never as a bearer token
+	apiKey := cfg.APIKey
+	cfg.APIKey = ""
+
+	openaiOpts, tracker := translateConfig(cfg)
+	routing := &azureRouting{
+		endpoint:    strings.TrimSuffix(endpoint, "/") + "/openai",
+		deployments: cfg.Deployments,
+	}
+	openaiOpts = append(openaiOpts,
+		option.WithBaseURL(routing.endpoint+"/"),
+		option.WithQuery("api-version", apiVersion),
+		option.WithHeaderDel("authorization"), // drop any OPENAI_API_KEY picked up from the environment
+	)
+	if apiKey != "" {
+		openaiOpts = append(openaiOpts, option.WithHeader("api-key", apiKey))
+	}
+
+	return &Client{
+		client:       openai.NewClient(openaiOpts...),
+		tracker:      tracker,
+		strictModels: cfg.StrictModelValidation,
+		ownModels:    true, // Requests name deployments, not models
+		azure:        routing,
+	}
+}
+
+// bearerTokenMiddleware sets the Authorization header from provider on each request.
+func bearerTokenMiddleware(provider func(ctx context.Context) (string, error)) option.Middleware {
+	return func(r *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		token, err := provider(r.Context())
+		if err != nil {
+			return nil, fmt.Errorf("token provider: %w", err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+		return next(r)
+	}
+}
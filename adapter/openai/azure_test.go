@@ -0,0 +1,103 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+type recordedRequest struct {
+	path, apiVersion, apiKey, authorization string
+}
+
+func newAzureTestServer(t *testing.T) (*httptest.Server, *recordedRequest) {
+	t.Helper()
+	rec := &recordedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.path = r.URL.Path
+		rec.apiVersion = r.URL.Query().Get("api-version")
+		rec.apiKey = r.Header.Get("Api-Key")
+		rec.authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(sampleCompletionJSON))
+	}))
+	t.Cleanup(server.Close)
+	return server, rec
+}
+
+func TestAzureClientDeploymentRouting(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-should-not-leak")
+	server, rec := newAzureTestServer(t)
+
+	c := NewAzureClient(server.URL,
+		client.WithAPIKey("azure-key"),
+		client.WithAPIVersion("2025-01-01-preview"),
+		client.WithDeployment("gpt-4o-mini", "prod-mini"),
+		client.WithMaxRetries(0),
+	)
+
+	params := &types.ChatParams{
+		Model:    "gpt-4o-mini",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+	}
+	if _, err := c.Chat(context.Background(), params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec.path != "/openai/deployments/prod-mini/chat/completions" {
+		t.Errorf("unexpected path: %s", rec.path)
+	}
+	if rec.apiVersion != "2025-01-01-preview" {
+		t.Errorf("unexpected api-version: %q", rec.apiVersion)
+	}
+	if rec.apiKey != "azure-key" {
+		t.Errorf("expected api-key header, got %q", rec.apiKey)
+	}
+	if rec.authorization != "" {
+		t.Errorf("expected no Authorization header, got %q", rec.authorization)
+	}
+
+	// Unmapped models use the model name as the deployment
+	params.Model = "gpt-4o"
+	if _, err := c.Chat(context.Background(), params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.path != "/openai/deployments/gpt-4o/chat/completions" {
+		t.Errorf("unexpected path for unmapped model: %s", rec.path)
+	}
+}
+
+func TestAzureClientTokenProvider(t *testing.T) {
+	server, rec := newAzureTestServer(t)
+
+	var calls int
+	c := NewAzureClient(server.URL,
+		client.WithTokenProvider(func(ctx context.Context) (string, error) {
+			calls++
+			return "entra-token", nil
+		}),
+		client.WithMaxRetries(0),
+	)
+
+	_, err := c.Chat(context.Background(), &types.ChatParams{
+		Model:    "gpt-4o",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec.authorization != "Bearer entra-token" {
+		t.Errorf("unexpected Authorization header: %q", rec.authorization)
+	}
+	if rec.apiVersion != DefaultAzureAPIVersion {
+		t.Errorf("expected default api-version, got %q", rec.apiVersion)
+	}
+	if calls != 1 {
+		t.Errorf("expected token provider to be called once, got %d", calls)
+	}
+}
@@ -1,6 +1,12 @@
 package openai
 
-import "errors"
+import (
+	"errors"
+	"time"
+
+	"github.com/KennyKeni/elysia/types"
+	"github.com/openai/openai-go/v3"
+)
 
 var (
 	// ErrNilCompletion is returned when the OpenAI SDK yields a nil completion response.
@@ -21,6 +27,51 @@ var (
 	// ErrUnsupportedToolContentPart indicates that a tool result message includes unsupported content.
 	ErrUnsupportedToolContentPart = errors.New("openai chat: unsupported content part for tool message")
 
-	// ErrMissingToolCallID indicates that a tool result message is missing the required ToolCallID.
+	// ErrMissingToolCallID indicates that a tool result message is missing the required ToolCallID,
+	// or that an assistant message contains a tool call with an empty ID.
 	ErrMissingToolCallID = errors.New("openai chat: tool message missing ToolCallID")
+
+	// ErrNilModeration is returned when the OpenAI SDK yields a nil moderation response.
+	ErrNilModeration = errors.New("openai moderation: empty moderation response")
+
+	// ErrNilImage is returned when the OpenAI SDK yields a nil image generation response.
+	ErrNilImage = errors.New("openai images: empty image response")
+
+	// ErrNilTranscription is returned when the OpenAI SDK yields a nil transcription response.
+	ErrNilTranscription = errors.New("openai audio: empty transcription response")
+
+	// ErrMultipleChoicesNotSupported is returned by the Responses API client when ChatParams.N > 1.
+	ErrMultipleChoicesNotSupported = errors.New("openai responses: n > 1 is not supported")
 )
+
+// apiError wraps SDK API errors so the agent can classify them through types.TransientError.
+// errors.As still reaches the underlying *openai.Error.
+type apiError struct {
+	err *openai.Error
+}
+
+func (e *apiError) Error() string { return e.err.Error() }
+
+func (e *apiError) Unwrap() error { return e.err }
+
+// Transient reports whether the request may succeed if sent again
+func (e *apiError) Transient() bool {
+	code := e.err.StatusCode
+	return code == 408 || code == 409 || code == 429 || code >= 500
+}
+
+// RetryAfter returns the delay the API asked for before retrying, zero if none
+func (e *apiError) RetryAfter() time.Duration {
+	if e.err.Response == nil {
+		return 0
+	}
+	return types.RetryAfter(e.err.Response.Header)
+}
+
+// wrapAPIError marks errors returned by the SDK for an API status, other errors pass through
+func wrapAPIError(err error) error {
+	if sdkErr, ok := err.(*openai.Error); ok {
+		return &apiError{err: sdkErr}
+	}
+	return err
+}
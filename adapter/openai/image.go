@@ -0,0 +1,94 @@
+package openai
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+	"github.com/openai/openai-go/v3"
+)
+
+// NewImageGenerator creates a client for the OpenAI image generation endpoint; it accepts the same options as NewClient.
+func NewImageGenerator(opts ...client.Option) types.ImageGenerator {
+	return newRawClient(opts...)
+}
+
+// GenerateImage creates images for params.Prompt, e.g. with gpt-image-1
+func (c *Client) GenerateImage(ctx context.Context, params *types.ImageParams) (*types.ImageResponse, error) {
+	openaiParams, err := ToImageGenerateParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.client.Images.Generate(ctx, openaiParams, c.requestOptions(params.Model)...)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, ErrNilImage
+	}
+
+	return FromImagesResponse(response), nil
+}
+
+func ToImageGenerateParams(imageParams *types.ImageParams) (openai.ImageGenerateParams, error) {
+	if imageParams == nil {
+		return openai.ImageGenerateParams{}, errors.New("nil imageParams")
+	}
+
+	request := openai.ImageGenerateParams{
+		Prompt:       imageParams.Prompt,
+		Model:        openai.ImageModel(imageParams.Model),
+		Size:         openai.ImageGenerateParamsSize(imageParams.Size),
+		Quality:      openai.ImageGenerateParamsQuality(imageParams.Quality),
+		OutputFormat: openai.ImageGenerateParamsOutputFormat(imageParams.OutputFormat),
+	}
+	if imageParams.N != nil {
+		request.N = openai.Int(int64(*imageParams.N))
+	}
+	if len(imageParams.Extra) > 0 {
+		request.SetExtraFields(imageParams.Extra)
+	}
+
+	return request, nil
+}
+
+// FromImagesResponse converts an image generation response to the unified types.ImageResponse
+func FromImagesResponse(response *openai.ImagesResponse) *types.ImageResponse {
+	if response == nil {
+		return nil
+	}
+
+	mimeType := "image/png"
+	if response.OutputFormat != "" {
+		mimeType = "image/" + string(response.OutputFormat)
+	}
+
+	result := &types.ImageResponse{
+		Created: response.Created,
+		Images:  make([]types.GeneratedImage, len(response.Data)),
+		Extra:   make(map[string]any),
+	}
+	for i, image := range response.Data {
+		result.Images[i] = types.GeneratedImage{
+			Data:          image.B64JSON,
+			URL:           image.URL,
+			RevisedPrompt: image.RevisedPrompt,
+		}
+		if image.B64JSON != "" {
+			result.Images[i].MIMEType = mimeType
+		}
+	}
+
+	// Only gpt-image-1 reports usage
+	if response.Usage.JSON.TotalTokens.Raw() != "" {
+		result.Usage = &types.Usage{
+			PromptTokens:     response.Usage.InputTokens,
+			CompletionTokens: response.Usage.OutputTokens,
+			TotalTokens:      response.Usage.TotalTokens,
+		}
+	}
+
+	return result
+}
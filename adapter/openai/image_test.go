@@ -0,0 +1,65 @@
+package openai
+
+import (
+	json "encoding/json/v2"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+const sampleImagesJSON = `{
+  "created": 1700000000, "output_format": "webp",
+  "data": [{"b64_json": "UklGRg=="}],
+  "usage": {"input_tokens": 10, "input_tokens_details": {"image_tokens": 0, "text_tokens": 10}, "output_tokens": 272, "total_tokens": 282}
+}`
+
+func TestGenerateImage(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/images/generations" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, sampleImagesJSON)
+	}))
+	defer server.Close()
+
+	n := 1
+	generator := NewImageGenerator(client.WithBaseURL(server.URL), client.WithAPIKey("test"), client.WithMaxRetries(0))
+	resp, err := generator.GenerateImage(t.Context(), &types.ImageParams{
+		Model:        "gpt-image-1",
+		Prompt:       "a lighthouse at dusk",
+		N:            &n,
+		Size:         "1024x1024",
+		OutputFormat: "webp",
+	})
+	if err != nil {
+		t.Fatalf("GenerateImage failed: %v", err)
+	}
+
+	if body["model"] != "gpt-image-1" || body["size"] != "1024x1024" || body["n"] != float64(1) {
+		t.Errorf("unexpected request body: %v", body)
+	}
+	if _, ok := body["quality"]; ok {
+		t.Errorf("expected empty quality to be omitted: %v", body)
+	}
+	if len(resp.Images) != 1 || resp.Images[0].Data != "UklGRg==" || resp.Images[0].MIMEType != "image/webp" {
+		t.Fatalf("unexpected images: %+v", resp.Images)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 282 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+
+	part, ok := resp.Images[0].ContentPart().(*types.ContentPartImage)
+	if !ok || part.MIMEType != "image/webp" {
+		t.Errorf("unexpected content part: %#v", resp.Images[0].ContentPart())
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/KennyKeni/elysia/types"
 	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/respjson"
 )
 
 // ToChatCompletionMessage converts unified messages to OpenAI chat completion message parameters
@@ -56,8 +57,12 @@ func toUserMessage(message *types.Message) (openai.ChatCompletionMessageParamUni
 			content = append(content, toUserImageDataPart(part))
 		case *types.ContentPartImageURL:
 			content = append(content, toUserImageURLPart(part))
+		case *types.ContentPartAudio:
+			content = append(content, toUserAudioPart(part))
+		case *types.ContentPartFile:
+			content = append(content, toUserFilePart(part))
 		default:
-			return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("%w: %T", ErrUnsupportedUserContentPart, part)
+			return openai.ChatCompletionMessageParamUnion{}, &types.UnsupportedContentError{Part: part, Err: ErrUnsupportedUserContentPart}
 		}
 	}
 
@@ -80,8 +85,10 @@ func toAssistantMessage(message *types.Message) (openai.ChatCompletionMessagePar
 			content = append(content, toAssistantTextPart(part))
 		case *types.ContentPartRefusal:
 			content = append(content, toAssistantRefusalPart(part))
+		case *types.ContentPartReasoning:
+			// Reasoning is not sent back; providers such as DeepSeek reject it in input messages
 		default:
-			return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("%w: %T", ErrUnsupportedAssistantContentPart, part)
+			return openai.ChatCompletionMessageParamUnion{}, &types.UnsupportedContentError{Part: part, Err: ErrUnsupportedAssistantContentPart}
 		}
 	}
 
@@ -118,7 +125,7 @@ func toToolResultMessage(message *types.Message) (openai.ChatCompletionMessagePa
 				Text: part.Text,
 			})
 		default:
-			return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("%w: %T", ErrUnsupportedToolContentPart, part)
+			return openai.ChatCompletionMessageParamUnion{}, &types.UnsupportedContentError{Part: part, Err: ErrUnsupportedToolContentPart}
 		}
 	}
 
@@ -143,7 +150,11 @@ func toUserTextPart(part *types.ContentPartText) openai.ChatCompletionContentPar
 
 // toUserImageDataPart converts base64 image data to OpenAI user message image part with data URL format
 func toUserImageDataPart(part *types.ContentPartImage) openai.ChatCompletionContentPartUnionParam {
-	dataURL := fmt.Sprintf("data:image/png;base64,%s", part.Data)
+	mimeType := part.MIMEType
+	if mimeType == "" {
+		mimeType = "image/png"
+	}
+	dataURL := fmt.Sprintf("data:%s;base64,%s", mimeType, part.Data)
 	return openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
 		URL:    dataURL,
 		Detail: part.Detail,
@@ -157,6 +168,38 @@ func toUserImageURLPart(part *types.ContentPartImageURL) openai.ChatCompletionCo
 	})
 }
 
+// toUserAudioPart converts base64 audio to an OpenAI input_audio part
+func toUserAudioPart(part *types.ContentPartAudio) openai.ChatCompletionContentPartUnionParam {
+	return openai.InputAudioContentPart(openai.ChatCompletionContentPartInputAudioInputAudioParam{
+		Data:   part.Data,
+		Format: string(part.Format),
+	})
+}
+
+// toUserFilePart converts a file reference or inline file data to an OpenAI file part
+func toUserFilePart(part *types.ContentPartFile) openai.ChatCompletionContentPartUnionParam {
+	var file openai.ChatCompletionContentPartFileFileParam
+	if part.FileID != "" {
+		file.FileID = openai.String(part.FileID)
+	}
+	if part.Data != "" {
+		file.FileData = openai.String(fileDataURL(part))
+	}
+	if part.Filename != "" {
+		file.Filename = openai.String(part.Filename)
+	}
+	return openai.FileContentPart(file)
+}
+
+// fileDataURL returns the data URL of inline file data, assuming application/pdf
+func fileDataURL(part *types.ContentPartFile) string {
+	mimeType := part.MIMEType
+	if mimeType == "" {
+		mimeType = "application/pdf"
+	}
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, part.Data)
+}
+
 // toAssistantTextPart converts text content to OpenAI assistant message text part
 func toAssistantTextPart(part *types.ContentPartText) openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion {
 	return openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion{
@@ -177,6 +220,10 @@ func toAssistantRefusalPart(part *types.ContentPartRefusal) openai.ChatCompletio
 
 // toToolCallParam converts a tool call to OpenAI tool call parameters with marshaled arguments
 func toToolCallParam(toolCall *types.ToolCall) (openai.ChatCompletionMessageToolCallUnionParam, error) {
+	if toolCall.ID == "" {
+		return openai.ChatCompletionMessageToolCallUnionParam{}, fmt.Errorf("%w: tool call %q has empty ID", ErrMissingToolCallID, toolCall.Function.Name)
+	}
+
 	argsJSON, err := json.Marshal(toolCall.Function.Arguments)
 	if err != nil {
 		return openai.ChatCompletionMessageToolCallUnionParam{}, fmt.Errorf("failed to marshal tool call arguments: %w", err)
@@ -205,6 +252,11 @@ func FromChatCompletionMessage(msg *openai.ChatCompletionMessage) *types.Message
 		ToolCalls:   make([]types.ToolCall, 0),
 	}
 
+	// Add reasoning content if present (DeepSeek and other compatible servers)
+	if reasoning := extraString(msg.JSON.ExtraFields, "reasoning_content"); reasoning != "" {
+		message.ContentPart = append(message.ContentPart, types.NewContentPartReasoning(reasoning))
+	}
+
 	// Add text content if present
 	if msg.Content != "" {
 		message.ContentPart = append(message.ContentPart, types.NewContentPartText(msg.Content))
@@ -215,47 +267,49 @@ func FromChatCompletionMessage(msg *openai.ChatCompletionMessage) *types.Message
 		message.ContentPart = append(message.ContentPart, types.NewContentPartRefusal(msg.Refusal))
 	}
 
-	// Convert tool calls if present
+	// Web search citations
+	for _, annotation := range msg.Annotations {
+		citation := annotation.URLCitation
+		message.Annotations = append(message.Annotations, types.Annotation{
+			Type:       types.AnnotationURLCitation,
+			StartIndex: int(citation.StartIndex),
+			EndIndex:   int(citation.EndIndex),
+			URL:        citation.URL,
+			Title:      citation.Title,
+		})
+	}
+
+	// Convert tool calls if present. Arguments that aren't valid JSON are repaired or kept
+	// raw, so the agent can ask the model to fix them.
 	for _, toolCall := range msg.ToolCalls {
-		tc := fromToolCall(toolCall)
-		if tc != nil {
-			message.ToolCalls = append(message.ToolCalls, *tc)
-		}
-		// Skip tool calls with invalid JSON arguments
+		message.ToolCalls = append(message.ToolCalls, fromToolCall(toolCall))
 	}
 
 	return message
 }
 
-// fromToolCall converts an OpenAI tool call to types.ToolCall
-// Returns nil if the arguments cannot be parsed as valid JSON
-func fromToolCall(toolCall openai.ChatCompletionMessageToolCallUnion) *types.ToolCall {
-	// Use AsFunction() to get the function tool call from the union
-	functionCall := toolCall.AsFunction()
-
-	args, err := parseArguments(functionCall.Function.Arguments)
-	if err != nil {
-		// Return nil for tool calls with invalid JSON arguments
-		// Caller should handle this appropriately
-		return nil
+// extraString decodes the string value of a non-standard response field, returning "" when
+// the field is absent, null or not a string
+func extraString(fields map[string]respjson.Field, name string) string {
+	// The SDK marks unknown fields invalid, so only the raw JSON is usable
+	raw := fields[name].Raw()
+	if raw == "" || raw == respjson.Null {
+		return ""
 	}
-
-	return &types.ToolCall{
-		ID: functionCall.ID,
-		Function: types.ToolFunction{
-			Name:      functionCall.Function.Name,
-			Arguments: args,
-		},
+	var value string
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return ""
 	}
+	return value
 }
 
-// parseArguments converts JSON string arguments to map[string]any
-// The Tool interface expects []byte for Execute(), but ToolFunction stores map[string]any
-// for easier inspection without reparsing
-func parseArguments(args string) (map[string]any, error) {
-	var result map[string]any
-	if err := json.Unmarshal([]byte(args), &result); err != nil {
-		return nil, err
+// fromToolCall converts an OpenAI tool call to types.ToolCall
+func fromToolCall(toolCall openai.ChatCompletionMessageToolCallUnion) types.ToolCall {
+	// Use AsFunction() to get the function tool call from the union
+	functionCall := toolCall.AsFunction()
+
+	return types.ToolCall{
+		ID:       functionCall.ID,
+		Function: types.NewToolFunction(functionCall.Function.Name, functionCall.Function.Arguments),
 	}
-	return result, nil
 }
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/KennyKeni/elysia/types"
+	"github.com/openai/openai-go/v3"
 )
 
 type unsupportedContentPart struct{}
@@ -50,6 +51,59 @@ func TestToChatCompletionMessageUnsupportedToolContent(t *testing.T) {
 	}
 }
 
+func TestToChatCompletionMessageImageMIMEType(t *testing.T) {
+	msg := types.NewUserMessage()
+	msg.ContentPart = append(msg.ContentPart, &types.ContentPartImage{Data: "aGVsbG8=", MIMEType: "image/jpeg"})
+
+	converted, err := ToChatCompletionMessage("", []types.Message{msg})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	url := converted[0].OfUser.Content.OfArrayOfContentParts[0].OfImageURL.ImageURL.URL
+	if url != "data:image/jpeg;base64,aGVsbG8=" {
+		t.Errorf("unexpected data URL: %s", url)
+	}
+}
+
+func TestToChatCompletionMessageAudio(t *testing.T) {
+	msg := types.NewUserMessage(types.WithText("Transcribe this"), types.WithAudio("UklGRg==", types.AudioFormatWAV))
+
+	converted, err := ToChatCompletionMessage("", []types.Message{msg})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	audio := converted[0].OfUser.Content.OfArrayOfContentParts[1].OfInputAudio
+	if audio == nil {
+		t.Fatal("expected input_audio part")
+	}
+	if audio.InputAudio.Data != "UklGRg==" || audio.InputAudio.Format != "wav" {
+		t.Errorf("unexpected input audio: %+v", audio.InputAudio)
+	}
+}
+
+func TestToChatCompletionMessageFile(t *testing.T) {
+	msg := types.NewUserMessage(
+		types.WithFile(types.NewContentPartFileData("JVBERi0=", "report.pdf")),
+		types.WithFile(types.NewContentPartFileID("file-abc")),
+	)
+
+	converted, err := ToChatCompletionMessage("", []types.Message{msg})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parts := converted[0].OfUser.Content.OfArrayOfContentParts
+	inline := parts[0].OfFile.File
+	if inline.FileData.Value != "data:application/pdf;base64,JVBERi0=" || inline.Filename.Value != "report.pdf" {
+		t.Errorf("unexpected inline file: %+v", inline)
+	}
+	if parts[1].OfFile.File.FileID.Value != "file-abc" {
+		t.Errorf("unexpected file ID: %+v", parts[1].OfFile.File)
+	}
+}
+
 func TestToChatCompletionMessageMissingToolCallID(t *testing.T) {
 	msg := types.NewToolMessage(types.WithText("result"))
 
@@ -58,6 +112,16 @@ func TestToChatCompletionMessageMissingToolCallID(t *testing.T) {
 	}
 }
 
+func TestToChatCompletionMessageEmptyAssistantToolCallID(t *testing.T) {
+	msg := types.NewAssistantMessage(types.WithToolCalls(types.ToolCall{
+		Function: types.ToolFunction{Name: "lookup", Arguments: map[string]any{}},
+	}))
+
+	if _, err := ToChatCompletionMessage("", []types.Message{msg}); err == nil || !errors.Is(err, ErrMissingToolCallID) {
+		t.Fatalf("expected ErrMissingToolCallID, got %v", err)
+	}
+}
+
 func TestToChatCompletionMessageSuccess(t *testing.T) {
 	toolCall := &types.ToolCall{
 		ID: "call-1",
@@ -166,3 +230,38 @@ func BenchmarkToChatCompletionMessage(b *testing.B) {
 		}
 	}
 }
+
+func TestFromChatCompletionMessageMalformedArguments(t *testing.T) {
+	var msg openai.ChatCompletionMessage
+	if err := json.Unmarshal([]byte(`{"role": "assistant", "tool_calls": [
+		{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\": \"Paris\",}"}},
+		{"id": "call_2", "type": "function", "function": {"name": "weather", "arguments": "{\"city\" \"Rome\"}"}}
+	]}`), &msg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	calls := FromChatCompletionMessage(&msg).ToolCalls
+	if len(calls) != 2 {
+		t.Fatalf("expected both tool calls to be kept, got %+v", calls)
+	}
+	if calls[0].Function.Arguments["city"] != "Paris" || calls[0].Function.RawArguments == "" {
+		t.Errorf("expected repaired arguments, got %+v", calls[0].Function)
+	}
+	if calls[1].ID != "call_2" || calls[1].Function.ArgumentsError() == nil {
+		t.Errorf("expected unrepairable arguments to be kept raw, got %+v", calls[1])
+	}
+}
+
+func TestFromChatCompletionMessageAnnotations(t *testing.T) {
+	var msg openai.ChatCompletionMessage
+	if err := json.Unmarshal([]byte(`{"role": "assistant", "content": "Rain is likely.", "annotations": [
+		{"type": "url_citation", "url_citation": {"start_index": 0, "end_index": 15, "url": "https://weather.example", "title": "Forecast"}}
+	]}`), &msg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	want := []types.Annotation{{Type: types.AnnotationURLCitation, StartIndex: 0, EndIndex: 15, URL: "https://weather.example", Title: "Forecast"}}
+	if got := FromChatCompletionMessage(&msg).Annotations; !reflect.DeepEqual(got, want) {
+		t.Errorf("Annotations = %+v, want %+v", got, want)
+	}
+}
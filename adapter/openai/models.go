@@ -0,0 +1,62 @@
+package openai
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// AdapterName identifies this adapter in model validation errors.
+const AdapterName = "openai"
+
+var strictModelValidation atomic.Bool
+
+func init() {
+	types.RegisterModels(AdapterName, SupportedModels())
+}
+
+// SupportedModels returns the OpenAI chat model names known to this adapter.
+func SupportedModels() []string {
+	return []string{
+		"gpt-5",
+		"gpt-5-mini",
+		"gpt-5-nano",
+		"gpt-5-chat-latest",
+		"gpt-4.1",
+		"gpt-4.1-mini",
+		"gpt-4.1-nano",
+		"gpt-4o",
+		"gpt-4o-mini",
+		"gpt-4o-2024-11-20",
+		"gpt-4o-2024-08-06",
+		"gpt-4o-mini-2024-07-18",
+		"gpt-4-turbo",
+		"gpt-4",
+		"gpt-3.5-turbo",
+		"o1",
+		"o1-mini",
+		"o3",
+		"o3-mini",
+		"o4-mini",
+	}
+}
+
+// IsReasoningModel reports whether model is an o-series or gpt-5 reasoning model. These take
+// max_completion_tokens and reasoning_effort and reject the sampling parameters.
+func IsReasoningModel(model string) bool {
+	switch {
+	case strings.HasPrefix(model, "gpt-5-chat"):
+		return false
+	case strings.HasPrefix(model, "gpt-5"):
+		return true
+	}
+	return len(model) > 1 && model[0] == 'o' && model[1] >= '1' && model[1] <= '9'
+}
+
+// SetStrictModelValidation enables or disables model name validation for all OpenAI clients.
+// Azure clients and those of adapters built on NewRawClient, such as deepseek, are not
+// affected. Clients created with client.WithStrictModelValidation validate regardless.
+func SetStrictModelValidation(enabled bool) {
+	strictModelValidation.Store(enabled)
+}
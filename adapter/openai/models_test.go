@@ -0,0 +1,80 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+func TestStrictModelValidation(t *testing.T) {
+	SetStrictModelValidation(true)
+	defer SetStrictModelValidation(false)
+
+	c := newRawClient(client.WithAPIKey("test"), client.WithBaseURL("http://127.0.0.1:0"))
+	if err := c.validateModel(&types.ChatParams{Model: "gpt-4o-mini"}); err != nil {
+		t.Fatalf("unexpected error for known model: %v", err)
+	}
+
+	_, err := c.RawChat(context.Background(), &types.ChatParams{
+		Model:    "gpt-4o-mnii",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+	})
+	var unknown *types.ErrUnknownModel
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected *types.ErrUnknownModel, got %v", err)
+	}
+	if unknown.Adapter != AdapterName || unknown.Model != "gpt-4o-mnii" {
+		t.Errorf("unexpected error fields: %+v", unknown)
+	}
+
+	// Adapters serving other models are not affected
+	if err := NewRawClient().validateModel(&types.ChatParams{Model: "deepseek-chat"}); err != nil {
+		t.Errorf("expected the global flag to skip NewRawClient clients, got %v", err)
+	}
+	if _, err := ToChatCompletionParams(&types.ChatParams{Model: "my-deployment"}); err != nil {
+		t.Errorf("expected the converter not to validate models, got %v", err)
+	}
+}
+
+func TestStrictModelValidationDisabledByDefault(t *testing.T) {
+	if _, err := ToChatCompletionParams(&types.ChatParams{Model: "my-finetune"}); err != nil {
+		t.Fatalf("expected unknown model to pass without strict validation, got %v", err)
+	}
+}
+
+func TestClientWithStrictModelValidation(t *testing.T) {
+	c := newRawClient(
+		client.WithAPIKey("test"),
+		client.WithBaseURL("http://127.0.0.1:0"),
+		client.WithStrictModelValidation(),
+	)
+
+	_, err := c.RawChat(context.Background(), &types.ChatParams{
+		Model:    "not-a-model",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+	})
+	var unknown *types.ErrUnknownModel
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected *types.ErrUnknownModel before any request, got %v", err)
+	}
+}
+
+func TestIsReasoningModel(t *testing.T) {
+	for model, want := range map[string]bool{
+		"o1":                true,
+		"o3-mini":           true,
+		"o4-mini":           true,
+		"gpt-5":             true,
+		"gpt-5-mini":        true,
+		"gpt-5-chat-latest": false,
+		"gpt-4o":            false,
+		"omni-moderation":   false,
+	} {
+		if got := IsReasoningModel(model); got != want {
+			t.Errorf("IsReasoningModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package openai
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+	"github.com/openai/openai-go/v3"
+)
+
+// NewModerator creates a client for the OpenAI moderation endpoint; it accepts the same options as NewClient.
+func NewModerator(opts ...client.Option) types.Moderator {
+	return newRawClient(opts...)
+}
+
+// Moderate classifies params.Input with the moderation endpoint
+func (c *Client) Moderate(ctx context.Context, params *types.ModerationParams) (*types.ModerationResponse, error) {
+	openaiParams, err := ToModerationParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.client.Moderations.New(ctx, openaiParams, c.requestOptions(params.Model)...)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, ErrNilModeration
+	}
+
+	return FromModerationResponse(response), nil
+}
+
+func ToModerationParams(moderationParams *types.ModerationParams) (openai.ModerationNewParams, error) {
+	if moderationParams == nil {
+		return openai.ModerationNewParams{}, errors.New("nil moderationParams")
+	}
+
+	request := openai.ModerationNewParams{
+		Input: openai.ModerationNewParamsInputUnion{OfStringArray: moderationParams.Input},
+	}
+	if moderationParams.Model != "" {
+		request.Model = openai.ModerationModel(moderationParams.Model)
+	}
+	if len(moderationParams.Extra) > 0 {
+		request.SetExtraFields(moderationParams.Extra)
+	}
+
+	return request, nil
+}
+
+// FromModerationResponse converts a moderation response to the unified types.ModerationResponse.
+// Categories are read from the raw JSON so categories newer than the SDK are kept.
+func FromModerationResponse(response *openai.ModerationNewResponse) *types.ModerationResponse {
+	if response == nil {
+		return nil
+	}
+
+	result := &types.ModerationResponse{
+		ID:      response.ID,
+		Model:   response.Model,
+		Results: make([]types.ModerationResult, len(response.Results)),
+		Extra:   make(map[string]any),
+	}
+
+	for i, moderation := range response.Results {
+		categories := make(map[string]bool)
+		_ = json.Unmarshal([]byte(moderation.Categories.RawJSON()), &categories)
+		scores := make(map[string]float64)
+		_ = json.Unmarshal([]byte(moderation.CategoryScores.RawJSON()), &scores)
+
+		result.Results[i] = types.ModerationResult{
+			Flagged:        moderation.Flagged,
+			Categories:     categories,
+			CategoryScores: scores,
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,64 @@
+package openai
+
+import (
+	json "encoding/json/v2"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+const sampleModerationJSON = `{
+  "id": "modr_1", "model": "omni-moderation-latest",
+  "results": [
+    {"flagged": false, "categories": {"hate": false, "violence": false}, "category_scores": {"hate": 0.01, "violence": 0.02},
+     "category_applied_input_types": {}},
+    {"flagged": true, "categories": {"hate": false, "violence": true}, "category_scores": {"hate": 0.03, "violence": 0.91},
+     "category_applied_input_types": {}}
+  ]
+}`
+
+func TestModerate(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/moderations" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, sampleModerationJSON)
+	}))
+	defer server.Close()
+
+	moderator := NewModerator(client.WithBaseURL(server.URL), client.WithAPIKey("test"), client.WithMaxRetries(0))
+	resp, err := moderator.Moderate(t.Context(), &types.ModerationParams{
+		Model: "omni-moderation-latest",
+		Input: []string{"hello", "something violent"},
+	})
+	if err != nil {
+		t.Fatalf("Moderate failed: %v", err)
+	}
+
+	if body["model"] != "omni-moderation-latest" || len(body["input"].([]any)) != 2 {
+		t.Errorf("unexpected request body: %v", body)
+	}
+	if resp.ID != "modr_1" || len(resp.Results) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.Results[0].Flagged || !resp.Results[1].Flagged {
+		t.Errorf("unexpected flags: %+v", resp.Results)
+	}
+	if resp.Results[1].CategoryScores["violence"] != 0.91 {
+		t.Errorf("unexpected scores: %v", resp.Results[1].CategoryScores)
+	}
+	if !resp.Flagged() || !slices.Equal(resp.FlaggedCategories(), []string{"violence"}) {
+		t.Errorf("unexpected flagged categories: %v", resp.FlaggedCategories())
+	}
+}
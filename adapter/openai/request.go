@@ -9,6 +9,9 @@ import (
 	"github.com/openai/openai-go/v3/shared"
 )
 
+// ToChatCompletionParams converts unified chat params to Chat Completions request parameters.
+// Extra entries are sent as additional JSON body fields and override mapped fields of the
+// same name, except for the keys this package interprets itself (see ExtraBuiltinTools).
 func ToChatCompletionParams(chatParams *types.ChatParams) (openai.ChatCompletionNewParams, error) {
 	if chatParams == nil {
 		return openai.ChatCompletionNewParams{}, errors.New("nil chatParams")
@@ -19,19 +22,72 @@ func ToChatCompletionParams(chatParams *types.ChatParams) (openai.ChatCompletion
 		Stop:  openai.ChatCompletionNewParamsStopUnion{OfStringArray: chatParams.Stop},
 	}
 
+	// Reasoning models reject max_tokens and the sampling parameters, so those are
+	// translated or dropped instead of failing the request
+	reasoning := IsReasoningModel(chatParams.Model)
+
 	if chatParams.MaxTokens != nil {
-		request.MaxTokens = openai.Int(int64(*chatParams.MaxTokens))
+		if reasoning {
+			request.MaxCompletionTokens = openai.Int(int64(*chatParams.MaxTokens))
+		} else {
+			request.MaxTokens = openai.Int(int64(*chatParams.MaxTokens))
+		}
+	}
+
+	if chatParams.ReasoningEffort != "" {
+		request.ReasoningEffort = shared.ReasoningEffort(chatParams.ReasoningEffort)
+	}
+
+	if !reasoning {
+		if chatParams.Temperature != nil {
+			request.Temperature = openai.Float(*chatParams.Temperature)
+		}
+
+		if chatParams.TopP != nil {
+			request.TopP = openai.Float(*chatParams.TopP)
+		}
+
+		// topK is ignored
+
+		if chatParams.FrequencyPenalty != nil {
+			request.FrequencyPenalty = openai.Float(*chatParams.FrequencyPenalty)
+		}
+
+		if chatParams.PresencePenalty != nil {
+			request.PresencePenalty = openai.Float(*chatParams.PresencePenalty)
+		}
+
+		if len(chatParams.LogitBias) > 0 {
+			request.LogitBias = make(map[string]int64, len(chatParams.LogitBias))
+			for token, bias := range chatParams.LogitBias {
+				request.LogitBias[token] = int64(bias)
+			}
+		}
+	}
+
+	if chatParams.N != nil {
+		request.N = openai.Int(int64(*chatParams.N))
 	}
 
-	if chatParams.Temperature != nil {
-		request.Temperature = openai.Float(*chatParams.Temperature)
+	if chatParams.Seed != nil {
+		request.Seed = openai.Int(*chatParams.Seed)
 	}
 
-	if chatParams.TopP != nil {
-		request.TopP = openai.Float(*chatParams.TopP)
+	if chatParams.Store != nil {
+		request.Store = openai.Bool(*chatParams.Store)
 	}
 
-	// topK is ignored
+	if len(chatParams.Metadata) > 0 {
+		request.Metadata = shared.Metadata(chatParams.Metadata)
+	}
+
+	if chatParams.ServiceTier != "" {
+		request.ServiceTier = openai.ChatCompletionNewParamsServiceTier(chatParams.ServiceTier)
+	}
+
+	if chatParams.PromptCacheKey != "" {
+		request.PromptCacheKey = openai.String(chatParams.PromptCacheKey)
+	}
 
 	messages, err := ToChatCompletionMessage(chatParams.SystemPrompt, chatParams.Messages)
 	if err != nil {
@@ -51,6 +107,11 @@ func ToChatCompletionParams(chatParams *types.ChatParams) (openai.ChatCompletion
 		if chatParams.ToolChoice != nil {
 			request.ToolChoice = ToToolChoice(chatParams.ToolChoice)
 		}
+
+		// Only valid alongside tools
+		if chatParams.ParallelToolCalls != nil {
+			request.ParallelToolCalls = openai.Bool(*chatParams.ParallelToolCalls)
+		}
 	}
 
 	if chatParams.StreamOptions != nil && chatParams.StreamOptions.IncludeUsage {
@@ -72,11 +133,34 @@ func ToChatCompletionParams(chatParams *types.ChatParams) (openai.ChatCompletion
 					Name:        name,
 					Description: openai.String(rf.Description),
 					Schema:      rf.Schema,
-					Strict:      openai.Bool(true),
+					Strict:      openai.Bool(rf.Strict),
 				},
 			},
 		}
 	}
+	if rf.Mode == types.ResponseFormatModeJSON {
+		request.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONObject: &shared.ResponseFormatJSONObjectParam{},
+		}
+	}
+
+	if fields := extraBodyFields(chatParams.Extra); len(fields) > 0 {
+		request.SetExtraFields(fields)
+	}
 
 	return request, nil
 }
+
+// extraBodyFields returns the ChatParams.Extra entries to send as additional JSON body fields
+func extraBodyFields(extra map[string]any) map[string]any {
+	fields := make(map[string]any, len(extra))
+	for key, value := range extra {
+		switch key {
+		case ExtraPreviousResponseID, ExtraBuiltinTools:
+			// Interpreted by ToResponseNewParams
+			continue
+		}
+		fields[key] = value
+	}
+	return fields
+}
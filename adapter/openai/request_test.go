@@ -1,6 +1,8 @@
 package openai
 
 import (
+	json "encoding/json/v2"
+	"errors"
 	"testing"
 
 	"github.com/KennyKeni/elysia/types"
@@ -43,3 +45,227 @@ func TestToChatCompletionParamsStreamOptionsOmittedWhenFalse(t *testing.T) {
 		t.Fatalf("expected include_usage to be omitted when false")
 	}
 }
+
+func TestToChatCompletionParamsN(t *testing.T) {
+	params := &types.ChatParams{Model: "gpt-4o-mini"}
+	types.WithN(3)(params)
+
+	openaiParams, err := ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+	if openaiParams.N.Or(0) != 3 {
+		t.Fatalf("expected n=3, got %v", openaiParams.N)
+	}
+
+	if _, err := ToResponseNewParams(params); !errors.Is(err, ErrMultipleChoicesNotSupported) {
+		t.Fatalf("expected ErrMultipleChoicesNotSupported from Responses API, got %v", err)
+	}
+}
+
+func TestToChatCompletionParamsSeed(t *testing.T) {
+	params := &types.ChatParams{Model: "gpt-4o-mini"}
+	types.WithSeed(7)(params)
+
+	openaiParams, err := ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+	if openaiParams.Seed.Or(0) != 7 {
+		t.Fatalf("expected seed=7, got %v", openaiParams.Seed)
+	}
+}
+
+func TestToChatCompletionParamsPenalties(t *testing.T) {
+	params := &types.ChatParams{Model: "gpt-4o-mini"}
+	for _, opt := range []types.ChatParamOption{
+		types.WithFrequencyPenalty(0.5),
+		types.WithPresencePenalty(-0.25),
+		types.WithLogitBias(map[string]int{"50256": -100}),
+	} {
+		opt(params)
+	}
+
+	openaiParams, err := ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+	if openaiParams.FrequencyPenalty.Or(0) != 0.5 || openaiParams.PresencePenalty.Or(0) != -0.25 {
+		t.Fatalf("unexpected penalties: %v / %v", openaiParams.FrequencyPenalty, openaiParams.PresencePenalty)
+	}
+	if openaiParams.LogitBias["50256"] != -100 {
+		t.Fatalf("unexpected logit bias: %v", openaiParams.LogitBias)
+	}
+}
+
+func TestToChatCompletionParamsParallelToolCalls(t *testing.T) {
+	params := &types.ChatParams{Model: "gpt-4o-mini"}
+	types.WithParallelToolCalls(false)(params)
+
+	openaiParams, err := ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+	if openaiParams.ParallelToolCalls.Valid() {
+		t.Fatalf("expected parallel_tool_calls to be omitted without tools")
+	}
+
+	params.Tools = []types.ToolDefinition{{Name: "lookup", InputSchema: map[string]any{"type": "object"}}}
+	openaiParams, err = ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+	if !openaiParams.ParallelToolCalls.Valid() || openaiParams.ParallelToolCalls.Or(true) {
+		t.Fatalf("expected parallel_tool_calls=false, got %v", openaiParams.ParallelToolCalls)
+	}
+}
+
+func TestToChatCompletionParamsExtraBodyFields(t *testing.T) {
+	params := &types.ChatParams{Model: "gpt-4o-mini"}
+	types.WithExtras(map[string]any{"top_k": 20, ExtraPreviousResponseID: "resp_1"})(params)
+
+	openaiParams, err := ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+
+	data, err := openaiParams.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON returned error: %v", err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body["top_k"] != float64(20) {
+		t.Errorf("expected top_k extra field in body, got %s", data)
+	}
+	if _, ok := body[ExtraPreviousResponseID]; ok {
+		t.Errorf("expected Responses API key to be left out, got %s", data)
+	}
+}
+
+func TestToChatCompletionParamsReasoningModel(t *testing.T) {
+	params := &types.ChatParams{Model: "o4-mini"}
+	for _, opt := range []types.ChatParamOption{
+		types.WithMaxTokens(1000),
+		types.WithTemperature(0.2),
+		types.WithReasoningEffort(types.ReasoningEffortHigh),
+	} {
+		opt(params)
+	}
+
+	openaiParams, err := ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+	if openaiParams.MaxTokens.Valid() || openaiParams.MaxCompletionTokens.Or(0) != 1000 {
+		t.Errorf("expected max_completion_tokens=1000 instead of max_tokens, got %v / %v", openaiParams.MaxTokens, openaiParams.MaxCompletionTokens)
+	}
+	if openaiParams.Temperature.Valid() {
+		t.Errorf("expected temperature to be dropped for a reasoning model")
+	}
+	if openaiParams.ReasoningEffort != "high" {
+		t.Errorf("expected reasoning_effort=high, got %q", openaiParams.ReasoningEffort)
+	}
+
+	params.Model = "gpt-4o-mini"
+	openaiParams, err = ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+	if openaiParams.MaxTokens.Or(0) != 1000 || openaiParams.Temperature.Or(0) != 0.2 {
+		t.Errorf("expected max_tokens and temperature for a non-reasoning model")
+	}
+}
+
+func TestToChatCompletionParamsStrict(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"city": map[string]any{"type": "string"}},
+	}
+	params := &types.ChatParams{
+		Model:          "gpt-4o-mini",
+		Tools:          []types.ToolDefinition{{Name: "lookup", InputSchema: schema, Strict: true}},
+		ResponseFormat: types.ResponseFormat{Mode: types.ResponseFormatModeNative, Schema: schema},
+	}
+
+	openaiParams, err := ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+
+	function := openaiParams.Tools[0].OfFunction.Function
+	if !function.Strict.Or(false) || function.Parameters["additionalProperties"] != false {
+		t.Errorf("expected strict tool with strict schema, got %+v", function)
+	}
+	if openaiParams.ResponseFormat.OfJSONSchema.JSONSchema.Strict.Or(true) {
+		t.Errorf("expected non-strict response format")
+	}
+	if _, ok := schema["additionalProperties"]; ok {
+		t.Errorf("caller's schema was modified")
+	}
+}
+
+func TestToChatCompletionParamsJSONMode(t *testing.T) {
+	params := &types.ChatParams{
+		Model:          "gpt-4o-mini",
+		ResponseFormat: types.ResponseFormat{Mode: types.ResponseFormatModeJSON},
+	}
+
+	openaiParams, err := ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+	if openaiParams.ResponseFormat.OfJSONObject == nil || openaiParams.ResponseFormat.OfJSONSchema != nil {
+		t.Errorf("expected json_object response format, got %+v", openaiParams.ResponseFormat)
+	}
+}
+
+func TestToChatCompletionParamsStoreMetadataServiceTier(t *testing.T) {
+	params := &types.ChatParams{Model: "gpt-4o-mini"}
+	for _, opt := range []types.ChatParamOption{
+		types.WithStore(true),
+		types.WithRequestMetadata(map[string]string{"run": "eval-1"}),
+		types.WithServiceTier("flex"),
+	} {
+		opt(params)
+	}
+
+	openaiParams, err := ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+	if !openaiParams.Store.Or(false) || openaiParams.Metadata["run"] != "eval-1" || openaiParams.ServiceTier != "flex" {
+		t.Errorf("unexpected store/metadata/service tier: %v %v %q", openaiParams.Store, openaiParams.Metadata, openaiParams.ServiceTier)
+	}
+
+	responseParams, err := ToResponseNewParams(params)
+	if err != nil {
+		t.Fatalf("ToResponseNewParams returned error: %v", err)
+	}
+	if !responseParams.Store.Or(false) || responseParams.Metadata["run"] != "eval-1" || responseParams.ServiceTier != "flex" {
+		t.Errorf("unexpected Responses API store/metadata/service tier")
+	}
+}
+
+func TestToChatCompletionParamsPromptCacheKey(t *testing.T) {
+	params := &types.ChatParams{Model: "gpt-4o-mini"}
+	types.WithPromptCacheKey("support-agent")(params)
+
+	openaiParams, err := ToChatCompletionParams(params)
+	if err != nil {
+		t.Fatalf("ToChatCompletionParams returned error: %v", err)
+	}
+	if openaiParams.PromptCacheKey.Or("") != "support-agent" {
+		t.Errorf("PromptCacheKey = %v, want support-agent", openaiParams.PromptCacheKey)
+	}
+
+	responseParams, err := ToResponseNewParams(params)
+	if err != nil {
+		t.Fatalf("ToResponseNewParams returned error: %v", err)
+	}
+	if responseParams.PromptCacheKey.Or("") != "support-agent" {
+		t.Errorf("Responses API PromptCacheKey = %v, want support-agent", responseParams.PromptCacheKey)
+	}
+}
@@ -1,6 +1,8 @@
 package openai
 
 import (
+	"encoding/json/jsontext"
+
 	"github.com/KennyKeni/elysia/types"
 	"github.com/openai/openai-go/v3"
 )
@@ -17,7 +19,9 @@ func FromChatCompletion(completion *openai.ChatCompletion) *types.ChatResponse {
 		Model:   completion.Model,
 		Choices: make([]types.Choice, len(completion.Choices)),
 		Usage:   FromUsage(&completion.Usage),
-		Extra:   make(map[string]any),
+		Extra:   fromExtra(completion.RawJSON(), string(completion.ServiceTier)),
+
+		SystemFingerprint: completion.SystemFingerprint,
 	}
 
 	for i, choice := range completion.Choices {
@@ -27,6 +31,19 @@ func FromChatCompletion(completion *openai.ChatCompletion) *types.ChatResponse {
 	return response
 }
 
+// fromExtra returns the ChatResponse.Extra of a response or chunk: its raw JSON and the
+// service tier that processed it
+func fromExtra(raw string, serviceTier string) map[string]any {
+	extra := make(map[string]any)
+	if raw != "" {
+		extra[types.ExtraRaw] = jsontext.Value(raw)
+	}
+	if serviceTier != "" {
+		extra["service_tier"] = serviceTier
+	}
+	return extra
+}
+
 // fromChoice converts an OpenAI ChatCompletionChoice to types.Choice
 func fromChoice(choice *openai.ChatCompletionChoice) types.Choice {
 	if choice == nil {
@@ -50,5 +67,13 @@ func FromUsage(usage *openai.CompletionUsage) *types.Usage {
 		PromptTokens:     usage.PromptTokens,
 		CompletionTokens: usage.CompletionTokens,
 		TotalTokens:      usage.TotalTokens,
+		PromptTokensDetails: types.PromptTokensDetails{
+			CachedTokens: usage.PromptTokensDetails.CachedTokens,
+			AudioTokens:  usage.PromptTokensDetails.AudioTokens,
+		},
+		CompletionTokensDetails: types.CompletionTokensDetails{
+			ReasoningTokens: usage.CompletionTokensDetails.ReasoningTokens,
+			AudioTokens:     usage.CompletionTokensDetails.AudioTokens,
+		},
 	}
 }
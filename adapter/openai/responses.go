@@ -0,0 +1,68 @@
+package openai
+
+import (
+	"context"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+	"github.com/openai/openai-go/v3"
+)
+
+// ResponsesClient calls the OpenAI Responses API instead of Chat Completions and implements the
+// unified chat interface. ChatResponse.ID is the response ID, usable with WithPreviousResponseID.
+type ResponsesClient struct {
+	*Client
+}
+
+// NewResponsesClient creates a new OpenAI Responses API client wrapped with ResponseFormat handling
+func NewResponsesClient(opts ...client.Option) types.Client {
+	cfg := client.NewConfig(opts...)
+	return types.NewClient(newResponsesRawClient(opts...), types.WithLogger(cfg.Logger), types.WithLogContent(cfg.LogContent), types.WithDocumentConverter(cfg.DocumentConverter), types.WithChunkTimeout(cfg.ChunkTimeout))
+}
+
+// newResponsesRawClient creates the raw Responses API client (internal)
+func newResponsesRawClient(opts ...client.Option) *ResponsesClient {
+	return &ResponsesClient{Client: newRawClient(opts...)}
+}
+
+// NewResponsesClientFromOpenAI creates a new Responses API client from an existing OpenAI SDK client
+func NewResponsesClientFromOpenAI(c openai.Client) types.Client {
+	return types.NewClient(&ResponsesClient{Client: &Client{client: c}})
+}
+
+// RawChat performs a non-streaming Responses API request
+func (c *ResponsesClient) RawChat(ctx context.Context, params *types.ChatParams) (*types.ChatResponse, error) {
+	if err := c.validateModel(params); err != nil {
+		return nil, err
+	}
+
+	responseParams, err := ToResponseNewParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.client.Responses.New(ctx, responseParams, c.requestOptions(params.Model)...)
+	if err != nil {
+		return nil, wrapAPIError(err)
+	}
+	if response == nil {
+		return nil, ErrNilCompletion
+	}
+
+	return FromResponse(response), nil
+}
+
+// RawChatStream performs a streaming Responses API request and returns an iterator over chunks.
+func (c *ResponsesClient) RawChatStream(ctx context.Context, params *types.ChatParams) (*types.Stream, error) {
+	if err := c.validateModel(params); err != nil {
+		return nil, err
+	}
+
+	responseParams, err := ToResponseNewParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := c.client.Responses.NewStreaming(ctx, responseParams, c.requestOptions(params.Model)...)
+	return newResponseStream(stream), nil
+}
@@ -0,0 +1,299 @@
+package openai
+
+import (
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/types"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+const (
+	// ExtraPreviousResponseID is the ChatParams.Extra key holding the ID of a previous Responses
+	// API response to continue from, see WithPreviousResponseID.
+	ExtraPreviousResponseID = "previous_response_id"
+
+	// ExtraBuiltinTools is the ChatParams.Extra key holding []responses.ToolUnionParam of
+	// built-in Responses API tools, see WithWebSearch and WithFileSearch.
+	ExtraBuiltinTools = "builtin_tools"
+)
+
+// WithPreviousResponseID continues the conversation stored server-side under a previous
+// Responses API response ID (ChatResponse.ID). Messages then only need the new turns.
+func WithPreviousResponseID(id string) types.ChatParamOption {
+	return types.WithExtras(map[string]any{ExtraPreviousResponseID: id})
+}
+
+// WithWebSearch enables the built-in web_search tool of the Responses API
+func WithWebSearch() types.ChatParamOption {
+	return withBuiltinTool(responses.ToolParamOfWebSearch(responses.WebSearchToolTypeWebSearch))
+}
+
+// WithFileSearch enables the built-in file_search tool of the Responses API over the given vector stores
+func WithFileSearch(vectorStoreIDs ...string) types.ChatParamOption {
+	return withBuiltinTool(responses.ToolParamOfFileSearch(vectorStoreIDs))
+}
+
+func withBuiltinTool(tool responses.ToolUnionParam) types.ChatParamOption {
+	return func(p *types.ChatParams) {
+		tools, _ := p.Extra[ExtraBuiltinTools].([]responses.ToolUnionParam)
+		// Copy so params cloned from the same base don't share the backing array
+		tools = append(tools[:len(tools):len(tools)], tool)
+		types.WithExtras(map[string]any{ExtraBuiltinTools: tools})(p)
+	}
+}
+
+// ToResponseNewParams converts unified chat params to Responses API request parameters.
+// The system prompt is sent as instructions; Stop, TopK, penalties and LogitBias have no
+// Responses API equivalent and are ignored. Other Extra entries are sent as additional JSON body fields.
+func ToResponseNewParams(chatParams *types.ChatParams) (responses.ResponseNewParams, error) {
+	if chatParams == nil {
+		return responses.ResponseNewParams{}, errors.New("nil chatParams")
+	}
+
+	if chatParams.N != nil && *chatParams.N > 1 {
+		return responses.ResponseNewParams{}, ErrMultipleChoicesNotSupported
+	}
+
+	params := responses.ResponseNewParams{
+		Model: chatParams.Model,
+	}
+
+	if chatParams.SystemPrompt != "" {
+		params.Instructions = openai.String(chatParams.SystemPrompt)
+	}
+	if chatParams.MaxTokens != nil {
+		params.MaxOutputTokens = openai.Int(int64(*chatParams.MaxTokens))
+	}
+	// Sampling parameters are rejected by reasoning models
+	if !IsReasoningModel(chatParams.Model) {
+		if chatParams.Temperature != nil {
+			params.Temperature = openai.Float(*chatParams.Temperature)
+		}
+		if chatParams.TopP != nil {
+			params.TopP = openai.Float(*chatParams.TopP)
+		}
+	}
+	if chatParams.ReasoningEffort != "" {
+		params.Reasoning = shared.ReasoningParam{Effort: shared.ReasoningEffort(chatParams.ReasoningEffort)}
+	}
+	if chatParams.Store != nil {
+		params.Store = openai.Bool(*chatParams.Store)
+	}
+	if len(chatParams.Metadata) > 0 {
+		params.Metadata = shared.Metadata(chatParams.Metadata)
+	}
+	if chatParams.ServiceTier != "" {
+		params.ServiceTier = responses.ResponseNewParamsServiceTier(chatParams.ServiceTier)
+	}
+	if chatParams.PromptCacheKey != "" {
+		params.PromptCacheKey = openai.String(chatParams.PromptCacheKey)
+	}
+	if id, ok := chatParams.Extra[ExtraPreviousResponseID].(string); ok && id != "" {
+		params.PreviousResponseID = openai.String(id)
+	}
+
+	input, err := ToResponseInput(chatParams.Messages)
+	if err != nil {
+		return responses.ResponseNewParams{}, fmt.Errorf("ToResponseInput failed: %w", err)
+	}
+	params.Input = responses.ResponseNewParamsInputUnion{OfInputItemList: input}
+
+	for _, definition := range chatParams.Tools {
+		if definition.InputSchema == nil {
+			return responses.ResponseNewParams{}, fmt.Errorf("tool %s has nil input schema", definition.Name)
+		}
+		tool := responses.FunctionToolParam{
+			Name:       definition.Name,
+			Parameters: definition.InputSchema,
+			Strict:     openai.Bool(definition.Strict),
+		}
+		if definition.Strict {
+			tool.Parameters = types.StrictSchema(definition.InputSchema)
+		}
+		if definition.Description != "" {
+			tool.Description = openai.String(definition.Description)
+		}
+		params.Tools = append(params.Tools, responses.ToolUnionParam{OfFunction: &tool})
+	}
+	if builtin, ok := chatParams.Extra[ExtraBuiltinTools].([]responses.ToolUnionParam); ok {
+		params.Tools = append(params.Tools, builtin...)
+	}
+
+	if len(params.Tools) > 0 && chatParams.ToolChoice != nil {
+		params.ToolChoice = toResponseToolChoice(chatParams.ToolChoice)
+	}
+	if len(params.Tools) > 0 && chatParams.ParallelToolCalls != nil {
+		params.ParallelToolCalls = openai.Bool(*chatParams.ParallelToolCalls)
+	}
+
+	rf := chatParams.ResponseFormat
+	if rf.Mode == types.ResponseFormatModeNative && rf.Schema != nil {
+		name := rf.Name
+		if name == "" {
+			name = "response"
+		}
+		format := responses.ResponseFormatTextJSONSchemaConfigParam{
+			Name:   name,
+			Schema: rf.Schema,
+			Strict: openai.Bool(rf.Strict),
+		}
+		if rf.Description != "" {
+			format.Description = openai.String(rf.Description)
+		}
+		params.Text = responses.ResponseTextConfigParam{
+			Format: responses.ResponseFormatTextConfigUnionParam{OfJSONSchema: &format},
+		}
+	}
+	if rf.Mode == types.ResponseFormatModeJSON {
+		params.Text = responses.ResponseTextConfigParam{
+			Format: responses.ResponseFormatTextConfigUnionParam{OfJSONObject: &shared.ResponseFormatJSONObjectParam{}},
+		}
+	}
+
+	if fields := extraBodyFields(chatParams.Extra); len(fields) > 0 {
+		params.SetExtraFields(fields)
+	}
+
+	return params, nil
+}
+
+func toResponseToolChoice(toolChoice *types.ToolChoice) responses.ResponseNewParamsToolChoiceUnion {
+	switch toolChoice.Mode {
+	case types.ToolChoiceModeNone:
+		return responses.ResponseNewParamsToolChoiceUnion{OfToolChoiceMode: openai.Opt(responses.ToolChoiceOptionsNone)}
+	case types.ToolChoiceModeRequired:
+		return responses.ResponseNewParamsToolChoiceUnion{OfToolChoiceMode: openai.Opt(responses.ToolChoiceOptionsRequired)}
+	case types.ToolChoiceModeTool:
+		return responses.ResponseNewParamsToolChoiceUnion{OfFunctionTool: &responses.ToolChoiceFunctionParam{Name: toolChoice.Name}}
+	default:
+		return responses.ResponseNewParamsToolChoiceUnion{OfToolChoiceMode: openai.Opt(responses.ToolChoiceOptionsAuto)}
+	}
+}
+
+// ToResponseInput converts unified messages to Responses API input items. Assistant tool calls
+// become function_call items and tool messages function_call_output items.
+func ToResponseInput(messages []types.Message) (responses.ResponseInputParam, error) {
+	input := make(responses.ResponseInputParam, 0, len(messages))
+
+	for _, message := range messages {
+		switch message.Role {
+		case types.RoleUser:
+			content, err := toResponseUserContent(&message)
+			if err != nil {
+				return nil, err
+			}
+			input = append(input, responses.ResponseInputItemUnionParam{OfMessage: &responses.EasyInputMessageParam{
+				Role:    responses.EasyInputMessageRoleUser,
+				Content: responses.EasyInputMessageContentUnionParam{OfInputItemContentList: content},
+			}})
+
+		case types.RoleAssistant:
+			for _, contentPart := range message.ContentPart {
+				switch contentPart.(type) {
+				case *types.ContentPartText, *types.ContentPartRefusal, *types.ContentPartReasoning:
+				default:
+					return nil, &types.UnsupportedContentError{Part: contentPart, Err: ErrUnsupportedAssistantContentPart}
+				}
+			}
+			if text := message.TextContent(); text != "" {
+				input = append(input, responses.ResponseInputItemUnionParam{OfMessage: &responses.EasyInputMessageParam{
+					Role:    responses.EasyInputMessageRoleAssistant,
+					Content: responses.EasyInputMessageContentUnionParam{OfString: openai.String(text)},
+				}})
+			}
+			for _, toolCall := range message.ToolCalls {
+				if toolCall.ID == "" {
+					return nil, fmt.Errorf("%w: tool call %q has empty ID", ErrMissingToolCallID, toolCall.Function.Name)
+				}
+				args := toolCall.Function.Arguments
+				if args == nil {
+					args = map[string]any{}
+				}
+				argsJSON, err := json.Marshal(args)
+				if err != nil {
+					return nil, fmt.Errorf("failed to marshal tool call arguments: %w", err)
+				}
+				input = append(input, responses.ResponseInputItemUnionParam{OfFunctionCall: &responses.ResponseFunctionToolCallParam{
+					CallID:    toolCall.ID,
+					Name:      toolCall.Function.Name,
+					Arguments: string(argsJSON),
+				}})
+			}
+
+		case types.RoleTool:
+			for _, contentPart := range message.ContentPart {
+				if _, ok := contentPart.(*types.ContentPartText); !ok {
+					return nil, &types.UnsupportedContentError{Part: contentPart, Err: ErrUnsupportedToolContentPart}
+				}
+			}
+			if message.ToolCallID == nil {
+				return nil, ErrMissingToolCallID
+			}
+			input = append(input, responses.ResponseInputItemUnionParam{OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
+				CallID: *message.ToolCallID,
+				Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfString: openai.String(message.TextContent())},
+			}})
+
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedMessageRole, message.Role)
+		}
+	}
+
+	return input, nil
+}
+
+func toResponseUserContent(message *types.Message) (responses.ResponseInputMessageContentListParam, error) {
+	content := make(responses.ResponseInputMessageContentListParam, 0, len(message.ContentPart))
+
+	for _, contentPart := range message.ContentPart {
+		switch part := contentPart.(type) {
+		case *types.ContentPartText:
+			content = append(content, responses.ResponseInputContentUnionParam{OfInputText: &responses.ResponseInputTextParam{Text: part.Text}})
+		case *types.ContentPartImage:
+			mimeType := part.MIMEType
+			if mimeType == "" {
+				mimeType = "image/png"
+			}
+			content = append(content, responses.ResponseInputContentUnionParam{OfInputImage: &responses.ResponseInputImageParam{
+				ImageURL: openai.String(fmt.Sprintf("data:%s;base64,%s", mimeType, part.Data)),
+				Detail:   toResponseImageDetail(part.Detail),
+			}})
+		case *types.ContentPartImageURL:
+			content = append(content, responses.ResponseInputContentUnionParam{OfInputImage: &responses.ResponseInputImageParam{
+				ImageURL: openai.String(part.URL),
+				Detail:   responses.ResponseInputImageDetailAuto,
+			}})
+		case *types.ContentPartFile:
+			file := &responses.ResponseInputFileParam{}
+			if part.FileID != "" {
+				file.FileID = openai.String(part.FileID)
+			}
+			if part.Data != "" {
+				file.FileData = openai.String(fileDataURL(part))
+			}
+			if part.Filename != "" {
+				file.Filename = openai.String(part.Filename)
+			}
+			content = append(content, responses.ResponseInputContentUnionParam{OfInputFile: file})
+		default:
+			return nil, &types.UnsupportedContentError{Part: part, Err: ErrUnsupportedUserContentPart}
+		}
+	}
+
+	return content, nil
+}
+
+func toResponseImageDetail(detail string) responses.ResponseInputImageDetail {
+	switch types.ImageDetail(detail) {
+	case types.ImageDetailLow:
+		return responses.ResponseInputImageDetailLow
+	case types.ImageDetailHigh:
+		return responses.ResponseInputImageDetailHigh
+	default:
+		return responses.ResponseInputImageDetailAuto
+	}
+}
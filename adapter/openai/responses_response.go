@@ -0,0 +1,252 @@
+package openai
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KennyKeni/elysia/types"
+	"github.com/openai/openai-go/v3/packages/ssestream"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// FromResponse converts a Responses API response to the unified types.ChatResponse.
+// All output items are folded into a single assistant message: text and refusals become
+// content parts, reasoning summaries a ContentPartReasoning and function_call items tool calls.
+// Citations in the text become Message.Annotations.
+// Built-in tool calls (web_search, file_search) run server-side and are not surfaced.
+func FromResponse(response *responses.Response) *types.ChatResponse {
+	if response == nil {
+		return nil
+	}
+
+	message := &types.Message{
+		Role:        types.RoleAssistant,
+		ContentPart: make([]types.ContentPart, 0),
+		ToolCalls:   make([]types.ToolCall, 0),
+	}
+
+	var reasoning, text, refusal strings.Builder
+	for _, item := range response.Output {
+		switch item.Type {
+		case "message":
+			for _, content := range item.Content {
+				switch content.Type {
+				case "output_text":
+					offset := utf8.RuneCountInString(text.String())
+					for _, annotation := range content.Annotations {
+						if converted, ok := fromResponseAnnotation(annotation, offset); ok {
+							message.Annotations = append(message.Annotations, converted)
+						}
+					}
+					text.WriteString(content.Text)
+				case "refusal":
+					refusal.WriteString(content.Refusal)
+				}
+			}
+		case "reasoning":
+			for _, summary := range item.Summary {
+				reasoning.WriteString(summary.Text)
+			}
+		case "function_call":
+			message.ToolCalls = append(message.ToolCalls, types.ToolCall{
+				ID:       item.CallID,
+				Function: types.NewToolFunction(item.Name, item.Arguments),
+			})
+		}
+	}
+
+	if reasoning.Len() > 0 {
+		message.ContentPart = append(message.ContentPart, types.NewContentPartReasoning(reasoning.String()))
+	}
+	if text.Len() > 0 {
+		message.ContentPart = append(message.ContentPart, types.NewContentPartText(text.String()))
+	}
+	if refusal.Len() > 0 {
+		message.ContentPart = append(message.ContentPart, types.NewContentPartRefusal(refusal.String()))
+	}
+
+	return &types.ChatResponse{
+		ID:      response.ID,
+		Created: int64(response.CreatedAt),
+		Model:   string(response.Model),
+		Choices: []types.Choice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: fromResponseStatus(response, len(message.ToolCalls) > 0),
+		}},
+		Usage: fromResponseUsage(&response.Usage),
+		Extra: fromExtra(response.RawJSON(), string(response.ServiceTier)),
+	}
+}
+
+// fromResponseAnnotation converts a citation of output text starting offset characters into
+// the message text; ok is false for annotations that are not citations (file_path).
+func fromResponseAnnotation(annotation responses.ResponseOutputTextAnnotationUnion, offset int) (converted types.Annotation, ok bool) {
+	switch annotation.Type {
+	case "url_citation":
+		return types.Annotation{
+			Type:       types.AnnotationURLCitation,
+			StartIndex: offset + int(annotation.StartIndex),
+			EndIndex:   offset + int(annotation.EndIndex),
+			URL:        annotation.URL,
+			Title:      annotation.Title,
+		}, true
+	case "file_citation":
+		// Cites a position rather than a span
+		index := offset + int(annotation.Index)
+		return types.Annotation{
+			Type:       types.AnnotationFileCitation,
+			StartIndex: index,
+			EndIndex:   index,
+			FileID:     annotation.FileID,
+			Filename:   annotation.Filename,
+		}, true
+	case "container_file_citation":
+		return types.Annotation{
+			Type:       types.AnnotationFileCitation,
+			StartIndex: offset + int(annotation.StartIndex),
+			EndIndex:   offset + int(annotation.EndIndex),
+			FileID:     annotation.FileID,
+			Filename:   annotation.Filename,
+		}, true
+	}
+	return types.Annotation{}, false
+}
+
+// fromResponseStatus maps a response status to the Chat Completions finish reasons used across adapters
+func fromResponseStatus(response *responses.Response, hasToolCalls bool) string {
+	if response.Status == responses.ResponseStatusIncomplete {
+		switch response.IncompleteDetails.Reason {
+		case "max_output_tokens":
+			return "length"
+		case "content_filter":
+			return "content_filter"
+		}
+	}
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func fromResponseUsage(usage *responses.ResponseUsage) *types.Usage {
+	if usage == nil || usage.JSON.TotalTokens.Raw() == "" {
+		return nil
+	}
+	return &types.Usage{
+		PromptTokens:     usage.InputTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      usage.TotalTokens,
+		PromptTokensDetails: types.PromptTokensDetails{
+			CachedTokens: usage.InputTokensDetails.CachedTokens,
+		},
+		CompletionTokensDetails: types.CompletionTokensDetails{
+			ReasoningTokens: usage.OutputTokensDetails.ReasoningTokens,
+		},
+	}
+}
+
+// responseStream turns Responses API events into unified stream chunks
+type responseStream struct {
+	stream      *ssestream.Stream[responses.ResponseStreamEventUnion]
+	id          string
+	model       string
+	created     int64
+	toolIndexes map[int64]int // output_index -> tool call index
+}
+
+func newResponseStream(stream *ssestream.Stream[responses.ResponseStreamEventUnion]) *types.Stream {
+	s := &responseStream{stream: stream, toolIndexes: make(map[int64]int)}
+	return types.NewStream(s.next, s)
+}
+
+func (s *responseStream) next() (*types.StreamChunk, error) {
+	for s.stream.Next() {
+		event := s.stream.Current()
+		chunk, err := s.toChunk(&event)
+		if err != nil {
+			return nil, err
+		}
+		if chunk != nil {
+			chunk.Extra = fromExtra(event.RawJSON(), string(event.Response.ServiceTier))
+			return chunk, nil
+		}
+	}
+	if err := s.stream.Err(); err != nil {
+		return nil, wrapAPIError(err)
+	}
+	return nil, io.EOF
+}
+
+// toChunk converts an event to a chunk; events without a unified equivalent return nil.
+func (s *responseStream) toChunk(event *responses.ResponseStreamEventUnion) (*types.StreamChunk, error) {
+	switch event.Type {
+	case "response.created":
+		s.id = event.Response.ID
+		s.model = string(event.Response.Model)
+		s.created = int64(event.Response.CreatedAt)
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Role: types.RoleAssistant}}), nil
+
+	case "response.output_text.delta":
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Content: event.Delta}}), nil
+
+	case "response.refusal.delta":
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Refusal: event.Delta}}), nil
+
+	case "response.reasoning_summary_text.delta":
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{Reasoning: event.Delta}}), nil
+
+	case "response.output_item.added":
+		if event.Item.Type != "function_call" {
+			return nil, nil
+		}
+		index := len(s.toolIndexes)
+		s.toolIndexes[event.OutputIndex] = index
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{ToolCalls: []types.ToolCallDelta{{
+			Index:        index,
+			ID:           event.Item.CallID,
+			FunctionName: event.Item.Name,
+		}}}}), nil
+
+	case "response.function_call_arguments.delta":
+		index, ok := s.toolIndexes[event.OutputIndex]
+		if !ok {
+			return nil, nil
+		}
+		return s.chunk(types.StreamChoice{Delta: &types.MessageDelta{ToolCalls: []types.ToolCallDelta{{
+			Index:     index,
+			Arguments: event.Delta,
+		}}}}), nil
+
+	case "response.completed", "response.incomplete":
+		chunk := s.chunk(types.StreamChoice{
+			Delta:        &types.MessageDelta{},
+			FinishReason: fromResponseStatus(&event.Response, len(s.toolIndexes) > 0),
+		})
+		chunk.Usage = fromResponseUsage(&event.Response.Usage)
+		return chunk, nil
+
+	case "response.failed":
+		return nil, fmt.Errorf("openai responses: %s: %s", event.Response.Error.Code, event.Response.Error.Message)
+
+	case "error":
+		return nil, fmt.Errorf("openai responses: %s: %s", event.Code, event.Message)
+	}
+
+	return nil, nil
+}
+
+func (s *responseStream) chunk(choice types.StreamChoice) *types.StreamChunk {
+	return &types.StreamChunk{
+		ID:      s.id,
+		Created: s.created,
+		Model:   s.model,
+		Choices: []types.StreamChoice{choice},
+	}
+}
+
+func (s *responseStream) Close() error {
+	return s.stream.Close()
+}
@@ -0,0 +1,268 @@
+package openai
+
+import (
+	json "encoding/json/v2"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+const sampleResponseJSON = `{
+  "id": "resp_1", "object": "response", "created_at": 1700000000, "model": "gpt-4.1",
+  "status": "completed", "error": null, "incomplete_details": null, "instructions": null,
+  "metadata": {}, "parallel_tool_calls": true, "temperature": 1, "tool_choice": "auto", "tools": [], "top_p": 1,
+  "output": [
+    {"type": "web_search_call", "id": "ws_1", "status": "completed"},
+    {"type": "reasoning", "id": "rs_1", "summary": [{"type": "summary_text", "text": "Searched the web."}]},
+    {"type": "message", "id": "msg_1", "role": "assistant", "status": "completed",
+     "content": [{"type": "output_text", "text": "It is sunny.", "annotations": []}]},
+    {"type": "function_call", "id": "fc_1", "call_id": "call_1", "name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}
+  ],
+  "usage": {"input_tokens": 10, "input_tokens_details": {"cached_tokens": 0},
+            "output_tokens": 5, "output_tokens_details": {"reasoning_tokens": 0}, "total_tokens": 15}
+}`
+
+const sampleResponseStream = `event: response.created
+data: {"type":"response.created","sequence_number":0,"response":{"id":"resp_2","object":"response","created_at":1700000000,"model":"gpt-4.1","status":"in_progress","output":[]}}
+
+event: response.output_text.delta
+data: {"type":"response.output_text.delta","sequence_number":1,"item_id":"msg_1","output_index":0,"content_index":0,"delta":"Hel"}
+
+event: response.output_text.delta
+data: {"type":"response.output_text.delta","sequence_number":2,"item_id":"msg_1","output_index":0,"content_index":0,"delta":"lo"}
+
+event: response.output_item.added
+data: {"type":"response.output_item.added","sequence_number":3,"output_index":1,"item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"get_weather","arguments":""}}
+
+event: response.function_call_arguments.delta
+data: {"type":"response.function_call_arguments.delta","sequence_number":4,"item_id":"fc_1","output_index":1,"delta":"{\"city\":"}
+
+event: response.function_call_arguments.delta
+data: {"type":"response.function_call_arguments.delta","sequence_number":5,"item_id":"fc_1","output_index":1,"delta":"\"Paris\"}"}
+
+event: response.completed
+data: {"type":"response.completed","sequence_number":6,"response":{"id":"resp_2","object":"response","created_at":1700000000,"model":"gpt-4.1","status":"completed","output":[],"usage":{"input_tokens":3,"output_tokens":4,"total_tokens":7,"input_tokens_details":{"cached_tokens":0},"output_tokens_details":{"reasoning_tokens":0}}}}
+
+`
+
+func newResponsesTestServer(t *testing.T, contentType, reply string) (*httptest.Server, *map[string]any) {
+	t.Helper()
+	body := map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", contentType)
+		_, _ = io.WriteString(w, reply)
+	}))
+	t.Cleanup(server.Close)
+	return server, &body
+}
+
+func TestResponsesClientChat(t *testing.T) {
+	server, body := newResponsesTestServer(t, "application/json", sampleResponseJSON)
+	c := NewResponsesClient(client.WithBaseURL(server.URL), client.WithAPIKey("test"), client.WithMaxRetries(0))
+
+	toolCallID := "call_0"
+	params := &types.ChatParams{Model: "gpt-4.1"}
+	for _, opt := range []types.ChatParamOption{
+		types.WithMessages([]types.Message{
+			types.NewUserMessage(types.WithText("Weather?")),
+			types.NewAssistantMessage(types.WithToolCalls(types.ToolCall{
+				ID:       toolCallID,
+				Function: types.ToolFunction{Name: "get_weather", Arguments: map[string]any{"city": "Rome"}},
+			})),
+			types.NewToolMessage(types.WithText("rain"), types.WithToolCallID(toolCallID)),
+		}),
+		types.WithSystemPrompt("Be brief."),
+		types.WithToolDefinitions([]types.ToolDefinition{{Name: "get_weather", InputSchema: map[string]any{"type": "object"}}}),
+		WithWebSearch(),
+		WithFileSearch("vs_1"),
+		WithPreviousResponseID("resp_0"),
+	} {
+		opt(params)
+	}
+
+	resp, err := c.Chat(t.Context(), params)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	// Request mapping
+	req := *body
+	if req["instructions"] != "Be brief." || req["previous_response_id"] != "resp_0" {
+		t.Errorf("unexpected instructions/previous_response_id: %v / %v", req["instructions"], req["previous_response_id"])
+	}
+	var toolTypes []any
+	for _, tool := range req["tools"].([]any) {
+		toolTypes = append(toolTypes, tool.(map[string]any)["type"])
+	}
+	if len(toolTypes) != 3 || toolTypes[0] != "function" || toolTypes[1] != "web_search" || toolTypes[2] != "file_search" {
+		t.Errorf("unexpected tools: %v", toolTypes)
+	}
+	var itemTypes []any
+	for _, item := range req["input"].([]any) {
+		itemTypes = append(itemTypes, item.(map[string]any)["type"])
+	}
+	if len(itemTypes) != 3 || itemTypes[1] != "function_call" || itemTypes[2] != "function_call_output" {
+		t.Errorf("unexpected input items: %v", itemTypes)
+	}
+
+	// Response mapping
+	if resp.ID != "resp_1" {
+		t.Errorf("ID = %q", resp.ID)
+	}
+	msg := resp.Choices[0].Message
+	if msg.TextContent() != "It is sunny." || msg.ReasoningContent() != "Searched the web." {
+		t.Errorf("unexpected content: text %q, reasoning %q", msg.TextContent(), msg.ReasoningContent())
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID != "call_1" || msg.ToolCalls[0].Function.Arguments["city"] != "Paris" {
+		t.Errorf("unexpected tool calls: %+v", msg.ToolCalls)
+	}
+	if resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q", resp.Choices[0].FinishReason)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 15 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestResponsesClientNativeResponseFormat(t *testing.T) {
+	schema := map[string]any{"type": "object"}
+	params, err := ToResponseNewParams(&types.ChatParams{
+		Model:          "gpt-4.1",
+		ResponseFormat: types.ResponseFormat{Mode: types.ResponseFormatModeNative, Name: "answer", Schema: schema},
+	})
+	if err != nil {
+		t.Fatalf("ToResponseNewParams failed: %v", err)
+	}
+
+	format := params.Text.Format.OfJSONSchema
+	if format == nil || format.Name != "answer" {
+		t.Fatalf("expected json_schema text format, got %+v", params.Text.Format)
+	}
+}
+
+func TestResponsesClientStream(t *testing.T) {
+	server, _ := newResponsesTestServer(t, "text/event-stream", sampleResponseStream)
+	c := NewResponsesClient(client.WithBaseURL(server.URL), client.WithMaxRetries(0))
+
+	resp, err := types.StreamWithHandler(t.Context(), c, &types.ChatParams{
+		Model:    "gpt-4.1",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+	}, nil)
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+
+	if resp.ID != "resp_2" {
+		t.Errorf("ID = %q", resp.ID)
+	}
+	msg := resp.Choices[0].Message
+	if msg.TextContent() != "Hello" {
+		t.Errorf("text = %q", msg.TextContent())
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Arguments["city"] != "Paris" {
+		t.Errorf("unexpected tool calls: %+v", msg.ToolCalls)
+	}
+	if resp.Choices[0].FinishReason != "tool_calls" || resp.Usage == nil || resp.Usage.TotalTokens != 7 {
+		t.Errorf("unexpected finish %q / usage %+v", resp.Choices[0].FinishReason, resp.Usage)
+	}
+}
+
+func TestWithBuiltinToolDoesNotShareBackingArray(t *testing.T) {
+	base := &types.ChatParams{Model: "gpt-4.1"}
+	WithWebSearch()(base)
+	a := base.Clone()
+	b := base.Clone()
+	WithFileSearch("vs_a")(a)
+	WithFileSearch("vs_b")(b)
+
+	toolsA := a.Extra[ExtraBuiltinTools].([]responses.ToolUnionParam)
+	if len(toolsA) != 2 || toolsA[1].OfFileSearch.VectorStoreIDs[0] != "vs_a" {
+		t.Errorf("tools of a were overwritten: %+v", toolsA)
+	}
+}
+
+func TestChatTransientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "0.01")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"error":{"message":"slow down","type":"rate_limit_exceeded"}}`)
+	}))
+	t.Cleanup(server.Close)
+	c := NewClient(client.WithBaseURL(server.URL), client.WithAPIKey("test"), client.WithMaxRetries(0))
+
+	_, err := c.Chat(t.Context(), &types.ChatParams{
+		Model:    "gpt-4o",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+	})
+	transient, retryAfter := types.IsTransient(err)
+	if !transient || retryAfter != 10*time.Millisecond {
+		t.Errorf("IsTransient = %v, %v for %v", transient, retryAfter, err)
+	}
+	var sdkErr *openai.Error
+	if !errors.As(err, &sdkErr) || sdkErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected the SDK error to stay reachable, got %v", err)
+	}
+}
+
+func TestChatContextHeaders(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"error":{"message":"bad request"}}`)
+	}))
+	t.Cleanup(server.Close)
+	c := NewClient(client.WithBaseURL(server.URL), client.WithAPIKey("test"), client.WithHeader("X-Team", "search"))
+
+	ctx := types.WithRequestHeaders(t.Context(), http.Header{"X-Run-Id": {"run-1"}})
+	_, _ = c.Chat(ctx, &types.ChatParams{
+		Model:    "gpt-4o",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+	})
+	if header.Get("X-Run-Id") != "run-1" || header.Get("X-Team") != "search" {
+		t.Errorf("expected context and client headers, got %v", header)
+	}
+}
+
+func TestFromResponseAnnotations(t *testing.T) {
+	var response responses.Response
+	if err := json.Unmarshal([]byte(`{"id": "resp_1", "status": "completed", "output": [
+		{"type": "message", "id": "msg_1", "role": "assistant", "status": "completed", "content": [
+			{"type": "output_text", "text": "Süd: sunny. ", "annotations": [
+				{"type": "url_citation", "start_index": 0, "end_index": 11, "url": "https://weather.example", "title": "Forecast"}
+			]},
+			{"type": "output_text", "text": "See the report.", "annotations": [
+				{"type": "file_citation", "index": 15, "file_id": "file-1", "filename": "report.pdf"},
+				{"type": "file_path", "index": 4, "file_id": "file-2"}
+			]}
+		]}
+	]}`), &response); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	want := []types.Annotation{
+		{Type: types.AnnotationURLCitation, StartIndex: 0, EndIndex: 11, URL: "https://weather.example", Title: "Forecast"},
+		{Type: types.AnnotationFileCitation, StartIndex: 27, EndIndex: 27, FileID: "file-1", Filename: "report.pdf"},
+	}
+	if got := FromResponse(&response).Choices[0].Message.Annotations; !reflect.DeepEqual(got, want) {
+		t.Errorf("Annotations = %+v, want %+v", got, want)
+	}
+}
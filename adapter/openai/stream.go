@@ -19,6 +19,9 @@ func FromChatCompletionChunk(chunk *openai.ChatCompletionChunk) *types.StreamChu
 		Created: chunk.Created,
 		Model:   chunk.Model,
 		Choices: make([]types.StreamChoice, len(chunk.Choices)),
+		Extra:   fromExtra(chunk.RawJSON(), string(chunk.ServiceTier)),
+
+		SystemFingerprint: chunk.SystemFingerprint,
 	}
 
 	for i := range chunk.Choices {
@@ -58,9 +61,10 @@ func toMessageDelta(delta *openai.ChatCompletionChunkChoiceDelta) *types.Message
 	}
 
 	messageDelta := &types.MessageDelta{
-		Role:    types.Role(delta.Role),
-		Content: delta.Content,
-		Refusal: delta.Refusal,
+		Role:      types.Role(delta.Role),
+		Content:   delta.Content,
+		Refusal:   delta.Refusal,
+		Reasoning: extraString(delta.JSON.ExtraFields, "reasoning_content"),
 	}
 
 	toolCalls := make([]types.ToolCallDelta, 0, len(delta.ToolCalls))
@@ -105,7 +109,7 @@ func (w *chatStreamWrapper) next() (*types.StreamChunk, error) {
 
 	if !w.stream.Next() {
 		if err := w.stream.Err(); err != nil {
-			return nil, err
+			return nil, wrapAPIError(err)
 		}
 		return nil, io.EOF
 	}
@@ -1,6 +1,7 @@
 package openai
 
 import (
+	"encoding/json/jsontext"
 	json "encoding/json/v2"
 	"errors"
 	"testing"
@@ -38,13 +39,13 @@ const sampleChunkJSON = `{
 		}
 	],
 	"service_tier": null,
-	"system_fingerprint": "",
+	"system_fingerprint": "fp_1",
 	"usage": {
 		"prompt_tokens": 1,
 		"completion_tokens": 2,
 		"total_tokens": 3,
-		"completion_tokens_details": {},
-		"prompt_tokens_details": {}
+		"completion_tokens_details": {"reasoning_tokens": 1},
+		"prompt_tokens_details": {"cached_tokens": 1}
 	}
 }`
 
@@ -63,9 +64,16 @@ func TestFromChatCompletionChunk(t *testing.T) {
 		t.Fatalf("expected chunk ID %q, got %q", "chunk_1", streamChunk.ID)
 	}
 
+	if streamChunk.SystemFingerprint != "fp_1" {
+		t.Fatalf("expected system fingerprint %q, got %q", "fp_1", streamChunk.SystemFingerprint)
+	}
+
 	if streamChunk.Usage == nil || streamChunk.Usage.TotalTokens != 3 {
 		t.Fatalf("expected usage total tokens to be 3, got %#v", streamChunk.Usage)
 	}
+	if streamChunk.Usage.PromptTokensDetails.CachedTokens != 1 || streamChunk.Usage.CompletionTokensDetails.ReasoningTokens != 1 {
+		t.Fatalf("expected usage details to be populated, got %#v", streamChunk.Usage)
+	}
 
 	if len(streamChunk.Choices) != 1 {
 		t.Fatalf("expected 1 choice, got %d", len(streamChunk.Choices))
@@ -90,6 +98,13 @@ func TestFromChatCompletionChunk(t *testing.T) {
 	if delta.ToolCalls[0].Arguments != `{"arg": "value"}` {
 		t.Fatalf("expected tool call arguments %q, got %q", `{"arg": "value"}`, delta.ToolCalls[0].Arguments)
 	}
+
+	if raw, _ := streamChunk.Extra[types.ExtraRaw].(jsontext.Value); string(raw) != sampleChunkJSON {
+		t.Fatalf("expected the raw chunk in Extra, got %q", raw)
+	}
+	if _, ok := streamChunk.Extra["service_tier"]; ok {
+		t.Fatalf("expected no service tier for a null service_tier, got %v", streamChunk.Extra["service_tier"])
+	}
 }
 
 func TestChatStreamWrapper(t *testing.T) {
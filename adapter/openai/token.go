@@ -0,0 +1,112 @@
+package openai
+
+import (
+	json "encoding/json/v2"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+const (
+	// tokensPerMessage wraps every message in the chat format (<|start|>role ... <|end|>)
+	tokensPerMessage = 3
+
+	// tokensPerReply primes the assistant reply
+	tokensPerReply = 3
+
+	// tokensPerToolCall covers the structure around a tool call's name and arguments
+	tokensPerToolCall = 3
+
+	// Image tokens by detail level; high and auto assume a 1024x1024 image (4 tiles)
+	lowDetailImageTokens  = 85
+	highDetailImageTokens = 765
+)
+
+// pretokenizer splits text like the cl100k_base and o200k_base encodings do before applying
+// BPE merges. Go's regexp has no lookahead, so trailing whitespace runs are not split.
+var pretokenizer = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// TokenCounter estimates prompt tokens for OpenAI chat models. Messages are counted the way
+// the chat format encodes them, with a fixed overhead per message, images by detail level,
+// and text split with the tokenizer's pre-tokenization rules. The BPE ranks are not bundled,
+// so each piece's tokens are estimated from its length and counts are close to, but not
+// exactly, the PromptTokens reported in Usage.
+type TokenCounter struct{}
+
+// NewTokenCounter returns a types.TokenCounter for OpenAI chat models
+func NewTokenCounter() types.TokenCounter {
+	return TokenCounter{}
+}
+
+// CountMessages implements types.TokenCounter.
+func (c TokenCounter) CountMessages(messages []types.Message) int {
+	if len(messages) == 0 {
+		return 0
+	}
+
+	tokens := tokensPerReply
+	for i := range messages {
+		message := &messages[i]
+		tokens += tokensPerMessage
+		for _, contentPart := range message.ContentPart {
+			switch part := contentPart.(type) {
+			case *types.ContentPartText:
+				tokens += c.CountText(part.Text)
+			case *types.ContentPartRefusal:
+				tokens += c.CountText(part.Refusal)
+			case *types.ContentPartReasoning:
+				// Reasoning is not sent back, see toAssistantMessage
+			case *types.ContentPartImage:
+				tokens += imageTokens(types.ImageDetail(part.Detail))
+			default:
+				// Image URLs, audio and files: sizes are unknown, assume a high detail image
+				tokens += imageTokens(types.ImageDetailHigh)
+			}
+		}
+		for _, toolCall := range message.ToolCalls {
+			tokens += tokensPerToolCall + c.CountText(toolCall.Function.Name)
+			if args, err := json.Marshal(toolCall.Function.Arguments); err == nil {
+				tokens += c.CountText(string(args))
+			}
+		}
+	}
+	return tokens
+}
+
+// CountText estimates the tokens of text.
+func (TokenCounter) CountText(text string) int {
+	tokens := 0
+	for _, piece := range pretokenizer.FindAllString(text, -1) {
+		tokens += pieceTokens(piece)
+	}
+	return tokens
+}
+
+// pieceTokens estimates the BPE tokens of one pre-tokenized piece: common English words are a
+// single token, longer words about one per six letters, and non-Latin scripts about one per rune
+func pieceTokens(piece string) int {
+	word := strings.TrimSpace(piece)
+	if word == "" {
+		return 1
+	}
+	first, _ := utf8.DecodeRuneInString(word)
+	if unicode.IsNumber(first) {
+		return 1
+	}
+	runes := utf8.RuneCountInString(word)
+	if runes != len(word) {
+		// Non-ASCII letters are rarely merged
+		return runes
+	}
+	return max(1, (runes+5)/6)
+}
+
+func imageTokens(detail types.ImageDetail) int {
+	if detail == types.ImageDetailLow {
+		return lowDetailImageTokens
+	}
+	return highDetailImageTokens
+}
@@ -0,0 +1,54 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+func TestTokenCounterCountText(t *testing.T) {
+	c := TokenCounter{}
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"Hello world", 2},
+		{"Hello, world!", 4},
+		{"12345", 2},
+		{"internationalization", 4},
+	}
+	for _, tt := range tests {
+		if got := c.CountText(tt.text); got != tt.want {
+			t.Errorf("CountText(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenCounterCountMessages(t *testing.T) {
+	c := NewTokenCounter()
+	if got := c.CountMessages(nil); got != 0 {
+		t.Errorf("empty history = %d tokens, want 0", got)
+	}
+
+	// 3 reply priming + 3 per message + 2 text
+	messages := []types.Message{types.NewUserMessage(types.WithText("Hello world"))}
+	if got := c.CountMessages(messages); got != 8 {
+		t.Errorf("CountMessages = %d, want 8", got)
+	}
+
+	low := []types.Message{{Role: types.RoleUser, ContentPart: []types.ContentPart{types.NewContentPartImageWithDetail("aGVsbG8=", types.ImageDetailLow)}}}
+	high := []types.Message{{Role: types.RoleUser, ContentPart: []types.ContentPart{types.NewContentPartImageWithDetail("aGVsbG8=", types.ImageDetailHigh)}}}
+	if c.CountMessages(low) != 6+lowDetailImageTokens || c.CountMessages(high) != 6+highDetailImageTokens {
+		t.Errorf("unexpected image tokens: low %d, high %d", c.CountMessages(low), c.CountMessages(high))
+	}
+
+	// Reasoning is not sent back and not counted
+	withReasoning := []types.Message{{Role: types.RoleAssistant, ContentPart: []types.ContentPart{
+		types.NewContentPartReasoning("long thoughts about the question"),
+		types.NewContentPartText("Hello world"),
+	}}}
+	if got := c.CountMessages(withReasoning); got != 8 {
+		t.Errorf("CountMessages with reasoning = %d, want 8", got)
+	}
+}
@@ -29,14 +29,18 @@ func toToolDefinitionParam(tool types.ToolDefinition) (openai.ChatCompletionTool
 		return openai.ChatCompletionToolUnionParam{}, fmt.Errorf("tool %s has nil input schema", tool.Name)
 	}
 
+	function := openai.FunctionDefinitionParam{
+		Name:        tool.Name,
+		Description: openai.String(tool.Description),
+		Parameters:  openai.FunctionParameters(tool.InputSchema),
+	}
+	if tool.Strict {
+		function.Parameters = types.StrictSchema(tool.InputSchema)
+		function.Strict = openai.Bool(true)
+	}
+
 	return openai.ChatCompletionToolUnionParam{
-		OfFunction: &openai.ChatCompletionFunctionToolParam{
-			Function: openai.FunctionDefinitionParam{
-				Name:        tool.Name,
-				Description: openai.String(tool.Description),
-				Parameters:  openai.FunctionParameters(tool.InputSchema),
-			},
-		},
+		OfFunction: &openai.ChatCompletionFunctionToolParam{Function: function},
 	}, nil
 }
 
@@ -0,0 +1,99 @@
+package openai
+
+import (
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/http2"
+)
+
+// ConnectionInfo describes the connection used for the most recent request
+type ConnectionInfo struct {
+	// Proto is the protocol of the last response (e.g. "HTTP/1.1", "HTTP/2.0")
+	Proto string
+
+	// IsHTTP2 is true if the last response was served over HTTP/2
+	IsHTTP2 bool
+}
+
+// connTracker is a RoundTripper that records the protocol of each response
+type connTracker struct {
+	base http.RoundTripper
+
+	mu   sync.Mutex
+	last ConnectionInfo
+}
+
+func newConnTracker(base http.RoundTripper) *connTracker {
+	return &connTracker{base: base}
+}
+
+func (t *connTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.last = ConnectionInfo{Proto: resp.Proto, IsHTTP2: resp.ProtoMajor == 2}
+	t.mu.Unlock()
+
+	return resp, nil
+}
+
+func (t *connTracker) info() ConnectionInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
+
+// configureHTTP2 returns a transport with HTTP/2 enabled via golang.org/x/net/http2.
+// Transports that are not *http.Transport are returned unchanged.
+func configureHTTP2(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	base, ok := rt.(*http.Transport)
+	if !ok {
+		return rt
+	}
+
+	// Clone so a caller-owned or default transport is never mutated
+	t1 := base.Clone()
+	if _, err := http2.ConfigureTransports(t1); err != nil {
+		// Already configured for HTTP/2
+		return base
+	}
+	return t1
+}
+
+// SupportsHTTP2 reports whether http.DefaultTransport, used by clients without a custom HTTP
+// client, negotiates HTTP/2 over TLS. See Client.SupportsHTTP2 for a configured client.
+func SupportsHTTP2() bool {
+	return transportSupportsHTTP2(http.DefaultTransport)
+}
+
+// transportSupportsHTTP2 reports whether rt will attempt HTTP/2 over TLS
+func transportSupportsHTTP2(rt http.RoundTripper) bool {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	switch t := rt.(type) {
+	case *http2.Transport:
+		return true
+	case *http.Transport:
+		if _, ok := t.TLSNextProto[http2.NextProtoTLS]; ok {
+			return true
+		}
+		return t.ForceAttemptHTTP2 && t.TLSNextProto == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,117 @@
+package openai
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+const sampleCompletionJSON = `{
+	"id": "chatcmpl_1",
+	"object": "chat.completion",
+	"created": 123,
+	"model": "gpt-4o-mini",
+	"choices": [
+		{
+			"index": 0,
+			"message": {"role": "assistant", "content": "Hello"},
+			"finish_reason": "stop",
+			"logprobs": null
+		}
+	],
+	"usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}
+}`
+
+func newTLSCompletionServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(sampleCompletionJSON))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newTrustingHTTPClient returns a client that trusts the test server's certificate
+// but does not enable HTTP/2 on its own.
+func newTrustingHTTPClient(server *httptest.Server) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+}
+
+func TestWithHTTP2TransportNegotiatesHTTP2(t *testing.T) {
+	server := newTLSCompletionServer(t)
+
+	c := newRawClient(
+		client.WithAPIKey("test"),
+		client.WithBaseURL(server.URL),
+		client.WithHTTPClient(newTrustingHTTPClient(server)),
+		client.WithHTTP2Transport(),
+	)
+
+	if !c.SupportsHTTP2() {
+		t.Fatal("expected transport to support HTTP/2")
+	}
+
+	_, err := c.RawChat(context.Background(), &types.ChatParams{
+		Model:    "gpt-4o-mini",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+	})
+	if err != nil {
+		t.Fatalf("RawChat returned error: %v", err)
+	}
+
+	info := c.ConnectionInfo()
+	if !info.IsHTTP2 {
+		t.Fatalf("expected HTTP/2 connection, got %q", info.Proto)
+	}
+}
+
+func TestWithoutHTTP2TransportUsesHTTP1(t *testing.T) {
+	server := newTLSCompletionServer(t)
+
+	c := newRawClient(
+		client.WithAPIKey("test"),
+		client.WithBaseURL(server.URL),
+		client.WithHTTPClient(newTrustingHTTPClient(server)),
+	)
+
+	if c.SupportsHTTP2() {
+		t.Fatal("expected custom TLS transport not to support HTTP/2")
+	}
+
+	_, err := c.RawChat(context.Background(), &types.ChatParams{
+		Model:    "gpt-4o-mini",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+	})
+	if err != nil {
+		t.Fatalf("RawChat returned error: %v", err)
+	}
+
+	info := c.ConnectionInfo()
+	if info.IsHTTP2 || info.Proto != "HTTP/1.1" {
+		t.Fatalf("expected HTTP/1.1 connection, got %+v", info)
+	}
+}
+
+func TestSupportsHTTP2DefaultTransport(t *testing.T) {
+	if !SupportsHTTP2() {
+		t.Error("expected the default transport to support HTTP/2")
+	}
+}
@@ -0,0 +1,192 @@
+// Package openaicompat adapts servers that implement most, but not all, of the OpenAI Chat
+// Completions API, such as vLLM, LM Studio, llama.cpp and Together. The caller declares what
+// the server supports and the client degrades unsupported features before the request is sent.
+package openaicompat
+
+import (
+	"context"
+
+	"github.com/KennyKeni/elysia/adapter/openai"
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ImagePlaceholder replaces image content when the server has no vision support.
+const ImagePlaceholder = "[image omitted: the model cannot view images]"
+
+// Capabilities declares which optional OpenAI features a server supports.
+type Capabilities struct {
+	// NativeJSONSchema reports support for response_format json_schema. Without it the Native
+	// response format falls back to Tool mode, or to Prompted mode when ToolCalling is false.
+	NativeJSONSchema bool
+
+	// JSONMode reports support for response_format json_object. Without it the JSON response
+	// format falls back to Prompted mode.
+	JSONMode bool
+
+	// ToolCalling reports support for tools. Without it the Tool response format falls back to
+	// Prompted mode and requests with tools fail with ErrToolCallingNotSupported.
+	ToolCalling bool
+
+	// ParallelToolCalls reports whether the model may return several tool calls in one turn.
+	// Without it only the first tool call of each response is kept, so the conversation never
+	// holds a turn the server's chat template cannot render.
+	ParallelToolCalls bool
+
+	// Vision reports support for image content. Without it images are replaced by ImagePlaceholder.
+	Vision bool
+}
+
+// FullCapabilities declares a server that supports every optional feature.
+func FullCapabilities() Capabilities {
+	return Capabilities{
+		NativeJSONSchema:  true,
+		JSONMode:          true,
+		ToolCalling:       true,
+		ParallelToolCalls: true,
+		Vision:            true,
+	}
+}
+
+// Client sends OpenAI-format requests to a compatible server, adjusted to its capabilities
+type Client struct {
+	raw  types.RawClient
+	caps Capabilities
+}
+
+// NewClient creates a client for an OpenAI-compatible server, usually with client.WithBaseURL.
+// client.WithStrictModelValidation has no effect, as the server decides which models it serves.
+func NewClient(caps Capabilities, opts ...client.Option) types.Client {
+	cfg := client.NewConfig(opts...)
+	return types.NewClient(newRawClient(caps, opts...), types.WithLogger(cfg.Logger), types.WithLogContent(cfg.LogContent), types.WithDocumentConverter(cfg.DocumentConverter), types.WithChunkTimeout(cfg.ChunkTimeout))
+}
+
+// newRawClient creates the raw compatible client (internal)
+func newRawClient(caps Capabilities, opts ...client.Option) *Client {
+	// The server's models are its own, so they are never checked against the OpenAI list
+	opts = append(opts[:len(opts):len(opts)], func(c *client.Config) { c.StrictModelValidation = false })
+	return &Client{raw: openai.NewRawClient(opts...), caps: caps}
+}
+
+// Capabilities returns the capabilities the client was created with
+func (c *Client) Capabilities() Capabilities {
+	return c.caps
+}
+
+// SupportsResponseFormat reports whether the server can serve mode, see types.ResponseFormatSupporter
+func (c *Client) SupportsResponseFormat(mode types.ResponseFormatMode) bool {
+	switch mode {
+	case types.ResponseFormatModeNative:
+		return c.caps.NativeJSONSchema
+	case types.ResponseFormatModeJSON:
+		return c.caps.JSONMode
+	case types.ResponseFormatModeTool:
+		return c.caps.ToolCalling
+	default:
+		return true
+	}
+}
+
+// RawChat performs a non-streaming chat completion request
+func (c *Client) RawChat(ctx context.Context, params *types.ChatParams) (*types.ChatResponse, error) {
+	params, err := c.prepare(params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.raw.RawChat(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.caps.ParallelToolCalls {
+		for _, choice := range resp.Choices {
+			if choice.Message != nil && len(choice.Message.ToolCalls) > 1 {
+				choice.Message.ToolCalls = choice.Message.ToolCalls[:1]
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// RawChatStream performs a streaming chat completion request and returns an iterator over chunks.
+func (c *Client) RawChatStream(ctx context.Context, params *types.ChatParams) (*types.Stream, error) {
+	params, err := c.prepare(params)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := c.raw.RawChatStream(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.caps.ParallelToolCalls {
+		return firstToolCallOnly(stream), nil
+	}
+	return stream, nil
+}
+
+// RawEmbed performs an embedding request
+func (c *Client) RawEmbed(ctx context.Context, params *types.EmbeddingParams) (*types.EmbeddingResponse, error) {
+	return c.raw.RawEmbed(ctx, params)
+}
+
+// prepare returns a copy of params with unsupported features removed
+func (c *Client) prepare(params *types.ChatParams) (*types.ChatParams, error) {
+	if params == nil {
+		return nil, nil
+	}
+
+	if !c.caps.ToolCalling && len(params.Tools) > 0 {
+		return nil, ErrToolCallingNotSupported
+	}
+
+	params = params.Clone()
+	if !c.caps.ParallelToolCalls {
+		// Servers without parallel calls often reject the parameter; calls are trimmed instead
+		params.ParallelToolCalls = nil
+	}
+	if !c.caps.Vision {
+		for i := range params.Messages {
+			stripImages(&params.Messages[i])
+		}
+	}
+
+	return params, nil
+}
+
+// stripImages replaces the image parts of msg with ImagePlaceholder in place
+func stripImages(msg *types.Message) {
+	for i, part := range msg.ContentPart {
+		switch part.(type) {
+		case *types.ContentPartImage, *types.ContentPartImageURL:
+			msg.ContentPart[i] = types.NewContentPartText(ImagePlaceholder)
+		}
+	}
+}
+
+// firstToolCallOnly drops tool call deltas after the first call of each choice
+func firstToolCallOnly(stream *types.Stream) *types.Stream {
+	return types.NewStream(func() (*types.StreamChunk, error) {
+		if !stream.Next() {
+			return nil, stream.Err()
+		}
+
+		chunk := stream.Chunk()
+		for _, choice := range chunk.Choices {
+			if choice.Delta == nil || len(choice.Delta.ToolCalls) == 0 {
+				continue
+			}
+			kept := choice.Delta.ToolCalls[:0]
+			for _, delta := range choice.Delta.ToolCalls {
+				if delta.Index == 0 {
+					kept = append(kept, delta)
+				}
+			}
+			choice.Delta.ToolCalls = kept
+		}
+		return chunk, nil
+	}, stream)
+}
@@ -0,0 +1,220 @@
+package openaicompat
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KennyKeni/elysia/client"
+	"github.com/KennyKeni/elysia/types"
+)
+
+// newTestServer replies with response and records the decoded request bodies.
+func newTestServer(t *testing.T, response string) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		requests = append(requests, body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func completion(message string) string {
+	return `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"local",` +
+		`"choices":[{"index":0,"finish_reason":"stop","message":` + message + `}]}`
+}
+
+var outputSchema = map[string]any{
+	"type":       "object",
+	"properties": map[string]any{"n": map[string]any{"type": "integer"}},
+	"required":   []any{"n"},
+}
+
+func TestResponseFormatFallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		caps       Capabilities
+		reply      string
+		wantTools  bool
+		wantSuffix bool
+	}{
+		{
+			name:      "native without json schema uses tool mode",
+			caps:      Capabilities{ToolCalling: true},
+			reply:     `{"role":"assistant","content":null,"tool_calls":[{"id":"c1","type":"function","function":{"name":"_output","arguments":"{\"n\":1}"}}]}`,
+			wantTools: true,
+		},
+		{
+			name:       "native without tools uses prompted mode",
+			caps:       Capabilities{},
+			reply:      `{"role":"assistant","content":"Here you go: {\"n\":1}"}`,
+			wantSuffix: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newTestServer(t, completion(tt.reply))
+			c := NewClient(tt.caps, client.WithBaseURL(server.URL), client.WithAPIKey("test"))
+
+			resp, err := c.Chat(t.Context(), &types.ChatParams{
+				Model:          "local",
+				SystemPrompt:   "Be terse.",
+				Messages:       []types.Message{types.NewUserMessage(types.WithText("count"))},
+				ResponseFormat: types.ResponseFormat{Mode: types.ResponseFormatModeNative, Schema: outputSchema},
+			})
+			if err != nil {
+				t.Fatalf("Chat failed: %v", err)
+			}
+			if got := resp.Choices[0].StructuredContent; got != `{"n":1}` {
+				t.Errorf("structured content = %q", got)
+			}
+
+			body := (*requests)[0]
+			if _, ok := body["response_format"]; ok {
+				t.Error("response_format sent to server without json schema support")
+			}
+			if _, ok := body["tools"]; ok != tt.wantTools {
+				t.Errorf("tools present = %v, want %v", ok, tt.wantTools)
+			}
+			system := body["messages"].([]any)[0].(map[string]any)["content"].(string)
+			if strings.Contains(system, "Schema:") != tt.wantSuffix {
+				t.Errorf("prompted suffix present = %v, want %v", !tt.wantSuffix, tt.wantSuffix)
+			}
+		})
+	}
+}
+
+func TestStrictModelValidationIgnored(t *testing.T) {
+	server, requests := newTestServer(t, completion(`{"role":"assistant","content":"hi"}`))
+	c := NewClient(FullCapabilities(), client.WithBaseURL(server.URL), client.WithStrictModelValidation())
+
+	_, err := c.Chat(t.Context(), &types.ChatParams{
+		Model:    "Qwen/Qwen2.5-7B-Instruct",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+	})
+	if err != nil || len(*requests) != 1 {
+		t.Fatalf("expected the server's model to be sent, got %v", err)
+	}
+}
+
+func TestToolCallingNotSupported(t *testing.T) {
+	server, requests := newTestServer(t, completion(`{"role":"assistant","content":"hi"}`))
+	c := NewClient(Capabilities{}, client.WithBaseURL(server.URL))
+
+	_, err := c.Chat(t.Context(), &types.ChatParams{
+		Model:    "local",
+		Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))},
+		Tools:    []types.ToolDefinition{{Name: "lookup", InputSchema: map[string]any{"type": "object"}}},
+	})
+	if !errors.Is(err, ErrToolCallingNotSupported) {
+		t.Fatalf("expected ErrToolCallingNotSupported, got %v", err)
+	}
+	if len(*requests) != 0 {
+		t.Error("request was sent to the server")
+	}
+}
+
+func TestVisionNotSupported(t *testing.T) {
+	server, requests := newTestServer(t, completion(`{"role":"assistant","content":"a cat"}`))
+	c := NewClient(Capabilities{}, client.WithBaseURL(server.URL))
+
+	msg := types.NewUserMessage(types.WithText("what is this?"), types.WithImage("aGVsbG8="))
+	params := &types.ChatParams{Model: "local", Messages: []types.Message{msg}}
+	if _, err := c.Chat(t.Context(), params); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	content := (*requests)[0]["messages"].([]any)[0].(map[string]any)["content"].([]any)
+	if len(content) != 2 {
+		t.Fatalf("expected 2 content parts, got %d", len(content))
+	}
+	part := content[1].(map[string]any)
+	if part["type"] != "text" || part["text"] != ImagePlaceholder {
+		t.Errorf("image not replaced: %v", part)
+	}
+	if _, ok := params.Messages[0].ContentPart[1].(*types.ContentPartImage); !ok {
+		t.Error("caller message was mutated")
+	}
+}
+
+func TestParallelToolCallsNotSupported(t *testing.T) {
+	reply := `{"role":"assistant","content":null,"tool_calls":[` +
+		`{"id":"c1","type":"function","function":{"name":"a","arguments":"{}"}},` +
+		`{"id":"c2","type":"function","function":{"name":"b","arguments":"{}"}}]}`
+	tools := []types.ToolDefinition{
+		{Name: "a", InputSchema: map[string]any{"type": "object"}},
+		{Name: "b", InputSchema: map[string]any{"type": "object"}},
+	}
+
+	for _, parallel := range []bool{false, true} {
+		server, _ := newTestServer(t, completion(reply))
+		c := NewClient(Capabilities{ToolCalling: true, ParallelToolCalls: parallel}, client.WithBaseURL(server.URL))
+
+		resp, err := c.Chat(t.Context(), &types.ChatParams{
+			Model:    "local",
+			Messages: []types.Message{types.NewUserMessage(types.WithText("go"))},
+			Tools:    tools,
+		})
+		if err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+
+		want := 1
+		if parallel {
+			want = 2
+		}
+		if got := len(resp.Choices[0].Message.ToolCalls); got != want {
+			t.Errorf("parallel=%v: got %d tool calls, want %d", parallel, got, want)
+		}
+	}
+}
+
+func TestFirstToolCallOnly(t *testing.T) {
+	chunks := []*types.StreamChunk{
+		{Choices: []types.StreamChoice{{Delta: &types.MessageDelta{ToolCalls: []types.ToolCallDelta{
+			{Index: 0, ID: "c1", FunctionName: "a"},
+			{Index: 1, ID: "c2", FunctionName: "b"},
+		}}}}},
+		{Choices: []types.StreamChoice{{Delta: &types.MessageDelta{ToolCalls: []types.ToolCallDelta{
+			{Index: 1, Arguments: "{}"},
+		}}}}},
+	}
+	i := 0
+	inner := types.NewStream(func() (*types.StreamChunk, error) {
+		if i == len(chunks) {
+			return nil, io.EOF
+		}
+		i++
+		return chunks[i-1], nil
+	}, nil)
+
+	stream := firstToolCallOnly(inner)
+	acc := types.NewMessageAccumulator()
+	for stream.Next() {
+		acc.Update(stream.Chunk().Choices[0].Delta)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+
+	msg, err := acc.Message()
+	if err != nil {
+		t.Fatalf("accumulate: %v", err)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID != "c1" {
+		t.Errorf("expected only the first tool call, got %+v", msg.ToolCalls)
+	}
+}
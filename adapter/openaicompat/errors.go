@@ -0,0 +1,9 @@
+package openaicompat
+
+import "errors"
+
+var (
+	// ErrToolCallingNotSupported is returned when a request carries tools but the server was
+	// declared without tool calling support.
+	ErrToolCallingNotSupported = errors.New("openaicompat chat: server does not support tool calling")
+)
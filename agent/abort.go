@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"maps"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// AbortToolName is the name of the tool offered by WithAbortTool.
+const AbortToolName = "_abort"
+
+// abortToolDefinition lets the model give up on the task with a reason
+var abortToolDefinition = types.ToolDefinition{
+	Name:        AbortToolName,
+	Description: "Stop working on the task because it cannot be completed, e.g. it is impossible or information is missing. Explain why in reason.",
+	InputSchema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"reason": map[string]any{"type": "string", "description": "Why the task cannot be completed"},
+		},
+		"required":             []any{"reason"},
+		"additionalProperties": false,
+	},
+}
+
+// RunAborted describes a run the model ended early through the abort tool.
+type RunAborted struct {
+	Reason string `json:"reason"`
+}
+
+// WithAbortTool offers the model a built-in AbortToolName tool to end the run early when the
+// task cannot be completed, instead of exhausting its iterations. An aborted run returns a
+// RunResult with a zero Output and Aborted set to the model's reason.
+func WithAbortTool[TDep, TOut any]() Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		a.abortTool = true
+		return nil
+	}
+}
+
+// findAbort returns the abort tool call of msg, if any
+func (a *Agent[TDep, TOut]) findAbort(msg *types.Message) *types.ToolCall {
+	if !a.abortTool {
+		return nil
+	}
+	for i := range msg.ToolCalls {
+		if msg.ToolCalls[i].Function.Name == AbortToolName {
+			return &msg.ToolCalls[i]
+		}
+	}
+	return nil
+}
+
+// abort answers the tool calls of msg and ends the run with the reason of call.
+// Other tool calls of the turn are not executed.
+func (a *Agent[TDep, TOut]) abort(rc *RunContext[TDep], runCfg *runConfig, msg *types.Message, call *types.ToolCall) *RunResult[TOut] {
+	for _, tc := range msg.ToolCalls {
+		result := &types.ToolResult{ContentPart: []types.ContentPart{types.NewContentPartText("Run aborted.")}}
+		if tc.ID != call.ID {
+			result = &types.ToolResult{
+				ContentPart: []types.ContentPart{types.NewContentPartText("Not executed: the run was aborted.")},
+				IsError:     true,
+			}
+		}
+		rc.Messages = append(rc.Messages, types.NewToolResultMessage(tc.ID, result))
+	}
+
+	reason, _ := call.Function.Arguments["reason"].(string)
+	var zero TOut
+	runCfg.emit(RunFinishedEvent{AgentName: a.name, Output: zero, Usage: rc.Usage})
+	return &RunResult[TOut]{
+		Output:   zero,
+		Messages: rc.Messages,
+		Usage:    rc.Usage,
+		Aborted:  &RunAborted{Reason: reason},
+
+		AgentName:     a.name,
+		AgentMetadata: maps.Clone(a.metadata),
+	}
+}
@@ -1,10 +1,15 @@
 package agent
 
 import (
+	"cmp"
 	"context"
-	"encoding/json/v2"
 	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/KennyKeni/elysia/types"
 	"github.com/google/uuid"
@@ -14,6 +19,24 @@ type RunResult[TOut any] struct {
 	Output   TOut
 	Messages []types.Message
 	Usage    types.Usage
+
+	// OutputVariant is the name of the variant Output holds, see WithOutputVariants
+	OutputVariant string
+
+	// Incomplete is set when the run stopped at the maximum iterations without an output,
+	// see ExhaustionReturnPartial
+	Incomplete bool
+
+	// Aborted is set when the model ended the run through the abort tool, see WithAbortTool
+	Aborted *RunAborted
+
+	// Trace records the run, see WithRunTrace (nil = not traced)
+	Trace *RunTrace
+
+	// AgentName and AgentMetadata identify the agent that produced the output, which is
+	// the target agent after a handoff. See WithName and WithMetadata.
+	AgentName     string
+	AgentMetadata map[string]string
 }
 
 // UsageLimits sets hard ceilings on an agent run.
@@ -27,6 +50,12 @@ type UsageLimits struct {
 	// ToolCallsLimit is the maximum successful tool executions (0 = unlimited)
 	// Failed/retrying calls don't count
 	ToolCallsLimit int
+
+	// PromptTokensLimit is the maximum prompt tokens summed over the run (0 = unlimited)
+	PromptTokensLimit int
+
+	// TotalTokensLimit is the maximum total tokens summed over the run (0 = unlimited)
+	TotalTokensLimit int
 }
 
 // UsageLimitExceeded is returned when a usage limit is exceeded.
@@ -41,26 +70,96 @@ func (e *UsageLimitExceeded) Error() string {
 }
 
 type Agent[TDep, TOut any] struct {
-	systemPrompt       string
-	systemPromptFunc   func(TDep) string
+	instructions       []func(TDep) string // System prompt parts, joined in registration order
 	client             types.Client
 	model              string                 // Model to use for chat requests
+	name               string                 // Identifies the agent in results and events
+	metadata           map[string]string      // Attributes for tracing, see WithMetadata
 	toolMap            map[string]*Tool[TDep] // For O(1) lookup
 	toolList           []*Tool[TDep]          // For O(1) iteration, preserves order
 	maxIterations      int
+	exhaustionPolicy   ExhaustionPolicy // What a run does at maxIterations ("" = fail)
 	responseFormatMode types.ResponseFormatMode
-	retries            int // Default retry count for tools
-	outputRetries      int // Retry count for output validation (falls back to retries if 0)
+	outputToolName     string               // Name of the output tool in Tool mode ("" = types.OutputToolName)
+	extraOutputProps   bool                 // Output objects may hold undeclared properties
+	outputSchemaOpts   []types.SchemaOption // Customize the output schema of TOut
+	retries            int                  // Default retry count for tools
+	outputRetries      int                  // Retry count for output validation (falls back to retries if 0)
+	unknownToolRetries int                  // Calls of unknown tools answered per run (0 = fail the run)
+
+	outputRetryMessageBuilder OutputRetryMessageBuilder // Feedback sent to the LLM on output retries
+	outputRetryPrompt         OutputRetryPrompt         // Builds the whole feedback message (nil = user message from the builder)
+	outputRetryBackoff        Backoff                   // Delay before output retries (nil = retry immediately)
+	transientRetry            TransientRetryPolicy      // Retries of model calls failing with transient provider errors
+	toolCallIDGenerator       func() string             // Fills empty tool call IDs (nil = leave as-is)
+
+	parallelToolAggregator func([]types.ToolResult) *types.ToolResult // Combines a turn's tool results (nil = one message per call)
+	preRunChecks           []types.HealthCheck                        // Must all pass before Run calls the LLM
+	hooks                  hooks[TDep]                                // Lifecycle callbacks, see WithOnModelRequest
+	logContent             func(string) string                        // Redacts text logged by WithLogger (nil = text not logged)
+	middleware             []Middleware[TDep]                         // Wraps model requests and tool executions, outermost first
+	outputValidators       []OutputValidator[TDep, TOut]              // Run in order on the parsed output
+	outputVariants         []OutputVariant[TOut]                      // Union output types (nil = TOut itself)
+	outputFunc             *outputFunc[TDep, TOut]                    // Produces the output from the model's arguments (nil = decode TOut)
+	prepareTools           []PrepareToolsFunc[TDep]                   // Adjust the tools offered before each model request
+	toolCache              ToolResultCache                            // Results of tools with ToolCache
+	handoffs               []*Handoff[TDep, TOut]                     // Agents the conversation can be transferred to
+	abortTool              bool                                       // Offer AbortToolName, see WithAbortTool
+	inputGuardrails        []InputGuardrail[TDep]                     // Check the prompt before the model answers it
+	outputGuardrails       []outputGuardrail[TDep, TOut]              // Check the validated output before it is returned
+	reflection             *reflection                                // Critique pass over the output (nil = none)
+	historyProcessors      []agentHistoryProcessor[TDep, TOut]        // Transform the messages sent on each request
+	tokenCounter           types.TokenCounter                         // Counts tokens for WithContextWindow and tool result limits (nil = heuristic)
+	promptCache            *types.CacheControl                        // Caching of the system prompt and tools, see WithPromptCaching (nil = none)
+
+	maxToolResultBytes   int                  // Truncate longer tool results, see WithMaxToolResultBytes (0 = no limit)
+	maxToolResultTokens  int                  // Truncate longer tool results, see WithMaxToolResultTokens (0 = no limit)
+	toolResultSummarizer ToolResultSummarizer // Shortens oversized tool results before truncation (nil = truncate)
+
+	depsSetup    DepsSetup[TDep]    // Prepares the deps at the start of each run (nil = use as passed)
+	depsTeardown DepsTeardown[TDep] // Releases the deps when each run ends (nil = none)
+
+	candidates        int               // Choices requested per turn (0 = provider default)
+	choiceSelector    ChoiceSelector    // Picks the choice a run continues with (nil = first)
+	parallelToolCalls *bool             // Sent as ChatParams.ParallelToolCalls when tools are offered (nil = provider default)
+	toolChoice        *types.ToolChoice // How the model may use tools, see WithToolChoice (nil = provider default)
+
+	concurrentInputGuardrails bool // Run input guardrails alongside the first model request
+	stripReasoning            bool // Leave reasoning out of the messages sent, see WithReasoningHistory
 }
 
 type Option[TDep, TOut any] func(*Agent[TDep, TOut]) error
 
+// OutputValidator checks the parsed output of a run and may return a modified output.
+// Returning a ModelRetry sends its message back to the LLM and retries the output,
+// counted against the output retries; any other error fails the run.
+type OutputValidator[TDep, TOut any] func(ctx context.Context, rc *RunContext[TDep], output TOut) (TOut, error)
+
+// PrepareToolsFunc adjusts the tool definitions offered to the model for one request.
+// It receives a copy of the run's definitions and returns the ones to offer.
+type PrepareToolsFunc[TDep any] func(ctx context.Context, rc *RunContext[TDep], tools []types.ToolDefinition) []types.ToolDefinition
+
+// HistoryProcessor transforms the messages sent to the model, e.g. to drop old tool results,
+// redact PII or collapse turns. It receives a copy of the run's history and must not modify
+// the messages themselves; return new ones instead.
+type HistoryProcessor func(ctx context.Context, messages []types.Message) []types.Message
+
+// agentHistoryProcessor is a HistoryProcessor receiving the agent making the request, so
+// processors registered before Override use the copy's client and token counter
+type agentHistoryProcessor[TDep, TOut any] func(ctx context.Context, a *Agent[TDep, TOut], messages []types.Message) []types.Message
+
+// ChoiceSelector picks the choice a run continues with and returns its index in choices.
+type ChoiceSelector func(choices []types.Choice) (int, error)
+
 func New[TDep, TOut any](client types.Client, opts ...Option[TDep, TOut]) (*Agent[TDep, TOut], error) {
 	a := &Agent[TDep, TOut]{
-		client:        client,
-		maxIterations: 10,
-		toolMap:       make(map[string]*Tool[TDep]),
-		toolList:      make([]*Tool[TDep], 0),
+		client:                    client,
+		maxIterations:             10,
+		toolMap:                   make(map[string]*Tool[TDep]),
+		toolList:                  make([]*Tool[TDep], 0),
+		outputRetryMessageBuilder: DefaultOutputRetryMessage,
+		toolCache:                 NewLRUToolCache(DefaultToolCacheSize),
+		transientRetry:            DefaultTransientRetryPolicy,
 	}
 
 	for _, opt := range opts {
@@ -68,20 +167,47 @@ func New[TDep, TOut any](client types.Client, opts ...Option[TDep, TOut]) (*Agen
 			return nil, err
 		}
 	}
+	if err := a.checkOutputToolName(a.toolList); err != nil {
+		return nil, err
+	}
 
 	return a, nil
 }
 
+// WithSystemPrompt adds a static part to the system prompt. Parts from WithSystemPrompt,
+// WithSystemPromptFunc and WithRunInstructions are joined with blank lines in the order
+// they were registered; empty parts are skipped.
 func WithSystemPrompt[TDep, TOut any](prompt string) Option[TDep, TOut] {
 	return func(a *Agent[TDep, TOut]) error {
-		a.systemPrompt = prompt
+		a.instructions = append(a.instructions, func(TDep) string { return prompt })
+		return nil
+	}
+}
+
+// WithSystemPromptTemplate adds tmpl rendered with data as a part of the system prompt.
+// Missing variables are reported when the agent is created. See WithSystemPrompt.
+func WithSystemPromptTemplate[TDep, TOut any](tmpl *types.PromptTemplate, data any) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if tmpl == nil {
+			return errors.New("system prompt template cannot be nil")
+		}
+		prompt, err := tmpl.Render(data)
+		if err != nil {
+			return err
+		}
+		a.instructions = append(a.instructions, func(TDep) string { return prompt })
 		return nil
 	}
 }
 
+// WithSystemPromptFunc adds a part to the system prompt built from the run's dependencies.
+// See WithSystemPrompt.
 func WithSystemPromptFunc[TDep, TOut any](fn func(TDep) string) Option[TDep, TOut] {
 	return func(a *Agent[TDep, TOut]) error {
-		a.systemPromptFunc = fn
+		if fn == nil {
+			return errors.New("system prompt func cannot be nil")
+		}
+		a.instructions = append(a.instructions, fn)
 		return nil
 	}
 }
@@ -106,6 +232,39 @@ func WithResponseFormat[TDep, TOut any](mode types.ResponseFormatMode) Option[TD
 	}
 }
 
+// WithOutputToolName renames the hidden tool the model calls with its output in Tool mode
+// (default types.OutputToolName), e.g. for providers rejecting names that start with an
+// underscore. Tools of the agent must not use the name.
+func WithOutputToolName[TDep, TOut any](name string) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if name == "" {
+			return errors.New("output tool name cannot be empty")
+		}
+		a.outputToolName = name
+		return nil
+	}
+}
+
+// WithAdditionalOutputProperties accepts output objects holding properties TOut doesn't
+// declare instead of retrying, see types.ResponseFormat.AllowAdditionalProperties. They are
+// dropped when the output is decoded. Native mode then asks the provider for non-strict output.
+func WithAdditionalOutputProperties[TDep, TOut any](allow bool) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		a.extraOutputProps = allow
+		return nil
+	}
+}
+
+// WithOutputSchemaOptions customizes the schema of TOut requested from the model, e.g. to
+// rename properties a provider rejects. Properties renamed with types.WithPropertyName are
+// renamed back before the output is decoded. Output functions and variants are not affected.
+func WithOutputSchemaOptions[TDep, TOut any](opts ...types.SchemaOption) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		a.outputSchemaOpts = append(a.outputSchemaOpts, opts...)
+		return nil
+	}
+}
+
 func WithRetries[TDep, TOut any](retries int) Option[TDep, TOut] {
 	return func(a *Agent[TDep, TOut]) error {
 		a.retries = retries
@@ -120,6 +279,185 @@ func WithOutputRetries[TDep, TOut any](retries int) Option[TDep, TOut] {
 	}
 }
 
+// WithOutputRetryMessageBuilder customizes the feedback sent to the LLM when
+// output validation fails. attempt starts at 1; schema is nil when no
+// response format is configured.
+func WithOutputRetryMessageBuilder[TDep, TOut any](fn OutputRetryMessageBuilder) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("output retry message builder cannot be nil")
+		}
+		a.outputRetryMessageBuilder = fn
+		return nil
+	}
+}
+
+// WithOutputRetryPrompt replaces the whole feedback message sent to the LLM when output
+// validation fails, e.g. to localize or template it. err is an
+// *OutputRetryError carrying the schema; attempt starts at 1. It takes precedence over
+// WithOutputRetryMessageBuilder.
+func WithOutputRetryPrompt[TDep, TOut any](fn OutputRetryPrompt) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("output retry prompt cannot be nil")
+		}
+		a.outputRetryPrompt = fn
+		return nil
+	}
+}
+
+// WithOutputRetryBackoff waits before output retries instead of calling the model again
+// immediately, e.g. ExponentialBackoff(500*time.Millisecond, 10*time.Second, 0.5).
+func WithOutputRetryBackoff[TDep, TOut any](backoff Backoff) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if backoff == nil {
+			return errors.New("output retry backoff cannot be nil")
+		}
+		a.outputRetryBackoff = backoff
+		return nil
+	}
+}
+
+// WithToolCallIDGenerator assigns generated IDs to tool calls the provider returned
+// without one, so tool results can still be threaded back to their calls.
+func WithToolCallIDGenerator[TDep, TOut any](fn func() string) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		a.toolCallIDGenerator = fn
+		return nil
+	}
+}
+
+// DefaultToolCallIDGenerator returns a generator producing random UUIDs.
+func DefaultToolCallIDGenerator() func() string {
+	return func() string {
+		return uuid.New().String()
+	}
+}
+
+// WithParallelToolAggregator combines the results of a turn with multiple tool calls
+// into a single tool message, reducing the context the LLM has to read.
+// fn receives results in tool-call order; the combined message answers the first tool call
+// and the other calls get a short result referring to it.
+func WithParallelToolAggregator[TDep, TOut any](fn func([]types.ToolResult) *types.ToolResult) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("parallel tool aggregator cannot be nil")
+		}
+		a.parallelToolAggregator = fn
+		return nil
+	}
+}
+
+// WithParallelToolCalls allows or forbids the model to request several tools in one turn.
+// Disable it when tools have ordering dependencies so each call sees the previous result.
+func WithParallelToolCalls[TDep, TOut any](enabled bool) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		a.parallelToolCalls = &enabled
+		return nil
+	}
+}
+
+// WithPreRunCheck registers a check that runs before every Run, e.g. types.ProviderHealthCheck.
+// Checks run in registration order; the first error aborts the run before any LLM call.
+func WithPreRunCheck[TDep, TOut any](check types.HealthCheck) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if check == nil {
+			return errors.New("pre-run check cannot be nil")
+		}
+		a.preRunChecks = append(a.preRunChecks, check)
+		return nil
+	}
+}
+
+// WithOutputValidator adds a validator for business rules the schema cannot express,
+// e.g. "date must be in the future". Validators run in registration order.
+func WithOutputValidator[TDep, TOut any](validator OutputValidator[TDep, TOut]) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if validator == nil {
+			return errors.New("output validator cannot be nil")
+		}
+		a.outputValidators = append(a.outputValidators, validator)
+		return nil
+	}
+}
+
+// WithPrepareTools evaluates fn before every model request to hide or re-describe tools
+// for that step, e.g. to offer delete_record only after a lookup succeeded. Returned
+// definitions must keep the names of registered tools; hidden tools cannot be called.
+// Several functions run in registration order, each receiving the previous one's result.
+func WithPrepareTools[TDep, TOut any](fn PrepareToolsFunc[TDep]) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("prepare tools function cannot be nil")
+		}
+		a.prepareTools = append(a.prepareTools, fn)
+		return nil
+	}
+}
+
+// WithHistoryProcessor processes the history right before every model request. Only the
+// request is affected: RunContext.Messages and RunResult.Messages keep the full history.
+// Several processors run in registration order, each receiving the previous one's result.
+func WithHistoryProcessor[TDep, TOut any](fn HistoryProcessor) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("history processor cannot be nil")
+		}
+		a.historyProcessors = append(a.historyProcessors, func(ctx context.Context, _ *Agent[TDep, TOut], messages []types.Message) []types.Message {
+			return fn(ctx, messages)
+		})
+		return nil
+	}
+}
+
+// WithChoiceSelector requests n candidate completions per turn (ChatParams.N) and lets selector
+// pick the one the run continues with. Without it the agent uses the first choice.
+// Adapters that return a single choice reject requests with n > 1.
+func WithChoiceSelector[TDep, TOut any](n int, selector ChoiceSelector) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if n < 1 {
+			return fmt.Errorf("choice count must be at least 1, got %d", n)
+		}
+		if selector == nil {
+			return errors.New("choice selector cannot be nil")
+		}
+		a.candidates = n
+		a.choiceSelector = selector
+		return nil
+	}
+}
+
+// WithName names the agent so multi-agent systems can attribute results, events and
+// logs to it. The name is available as RunContext.AgentName.
+func WithName[TDep, TOut any](name string) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		a.name = name
+		return nil
+	}
+}
+
+// WithMetadata attaches attributes such as a team or version to the agent, surfaced in
+// RunResult and RunStartedEvent. Several calls merge, later keys win.
+func WithMetadata[TDep, TOut any](metadata map[string]string) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if a.metadata == nil {
+			a.metadata = make(map[string]string, len(metadata))
+		}
+		maps.Copy(a.metadata, metadata)
+		return nil
+	}
+}
+
+// Name returns the agent's name, see WithName.
+func (a *Agent[TDep, TOut]) Name() string {
+	return a.name
+}
+
+// Metadata returns a copy of the agent's metadata, see WithMetadata.
+func (a *Agent[TDep, TOut]) Metadata() map[string]string {
+	return maps.Clone(a.metadata)
+}
+
 func WithModel[TDep, TOut any](model string) Option[TDep, TOut] {
 	return func(a *Agent[TDep, TOut]) error {
 		a.model = model
@@ -127,17 +465,72 @@ func WithModel[TDep, TOut any](model string) Option[TDep, TOut] {
 	}
 }
 
+// WithModelFromEnv sets the model from the named environment variable.
+// It returns an error if the variable is unset or empty.
+func WithModelFromEnv[TDep, TOut any](envVar string) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		model := os.Getenv(envVar)
+		if model == "" {
+			return fmt.Errorf("environment variable %s is not set", envVar)
+		}
+		a.model = model
+		return nil
+	}
+}
+
+// WithModelFromEnvOrDefault sets the model from the named environment variable,
+// falling back to defaultModel if the variable is unset or empty.
+func WithModelFromEnvOrDefault[TDep, TOut any](envVar, defaultModel string) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		a.model = cmp.Or(os.Getenv(envVar), defaultModel)
+		return nil
+	}
+}
+
 type runConfig struct {
-	prompt      string
-	messages    []types.Message
-	retries     *int         // Override agent-level retries if set
-	usageLimits *UsageLimits // Hard ceilings on this run
+	prompt       string
+	messages     []types.Message
+	retries      *int              // Override agent-level retries if set
+	usageLimits  *UsageLimits      // Hard ceilings on this run
+	parentRunID  string            // RunID of the delegating run
+	runID        string            // Replaces the generated RunID ("" = generate one)
+	traceContext map[string]string // Sent as headers with the model requests and set on events
+	tools        any               // []*Tool[TDep] added for this run (RunOption is not generic)
+	toolsOnly    bool              // Replace the agent's tools with tools instead of merging
+
+	eventHandler EventHandler       // Receives the run's events (nil = none)
+	trace        *traceRecorder     // Records the run, see WithRunTrace (nil = not traced)
+	onNode       func(Node) error   // Pauses the run at each step, see Agent.Iter (nil = run through)
+	onText       func(string) error // Caller's text delta handler, passed on to handoffs (nil = not streamed)
+
+	instructions  []string                // Appended to the agent's system prompt parts
+	modelSettings []types.ChatParamOption // Applied to the params of every model request
+	toolChoice    *types.ToolChoice       // Overrides the agent's tool choice (nil = agent's)
+
+	promptTemplate *types.PromptTemplate // Rendered with promptData into prompt (nil = use prompt)
+	promptData     any
+
+	historyStore HistoryStore // Loads and persists the session's messages (nil = none)
+	sessionID    string
+
+	resumed   *PendingApproval            // Run continued by Agent.Resume (nil = new run)
+	approvals map[string]ApprovalDecision // Decisions for the resumed turn's tool calls
 }
 type RunOption func(*runConfig)
 
 func WithPrompt(prompt string) RunOption {
 	return func(rc *runConfig) {
 		rc.prompt = prompt
+		rc.promptTemplate = nil
+	}
+}
+
+// WithPromptTemplate sets the user prompt to tmpl rendered with data. The run fails if
+// data is missing a variable of the template.
+func WithPromptTemplate(tmpl *types.PromptTemplate, data any) RunOption {
+	return func(rc *runConfig) {
+		rc.promptTemplate = tmpl
+		rc.promptData = data
 	}
 }
 
@@ -159,34 +552,141 @@ func WithUsageLimits(limits UsageLimits) RunOption {
 	}
 }
 
+// WithParentRunID records the RunID of the run that delegated to this one in RunContext.ParentRunID.
+func WithParentRunID(runID string) RunOption {
+	return func(rc *runConfig) {
+		rc.parentRunID = runID
+	}
+}
+
+// WithRunInstructions adds parts to the system prompt for a single run, after the agent's own.
+func WithRunInstructions(instructions ...string) RunOption {
+	return func(rc *runConfig) {
+		rc.instructions = append(rc.instructions, instructions...)
+	}
+}
+
+// WithRunTools adds tools for a single run on top of the agent's registered tools.
+// A run tool with the same name as an agent tool takes precedence.
+func WithRunTools[TDep any](tools ...*Tool[TDep]) RunOption {
+	return func(rc *runConfig) {
+		rc.tools = tools
+		rc.toolsOnly = false
+	}
+}
+
+// WithRunToolsOnly replaces the agent's registered tools with tools for a single run.
+func WithRunToolsOnly[TDep any](tools ...*Tool[TDep]) RunOption {
+	return func(rc *runConfig) {
+		rc.tools = tools
+		rc.toolsOnly = true
+	}
+}
+
 func (a *Agent[TDep, TOut]) Run(ctx context.Context, dep TDep, opts ...RunOption) (*RunResult[TOut], error) {
-	var err error
-	var res TOut
-	var rf types.ResponseFormat
+	return a.run(ctx, dep, nil, opts)
+}
 
+// run drives the agent loop. When onText is set, model requests are streamed and
+// onText receives the assistant text deltas of the first choice as they arrive.
+func (a *Agent[TDep, TOut]) run(ctx context.Context, dep TDep, onText func(string) error, opts []RunOption) (*RunResult[TOut], error) {
 	runCfg := runConfig{}
 	for _, opt := range opts {
 		opt(&runCfg)
 	}
 
-	if a.responseFormatMode != "" {
-		rf, err = types.ResponseFormatFor[TOut](a.responseFormatMode, "", "")
+	runCfg.onText = onText
+	if onText != nil && runCfg.eventHandler != nil {
+		next := onText
+		onText = func(delta string) error {
+			runCfg.emit(TextDeltaEvent{Text: delta})
+			return next(delta)
+		}
+	}
+
+	result, err := a.runDeps(ctx, dep, &runCfg, onText)
+	trace := runCfg.trace.finish(err)
+	if err != nil {
+		runCfg.emit(RunErrorEvent{AgentName: a.name, Err: err})
+		return nil, err
+	}
+	result.Trace = trace
+	return result, nil
+}
+
+// runSession runs the loop, loading and persisting the session's messages when a history store is set
+func (a *Agent[TDep, TOut]) runSession(ctx context.Context, dep TDep, runCfg *runConfig, onText func(string) error) (*RunResult[TOut], error) {
+	store := runCfg.historyStore
+	if store == nil {
+		return a.runLoop(ctx, dep, runCfg, onText)
+	}
+
+	var loaded int
+	if runCfg.resumed == nil {
+		history, err := store.Load(ctx, runCfg.sessionID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to build response format: %w", err)
+			return nil, fmt.Errorf("failed to load session: %w", err)
 		}
+		loaded = len(history)
+		runCfg.messages = append(history, runCfg.messages...)
 	}
 
-	var systemPrompt string
-	if a.systemPromptFunc != nil {
-		systemPrompt = a.systemPromptFunc(dep)
+	result, err := a.runLoop(ctx, dep, runCfg, onText)
+	if err != nil {
+		return nil, err
+	}
+
+	if runCfg.resumed != nil {
+		err = store.Save(ctx, runCfg.sessionID, result.Messages)
 	} else {
-		systemPrompt = a.systemPrompt
+		err = store.Append(ctx, runCfg.sessionID, result.Messages[loaded:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	return result, nil
+}
+
+func (a *Agent[TDep, TOut]) runLoop(ctx context.Context, dep TDep, runCfg *runConfig, onText func(string) error) (*RunResult[TOut], error) {
+	var err error
+	var res TOut
+	var variant string
+	var rf types.ResponseFormat
+
+	for _, check := range a.preRunChecks {
+		if err := check(ctx, a.client); err != nil {
+			return nil, fmt.Errorf("pre-run check failed: %w", err)
+		}
+	}
+
+	rf, err = a.responseFormat()
+	if err != nil {
+		return nil, err
+	}
+
+	systemPrompt := a.buildSystemPrompt(dep, runCfg)
+
+	if runCfg.promptTemplate != nil {
+		if runCfg.prompt, err = runCfg.promptTemplate.Render(runCfg.promptData); err != nil {
+			return nil, err
+		}
 	}
 
-	toolDefs := GetToolDefinitions(a.toolList)
+	toolMap, toolList, err := a.resolveTools(runCfg)
+	if err != nil {
+		return nil, err
+	}
+	toolDefs := GetToolDefinitions(toolList)
 
 	// Generate unique run ID
-	runID := uuid.New().String()
+	runID := cmp.Or(runCfg.runID, uuid.New().String())
+	if runCfg.resumed != nil {
+		runID = runCfg.resumed.RunID
+	}
+	runCfg.runID = runID
+	ctx = types.WithRequestHeaders(ctx, runCfg.requestHeaders())
+	ctx = withTraceRecorder(ctx, runCfg.trace)
+	runCfg.trace.start(runID, a.name)
 
 	// Initialize RunContext
 	rc := &RunContext[TDep]{
@@ -194,23 +694,63 @@ func (a *Agent[TDep, TOut]) Run(ctx context.Context, dep TDep, opts ...RunOption
 		Messages: runCfg.messages,
 		RunID:    runID,
 		Prompt:   runCfg.prompt,
+
+		ParentRunID:  runCfg.parentRunID,
+		TraceContext: runCfg.traceContext,
+		AgentName:    a.name,
 	}
 	if runCfg.prompt != "" {
 		rc.Messages = append(rc.Messages, types.NewUserMessage(types.WithText(runCfg.prompt)))
 	}
+	if runCfg.resumed != nil {
+		rc.Usage = runCfg.resumed.Usage
+	}
+	runCfg.emit(RunStartedEvent{Prompt: runCfg.prompt, AgentName: a.name, Metadata: maps.Clone(a.metadata)})
+
+	inputCheck, err := a.checkInput(ctx, rc, runCfg)
+	if err != nil {
+		return nil, err
+	}
+	if inputCheck != nil {
+		defer inputCheck.cancel()
+	}
 
-	// Track retry counts per tool across iterations
-	toolRetries := make(map[string]int)
+	model := a.wrapModel(func(ctx context.Context, rc *RunContext[TDep], params *types.ChatParams) (*types.ChatResponse, error) {
+		return a.chatWithRetry(ctx, params, onText)
+	})
+
+	// Track retry counts per tool and successful executions across iterations
+	tools := &toolState{retries: make(map[string]int)}
 
 	// Track usage for limits
 	var requestCount int
-	var successfulToolCalls int
 
 	// Track output validation retries
 	var outputRetryCount int
 	maxOutputRetries := a.getEffectiveOutputRetries()
 
-	for i := 0; i < a.maxIterations; i++ {
+	// Track regenerations requested by the critic, see WithReflection
+	var reflections int
+
+	if runCfg.resumed != nil {
+		// Execute the turn that was paused for approval before asking the model again
+		last := rc.Messages[len(rc.Messages)-1]
+		if err := a.executeToolCalls(ctx, rc, runCfg, &last, toolMap, tools); err != nil {
+			return nil, err
+		}
+		runCfg.approvals = nil
+	}
+
+	for i := 0; ; i++ {
+		final := false
+		if i >= a.maxIterations {
+			if a.exhaustionPolicy != ExhaustionForceFinal || i > a.maxIterations {
+				break
+			}
+			final = true
+			rc.Messages = append(rc.Messages, types.NewUserMessage(types.WithText(WrapUpPrompt)))
+		}
+
 		// Check request limit
 		if runCfg.usageLimits != nil && runCfg.usageLimits.RequestLimit > 0 {
 			if requestCount >= runCfg.usageLimits.RequestLimit {
@@ -218,37 +758,92 @@ func (a *Agent[TDep, TOut]) Run(ctx context.Context, dep TDep, opts ...RunOption
 			}
 		}
 
-		resp, err := a.client.Chat(ctx, &types.ChatParams{
+		stepDefs, stepTools, err := a.prepareStepTools(ctx, rc, toolDefs, toolMap)
+		if err != nil {
+			return nil, err
+		}
+
+		params := &types.ChatParams{
 			Model:          a.model,
-			Messages:       rc.Messages,
+			Messages:       a.processHistory(ctx, rc.Messages),
 			SystemPrompt:   systemPrompt,
-			Tools:          toolDefs,
+			Tools:          stepDefs,
 			ResponseFormat: rf,
-		})
+		}
+		if len(a.handoffs) > 0 || a.abortTool {
+			// Handoffs and the abort tool are offered like tools but never pass through prepare tools
+			params.Tools = slices.Clone(stepDefs)
+			for _, h := range a.handoffs {
+				params.Tools = append(params.Tools, h.definition)
+			}
+			if a.abortTool {
+				params.Tools = append(params.Tools, abortToolDefinition)
+			}
+		}
+		if a.candidates > 1 {
+			params.N = &a.candidates
+		}
+		if len(params.Tools) > 0 {
+			params.ParallelToolCalls = a.parallelToolCalls
+		}
+		params.ToolChoice = a.resolveToolChoice(runCfg, tools)
+		a.markCacheable(params)
+		for _, opt := range runCfg.modelSettings {
+			opt(params)
+		}
+		if final {
+			forceFinal(params)
+		}
+
+		a.hooks.onModelRequest(ctx, rc, params)
+		if err := runCfg.step(&ModelRequestNode[TDep]{RunContext: rc, Params: params}); err != nil {
+			return nil, err
+		}
+		runCfg.emit(ModelRequestEvent{Step: requestCount + 1, Params: params})
+		modelCtx := ctx
+		if inputCheck != nil {
+			modelCtx = inputCheck.ctx
+		}
+		started := time.Now()
+		resp, err := model(modelCtx, rc, params)
 		requestCount++
+		runCfg.trace.modelCall(requestCount, started, params, resp, err)
+		if inputCheck != nil {
+			// A tripped guardrail takes precedence over the cancelled request
+			if err := inputCheck.wait(); err != nil {
+				return nil, err
+			}
+			inputCheck = nil
+		}
 
 		if err != nil {
 			// Check if it's a recoverable output validation error
 			if isOutputValidationError(err) {
-				if outputRetryCount >= maxOutputRetries {
-					return nil, fmt.Errorf("output validation exceeded max retries (%d): %w", maxOutputRetries, err)
+				if err := a.retryOutput(ctx, rc, &outputRetryCount, maxOutputRetries, err, rf.Schema); err != nil {
+					return nil, err
 				}
-				outputRetryCount++
-				// Add feedback message for LLM to see
-				rc.Messages = append(rc.Messages, types.NewUserMessage(
-					types.WithText(fmt.Sprintf("Output validation error: %v. Please try again.", err)),
-				))
 				continue
 			}
 			return nil, err
 		}
+		if err := runCfg.step(&ModelResponseNode[TDep]{RunContext: rc, Response: resp}); err != nil {
+			return nil, err
+		}
 
-		if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
-			return nil, fmt.Errorf("no response from model")
+		choice, err := a.selectChoice(resp.Choices)
+		if err != nil {
+			return nil, err
 		}
-		choice := &resp.Choices[0]
 		msg := choice.Message
 
+		if a.toolCallIDGenerator != nil {
+			for i := range msg.ToolCalls {
+				if msg.ToolCalls[i].ID == "" {
+					msg.ToolCalls[i].ID = a.toolCallIDGenerator()
+				}
+			}
+		}
+
 		// Check completion tokens limit
 		if runCfg.usageLimits != nil && runCfg.usageLimits.CompletionTokensLimit > 0 && resp.Usage != nil {
 			if int(resp.Usage.CompletionTokens) > runCfg.usageLimits.CompletionTokensLimit {
@@ -257,100 +852,486 @@ func (a *Agent[TDep, TOut]) Run(ctx context.Context, dep TDep, opts ...RunOption
 		}
 
 		if resp.Usage != nil {
-			rc.Usage.PromptTokens += resp.Usage.PromptTokens
-			rc.Usage.CompletionTokens += resp.Usage.CompletionTokens
-			rc.Usage.TotalTokens += resp.Usage.TotalTokens
+			rc.Usage.Add(resp.Usage)
+		}
+
+		// Check cumulative token limits
+		if runCfg.usageLimits != nil && runCfg.usageLimits.PromptTokensLimit > 0 {
+			if int(rc.Usage.PromptTokens) > runCfg.usageLimits.PromptTokensLimit {
+				return nil, &UsageLimitExceeded{Limit: "prompt_tokens_limit", Value: int(rc.Usage.PromptTokens), Max: runCfg.usageLimits.PromptTokensLimit}
+			}
+		}
+		if runCfg.usageLimits != nil && runCfg.usageLimits.TotalTokensLimit > 0 {
+			if int(rc.Usage.TotalTokens) > runCfg.usageLimits.TotalTokensLimit {
+				return nil, &UsageLimitExceeded{Limit: "total_tokens_limit", Value: int(rc.Usage.TotalTokens), Max: runCfg.usageLimits.TotalTokensLimit}
+			}
 		}
 
 		rc.Messages = append(rc.Messages, *msg)
+		a.hooks.onModelResponse(ctx, rc, resp)
 
 		// Case 1: No tool calls - model is done
 		if len(msg.ToolCalls) == 0 {
-			if choice.StructuredContent != "" {
-				if err := json.Unmarshal([]byte(choice.StructuredContent), &res); err != nil {
+			if choice.StructuredContent != "" && a.outputFunc != nil {
+				var err error
+				if res, err = a.callOutputFunc(ctx, rc, choice.StructuredContent, outputRetryCount, maxOutputRetries); err != nil {
+					if _, ok := IsModelRetry(err); !ok {
+						return nil, fmt.Errorf("output function failed: %w", err)
+					}
+					if err := a.retryOutput(ctx, rc, &outputRetryCount, maxOutputRetries, err, rf.Schema); err != nil {
+						return nil, err
+					}
+					continue
+				}
+			} else if choice.StructuredContent != "" {
+				var err error
+				if res, variant, err = a.decodeOutput(choice.StructuredContent); err != nil {
 					// Unmarshal failed - retry if within limit
-					if outputRetryCount >= maxOutputRetries {
-						return nil, fmt.Errorf("output unmarshal exceeded max retries (%d): %w", maxOutputRetries, err)
+					if err := a.retryOutput(ctx, rc, &outputRetryCount, maxOutputRetries, fmt.Errorf("failed to parse output: %w", err), rf.Schema); err != nil {
+						return nil, err
 					}
-					outputRetryCount++
-					rc.Messages = append(rc.Messages, types.NewUserMessage(
-						types.WithText(fmt.Sprintf("Failed to parse output: %v. Please provide valid output.", err)),
-					))
 					continue
 				}
 			} else if rf.Schema != nil {
 				// Expected structured output but got none - retry if within limit
-				if outputRetryCount >= maxOutputRetries {
-					return nil, fmt.Errorf("expected structured output but got none (max retries %d exceeded)", maxOutputRetries)
+				if err := a.retryOutput(ctx, rc, &outputRetryCount, maxOutputRetries, ErrNoStructuredOutput, rf.Schema); err != nil {
+					return nil, err
 				}
-				outputRetryCount++
-				rc.Messages = append(rc.Messages, types.NewUserMessage(
-					types.WithText("Expected structured output but received none. Please provide the output in the required format."),
-				))
 				continue
 			}
+			validated, err := a.validateOutput(ctx, rc, res, outputRetryCount, maxOutputRetries)
+			if err != nil {
+				if _, ok := IsModelRetry(err); !ok {
+					return nil, fmt.Errorf("output validator failed: %w", err)
+				}
+				if err := a.retryOutput(ctx, rc, &outputRetryCount, maxOutputRetries, err, rf.Schema); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			res = validated
+			if err := a.checkOutput(ctx, rc, res); err != nil {
+				if _, ok := IsModelRetry(err); !ok {
+					return nil, err
+				}
+				if err := a.retryOutput(ctx, rc, &outputRetryCount, maxOutputRetries, err, rf.Schema); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if a.reflection != nil && reflections < a.reflection.maxRetries {
+				critique, err := a.critique(ctx, rc, runCfg, systemPrompt, rf.Schema, cmp.Or(choice.StructuredContent, msg.TextContent()))
+				if err != nil {
+					return nil, err
+				}
+				if critique != "" {
+					reflections++
+					rc.Messages = append(rc.Messages, types.NewUserMessage(types.WithText(reflectionFeedback(critique))))
+					continue
+				}
+			}
+
+			runCfg.emit(OutputValidatedEvent{Output: res})
+			runCfg.emit(RunFinishedEvent{AgentName: a.name, Output: res, Usage: rc.Usage})
 			return &RunResult[TOut]{
-				Output:   res,
-				Messages: rc.Messages,
-				Usage:    rc.Usage,
+				Output:        res,
+				Messages:      rc.Messages,
+				Usage:         rc.Usage,
+				OutputVariant: variant,
+				AgentName:     a.name,
+				AgentMetadata: maps.Clone(a.metadata),
 			}, nil
 		}
 
+		if final {
+			// The model ignored the wrap-up request
+			break
+		}
+
+		if call := a.findAbort(msg); call != nil {
+			return a.abort(rc, runCfg, msg, call), nil
+		}
+		if h, call := a.findHandoff(msg); h != nil {
+			return a.handoff(ctx, rc, runCfg, msg, h, call)
+		}
+
 		// Case 2: Has tool calls - execute them all, collect results
-		for _, tc := range msg.ToolCalls {
-			tool := a.findTool(tc.Function.Name)
-			if tool == nil {
-				return nil, fmt.Errorf("unknown tool: %s", tc.Function.Name)
+		if err := a.executeToolCalls(ctx, rc, runCfg, msg, stepTools, tools); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.exhaustionPolicy == ExhaustionReturnPartial {
+		var zero TOut
+		runCfg.emit(RunFinishedEvent{AgentName: a.name, Output: zero, Usage: rc.Usage})
+		return &RunResult[TOut]{
+			Output:     zero,
+			Messages:   rc.Messages,
+			Usage:      rc.Usage,
+			Incomplete: true,
+
+			AgentName:     a.name,
+			AgentMetadata: maps.Clone(a.metadata),
+		}, nil
+	}
+	return nil, fmt.Errorf("%w (%d)", ErrMaxIterations, a.maxIterations)
+}
+
+// executeTool runs tool through the agent's middleware, serving cacheable tools from the cache
+func (a *Agent[TDep, TOut]) executeTool(ctx context.Context, rc *RunContext[TDep], tool *Tool[TDep], args map[string]any) (*types.ToolResult, error) {
+	if !tool.Cache {
+		return a.wrapTool(tool)(ctx, rc, args)
+	}
+
+	key, err := toolCacheKey(tool.Name, args)
+	if err != nil {
+		// Arguments that cannot be encoded are executed uncached
+		return a.wrapTool(tool)(ctx, rc, args)
+	}
+	if result, ok := a.toolCache.Get(key); ok {
+		return result, nil
+	}
+
+	result, err := a.wrapTool(tool)(ctx, rc, args)
+	if err == nil && result != nil && !result.IsError {
+		a.toolCache.Set(key, result, tool.CacheTTL)
+	}
+	return result, err
+}
+
+// argumentsRetry sends a *types.ToolArgumentsError back to the model, counted against the
+// tool's retries like a ModelRetry
+type argumentsRetry struct {
+	*ModelRetry
+	err error
+}
+
+func newArgumentsRetry(err error) argumentsRetry {
+	return argumentsRetry{NewModelRetry(err.Error() + ". Call the tool again with a valid JSON object as arguments."), err}
+}
+
+func (e argumentsRetry) Unwrap() []error {
+	return []error{e.ModelRetry, e.err}
+}
+
+// toolState tracks tool executions across the iterations of a run
+type toolState struct {
+	retries    map[string]int // Retry count per tool name
+	successful int            // Successful executions, checked against ToolCallsLimit
+	called     bool           // The model called a tool, which ends forcing tool choices
+	unknown    int            // Calls of unknown tools answered, checked against unknownToolRetries
+}
+
+// executeToolCalls executes the tool calls of msg and appends their results to rc.Messages
+func (a *Agent[TDep, TOut]) executeToolCalls(ctx context.Context, rc *RunContext[TDep], runCfg *runConfig, msg *types.Message, toolMap map[string]*Tool[TDep], tools *toolState) error {
+	tools.called = true
+	if runCfg.approvals == nil {
+		if pending := pendingApprovals(msg, toolMap); len(pending) > 0 {
+			return &PendingApproval{RunID: rc.RunID, Messages: slices.Clone(rc.Messages), ToolCalls: pending, Usage: rc.Usage}
+		}
+	}
+
+	results := make([]types.ToolResult, 0, len(msg.ToolCalls))
+	for j := range msg.ToolCalls {
+		if err := runCfg.step(&ToolCallNode[TDep]{RunContext: rc, Call: &msg.ToolCalls[j]}); err != nil {
+			return err
+		}
+		tc := msg.ToolCalls[j]
+		tool := toolMap[tc.Function.Name]
+		if tool == nil {
+			result, err := a.unknownTool(ctx, rc, tc, toolMap, tools)
+			if err != nil {
+				return err
 			}
+			runCfg.emit(ToolResultEvent{ToolCallID: tc.ID, Name: tc.Function.Name, Result: result})
+			results = append(results, *result)
+			continue
+		}
 
-			// Get retry count for this tool and check limit
-			retryCount := toolRetries[tool.Name]
-			maxRetries := a.getEffectiveRetries(tool, runCfg.retries)
+		if decision, ok := runCfg.approvals[tc.ID]; ok && tool.RequiresApproval && !decision.Approved {
+			result := deniedResult(decision.Reason)
+			runCfg.emit(ToolResultEvent{ToolCallID: tc.ID, Name: tool.Name, Result: result})
+			results = append(results, *result)
+			continue
+		}
 
-			// Set RunContext fields for this tool call
-			rc.Retry = retryCount
-			rc.MaxRetries = maxRetries
-			rc.ToolCallID = tc.ID
+		// Get retry count for this tool and check limit
+		retryCount := tools.retries[tool.Name]
+		maxRetries := a.getEffectiveRetries(tool, runCfg.retries)
 
-			result, execErr := tool.Execute(ctx, rc, tc.Function.Arguments)
+		// Set RunContext fields for this tool call
+		rc.Retry = retryCount
+		rc.MaxRetries = maxRetries
+		rc.ToolCallID = tc.ID
 
-			if execErr != nil {
-				// Check if it's a ModelRetry error
-				if mr, ok := IsModelRetry(execErr); ok {
-					if retryCount >= maxRetries {
-						return nil, fmt.Errorf("tool %q exceeded max retries (%d): %w", tool.Name, maxRetries, execErr)
-					}
-					// Increment retry count for next iteration
-					toolRetries[tool.Name] = retryCount + 1
-					// Convert to error result for LLM to see
-					result = &types.ToolResult{
-						ContentPart: []types.ContentPart{
-							types.NewContentPartText(mr.Message),
-						},
-						IsError: true,
-					}
-				} else {
-					// Non-ModelRetry error - fatal
-					return nil, fmt.Errorf("tool execution failed: %w", execErr)
+		runCfg.emit(ToolCallStartedEvent{ToolCallID: tc.ID, Name: tool.Name, Arguments: tc.Function.Arguments})
+		a.hooks.onToolStart(ctx, rc, tc)
+		started := time.Now()
+		var result *types.ToolResult
+		execErr := tc.Function.ArgumentsError()
+		if execErr != nil {
+			execErr = newArgumentsRetry(execErr)
+		} else {
+			result, execErr = a.executeTool(ctx, rc, tool, tc.Function.Arguments)
+		}
+		a.hooks.onToolEnd(ctx, rc, tc, result, execErr)
+		runCfg.trace.toolCall(tc, started, result, execErr)
+
+		if execErr != nil {
+			// Check if it's a ModelRetry error
+			if mr, ok := IsModelRetry(execErr); ok {
+				if retryCount >= maxRetries {
+					return fmt.Errorf("tool %q exceeded max retries (%d): %w", tool.Name, maxRetries, execErr)
+				}
+				// Increment retry count for next iteration
+				tools.retries[tool.Name] = retryCount + 1
+				a.hooks.onRetry(ctx, rc, execErr)
+				// Convert to error result for LLM to see
+				result = &types.ToolResult{
+					ContentPart: []types.ContentPart{
+						types.NewContentPartText(mr.Message),
+					},
+					IsError: true,
 				}
 			} else {
-				// Success - reset retry count for this tool
-				toolRetries[tool.Name] = 0
-				successfulToolCalls++
-
-				// Check tool calls limit
-				if runCfg.usageLimits != nil && runCfg.usageLimits.ToolCallsLimit > 0 {
-					if successfulToolCalls > runCfg.usageLimits.ToolCallsLimit {
-						return nil, &UsageLimitExceeded{Limit: "tool_calls_limit", Value: successfulToolCalls, Max: runCfg.usageLimits.ToolCallsLimit}
-					}
+				// Non-ModelRetry error - fatal
+				return fmt.Errorf("tool execution failed: %w", execErr)
+			}
+		} else {
+			// Success - reset retry count for this tool
+			tools.retries[tool.Name] = 0
+			tools.successful++
+
+			// Check tool calls limit
+			if runCfg.usageLimits != nil && runCfg.usageLimits.ToolCallsLimit > 0 {
+				if tools.successful > runCfg.usageLimits.ToolCallsLimit {
+					return &UsageLimitExceeded{Limit: "tool_calls_limit", Value: tools.successful, Max: runCfg.usageLimits.ToolCallsLimit}
 				}
 			}
+		}
+
+		result = a.limitToolResult(ctx, tool, result)
+		runCfg.emit(ToolResultEvent{ToolCallID: tc.ID, Name: tool.Name, Result: result})
+		results = append(results, *result)
+	}
+
+	if a.parallelToolAggregator != nil && len(results) > 1 {
+		combined := a.parallelToolAggregator(results)
+		if combined == nil {
+			return errors.New("parallel tool aggregator returned nil result")
+		}
+		// Providers require an answer to every tool call, so the others point to the combined result
+		first := msg.ToolCalls[0].ID
+		rc.Messages = append(rc.Messages, types.NewToolResultMessage(first, combined))
+		for _, tc := range msg.ToolCalls[1:] {
+			stub := &types.ToolResult{ContentPart: []types.ContentPart{types.NewContentPartText("Combined into the result of " + first + ".")}}
+			rc.Messages = append(rc.Messages, types.NewToolResultMessage(tc.ID, stub))
+		}
+		return nil
+	}
+	for j, tc := range msg.ToolCalls {
+		rc.Messages = append(rc.Messages, types.NewToolResultMessage(tc.ID, &results[j]))
+	}
+	return nil
+}
+
+// responseFormat returns the response format requested from the model, if any
+func (a *Agent[TDep, TOut]) responseFormat() (types.ResponseFormat, error) {
+	mode := a.responseFormatMode
+	if a.outputFunc != nil {
+		if len(a.outputVariants) > 0 {
+			return types.ResponseFormat{}, errors.New("output function cannot be combined with output variants")
+		}
+		if mode == "" {
+			mode = types.ResponseFormatModeTool
+		}
+		return types.ResponseFormat{
+			Mode:        mode,
+			Description: a.outputFunc.description,
+			Schema:      a.outputFunc.schema,
+			Strict:      mode == types.ResponseFormatModeNative,
+			ToolName:    a.outputToolName,
+
+			AllowAdditionalProperties: a.extraOutputProps,
+		}, nil
+	}
+	if len(a.outputVariants) > 0 {
+		if mode == "" {
+			mode = types.ResponseFormatModeTool
+		}
+		return types.ResponseFormat{
+			Mode:     mode,
+			Schema:   a.unionSchema(),
+			Strict:   mode == types.ResponseFormatModeNative,
+			ToolName: a.outputToolName,
+
+			AllowAdditionalProperties: a.extraOutputProps,
+		}, nil
+	}
+	if mode == "" {
+		return types.ResponseFormat{}, nil
+	}
 
-			rc.Messages = append(rc.Messages, types.NewToolResultMessage(tc.ID, result))
+	rf, err := types.ResponseFormatFor[TOut](mode, "", "", a.outputSchemaOpts...)
+	if err != nil {
+		return types.ResponseFormat{}, fmt.Errorf("failed to build response format: %w", err)
+	}
+	// Native output is enforced by the provider, which needs the strict form of the schema
+	rf.Strict = mode == types.ResponseFormatModeNative
+	rf.ToolName = a.outputToolName
+	rf.AllowAdditionalProperties = a.extraOutputProps
+	return rf, nil
+}
+
+// checkOutputToolName rejects tools named like the output tool, whose calls would be taken
+// for the output
+func (a *Agent[TDep, TOut]) checkOutputToolName(tools []*Tool[TDep]) error {
+	name := cmp.Or(a.outputToolName, types.OutputToolName)
+	for _, t := range tools {
+		if t.Name == name {
+			return fmt.Errorf("tool name clashes with the output tool: %s (see WithOutputToolName)", name)
 		}
 	}
+	for _, h := range a.handoffs {
+		if h.definition.Name == name {
+			return fmt.Errorf("handoff name clashes with the output tool: %s (see WithOutputToolName)", name)
+		}
+	}
+	return nil
+}
 
-	return nil, fmt.Errorf("agent exceeded max iterations (%d)", a.maxIterations)
+// retryOutput sends err back to the LLM as output retry feedback and waits the output retry
+// backoff. Once the output retries are exhausted it returns an error wrapping err instead.
+func (a *Agent[TDep, TOut]) retryOutput(ctx context.Context, rc *RunContext[TDep], retries *int, maxRetries int, err error, schema map[string]any) error {
+	if *retries >= maxRetries {
+		return fmt.Errorf("output validation exceeded max retries (%d): %w", maxRetries, err)
+	}
+	*retries++
+	a.hooks.onRetry(ctx, rc, err)
+	rc.Messages = append(rc.Messages, a.outputRetryFeedback(*retries, err, schema))
+	return a.waitOutputRetry(ctx, *retries)
+}
+
+// outputRetryFeedback builds the message telling the LLM why its output was rejected, marked
+// with MetadataRetryFeedback
+func (a *Agent[TDep, TOut]) outputRetryFeedback(attempt int, err error, schema map[string]any) types.Message {
+	var message types.Message
+	if a.outputRetryPrompt != nil {
+		message = a.outputRetryPrompt(&OutputRetryError{Err: err, Schema: schema}, attempt)
+	} else {
+		message = types.NewUserMessage(types.WithText(a.outputRetryMessageBuilder(attempt, err, schema)))
+	}
+	message.Metadata = maps.Clone(message.Metadata) // May be shared by the prompt's messages
+	types.WithMessageMetadata(MetadataRetryFeedback, true)(&message)
+	return message
+}
+
+// waitOutputRetry waits the output retry backoff before the given 1-based retry attempt
+func (a *Agent[TDep, TOut]) waitOutputRetry(ctx context.Context, attempt int) error {
+	if a.outputRetryBackoff == nil {
+		return nil
+	}
+	return sleep(ctx, a.outputRetryBackoff(attempt))
+}
+
+// callOutputFunc runs the output function on the model's arguments.
+// rc carries the output retry state while it runs.
+func (a *Agent[TDep, TOut]) callOutputFunc(ctx context.Context, rc *RunContext[TDep], content string, retry, maxRetries int) (TOut, error) {
+	rc.Retry = retry
+	rc.MaxRetries = maxRetries
+	return a.outputFunc.call(ctx, rc, content)
+}
+
+// validateOutput runs the output validators in order, each receiving the previous one's output.
+// rc carries the output retry state while they run.
+func (a *Agent[TDep, TOut]) validateOutput(ctx context.Context, rc *RunContext[TDep], output TOut, retry, maxRetries int) (TOut, error) {
+	if len(a.outputValidators) == 0 {
+		return output, nil
+	}
+
+	rc.Retry = retry
+	rc.MaxRetries = maxRetries
+	for _, validator := range a.outputValidators {
+		var err error
+		output, err = validator(ctx, rc, output)
+		if err != nil {
+			return output, err
+		}
+	}
+	return output, nil
+}
+
+// buildSystemPrompt joins the non-empty system prompt parts of the agent and the run
+func (a *Agent[TDep, TOut]) buildSystemPrompt(dep TDep, runCfg *runConfig) string {
+	var parts []string
+	for _, instruction := range a.instructions {
+		if part := instruction(dep); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	for _, part := range runCfg.instructions {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// processHistory strips reasoning if configured and applies the history processors to the
+// messages of a request
+func (a *Agent[TDep, TOut]) processHistory(ctx context.Context, messages []types.Message) []types.Message {
+	if a.stripReasoning {
+		messages = stripReasoning(messages)
+	}
+	if len(a.historyProcessors) == 0 {
+		return messages
+	}
+	messages = slices.Clone(messages)
+	for _, process := range a.historyProcessors {
+		messages = process(ctx, a, messages)
+	}
+	return messages
+}
+
+// chat performs a single model request, streaming it when onText is set
+func (a *Agent[TDep, TOut]) chat(ctx context.Context, params *types.ChatParams, onText func(string) error) (*types.ChatResponse, error) {
+	if onText == nil {
+		return a.client.Chat(ctx, params)
+	}
+	return types.StreamWithHandler(ctx, a.client, params, func(chunk *types.StreamChunk) error {
+		for _, choice := range chunk.Choices {
+			if choice.Index == 0 && choice.Delta != nil && choice.Delta.Content != "" {
+				if err := onText(choice.Delta.Content); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// selectChoice returns the choice the run continues with: the first, unless a selector is set
+func (a *Agent[TDep, TOut]) selectChoice(choices []types.Choice) (*types.Choice, error) {
+	if len(choices) == 0 {
+		return nil, fmt.Errorf("no response from model")
+	}
+
+	idx := 0
+	if a.choiceSelector != nil && len(choices) > 1 {
+		var err error
+		idx, err = a.choiceSelector(choices)
+		if err != nil {
+			return nil, fmt.Errorf("choice selector failed: %w", err)
+		}
+		if idx < 0 || idx >= len(choices) {
+			return nil, fmt.Errorf("choice selector returned index %d for %d choices", idx, len(choices))
+		}
+	}
+
+	if choices[idx].Message == nil {
+		return nil, fmt.Errorf("no response from model")
+	}
+	return &choices[idx], nil
 }
 
 // getEffectiveRetries returns the retry count for a tool call.
@@ -384,6 +1365,67 @@ func isOutputValidationError(err error) bool {
 		errors.As(err, &misuseErr)
 }
 
-func (a *Agent[TDep, TOut]) findTool(name string) *Tool[TDep] {
-	return a.toolMap[name]
+// prepareStepTools applies the prepare tools functions for one model request and returns
+// the definitions to offer together with the tools that may be called
+func (a *Agent[TDep, TOut]) prepareStepTools(ctx context.Context, rc *RunContext[TDep], defs []types.ToolDefinition, toolMap map[string]*Tool[TDep]) ([]types.ToolDefinition, map[string]*Tool[TDep], error) {
+	if len(a.prepareTools) == 0 {
+		return defs, toolMap, nil
+	}
+
+	defs = slices.Clone(defs)
+	for _, prepare := range a.prepareTools {
+		defs = prepare(ctx, rc, defs)
+	}
+
+	stepTools := make(map[string]*Tool[TDep], len(defs))
+	for _, def := range defs {
+		tool, ok := toolMap[def.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("prepare tools returned unknown tool: %s", def.Name)
+		}
+		stepTools[def.Name] = tool
+	}
+	return defs, stepTools, nil
+}
+
+// resolveTools returns the tools available to a run: the agent's tools merged with
+// (or replaced by) the run-specific tools. The agent's own map and list are never mutated.
+func (a *Agent[TDep, TOut]) resolveTools(runCfg *runConfig) (map[string]*Tool[TDep], []*Tool[TDep], error) {
+	if runCfg.tools == nil {
+		return a.toolMap, a.toolList, nil
+	}
+
+	runTools, ok := runCfg.tools.([]*Tool[TDep])
+	if !ok {
+		return nil, nil, fmt.Errorf("run tools have type %T, which does not match the agent's dependency type", runCfg.tools)
+	}
+
+	toolMap := make(map[string]*Tool[TDep], len(a.toolMap)+len(runTools))
+	runToolNames := make(map[string]bool, len(runTools))
+	for _, t := range runTools {
+		if runToolNames[t.Name] {
+			return nil, nil, fmt.Errorf("duplicate run tool name: %s", t.Name)
+		}
+		runToolNames[t.Name] = true
+	}
+	if err := a.checkOutputToolName(runTools); err != nil {
+		return nil, nil, err
+	}
+
+	toolList := make([]*Tool[TDep], 0, len(a.toolList)+len(runTools))
+	if !runCfg.toolsOnly {
+		for _, t := range a.toolList {
+			if runToolNames[t.Name] {
+				continue
+			}
+			toolMap[t.Name] = t
+			toolList = append(toolList, t)
+		}
+	}
+	for _, t := range runTools {
+		toolMap[t.Name] = t
+		toolList = append(toolList, t)
+	}
+
+	return toolMap, toolList, nil
 }
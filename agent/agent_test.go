@@ -1,13 +1,22 @@
 package agent
 
 import (
+	"bytes"
 	"context"
 	"encoding/json/v2"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/KennyKeni/elysia/adapter/openai"
 	"github.com/KennyKeni/elysia/client"
@@ -24,6 +33,7 @@ type mockRawClient struct {
 	chatCalls    int
 	chatResponses []chatResponse // Queue of responses to return
 	chatErr      error          // Error to return (if set, overrides responses)
+	chatParams   []*types.ChatParams // Params received by each RawChat call
 }
 
 type chatResponse struct {
@@ -48,6 +58,7 @@ func (m *mockRawClient) RawChat(ctx context.Context, params *types.ChatParams) (
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.chatCalls++
+	m.chatParams = append(m.chatParams, params)
 
 	if m.chatErr != nil {
 		return nil, m.chatErr
@@ -62,8 +73,49 @@ func (m *mockRawClient) RawChat(ctx context.Context, params *types.ChatParams) (
 	return resp.response, resp.err
 }
 
+// RawChatStream replays the next queued response as a stream: text word by word,
+// then the tool calls, then the finish reason and usage
 func (m *mockRawClient) RawChatStream(ctx context.Context, params *types.ChatParams) (*types.Stream, error) {
-	return nil, fmt.Errorf("streaming not implemented in mock")
+	resp, err := m.RawChat(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []*types.StreamChunk
+	for _, choice := range resp.Choices {
+		delta := func(d *types.MessageDelta) {
+			chunks = append(chunks, &types.StreamChunk{ID: resp.ID, Model: resp.Model, Choices: []types.StreamChoice{{Index: choice.Index, Delta: d}}})
+		}
+		delta(&types.MessageDelta{Role: types.RoleAssistant})
+		for _, word := range strings.SplitAfter(choice.Message.TextContent(), " ") {
+			if word != "" {
+				delta(&types.MessageDelta{Content: word})
+			}
+		}
+		for i, tc := range choice.Message.ToolCalls {
+			args, err := json.Marshal(tc.Function.Arguments)
+			if err != nil {
+				return nil, err
+			}
+			if tc.Function.RawArguments != "" {
+				args = []byte(tc.Function.RawArguments)
+			}
+			delta(&types.MessageDelta{ToolCalls: []types.ToolCallDelta{{Index: i, ID: tc.ID, FunctionName: tc.Function.Name, Arguments: string(args)}}})
+		}
+		chunks = append(chunks, &types.StreamChunk{ID: resp.ID, Model: resp.Model, Choices: []types.StreamChoice{{Index: choice.Index, Delta: &types.MessageDelta{}, FinishReason: choice.FinishReason}}})
+	}
+	if len(chunks) > 0 {
+		chunks[len(chunks)-1].Usage = resp.Usage
+	}
+
+	return types.NewStream(func() (*types.StreamChunk, error) {
+		if len(chunks) == 0 {
+			return nil, io.EOF
+		}
+		chunk := chunks[0]
+		chunks = chunks[1:]
+		return chunk, nil
+	}, nil), nil
 }
 
 func (m *mockRawClient) RawEmbed(ctx context.Context, params *types.EmbeddingParams) (*types.EmbeddingResponse, error) {
@@ -301,7 +353,7 @@ func TestAgent_New(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if agent.systemPrompt != "You are a test assistant" {
+		if agent.buildSystemPrompt(testDeps{}, &runConfig{}) != "You are a test assistant" {
 			t.Errorf("expected system prompt to be set")
 		}
 		if agent.retries != 3 {
@@ -313,6 +365,113 @@ func TestAgent_New(t *testing.T) {
 	})
 }
 
+func TestAgent_WithModelFromEnv(t *testing.T) {
+	_, client := newTestClient()
+
+	t.Run("reads model from env", func(t *testing.T) {
+		t.Setenv("ELYSIA_TEST_MODEL", "gpt-4o-mini")
+		agent, err := New[testDeps, testOutput](client,
+			WithModelFromEnv[testDeps, testOutput]("ELYSIA_TEST_MODEL"),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if agent.model != "gpt-4o-mini" {
+			t.Errorf("expected model gpt-4o-mini, got %q", agent.model)
+		}
+	})
+
+	t.Run("errors when env is empty", func(t *testing.T) {
+		t.Setenv("ELYSIA_TEST_MODEL", "")
+		_, err := New[testDeps, testOutput](client,
+			WithModelFromEnv[testDeps, testOutput]("ELYSIA_TEST_MODEL"),
+		)
+		if err == nil {
+			t.Fatal("expected error for empty env var")
+		}
+	})
+
+	t.Run("falls back to default", func(t *testing.T) {
+		t.Setenv("ELYSIA_TEST_MODEL", "")
+		agent, err := New[testDeps, testOutput](client,
+			WithModelFromEnvOrDefault[testDeps, testOutput]("ELYSIA_TEST_MODEL", "gpt-4o"),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if agent.model != "gpt-4o" {
+			t.Errorf("expected default model gpt-4o, got %q", agent.model)
+		}
+	})
+
+	t.Run("env takes precedence over default", func(t *testing.T) {
+		t.Setenv("ELYSIA_TEST_MODEL", "o3-mini")
+		agent, _ := New[testDeps, testOutput](client,
+			WithModelFromEnvOrDefault[testDeps, testOutput]("ELYSIA_TEST_MODEL", "gpt-4o"),
+		)
+		if agent.model != "o3-mini" {
+			t.Errorf("expected model o3-mini, got %q", agent.model)
+		}
+	})
+}
+
+func TestAgent_WithPreRunCheck(t *testing.T) {
+	t.Run("checks run before the LLM", func(t *testing.T) {
+		raw, client := newTestClient()
+		raw.queueResponse(textResponse("Hello"), nil)
+
+		var order []string
+		agent, _ := New[testDeps, emptyOutput](client,
+			WithPreRunCheck[testDeps, emptyOutput](func(ctx context.Context, c types.Client) error {
+				order = append(order, fmt.Sprintf("first:%d", raw.chatCalls))
+				return nil
+			}),
+			WithPreRunCheck[testDeps, emptyOutput](func(ctx context.Context, c types.Client) error {
+				order = append(order, fmt.Sprintf("second:%d", raw.chatCalls))
+				return nil
+			}),
+		)
+
+		if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("Hi")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(order, ",") != "first:0,second:0" {
+			t.Errorf("expected both checks before any LLM call, got %v", order)
+		}
+		if raw.chatCalls != 1 {
+			t.Errorf("expected 1 chat call, got %d", raw.chatCalls)
+		}
+	})
+
+	t.Run("failing check aborts the run", func(t *testing.T) {
+		raw, client := newTestClient()
+		raw.queueResponse(textResponse("Hello"), nil)
+
+		quotaErr := errors.New("quota exhausted")
+		var secondCalled bool
+		agent, _ := New[testDeps, emptyOutput](client,
+			WithPreRunCheck[testDeps, emptyOutput](func(ctx context.Context, c types.Client) error {
+				return quotaErr
+			}),
+			WithPreRunCheck[testDeps, emptyOutput](func(ctx context.Context, c types.Client) error {
+				secondCalled = true
+				return nil
+			}),
+		)
+
+		_, err := agent.Run(context.Background(), testDeps{}, WithPrompt("Hi"))
+		if !errors.Is(err, quotaErr) {
+			t.Fatalf("expected quota error, got %v", err)
+		}
+		if secondCalled {
+			t.Error("expected later checks to be skipped")
+		}
+		if raw.chatCalls != 0 {
+			t.Errorf("expected no chat calls, got %d", raw.chatCalls)
+		}
+	})
+}
+
 func TestAgent_WithSystemPromptFunc(t *testing.T) {
 	raw, client := newTestClient()
 	raw.queueResponse(textResponse("Hello!"), nil)
@@ -333,6 +492,67 @@ func TestAgent_WithSystemPromptFunc(t *testing.T) {
 	}
 }
 
+func TestAgent_SystemPromptParts(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("Hello!"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithSystemPrompt[testDeps, emptyOutput]("You are helpful."),
+		WithSystemPromptFunc[testDeps, emptyOutput](func(deps testDeps) string {
+			return "The user is " + deps.Value + "."
+		}),
+		WithSystemPromptFunc[testDeps, emptyOutput](func(deps testDeps) string { return "" }),
+		WithSystemPrompt[testDeps, emptyOutput]("Be brief."),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = agent.Run(context.Background(), testDeps{Value: "Ada"}, WithPrompt("test"), WithRunInstructions("Answer in French."))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "You are helpful.\n\nThe user is Ada.\n\nBe brief.\n\nAnswer in French."
+	if got := raw.chatParams[0].SystemPrompt; got != want {
+		t.Errorf("system prompt = %q, want %q", got, want)
+	}
+}
+
+func TestAgent_PromptTemplates(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("Hello!"), nil)
+
+	system := types.MustPromptTemplate("You support {{.product}}.")
+	agent, err := New[testDeps, emptyOutput](client,
+		WithSystemPromptTemplate[testDeps, emptyOutput](system, map[string]string{"product": "Elysia"}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	prompt := types.MustPromptTemplate("Summarize ticket {{.id}}.")
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPromptTemplate(prompt, map[string]any{})); err == nil {
+		t.Error("expected an error for a missing prompt variable")
+	}
+	result, err := agent.Run(context.Background(), testDeps{}, WithPromptTemplate(prompt, map[string]any{"id": 42}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := raw.chatParams[0].SystemPrompt; got != "You support Elysia." {
+		t.Errorf("system prompt = %q", got)
+	}
+	if got := result.Messages[0].TextContent(); got != "Summarize ticket 42." {
+		t.Errorf("prompt = %q", got)
+	}
+
+	_, err = New[testDeps, emptyOutput](client, WithSystemPromptTemplate[testDeps, emptyOutput](system, map[string]string{}))
+	if err == nil || !strings.Contains(err.Error(), "product") {
+		t.Errorf("expected missing variable error at construction, got %v", err)
+	}
+}
+
 func TestAgent_DuplicateToolsError(t *testing.T) {
 	_, client := newTestClient()
 
@@ -407,6 +627,61 @@ func TestAgent_Run_NoResponse(t *testing.T) {
 	}
 }
 
+func TestAgent_Run_WithChoiceSelector(t *testing.T) {
+	raw, client := newTestClient()
+	resp := textResponse("short")
+	resp.Choices = append(resp.Choices, textResponse("a longer answer").Choices[0])
+	raw.queueResponse(resp, nil)
+
+	longest := func(choices []types.Choice) (int, error) {
+		best := 0
+		for i, choice := range choices {
+			if len(choice.Message.TextContent()) > len(choices[best].Message.TextContent()) {
+				best = i
+			}
+		}
+		return best, nil
+	}
+
+	agent, err := New[testDeps, emptyOutput](client, WithChoiceSelector[testDeps, emptyOutput](2, longest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("Answer"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := raw.chatParams[0].N; n == nil || *n != 2 {
+		t.Errorf("expected N=2 in chat params, got %v", n)
+	}
+	if got := result.Messages[len(result.Messages)-1].TextContent(); got != "a longer answer" {
+		t.Errorf("expected selected choice in history, got %q", got)
+	}
+}
+
+func TestAgent_Run_WithChoiceSelector_BadIndex(t *testing.T) {
+	raw, client := newTestClient()
+	resp := textResponse("a")
+	resp.Choices = append(resp.Choices, textResponse("b").Choices[0])
+	raw.queueResponse(resp, nil)
+
+	agent, err := New[testDeps, emptyOutput](client, WithChoiceSelector[testDeps, emptyOutput](2,
+		func([]types.Choice) (int, error) { return 5, nil }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("Answer")); err == nil {
+		t.Fatal("expected error for out-of-range choice index")
+	}
+
+	if _, err := New[testDeps, emptyOutput](client, WithChoiceSelector[testDeps, emptyOutput](0, nil)); err == nil {
+		t.Error("expected error for invalid choice selector")
+	}
+}
+
 func TestAgent_Run_WithMessages(t *testing.T) {
 	raw, client := newTestClient()
 	raw.queueResponse(textResponse("continuation"), nil)
@@ -479,6 +754,68 @@ func TestAgent_Run_SingleToolCall(t *testing.T) {
 	}
 }
 
+func TestAgent_Run_WithParallelToolCalls(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("done"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hello")),
+		WithParallelToolCalls[testDeps, emptyOutput](false),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("Greet Alice")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if p := raw.chatParams[0].ParallelToolCalls; p == nil || *p {
+		t.Errorf("expected ParallelToolCalls=false in chat params, got %v", p)
+	}
+}
+
+func TestAgent_Run_StrictToolAcceptsNullOptionalArgs(t *testing.T) {
+	type lookupInput struct {
+		City    string `json:"city"`
+		Country string `json:"country,omitempty"`
+	}
+
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call-1", "lookup", map[string]any{"city": "Paris", "country": nil}),
+	), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	var received lookupInput
+	lookup, err := NewTool[testDeps, lookupInput, testOutput](
+		"lookup", "Looks up a city",
+		func(ctx context.Context, rc *RunContext[testDeps], in lookupInput) (testOutput, error) {
+			received = in
+			return testOutput{Result: in.City}, nil
+		},
+		ToolStrict[testDeps](),
+	)
+	if err != nil {
+		t.Fatalf("failed to create tool: %v", err)
+	}
+
+	agent, err := New[testDeps, emptyOutput](client, WithTools[testDeps, emptyOutput](lookup))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("Look up Paris")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if received.City != "Paris" {
+		t.Errorf("expected tool to run with null optional argument, got %+v", received)
+	}
+	if def := raw.chatParams[0].Tools[0]; !def.Strict || def.InputSchema["additionalProperties"] != false {
+		t.Errorf("expected strict tool definition, got %+v", def)
+	}
+}
+
 func TestAgent_Run_MultipleToolCalls(t *testing.T) {
 	raw, client := newTestClient()
 
@@ -546,6 +883,103 @@ func TestAgent_Run_UnknownTool(t *testing.T) {
 	}
 }
 
+func TestAgent_Run_UnknownToolRetries(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call-1", "gret", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(toolCallResponse(makeToolCall("call-2", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi "), newGreetTool("wave", "Hey ")),
+		WithUnknownToolRetries[testDeps, emptyOutput](1),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	feedback := result.Messages[2]
+	if feedback.Role != types.RoleTool || feedback.TextContent() != "tool gret does not exist; available: greet, wave" {
+		t.Errorf("unexpected feedback: %+v", feedback)
+	}
+	if !strings.Contains(result.Messages[4].TextContent(), "Hi Ada") {
+		t.Errorf("expected the corrected call to run, got %+v", result.Messages[4])
+	}
+
+	// The budget is per run
+	raw.queueResponse(toolCallResponse(makeToolCall("call-1", "gret", map[string]any{})), nil)
+	raw.queueResponse(toolCallResponse(makeToolCall("call-2", "great", map[string]any{})), nil)
+	_, err = agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	if err == nil || err.Error() != "unknown tool: great (exceeded max retries (1))" {
+		t.Errorf("expected the second unknown tool to fail the run, got %v", err)
+	}
+
+	if _, err := New[testDeps, emptyOutput](client, WithUnknownToolRetries[testDeps, emptyOutput](0)); err == nil {
+		t.Error("expected zero retries to be rejected")
+	}
+}
+
+func TestAgent_Run_MalformedToolArguments(t *testing.T) {
+	raw, client := newTestClient()
+	broken := types.ToolCall{ID: "call-1", Function: types.NewToolFunction("greet", `{"name" "Ada"}`)}
+	raw.queueResponse(toolCallResponse(broken), nil)
+	raw.queueResponse(toolCallResponse(makeToolCall("call-2", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	var retried error
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")),
+		WithRetries[testDeps, emptyOutput](1),
+		WithOnRetry[testDeps, emptyOutput](func(ctx context.Context, rc *RunContext[testDeps], err error) {
+			retried = err
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := result.Messages[1].ToolCalls[0].Function.RawArguments; got != `{"name" "Ada"}` {
+		t.Errorf("expected the raw arguments in the transcript, got %q", got)
+	}
+	feedback := result.Messages[2]
+	if feedback.ToolCallID == nil || *feedback.ToolCallID != "call-1" || !strings.Contains(feedback.TextContent(), "invalid tool arguments") {
+		t.Errorf("unexpected feedback: %+v", feedback)
+	}
+	var argsErr *types.ToolArgumentsError
+	if !errors.As(retried, &argsErr) {
+		t.Errorf("expected the retry hook to receive a *types.ToolArgumentsError, got %v", retried)
+	}
+	if !strings.Contains(result.Messages[4].TextContent(), "Hi Ada") {
+		t.Errorf("expected the corrected call to run, got %+v", result.Messages[4])
+	}
+
+	// Streamed runs retry the same way
+	raw.queueResponse(toolCallResponse(broken), nil)
+	raw.queueResponse(toolCallResponse(makeToolCall("call-2", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("done"), nil)
+	run := agent.RunStream(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	defer run.Close()
+	for run.Next() {
+	}
+	result, err = run.Result()
+	if err != nil {
+		t.Fatalf("unexpected streaming error: %v", err)
+	}
+	if feedback := result.Messages[2]; !strings.Contains(feedback.TextContent(), "invalid tool arguments") {
+		t.Errorf("unexpected streamed feedback: %+v", feedback)
+	}
+	if !strings.Contains(result.Messages[4].TextContent(), "Hi Ada") {
+		t.Errorf("expected the corrected streamed call to run, got %+v", result.Messages[4])
+	}
+}
+
 func TestAgent_Run_ToolWithRunContext(t *testing.T) {
 	raw, client := newTestClient()
 
@@ -594,72 +1028,326 @@ func TestAgent_Run_ToolWithRunContext(t *testing.T) {
 	}
 }
 
-// =============================================================================
-// Tool Retry Tests
-// =============================================================================
-
-func TestAgent_Run_ModelRetry(t *testing.T) {
+func TestAgent_Run_ToolCallIDGenerator(t *testing.T) {
 	raw, client := newTestClient()
 
-	// First call: tool call
-	raw.queueResponse(toolCallResponse(
-		makeToolCall("call-1", "flaky_tool", map[string]any{"name": "test"}),
-	), nil)
-
-	// Second call (after retry): tool call again
 	raw.queueResponse(toolCallResponse(
-		makeToolCall("call-2", "flaky_tool", map[string]any{"name": "test"}),
+		makeToolCall("", "greet", map[string]any{"name": "Alice"}),
+		makeToolCall("call-2", "greet", map[string]any{"name": "Bob"}),
+		makeToolCall("", "greet", map[string]any{"name": "Carol"}),
 	), nil)
-
-	// Third call: success
 	raw.queueResponse(textResponse("Done"), nil)
 
-	callCount := 0
-	flakyTool, _ := NewTool[testDeps, testInput, testOutput](
-		"flaky_tool", "Fails first time",
+	greetTool, _ := NewTool[testDeps, testInput, testOutput](
+		"greet", "Greets a person",
 		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
-			callCount++
-			if callCount == 1 {
-				return testOutput{}, NewModelRetry("First attempt failed, try again")
-			}
-			return testOutput{Result: "success"}, nil
+			return testOutput{Result: "Hello, " + in.Name}, nil
 		},
 	)
 
+	var generated int
 	agent, err := New[testDeps, emptyOutput](client,
-		WithTools[testDeps, emptyOutput](flakyTool),
-		WithRetries[testDeps, emptyOutput](3),
+		WithTools[testDeps, emptyOutput](greetTool),
+		WithToolCallIDGenerator[testDeps, emptyOutput](func() string {
+			generated++
+			return fmt.Sprintf("gen-%d", generated)
+		}),
 	)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	_, err = agent.Run(context.Background(), testDeps{}, WithPrompt("test"))
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("Greet everyone"))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if callCount != 2 {
-		t.Errorf("expected tool to be called 2 times, got %d", callCount)
+	if generated != 2 {
+		t.Errorf("expected generator to be called 2 times, got %d", generated)
 	}
-}
 
-func TestAgent_Run_ModelRetry_ExceedsLimit(t *testing.T) {
-	raw, client := newTestClient()
+	calls := result.Messages[1].ToolCalls
+	wantIDs := []string{"gen-1", "call-2", "gen-2"}
+	for i, want := range wantIDs {
+		if calls[i].ID != want {
+			t.Errorf("tool call %d: expected ID %q, got %q", i, want, calls[i].ID)
+		}
+		toolMsg := result.Messages[2+i]
+		if toolMsg.ToolCallID == nil || *toolMsg.ToolCallID != want {
+			t.Errorf("tool result %d: expected ToolCallID %q, got %v", i, want, toolMsg.ToolCallID)
+		}
+	}
+}
 
-	// Queue enough responses for retries
-	for i := 0; i < 5; i++ {
-		raw.queueResponse(toolCallResponse(
-			makeToolCall(fmt.Sprintf("call-%d", i), "always_fails", map[string]any{"name": "test"}),
-		), nil)
+func TestDefaultToolCallIDGenerator(t *testing.T) {
+	gen := DefaultToolCallIDGenerator()
+	a, b := gen(), gen()
+	if a == "" || a == b {
+		t.Errorf("expected unique non-empty IDs, got %q and %q", a, b)
 	}
+}
 
-	alwaysFailsTool, _ := NewTool[testDeps, testInput, testOutput](
-		"always_fails", "Always fails",
-		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
-			return testOutput{}, NewModelRetry("Always fails")
-		},
-	)
+func TestAgent_Run_ParallelToolAggregator(t *testing.T) {
+	raw, client := newTestClient()
+
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call-1", "greet", map[string]any{"name": "Alice"}),
+		makeToolCall("call-2", "greet", map[string]any{"name": "Bob"}),
+	), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+
+	var received []string
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hello, ")),
+		WithParallelToolAggregator[testDeps, emptyOutput](func(results []types.ToolResult) *types.ToolResult {
+			texts := make([]string, len(results))
+			for i, r := range results {
+				texts[i] = r.ContentPart[0].(*types.ContentPartText).Text
+			}
+			received = texts
+			return types.NewToolResult(types.WithToolText(strings.Join(texts, "\n")))
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("Greet both"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{`{"result":"Hello, Alice"}`, `{"result":"Hello, Bob"}`}
+	if len(received) != 2 || received[0] != want[0] || received[1] != want[1] {
+		t.Errorf("expected aggregator to receive results in call order, got %v", received)
+	}
+
+	// user, assistant(tool calls), aggregated tool message, stub for call-2, assistant
+	if len(result.Messages) != 5 {
+		t.Fatalf("expected 5 messages, got %d", len(result.Messages))
+	}
+	toolMsg := result.Messages[2]
+	if toolMsg.Role != types.RoleTool || *toolMsg.ToolCallID != "call-1" {
+		t.Errorf("expected aggregated tool message for call-1, got %+v", toolMsg)
+	}
+	if got := toolMsg.TextContent(); got != strings.Join(want, "\n") {
+		t.Errorf("unexpected aggregated content: %q", got)
+	}
+
+	// Every tool call must be answered for the transcript to be valid
+	answered := map[string]string{}
+	for _, m := range raw.chatParams[1].Messages {
+		if m.Role == types.RoleTool {
+			answered[*m.ToolCallID] = m.TextContent()
+		}
+	}
+	for _, tc := range result.Messages[1].ToolCalls {
+		if _, ok := answered[tc.ID]; !ok {
+			t.Errorf("expected a tool message for %s", tc.ID)
+		}
+	}
+	if got := answered["call-2"]; got != "Combined into the result of call-1." {
+		t.Errorf("expected stub result for call-2, got %q", got)
+	}
+}
+
+// newGreetTool creates a tool that answers with a fixed greeting prefix
+func newGreetTool(name, prefix string) *Tool[testDeps] {
+	tool, _ := NewTool[testDeps, testInput, testOutput](
+		name, "Greets a person",
+		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
+			return testOutput{Result: prefix + in.Name}, nil
+		},
+	)
+	return tool
+}
+
+func toolNames(defs []types.ToolDefinition) []string {
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		names[i] = def.Name
+	}
+	return names
+}
+
+func TestAgent_Run_WithRunTools(t *testing.T) {
+	t.Run("run tool overrides agent tool", func(t *testing.T) {
+		raw, client := newTestClient()
+		raw.queueResponse(toolCallResponse(
+			makeToolCall("call-1", "greet", map[string]any{"name": "Alice"}),
+		), nil)
+		raw.queueResponse(textResponse("Done"), nil)
+
+		agent, _ := New[testDeps, emptyOutput](client,
+			WithTools[testDeps, emptyOutput](newGreetTool("greet", "agent: "), newGreetTool("farewell", "bye ")),
+		)
+
+		result, err := agent.Run(context.Background(), testDeps{},
+			WithPrompt("Greet Alice"),
+			WithRunTools(newGreetTool("greet", "run: ")),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := result.Messages[2].TextContent(); got != `{"result":"run: Alice"}` {
+			t.Errorf("expected run tool to handle the call, got %s", got)
+		}
+		names := toolNames(raw.chatParams[0].Tools)
+		if fmt.Sprint(names) != "[farewell greet]" {
+			t.Errorf("expected merged tools [farewell greet], got %v", names)
+		}
+	})
+
+	t.Run("agent tools remain available", func(t *testing.T) {
+		raw, client := newTestClient()
+		raw.queueResponse(toolCallResponse(
+			makeToolCall("call-1", "greet", map[string]any{"name": "Bob"}),
+		), nil)
+		raw.queueResponse(textResponse("Done"), nil)
+
+		agent, _ := New[testDeps, emptyOutput](client,
+			WithTools[testDeps, emptyOutput](newGreetTool("greet", "agent: ")),
+		)
+
+		result, err := agent.Run(context.Background(), testDeps{},
+			WithPrompt("Greet Bob"),
+			WithRunTools(newGreetTool("lookup", "found ")),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.Messages[2].TextContent(); got != `{"result":"agent: Bob"}` {
+			t.Errorf("expected agent tool to handle the call, got %s", got)
+		}
+	})
+
+	t.Run("run tools are isolated between runs", func(t *testing.T) {
+		raw, client := newTestClient()
+		raw.queueResponse(textResponse("first"), nil)
+		raw.queueResponse(toolCallResponse(
+			makeToolCall("call-1", "lookup", map[string]any{"name": "x"}),
+		), nil)
+
+		agent, _ := New[testDeps, emptyOutput](client,
+			WithTools[testDeps, emptyOutput](newGreetTool("greet", "agent: ")),
+		)
+
+		if _, err := agent.Run(context.Background(), testDeps{},
+			WithPrompt("first"),
+			WithRunTools(newGreetTool("lookup", "found ")),
+		); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, err := agent.Run(context.Background(), testDeps{}, WithPrompt("second"))
+		if err == nil || err.Error() != "unknown tool: lookup" {
+			t.Fatalf("expected run tool to be unavailable in a later run, got %v", err)
+		}
+		if names := toolNames(raw.chatParams[1].Tools); fmt.Sprint(names) != "[greet]" {
+			t.Errorf("expected only agent tools in second run, got %v", names)
+		}
+	})
+}
+
+func TestAgent_Run_WithRunToolsOnly(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call-1", "greet", map[string]any{"name": "Alice"}),
+	), nil)
+
+	agent, _ := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "agent: ")),
+	)
+
+	_, err := agent.Run(context.Background(), testDeps{},
+		WithPrompt("Greet Alice"),
+		WithRunToolsOnly(newGreetTool("lookup", "found ")),
+	)
+	if err == nil || err.Error() != "unknown tool: greet" {
+		t.Fatalf("expected agent tool to be unavailable, got %v", err)
+	}
+	if names := toolNames(raw.chatParams[0].Tools); fmt.Sprint(names) != "[lookup]" {
+		t.Errorf("expected only run tools, got %v", names)
+	}
+}
+
+func TestAgent_Run_WithRunTools_WrongDepType(t *testing.T) {
+	_, client := newTestClient()
+	agent, _ := New[testDeps, emptyOutput](client)
+
+	otherTool := &Tool[string]{ToolDefinition: types.ToolDefinition{Name: "other"}}
+	if _, err := agent.Run(context.Background(), testDeps{}, WithRunTools(otherTool)); err == nil {
+		t.Fatal("expected error for mismatched dependency type")
+	}
+}
+
+// =============================================================================
+// Tool Retry Tests
+// =============================================================================
+
+func TestAgent_Run_ModelRetry(t *testing.T) {
+	raw, client := newTestClient()
+
+	// First call: tool call
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call-1", "flaky_tool", map[string]any{"name": "test"}),
+	), nil)
+
+	// Second call (after retry): tool call again
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call-2", "flaky_tool", map[string]any{"name": "test"}),
+	), nil)
+
+	// Third call: success
+	raw.queueResponse(textResponse("Done"), nil)
+
+	callCount := 0
+	flakyTool, _ := NewTool[testDeps, testInput, testOutput](
+		"flaky_tool", "Fails first time",
+		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
+			callCount++
+			if callCount == 1 {
+				return testOutput{}, NewModelRetry("First attempt failed, try again")
+			}
+			return testOutput{Result: "success"}, nil
+		},
+	)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](flakyTool),
+		WithRetries[testDeps, emptyOutput](3),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = agent.Run(context.Background(), testDeps{}, WithPrompt("test"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if callCount != 2 {
+		t.Errorf("expected tool to be called 2 times, got %d", callCount)
+	}
+}
+
+func TestAgent_Run_ModelRetry_ExceedsLimit(t *testing.T) {
+	raw, client := newTestClient()
+
+	// Queue enough responses for retries
+	for i := 0; i < 5; i++ {
+		raw.queueResponse(toolCallResponse(
+			makeToolCall(fmt.Sprintf("call-%d", i), "always_fails", map[string]any{"name": "test"}),
+		), nil)
+	}
+
+	alwaysFailsTool, _ := NewTool[testDeps, testInput, testOutput](
+		"always_fails", "Always fails",
+		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
+			return testOutput{}, NewModelRetry("Always fails")
+		},
+	)
 
 	agent, err := New[testDeps, emptyOutput](client,
 		WithTools[testDeps, emptyOutput](alwaysFailsTool),
@@ -1138,105 +1826,440 @@ func TestAgent_Run_OutputRetries_FallsBackToRetries(t *testing.T) {
 	}
 }
 
-// =============================================================================
-// Usage Limits Tests
-// =============================================================================
-
-func TestAgent_Run_UsageLimits_RequestLimit(t *testing.T) {
+func TestAgent_Run_OutputRetryMessageBuilder(t *testing.T) {
 	raw, client := newTestClient()
 
-	// Queue tool calls that will exceed request limit
-	for i := 0; i < 5; i++ {
-		raw.queueResponse(toolCallResponse(
-			makeToolCall(fmt.Sprintf("call-%d", i), "echo_tool", map[string]any{"name": "test"}),
-		), nil)
+	schemaErr := &types.SchemaValidationError{
+		RawResponse: "invalid",
+		Err:         errors.New("schema mismatch"),
 	}
+	raw.queueResponse(nil, schemaErr)
+	raw.queueResponse(structuredResponse(`{"result":"success"}`), nil)
 
-	echoTool, _ := NewTool[testDeps, testInput, testOutput](
-		"echo_tool", "Echoes input",
-		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
-			return testOutput{Result: in.Name}, nil
-		},
-	)
+	var gotAttempt int
+	var gotErr error
+	var gotSchema map[string]any
 
-	agent, err := New[testDeps, emptyOutput](client,
-		WithTools[testDeps, emptyOutput](echoTool),
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithOutputRetries[testDeps, testOutput](2),
+		WithOutputRetryMessageBuilder[testDeps, testOutput](func(attempt int, err error, schema map[string]any) string {
+			gotAttempt = attempt
+			gotErr = err
+			gotSchema = schema
+			return "custom feedback"
+		}),
 	)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	_, err = agent.Run(context.Background(), testDeps{},
-		WithPrompt("test"),
-		WithUsageLimits(UsageLimits{
-			RequestLimit: 3,
-		}),
-	)
-	if err == nil {
-		t.Fatal("expected error for exceeded request limit")
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("test")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	var limitErr *UsageLimitExceeded
-	if !errors.As(err, &limitErr) {
-		t.Fatalf("expected UsageLimitExceeded error, got %T: %v", err, err)
+	if gotAttempt != 1 {
+		t.Errorf("expected attempt 1, got %d", gotAttempt)
 	}
-	if limitErr.Limit != "request_limit" {
-		t.Errorf("expected limit 'request_limit', got %q", limitErr.Limit)
+	if !errors.Is(gotErr, schemaErr) {
+		t.Errorf("expected schema validation error, got %v", gotErr)
 	}
-	if limitErr.Max != 3 {
-		t.Errorf("expected max 3, got %d", limitErr.Max)
+	if _, ok := gotSchema["properties"].(map[string]any)["result"]; !ok {
+		t.Errorf("expected output schema to be passed, got %v", gotSchema)
+	}
+
+	// The retry request should carry the custom feedback as the last message
+	msgs := raw.chatParams[1].Messages
+	if got := msgs[len(msgs)-1].TextContent(); got != "custom feedback" {
+		t.Errorf("expected custom feedback to reach the client, got %q", got)
 	}
 }
 
-func TestAgent_Run_UsageLimits_CompletionTokensLimit(t *testing.T) {
+func TestAgent_Run_OutputRetryPrompt(t *testing.T) {
 	raw, client := newTestClient()
 
-	// Response with high completion tokens
-	raw.queueResponse(responseWithUsage("response", 10, 500, 510), nil)
+	schemaErr := &types.SchemaValidationError{
+		RawResponse: "invalid",
+		Err:         errors.New("schema mismatch"),
+		Fields:      []string{"result"},
+	}
+	raw.queueResponse(nil, schemaErr)
+	raw.queueResponse(structuredResponse(`{"result":"success"}`), nil)
 
-	agent, err := New[testDeps, emptyOutput](client)
+	var gotAttempt int
+	var gotExcerpt map[string]any
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithOutputRetries[testDeps, testOutput](2),
+		WithOutputRetryMessageBuilder[testDeps, testOutput](func(int, error, map[string]any) string {
+			t.Error("expected the retry prompt to take precedence over the message builder")
+			return ""
+		}),
+		WithOutputRetryPrompt[testDeps, testOutput](func(err error, attempt int) types.Message {
+			gotAttempt = attempt
+			var retryErr *OutputRetryError
+			if !errors.As(err, &retryErr) || !errors.Is(err, schemaErr) {
+				t.Errorf("expected an *OutputRetryError wrapping the validation error, got %v", err)
+				return types.NewUserMessage()
+			}
+			gotExcerpt = retryErr.FailedSchema()
+			return types.NewUserMessage(types.WithText("Réessayez : " + err.Error()))
+		}),
+	)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	_, err = agent.Run(context.Background(), testDeps{},
-		WithPrompt("test"),
-		WithUsageLimits(UsageLimits{
-			CompletionTokensLimit: 100,
-		}),
-	)
-	if err == nil {
-		t.Fatal("expected error for exceeded completion tokens limit")
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("test")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	var limitErr *UsageLimitExceeded
-	if !errors.As(err, &limitErr) {
-		t.Fatalf("expected UsageLimitExceeded error, got %T: %v", err, err)
+	if gotAttempt != 1 {
+		t.Errorf("expected attempt 1, got %d", gotAttempt)
 	}
-	if limitErr.Limit != "completion_tokens_limit" {
-		t.Errorf("expected limit 'completion_tokens_limit', got %q", limitErr.Limit)
+	if field, ok := gotExcerpt["result"].(map[string]any); !ok || field["type"] != "string" {
+		t.Errorf("expected the schema of the failing field, got %v", gotExcerpt)
 	}
-}
-
-func TestAgent_Run_UsageLimits_ToolCallsLimit(t *testing.T) {
-	raw, client := newTestClient()
 
-	// Queue tool calls
-	for i := 0; i < 5; i++ {
-		raw.queueResponse(toolCallResponse(
-			makeToolCall(fmt.Sprintf("call-%d", i), "counted_tool", map[string]any{"name": "test"}),
-		), nil)
+	msgs := raw.chatParams[1].Messages
+	last := msgs[len(msgs)-1]
+	if last.TextContent() != "Réessayez : validation failed: schema mismatch" {
+		t.Errorf("expected the custom feedback message to reach the client, got %+v", last)
 	}
+}
 
-	countedTool, _ := NewTool[testDeps, testInput, testOutput](
-		"counted_tool", "Counted tool",
-		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
-			return testOutput{Result: in.Name}, nil
-		},
-	)
+func TestAgent_Run_RetryFeedbackMetadata(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(structuredResponse(`not json`), nil)
+	raw.queueResponse(structuredResponse(`{"result":"success"}`), nil)
 
-	agent, err := New[testDeps, emptyOutput](client,
-		WithTools[testDeps, emptyOutput](countedTool),
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithOutputRetries[testDeps, testOutput](1),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("test"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	feedback := result.Messages[1]
+	if feedback.Role != types.RoleUser || feedback.Metadata[MetadataRetryFeedback] != true {
+		t.Errorf("expected the feedback message to be marked, got %+v", feedback)
+	}
+	if sent := raw.chatParams[1].Messages[1]; sent.Metadata != nil {
+		t.Errorf("expected metadata to be stripped before the request, got %v", sent.Metadata)
+	}
+}
+
+func TestAgent_Run_OutputRetryBackoff(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(structuredResponse(`not json`), nil)
+	raw.queueResponse(textResponse("no output"), nil)
+	raw.queueResponse(structuredResponse(`{"result":"success"}`), nil)
+
+	var attempts []int
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithOutputRetries[testDeps, testOutput](3),
+		WithOutputRetryBackoff[testDeps, testOutput](func(attempt int) time.Duration {
+			attempts = append(attempts, attempt)
+			return time.Millisecond
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("test")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(attempts) != "[1 2]" {
+		t.Errorf("expected a backoff before each retry, got %v", attempts)
+	}
+
+	// The backoff is cut short when the run is cancelled
+	raw.queueResponse(structuredResponse(`not json`), nil)
+	slow, _ := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithOutputRetries[testDeps, testOutput](1),
+		WithOutputRetryBackoff[testDeps, testOutput](func(int) time.Duration { return time.Hour }),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := slow.Run(ctx, testDeps{}, WithPrompt("test")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to interrupt the backoff, got %v", err)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second, 0)
+	var got []time.Duration
+	for attempt := 1; attempt <= 6; attempt++ {
+		got = append(got, backoff(attempt))
+	}
+	if fmt.Sprint(got) != "[100ms 200ms 400ms 800ms 1s 1s]" {
+		t.Errorf("unexpected delays: %v", got)
+	}
+
+	jittered := ExponentialBackoff(100*time.Millisecond, time.Second, 0.5)
+	for attempt := 1; attempt <= 3; attempt++ {
+		full := backoff(attempt)
+		if d := jittered(attempt); d < full/2 || d > full {
+			t.Errorf("attempt %d: jittered delay %v outside [%v, %v]", attempt, d, full/2, full)
+		}
+	}
+}
+
+// transientTestError is a provider error that may succeed on retry
+type transientTestError struct {
+	retryAfter time.Duration
+}
+
+func (e *transientTestError) Error() string             { return "rate limited" }
+func (e *transientTestError) Transient() bool           { return true }
+func (e *transientTestError) RetryAfter() time.Duration { return e.retryAfter }
+
+func TestAgent_Run_TransientRetry(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(nil, &transientTestError{})
+	raw.queueResponse(nil, fmt.Errorf("wrapped: %w", &transientTestError{retryAfter: 5 * time.Millisecond}))
+	raw.queueResponse(structuredResponse(`not json`), nil)
+	raw.queueResponse(structuredResponse(`{"result":"success"}`), nil)
+
+	var delays []time.Duration
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithOutputRetries[testDeps, testOutput](1),
+		WithTransientRetryPolicy[testDeps, testOutput](TransientRetryPolicy{
+			MaxRetries: 2,
+			Backoff:    func(attempt int) time.Duration { return time.Millisecond },
+			IsTransient: func(err error) (bool, time.Duration) {
+				transient, retryAfter := types.IsTransient(err)
+				delays = append(delays, retryAfter)
+				return transient, retryAfter
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Transient retries use neither the single output retry nor the request limit
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("test"), WithUsageLimits(UsageLimits{RequestLimit: 2}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Output.Result != "success" {
+		t.Errorf("unexpected output: %+v", result.Output)
+	}
+	if fmt.Sprint(delays) != "[0s 5ms]" {
+		t.Errorf("expected Retry-After to be read from wrapped errors, got %v", delays)
+	}
+	if raw.chatCalls != 4 {
+		t.Errorf("expected 4 chat calls, got %d", raw.chatCalls)
+	}
+
+	// Retries stop at MaxRetries
+	for range 3 {
+		raw.queueResponse(nil, &transientTestError{})
+	}
+	var transient *transientTestError
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("test")); !errors.As(err, &transient) {
+		t.Errorf("expected the transient error after exhausting retries, got %v", err)
+	}
+
+	// Other errors and disabled policies fail immediately
+	calls := raw.chatCalls
+	raw.queueResponse(nil, errors.New("bad request"))
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("test")); err == nil || raw.chatCalls != calls+1 {
+		t.Errorf("expected a single call for a permanent error, got %d calls: %v", raw.chatCalls-calls, err)
+	}
+	noRetry, _ := New[testDeps, string](client, WithTransientRetryPolicy[testDeps, string](TransientRetryPolicy{}))
+	raw.queueResponse(nil, &transientTestError{})
+	if _, err := noRetry.Run(context.Background(), testDeps{}, WithPrompt("test")); !errors.As(err, &transient) {
+		t.Errorf("expected no retry with an empty policy, got %v", err)
+	}
+
+	if _, err := New[testDeps, string](client, WithTransientRetryPolicy[testDeps, string](TransientRetryPolicy{MaxRetries: -1})); err == nil {
+		t.Error("expected negative max retries to be rejected")
+	}
+}
+
+func TestDefaultOutputRetryMessage(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"result": map[string]any{"type": "string"},
+			"score":  map[string]any{"type": "integer"},
+		},
+		"required": []any{"result"},
+	}
+	err := &types.SchemaValidationError{
+		Err:    errors.New("validating root: required: missing properties: [\"result\"]"),
+		Fields: []string{"result"},
+	}
+
+	msg := DefaultOutputRetryMessage(2, err, schema)
+
+	for _, want := range []string{
+		"attempt 2",
+		"missing properties",
+		"Fields with errors: result",
+		"result (string, required)",
+		"score (integer)",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected message to contain %q, got %q", want, msg)
+		}
+	}
+
+	if strings.Contains(DefaultOutputRetryMessage(1, ErrNoStructuredOutput, nil), "Expected output") {
+		t.Error("expected no schema summary without a schema")
+	}
+}
+
+// =============================================================================
+// Usage Limits Tests
+// =============================================================================
+
+func TestAgent_Run_UsageLimits_RequestLimit(t *testing.T) {
+	raw, client := newTestClient()
+
+	// Queue tool calls that will exceed request limit
+	for i := 0; i < 5; i++ {
+		raw.queueResponse(toolCallResponse(
+			makeToolCall(fmt.Sprintf("call-%d", i), "echo_tool", map[string]any{"name": "test"}),
+		), nil)
+	}
+
+	echoTool, _ := NewTool[testDeps, testInput, testOutput](
+		"echo_tool", "Echoes input",
+		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
+			return testOutput{Result: in.Name}, nil
+		},
+	)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](echoTool),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = agent.Run(context.Background(), testDeps{},
+		WithPrompt("test"),
+		WithUsageLimits(UsageLimits{
+			RequestLimit: 3,
+		}),
+	)
+	if err == nil {
+		t.Fatal("expected error for exceeded request limit")
+	}
+
+	var limitErr *UsageLimitExceeded
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected UsageLimitExceeded error, got %T: %v", err, err)
+	}
+	if limitErr.Limit != "request_limit" {
+		t.Errorf("expected limit 'request_limit', got %q", limitErr.Limit)
+	}
+	if limitErr.Max != 3 {
+		t.Errorf("expected max 3, got %d", limitErr.Max)
+	}
+}
+
+func TestAgent_Run_UsageLimits_CompletionTokensLimit(t *testing.T) {
+	raw, client := newTestClient()
+
+	// Response with high completion tokens
+	raw.queueResponse(responseWithUsage("response", 10, 500, 510), nil)
+
+	agent, err := New[testDeps, emptyOutput](client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = agent.Run(context.Background(), testDeps{},
+		WithPrompt("test"),
+		WithUsageLimits(UsageLimits{
+			CompletionTokensLimit: 100,
+		}),
+	)
+	if err == nil {
+		t.Fatal("expected error for exceeded completion tokens limit")
+	}
+
+	var limitErr *UsageLimitExceeded
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected UsageLimitExceeded error, got %T: %v", err, err)
+	}
+	if limitErr.Limit != "completion_tokens_limit" {
+		t.Errorf("expected limit 'completion_tokens_limit', got %q", limitErr.Limit)
+	}
+}
+
+func TestAgent_Run_UsageLimits_CumulativeTokenLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		limits    UsageLimits
+		wantLimit string
+		wantValue int
+	}{
+		{"prompt tokens", UsageLimits{PromptTokensLimit: 25}, "prompt_tokens_limit", 30},
+		{"total tokens", UsageLimits{TotalTokensLimit: 40}, "total_tokens_limit", 45},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, client := newTestClient()
+			// Each response uses 10 prompt and 15 total tokens, no single one exceeds the limits
+			for i := 0; i < 5; i++ {
+				raw.queueResponse(toolCallResponse(makeToolCall(fmt.Sprintf("call-%d", i), "greet", map[string]any{"name": "Ada"})), nil)
+			}
+
+			agent, err := New[testDeps, emptyOutput](client, WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = agent.Run(context.Background(), testDeps{}, WithPrompt("test"), WithUsageLimits(tt.limits))
+
+			var limitErr *UsageLimitExceeded
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected UsageLimitExceeded error, got %T: %v", err, err)
+			}
+			if limitErr.Limit != tt.wantLimit || limitErr.Value != tt.wantValue {
+				t.Errorf("expected %s at %d, got %s at %d", tt.wantLimit, tt.wantValue, limitErr.Limit, limitErr.Value)
+			}
+			if raw.chatCalls != 3 {
+				t.Errorf("expected the run to stop after 3 requests, got %d", raw.chatCalls)
+			}
+		})
+	}
+}
+
+func TestAgent_Run_UsageLimits_ToolCallsLimit(t *testing.T) {
+	raw, client := newTestClient()
+
+	// Queue tool calls
+	for i := 0; i < 5; i++ {
+		raw.queueResponse(toolCallResponse(
+			makeToolCall(fmt.Sprintf("call-%d", i), "counted_tool", map[string]any{"name": "test"}),
+		), nil)
+	}
+
+	countedTool, _ := NewTool[testDeps, testInput, testOutput](
+		"counted_tool", "Counted tool",
+		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
+			return testOutput{Result: in.Name}, nil
+		},
+	)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](countedTool),
 	)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -1354,6 +2377,78 @@ func TestAgent_Run_MaxIterations(t *testing.T) {
 	}
 }
 
+func TestAgent_Run_ExhaustionPolicy(t *testing.T) {
+	loop := func(raw *mockRawClient) {
+		for i := 0; i < 10; i++ {
+			raw.queueResponse(toolCallResponse(makeToolCall(fmt.Sprintf("call-%d", i), "greet", map[string]any{"name": "Ada"})), nil)
+		}
+	}
+	newAgent := func(t *testing.T, client types.Client, policy ExhaustionPolicy) *Agent[testDeps, emptyOutput] {
+		t.Helper()
+		agent, err := New[testDeps, emptyOutput](client,
+			WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")),
+			WithExhaustionPolicy[testDeps, emptyOutput](policy),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return agent
+	}
+
+	t.Run("return partial", func(t *testing.T) {
+		raw, client := newTestClient()
+		loop(raw)
+
+		result, err := newAgent(t, client, ExhaustionReturnPartial).Run(context.Background(), testDeps{}, WithPrompt("test"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.Incomplete || len(result.Messages) != 21 || result.Usage.TotalTokens != 150 {
+			t.Errorf("expected the partial run, got incomplete %v with %d messages", result.Incomplete, len(result.Messages))
+		}
+	})
+
+	t.Run("force final", func(t *testing.T) {
+		raw, client := newTestClient()
+		loop(raw)
+		raw.queueResponse(textResponse("Ada was greeted"), nil)
+
+		result, err := newAgent(t, client, ExhaustionForceFinal).Run(context.Background(), testDeps{}, WithPrompt("test"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		last := raw.chatParams[len(raw.chatParams)-1]
+		if len(last.Tools) != 0 || last.ToolChoice == nil || last.ToolChoice.Mode != types.ToolChoiceModeNone {
+			t.Errorf("expected the final request without tools, got %d tools", len(last.Tools))
+		}
+		if got := last.Messages[len(last.Messages)-1].TextContent(); got != WrapUpPrompt {
+			t.Errorf("expected the wrap-up prompt, got %q", got)
+		}
+		if result.Incomplete || result.Messages[len(result.Messages)-1].TextContent() != "Ada was greeted" {
+			t.Errorf("expected the final answer, got %+v", result.Messages[len(result.Messages)-1])
+		}
+	})
+
+	t.Run("force final ignored", func(t *testing.T) {
+		raw, client := newTestClient()
+		loop(raw)
+		raw.queueResponse(toolCallResponse(makeToolCall("call-10", "greet", map[string]any{"name": "Ada"})), nil)
+
+		_, err := newAgent(t, client, ExhaustionForceFinal).Run(context.Background(), testDeps{}, WithPrompt("test"))
+		if !errors.Is(err, ErrMaxIterations) {
+			t.Errorf("expected ErrMaxIterations, got %v", err)
+		}
+		if raw.chatCalls != 11 {
+			t.Errorf("expected 11 requests, got %d", raw.chatCalls)
+		}
+	})
+
+	if _, err := New[testDeps, emptyOutput](nil, WithExhaustionPolicy[testDeps, emptyOutput]("retry")); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
 // =============================================================================
 // Usage Tracking Tests
 // =============================================================================
@@ -1391,11 +2486,14 @@ func TestAgent_Run_UsageTracking_Accumulated(t *testing.T) {
 	// First response: tool call with usage
 	resp1 := toolCallResponse(makeToolCall("call-1", "echo", map[string]any{"name": "test"}))
 	resp1.Usage = &types.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150}
+	resp1.Usage.PromptTokensDetails.CachedTokens = 40
 	raw.queueResponse(resp1, nil)
 
 	// Second response: final with more usage
 	resp2 := textResponse("Done")
 	resp2.Usage = &types.Usage{PromptTokens: 150, CompletionTokens: 30, TotalTokens: 180}
+	resp2.Usage.PromptTokensDetails.CachedTokens = 100
+	resp2.Usage.CompletionTokensDetails.ReasoningTokens = 12
 	raw.queueResponse(resp2, nil)
 
 	echoTool, _ := NewTool[testDeps, testInput, testOutput](
@@ -1427,6 +2525,9 @@ func TestAgent_Run_UsageTracking_Accumulated(t *testing.T) {
 	if result.Usage.TotalTokens != 330 {
 		t.Errorf("expected 330 total tokens, got %d", result.Usage.TotalTokens)
 	}
+	if result.Usage.PromptTokensDetails.CachedTokens != 140 || result.Usage.CompletionTokensDetails.ReasoningTokens != 12 {
+		t.Errorf("expected detail fields to accumulate, got %+v", result.Usage)
+	}
 }
 
 // =============================================================================
@@ -1635,20 +2736,206 @@ func TestWrapTool_WithRetries(t *testing.T) {
 	}
 }
 
-// =============================================================================
-// Tool Reset After Success Tests
-// =============================================================================
+func TestWrapTool_PreservesMetadata(t *testing.T) {
+	typesTool, _ := types.NewTool[testInput, testOutput](
+		"wrapped_tool", "A wrapped tool",
+		func(ctx context.Context, in testInput) (testOutput, error) {
+			return testOutput{}, nil
+		},
+	)
+	typesTool.Metadata = map[string]any{"readOnlyHint": true}
 
-func TestAgent_Run_ToolRetryResetAfterSuccess(t *testing.T) {
-	raw, client := newTestClient()
+	wrappedTool := WrapTool[testDeps](typesTool,
+		ToolWithMetadata[testDeps](map[string]any{"owner": "billing"}),
+	)
 
-	// First call: tool fails
-	raw.queueResponse(toolCallResponse(
-		makeToolCall("call-1", "resetable_tool", map[string]any{"name": "test"}),
-	), nil)
+	if wrappedTool.Metadata["readOnlyHint"] != true {
+		t.Errorf("expected readOnlyHint=true, got %v", wrappedTool.Metadata["readOnlyHint"])
+	}
+	if wrappedTool.Metadata["owner"] != "billing" {
+		t.Errorf("expected owner=billing, got %v", wrappedTool.Metadata["owner"])
+	}
+	if _, ok := typesTool.Metadata["owner"]; ok {
+		t.Error("expected original tool metadata to be unchanged")
+	}
+}
 
-	// Second call: tool succeeds
-	raw.queueResponse(toolCallResponse(
+func TestNewTool_WithMetadata(t *testing.T) {
+	tool, err := NewTool[testDeps, testInput, testOutput](
+		"meta_tool", "A tool with metadata",
+		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
+			return testOutput{}, nil
+		},
+		ToolWithMetadata[testDeps](map[string]any{"destructiveHint": false}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defs := GetToolDefinitions([]*Tool[testDeps]{tool})
+	if defs[0].Metadata["destructiveHint"] != false {
+		t.Errorf("expected destructiveHint=false in definition, got %v", defs[0].Metadata["destructiveHint"])
+	}
+}
+
+// =============================================================================
+// Tool Middleware Tests
+// =============================================================================
+
+// newErrorTool wraps a types.Tool that always returns an error result with the given text
+func newErrorTool(text string, opts ...ToolOption[testDeps]) *Tool[testDeps] {
+	return WrapTool[testDeps](&types.Tool{
+		ToolDefinition: types.ToolDefinition{Name: "mcp_tool", InputSchema: map[string]any{"type": "object"}},
+		Execute: func(ctx context.Context, args map[string]any) (*types.ToolResult, error) {
+			return types.ToolResultFromError(errors.New(text)), nil
+		},
+	}, opts...)
+}
+
+func TestMCPErrorClassifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		patterns  []string
+		wantRetry bool
+	}{
+		{name: "default rate limit", text: "Rate limit exceeded, slow down", wantRetry: true},
+		{name: "default unavailable", text: "Service temporarily unavailable", wantRetry: true},
+		{name: "default unmatched", text: "record not found", wantRetry: false},
+		{name: "custom pattern", text: "ERR_BUSY: try later", patterns: []string{"err_busy"}, wantRetry: true},
+		{name: "custom unmatched", text: "rate limit", patterns: []string{"err_busy"}, wantRetry: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := newErrorTool(tt.text, ToolWithMiddleware(MCPErrorClassifier[testDeps](tt.patterns)))
+
+			result, err := tool.Execute(context.Background(), &RunContext[testDeps]{}, map[string]any{})
+			mr, isRetry := IsModelRetry(err)
+			if isRetry != tt.wantRetry {
+				t.Fatalf("expected retry=%v, got result=%+v err=%v", tt.wantRetry, result, err)
+			}
+			if tt.wantRetry {
+				if mr.Message != tt.text {
+					t.Errorf("expected retry message %q, got %q", tt.text, mr.Message)
+				}
+				return
+			}
+			if result == nil || !result.IsError {
+				t.Errorf("expected error result to pass through, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestMCPErrorClassifier_PassesThroughSuccess(t *testing.T) {
+	tool := WrapTool[testDeps](&types.Tool{
+		ToolDefinition: types.ToolDefinition{Name: "ok_tool"},
+		Execute: func(ctx context.Context, args map[string]any) (*types.ToolResult, error) {
+			return types.NewToolResult(types.WithToolText("rate limit is 10/s")), nil
+		},
+	}, ToolWithMiddleware(MCPErrorClassifier[testDeps](nil)))
+
+	result, err := tool.Execute(context.Background(), &RunContext[testDeps]{}, map[string]any{})
+	if err != nil || result == nil || result.IsError {
+		t.Fatalf("expected successful result to pass through, got %+v, %v", result, err)
+	}
+}
+
+func TestToolWithMiddleware_Order(t *testing.T) {
+	var order []string
+	mark := func(name string) ToolMiddleware[testDeps] {
+		return func(next ToolFunc[testDeps]) ToolFunc[testDeps] {
+			return func(ctx context.Context, rc *RunContext[testDeps], args map[string]any) (*types.ToolResult, error) {
+				order = append(order, name)
+				return next(ctx, rc, args)
+			}
+		}
+	}
+
+	tool := newErrorTool("x", ToolWithMiddleware(mark("outer"), mark("inner")))
+	_, _ = tool.Execute(context.Background(), &RunContext[testDeps]{}, map[string]any{})
+
+	if fmt.Sprint(order) != "[outer inner]" {
+		t.Errorf("expected [outer inner], got %v", order)
+	}
+}
+
+// traceMiddleware records the model requests and tool executions it wraps
+type traceMiddleware struct {
+	name  string
+	trace *[]string
+}
+
+func (m traceMiddleware) WrapModel(next ModelFunc[testDeps]) ModelFunc[testDeps] {
+	return func(ctx context.Context, rc *RunContext[testDeps], params *types.ChatParams) (*types.ChatResponse, error) {
+		*m.trace = append(*m.trace, m.name+":model")
+		return next(ctx, rc, params)
+	}
+}
+
+func (m traceMiddleware) WrapTool(tool *Tool[testDeps], next ToolFunc[testDeps]) ToolFunc[testDeps] {
+	return func(ctx context.Context, rc *RunContext[testDeps], args map[string]any) (*types.ToolResult, error) {
+		*m.trace = append(*m.trace, m.name+":"+tool.Name)
+		return next(ctx, rc, args)
+	}
+}
+
+func TestAgent_Run_WithMiddleware(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+
+	var trace []string
+	cached := MiddlewareFuncs[testDeps]{
+		// Answers the second request without calling the model
+		Model: func(next ModelFunc[testDeps]) ModelFunc[testDeps] {
+			return func(ctx context.Context, rc *RunContext[testDeps], params *types.ChatParams) (*types.ChatResponse, error) {
+				if len(params.Messages) > 1 {
+					return textResponse("cached"), nil
+				}
+				return next(ctx, rc, params)
+			}
+		},
+	}
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")),
+		WithMiddleware[testDeps, emptyOutput](traceMiddleware{"outer", &trace}, traceMiddleware{"inner", &trace}, cached),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := strings.Join(trace, " "); got != "outer:model inner:model outer:greet inner:greet outer:model inner:model" {
+		t.Errorf("unexpected trace: %s", got)
+	}
+	if raw.chatCalls != 1 {
+		t.Errorf("expected 1 model call, got %d", raw.chatCalls)
+	}
+	if got := result.Messages[len(result.Messages)-1].TextContent(); got != "cached" {
+		t.Errorf("expected cached answer, got %q", got)
+	}
+}
+
+// =============================================================================
+// Tool Reset After Success Tests
+// =============================================================================
+
+func TestAgent_Run_ToolRetryResetAfterSuccess(t *testing.T) {
+	raw, client := newTestClient()
+
+	// First call: tool fails
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call-1", "resetable_tool", map[string]any{"name": "test"}),
+	), nil)
+
+	// Second call: tool succeeds
+	raw.queueResponse(toolCallResponse(
 		makeToolCall("call-2", "resetable_tool", map[string]any{"name": "test"}),
 	), nil)
 
@@ -1706,6 +2993,77 @@ func TestAgent_Run_ToolRetryResetAfterSuccess(t *testing.T) {
 	}
 }
 
+// =============================================================================
+// RunResult Serialization Tests
+// =============================================================================
+
+func TestRunResult_RoundTrip(t *testing.T) {
+	raw, client := newTestClient()
+
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call-1", "greet", map[string]any{"name": "Alice"}),
+	), nil)
+	raw.queueResponse(structuredResponse(`{"result":"Hello, Alice"}`), nil)
+
+	greetTool, _ := NewTool[testDeps, testInput, testOutput](
+		"greet", "Greets a person",
+		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
+			return testOutput{Result: "Hello, " + in.Name}, nil
+		},
+	)
+
+	agent, err := New[testDeps, testOutput](client,
+		WithTools[testDeps, testOutput](greetTool),
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("Greet Alice"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := t.TempDir() + "/result.json"
+	if err := result.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	loaded, err := LoadRunResultFromFile[testOutput](path)
+	if err != nil {
+		t.Fatalf("LoadRunResultFromFile failed: %v", err)
+	}
+
+	if loaded.Output != result.Output {
+		t.Errorf("expected output %+v, got %+v", result.Output, loaded.Output)
+	}
+	if loaded.Usage != result.Usage {
+		t.Errorf("expected usage %+v, got %+v", result.Usage, loaded.Usage)
+	}
+	if len(loaded.Messages) != len(result.Messages) {
+		t.Fatalf("expected %d messages, got %d", len(result.Messages), len(loaded.Messages))
+	}
+
+	call := loaded.Messages[1].ToolCalls[0]
+	if call.ID != "call-1" || call.Function.Name != "greet" || call.Function.Arguments["name"] != "Alice" {
+		t.Errorf("tool call did not round-trip: %+v", call)
+	}
+	toolMsg := loaded.Messages[2]
+	if toolMsg.ToolCallID == nil || *toolMsg.ToolCallID != "call-1" {
+		t.Errorf("expected tool call ID call-1, got %v", toolMsg.ToolCallID)
+	}
+	if toolMsg.TextContent() != result.Messages[2].TextContent() {
+		t.Errorf("expected tool result %q, got %q", result.Messages[2].TextContent(), toolMsg.TextContent())
+	}
+}
+
+func TestUnmarshalRunResult_InvalidJSON(t *testing.T) {
+	if _, err := UnmarshalRunResult[testOutput]([]byte("{not json")); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
+
 // =============================================================================
 // Nil Message Tests
 // =============================================================================
@@ -1733,6 +3091,2384 @@ func TestAgent_Run_NilMessage(t *testing.T) {
 	}
 }
 
+func TestAgent_Run_LifecycleHooks(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "flaky", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(toolCallResponse(makeToolCall("call_2", "flaky", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+
+	attempts := 0
+	flaky, err := NewTool[testDeps, testInput, testOutput]("flaky", "Fails once",
+		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
+			attempts++
+			if attempts == 1 {
+				return testOutput{}, NewModelRetry("try again")
+			}
+			return testOutput{Result: "ok"}, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to create tool: %v", err)
+	}
+
+	var calls []string
+	agent, err := New[testDeps, emptyOutput](client,
+		WithRetries[testDeps, emptyOutput](1),
+		WithTools[testDeps, emptyOutput](flaky),
+		WithOnModelRequest[testDeps, emptyOutput](func(ctx context.Context, rc *RunContext[testDeps], params *types.ChatParams) {
+			calls = append(calls, "request")
+		}),
+		WithOnModelResponse[testDeps, emptyOutput](func(ctx context.Context, rc *RunContext[testDeps], resp *types.ChatResponse) {
+			calls = append(calls, "response")
+		}),
+		WithOnToolStart[testDeps, emptyOutput](func(ctx context.Context, rc *RunContext[testDeps], call types.ToolCall) {
+			calls = append(calls, "start:"+call.ID)
+		}),
+		WithOnToolEnd[testDeps, emptyOutput](func(ctx context.Context, rc *RunContext[testDeps], call types.ToolCall, result *types.ToolResult, err error) {
+			calls = append(calls, fmt.Sprintf("end:%s:%v", call.ID, err != nil))
+		}),
+		WithOnRetry[testDeps, emptyOutput](func(ctx context.Context, rc *RunContext[testDeps], err error) {
+			calls = append(calls, "retry:"+err.Error())
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{Value: "deps"}, WithPrompt("go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "request response start:call_1 end:call_1:true retry:try again request response start:call_2 end:call_2:false request response"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("unexpected hook calls:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestAgent_New_NilHook(t *testing.T) {
+	_, client := newTestClient()
+	if _, err := New[testDeps, emptyOutput](client, WithOnRetry[testDeps, emptyOutput](nil)); err == nil {
+		t.Error("expected error for nil hook")
+	}
+}
+
+func TestAgent_Run_WithOutputValidator(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(outputToolResponse(`{"result":"yesterday"}`), nil)
+	raw.queueResponse(outputToolResponse(`{"result":"tomorrow"}`), nil)
+
+	var retries []int
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeTool),
+		WithOutputRetries[testDeps, testOutput](1),
+		WithOutputValidator(func(ctx context.Context, rc *RunContext[testDeps], out testOutput) (testOutput, error) {
+			retries = append(retries, rc.Retry)
+			if out.Result != "tomorrow" {
+				return out, NewModelRetry("date must be in the future")
+			}
+			return out, nil
+		}),
+		WithOutputValidator(func(ctx context.Context, rc *RunContext[testDeps], out testOutput) (testOutput, error) {
+			out.Result = strings.ToUpper(out.Result)
+			return out, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("when?"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Output.Result != "TOMORROW" {
+		t.Errorf("expected output of the validator chain, got %q", result.Output.Result)
+	}
+	if fmt.Sprint(retries) != "[0 1]" {
+		t.Errorf("unexpected retry counts: %v", retries)
+	}
+	feedback := raw.chatParams[1].Messages[len(raw.chatParams[1].Messages)-1].TextContent()
+	if !strings.Contains(feedback, "date must be in the future") {
+		t.Errorf("expected validator feedback to be sent to the LLM, got %q", feedback)
+	}
+}
+
+func TestAgent_Run_WithOutputValidator_Errors(t *testing.T) {
+	t.Run("exceeds output retries", func(t *testing.T) {
+		raw, client := newTestClient()
+		raw.queueResponse(outputToolResponse(`{"result":"a"}`), nil)
+		raw.queueResponse(outputToolResponse(`{"result":"b"}`), nil)
+
+		agent, _ := New[testDeps, testOutput](client,
+			WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeTool),
+			WithOutputRetries[testDeps, testOutput](1),
+			WithOutputValidator(func(ctx context.Context, rc *RunContext[testDeps], out testOutput) (testOutput, error) {
+				return out, NewModelRetry("never good enough")
+			}),
+		)
+		_, err := agent.Run(context.Background(), testDeps{}, WithPrompt("test"))
+		if err == nil || !strings.Contains(err.Error(), "exceeded max retries (1)") {
+			t.Errorf("expected max retries error, got %v", err)
+		}
+	})
+
+	t.Run("other errors are fatal", func(t *testing.T) {
+		raw, client := newTestClient()
+		raw.queueResponse(outputToolResponse(`{"result":"a"}`), nil)
+
+		dbErr := errors.New("db down")
+		agent, _ := New[testDeps, testOutput](client,
+			WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeTool),
+			WithOutputRetries[testDeps, testOutput](3),
+			WithOutputValidator(func(ctx context.Context, rc *RunContext[testDeps], out testOutput) (testOutput, error) {
+				return out, dbErr
+			}),
+		)
+		_, err := agent.Run(context.Background(), testDeps{}, WithPrompt("test"))
+		if !errors.Is(err, dbErr) || raw.chatCalls != 1 {
+			t.Errorf("expected fatal validator error after 1 call, got %v after %d calls", err, raw.chatCalls)
+		}
+	})
+}
+
+type reply interface{ isReply() }
+
+type answerReply struct {
+	Text string `json:"text"`
+}
+
+type clarificationReply struct {
+	Question string `json:"question"`
+}
+
+func (answerReply) isReply()         {}
+func (*clarificationReply) isReply() {}
+
+func TestAgent_Run_WithOutputVariants(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(outputToolResponse(`{"result":{"kind":"unknown","value":{}}}`), nil)
+	raw.queueResponse(outputToolResponse(`{"result":{"kind":"clarification","value":{"question":"Which city?"}}}`), nil)
+
+	agent, err := New[testDeps, reply](client,
+		WithOutputRetries[testDeps, reply](1),
+		WithOutputVariants[testDeps](
+			Variant[reply, answerReply]("answer"),
+			Variant[reply, clarificationReply]("clarification"),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("weather?"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clarification, ok := result.Output.(*clarificationReply)
+	if !ok || clarification.Question != "Which city?" {
+		t.Fatalf("unexpected output: %#v", result.Output)
+	}
+	if result.OutputVariant != "clarification" {
+		t.Errorf("expected variant clarification, got %q", result.OutputVariant)
+	}
+
+	// Tool mode is used by default and the schema lists both variants
+	rf := raw.chatParams[0].ResponseFormat
+	if rf.Mode != types.ResponseFormatModeTool {
+		t.Errorf("expected tool mode, got %q", rf.Mode)
+	}
+	options := rf.Schema["properties"].(map[string]any)["result"].(map[string]any)["anyOf"].([]any)
+	if len(options) != 2 {
+		t.Errorf("expected 2 variants in schema, got %d", len(options))
+	}
+}
+
+func TestWithOutputVariants_Errors(t *testing.T) {
+	_, client := newTestClient()
+
+	if _, err := New[testDeps, reply](client, WithOutputVariants[testDeps](Variant[reply, testOutput]("bad"))); err == nil {
+		t.Error("expected error for variant not implementing the output type")
+	}
+	if _, err := New[testDeps, reply](client, WithOutputVariants[testDeps](
+		Variant[reply, answerReply]("a"), Variant[reply, clarificationReply]("a"),
+	)); err == nil {
+		t.Error("expected error for duplicate variant names")
+	}
+}
+
+type bookingArgs struct {
+	Date string `json:"date"`
+}
+
+type booking struct {
+	ID   int
+	Date string
+}
+
+func TestAgent_Run_WithOutputFunc(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(outputToolResponse(`{"date":"2020-01-01"}`), nil)
+	raw.queueResponse(outputToolResponse(`{"date":"2030-01-01"}`), nil)
+
+	var saved []string
+	agent, err := New[testDeps, booking](client,
+		WithOutputRetries[testDeps, booking](1),
+		WithOutputFunc(
+			"Books the table",
+			func(ctx context.Context, rc *RunContext[testDeps], args bookingArgs) (booking, error) {
+				if args.Date < "2026" {
+					return booking{}, NewModelRetry("date must be in the future")
+				}
+				saved = append(saved, args.Date)
+				return booking{ID: 42, Date: args.Date}, nil
+			},
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("book a table"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Output != (booking{ID: 42, Date: "2030-01-01"}) {
+		t.Errorf("unexpected output: %+v", result.Output)
+	}
+	if fmt.Sprint(saved) != "[2030-01-01]" {
+		t.Errorf("expected a single booking, got %v", saved)
+	}
+
+	rf := raw.chatParams[0].ResponseFormat
+	if rf.Mode != types.ResponseFormatModeTool || rf.Description != "Books the table" {
+		t.Errorf("unexpected response format: %+v", rf)
+	}
+	if _, ok := rf.Schema["properties"].(map[string]any)["date"]; !ok {
+		t.Errorf("expected schema of the function arguments, got %v", rf.Schema)
+	}
+}
+
+func TestAgent_Run_WithOutputFunc_Error(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(outputToolResponse(`{"date":"2030-01-01"}`), nil)
+
+	dbErr := errors.New("db down")
+	agent, err := New[testDeps, booking](client,
+		WithOutputFunc("Books the table", func(ctx context.Context, rc *RunContext[testDeps], args bookingArgs) (booking, error) {
+			return booking{}, dbErr
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("book")); !errors.Is(err, dbErr) {
+		t.Errorf("expected output function error, got %v", err)
+	}
+}
+
+func TestAgent_Run_WithPrepareTools(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "lookup", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(toolCallResponse(makeToolCall("call_2", "delete", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("Deleted"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("lookup", "Found "), newGreetTool("delete", "Deleted ")),
+		WithPrepareTools[testDeps, emptyOutput](func(ctx context.Context, rc *RunContext[testDeps], tools []types.ToolDefinition) []types.ToolDefinition {
+			lookedUp := slices.ContainsFunc(rc.Messages, func(m types.Message) bool { return m.Role == types.RoleTool })
+			var prepared []types.ToolDefinition
+			for _, def := range tools {
+				if def.Name == "delete" && !lookedUp {
+					continue
+				}
+				def.Description = "Step tool: " + def.Description
+				prepared = append(prepared, def)
+			}
+			return prepared
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("delete Ada")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := toolNames(raw.chatParams[0].Tools); fmt.Sprint(got) != "[lookup]" {
+		t.Errorf("expected only lookup before the lookup succeeded, got %v", got)
+	}
+	if got := toolNames(raw.chatParams[1].Tools); fmt.Sprint(got) != "[lookup delete]" {
+		t.Errorf("expected delete after the lookup, got %v", got)
+	}
+	if desc := raw.chatParams[0].Tools[0].Description; desc != "Step tool: Greets a person" {
+		t.Errorf("unexpected description: %q", desc)
+	}
+	if desc := agent.toolMap["lookup"].Description; desc != "Greets a person" {
+		t.Errorf("agent tool was modified: %q", desc)
+	}
+}
+
+func TestAgent_Run_WithPrepareTools_HiddenToolCalled(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "delete", map[string]any{"name": "Ada"})), nil)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("delete", "Deleted ")),
+		WithPrepareTools[testDeps, emptyOutput](func(ctx context.Context, rc *RunContext[testDeps], tools []types.ToolDefinition) []types.ToolDefinition {
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("delete Ada")); err == nil || !strings.Contains(err.Error(), "unknown tool: delete") {
+		t.Errorf("expected unknown tool error, got %v", err)
+	}
+}
+
+func TestAgent_Run_ToolRequiresApproval(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call_1", "delete", map[string]any{"name": "report"}),
+		makeToolCall("call_2", "lookup", map[string]any{"name": "report"}),
+	), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+
+	executed := map[string]int{}
+	newTool := func(name string, opts ...ToolOption[testDeps]) *Tool[testDeps] {
+		tool, _ := NewTool[testDeps, testInput, testOutput](name, "Test tool",
+			func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
+				executed[name]++
+				return testOutput{Result: name + " " + in.Name}, nil
+			},
+			opts...,
+		)
+		return tool
+	}
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newTool("delete", ToolRequiresApproval[testDeps]()), newTool("lookup")),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = agent.Run(context.Background(), testDeps{}, WithPrompt("delete the report"))
+	var pending *PendingApproval
+	if !errors.As(err, &pending) {
+		t.Fatalf("expected PendingApproval, got %v", err)
+	}
+	if len(pending.ToolCalls) != 1 || pending.ToolCalls[0].ID != "call_1" {
+		t.Fatalf("unexpected pending calls: %+v", pending.ToolCalls)
+	}
+	if len(executed) != 0 {
+		t.Fatalf("expected no tool to run before approval, got %v", executed)
+	}
+
+	// The state survives a round trip through storage
+	data, err := json.Marshal(pending)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var restored PendingApproval
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	result, err := agent.Resume(context.Background(), testDeps{}, &restored, map[string]ApprovalDecision{"call_1": Deny("not on a Friday")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if executed["delete"] != 0 || executed["lookup"] != 1 {
+		t.Errorf("expected only lookup to run, got %v", executed)
+	}
+	denied := result.Messages[2]
+	if denied.ToolCallID == nil || *denied.ToolCallID != "call_1" || !strings.Contains(denied.TextContent(), "not on a Friday") {
+		t.Errorf("unexpected denied result: %+v", denied)
+	}
+	if result.Usage.TotalTokens != 30 {
+		t.Errorf("expected usage of both requests, got %d", result.Usage.TotalTokens)
+	}
+}
+
+func TestAgent_Resume_Approved(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+
+	tool := newGreetTool("greet", "Hi ")
+	tool.RequiresApproval = true
+	agent, err := New[testDeps, emptyOutput](client, WithTools[testDeps, emptyOutput](tool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	var pending *PendingApproval
+	if !errors.As(err, &pending) {
+		t.Fatalf("expected PendingApproval, got %v", err)
+	}
+
+	if _, err := agent.Resume(context.Background(), testDeps{}, pending, nil); err == nil {
+		t.Error("expected error for missing decision")
+	}
+
+	result, err := agent.Resume(context.Background(), testDeps{}, pending, map[string]ApprovalDecision{"call_1": Approve()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := result.Messages[2].TextContent(); !strings.Contains(got, "Hi Ada") {
+		t.Errorf("expected approved tool to run, got %q", got)
+	}
+}
+
+func TestAgent_Run_ToolCache(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "lookup", map[string]any{"name": "Ada", "extra": 1})), nil)
+	raw.queueResponse(toolCallResponse(makeToolCall("call_2", "lookup", map[string]any{"extra": 1, "name": "Ada"})), nil)
+	raw.queueResponse(toolCallResponse(makeToolCall("call_3", "lookup", map[string]any{"name": "Grace", "extra": 1})), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+
+	type lookupInput struct {
+		Name  string `json:"name"`
+		Extra int    `json:"extra"`
+	}
+	calls := 0
+	lookup, err := NewTool[testDeps, lookupInput, testOutput]("lookup", "Looks up a person",
+		func(ctx context.Context, rc *RunContext[testDeps], in lookupInput) (testOutput, error) {
+			calls++
+			return testOutput{Result: in.Name}, nil
+		},
+		ToolCache[testDeps](time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("failed to create tool: %v", err)
+	}
+
+	agent, err := New[testDeps, emptyOutput](client, WithTools[testDeps, emptyOutput](lookup))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("look up"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected 2 executions for 2 distinct argument sets, got %d", calls)
+	}
+	if got := result.Messages[4].TextContent(); !strings.Contains(got, "Ada") {
+		t.Errorf("expected cached result for the repeated call, got %q", got)
+	}
+}
+
+func TestLRUToolCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewLRUToolCache(2)
+	cache.now = func() time.Time { return now }
+	result := func(text string) *types.ToolResult {
+		return &types.ToolResult{ContentPart: []types.ContentPart{types.NewContentPartText(text)}}
+	}
+
+	cache.Set("a", result("a"), 0)
+	cache.Set("b", result("b"), time.Second)
+	cache.Get("a") // a is now the most recently used
+	cache.Set("c", result("c"), 0)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if got, ok := cache.Get("a"); !ok || got.TextContent() != "a" {
+		t.Errorf("expected a to be cached, got %v", got)
+	}
+
+	cache.Set("b", result("b"), time.Second)
+	now = now.Add(time.Second)
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected expired entry to be dropped")
+	}
+}
+
+func TestAsTool(t *testing.T) {
+	parentRaw, parentClient := newTestClient()
+	parentRaw.queueResponse(toolCallResponse(makeToolCall("call_1", "research", map[string]any{"prompt": "find Ada"})), nil)
+	parentRaw.queueResponse(textResponse("Ada was found"), nil)
+
+	childRaw, childClient := newTestClient()
+	childRaw.queueResponse(toolCallResponse(makeToolCall("call_2", "spy", map[string]any{"name": "Ada"})), nil)
+	childRaw.queueResponse(textResponse("Ada is in London"), nil)
+
+	var childRC *RunContext[string]
+	spy, _ := NewTool[string, testInput, testOutput]("spy", "Captures context",
+		func(ctx context.Context, rc *RunContext[string], in testInput) (testOutput, error) {
+			childRC = rc
+			return testOutput{}, nil
+		},
+	)
+
+	child, err := New[string, emptyOutput](childClient, WithTools[string, emptyOutput](spy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	research, err := AsTool(child, "research", "Researches a person", func(d testDeps) string { return "child-" + d.Value })
+	if err != nil {
+		t.Fatalf("AsTool failed: %v", err)
+	}
+
+	parent, err := New[testDeps, emptyOutput](parentClient, WithTools[testDeps, emptyOutput](research))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parentRunID string
+	result, err := parent.Run(context.Background(), testDeps{Value: "deps"}, WithPrompt("who is Ada?"), WithEventHandler(func(e Event) {
+		if started, ok := e.(RunStartedEvent); ok && parentRunID == "" {
+			parentRunID = started.RunID
+		}
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := result.Messages[2].TextContent(); got != "Ada is in London" {
+		t.Errorf("expected the child's answer as tool result, got %q", got)
+	}
+	if childRC.Deps != "child-deps" || childRC.Prompt != "find Ada" || childRC.ParentRunID != parentRunID {
+		t.Errorf("unexpected child context: deps %q, prompt %q, parent %q (want %q)", childRC.Deps, childRC.Prompt, childRC.ParentRunID, parentRunID)
+	}
+	// 2 parent requests + 2 child requests of 15 tokens each
+	if result.Usage.TotalTokens != 60 {
+		t.Errorf("expected usage including the child run, got %d", result.Usage.TotalTokens)
+	}
+}
+
+func TestAgent_Run_WithHandoffs(t *testing.T) {
+	triageRaw, triageClient := newTestClient()
+	triageRaw.queueResponse(toolCallResponse(makeToolCall("call_1", "transfer_to_billing", map[string]any{})), nil)
+
+	billingRaw, billingClient := newTestClient()
+	billingRaw.queueResponse(structuredResponse(`{"result":"refunded"}`), nil)
+
+	billing, err := New[string, testOutput](billingClient,
+		WithSystemPrompt[string, testOutput]("You handle billing."),
+		WithResponseFormat[string, testOutput](types.ResponseFormatModeNative),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	toBilling, err := NewHandoff(billing, "transfer_to_billing", "Transfers billing questions", func(d testDeps) string { return d.Value })
+	if err != nil {
+		t.Fatalf("NewHandoff failed: %v", err)
+	}
+
+	triage, err := New[testDeps, testOutput](triageClient, WithHandoffs(toBilling))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var events []Event
+	result, err := triage.Run(context.Background(), testDeps{Value: "acct-1"}, WithPrompt("refund me"), WithEventHandler(func(e Event) {
+		events = append(events, e)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Output.Result != "refunded" {
+		t.Errorf("expected the billing agent's output, got %+v", result.Output)
+	}
+	if got := toolNames(triageRaw.chatParams[0].Tools); fmt.Sprint(got) != "[transfer_to_billing]" {
+		t.Errorf("expected handoff to be offered as a tool, got %v", got)
+	}
+	// Billing sees the whole conversation, including the answered handoff call
+	sent := billingRaw.chatParams[0]
+	if len(sent.Messages) != 3 || sent.Messages[0].TextContent() != "refund me" || sent.SystemPrompt != "You handle billing." {
+		t.Errorf("unexpected conversation handed off: %d messages, system %q", len(sent.Messages), sent.SystemPrompt)
+	}
+	if result.Usage.TotalTokens != 30 {
+		t.Errorf("expected usage of both agents, got %d", result.Usage.TotalTokens)
+	}
+
+	var handoff *HandoffEvent
+	for _, e := range events {
+		if h, ok := e.(HandoffEvent); ok {
+			handoff = &h
+		}
+	}
+	if handoff == nil || handoff.Name != "transfer_to_billing" || handoff.ToolCallID != "call_1" {
+		t.Errorf("expected handoff event, got %v", eventNames(events))
+	}
+}
+
+func TestAgent_StreamText_WithHandoff(t *testing.T) {
+	triageRaw, triageClient := newTestClient()
+	triageRaw.queueResponse(toolCallResponse(makeToolCall("call_1", "transfer_to_support", map[string]any{})), nil)
+
+	supportRaw, supportClient := newTestClient()
+	supportRaw.queueResponse(textResponse("specialist answer here"), nil)
+
+	support, err := New[testDeps, emptyOutput](supportClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	toSupport, err := NewHandoff(support, "transfer_to_support", "Transfers support questions", func(d testDeps) testDeps { return d })
+	if err != nil {
+		t.Fatalf("NewHandoff failed: %v", err)
+	}
+	triage, err := New[testDeps, emptyOutput](triageClient, WithHandoffs(toSupport))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var deltas []string
+	var events []Event
+	for delta, err := range triage.StreamText(context.Background(), testDeps{}, WithPrompt("help"), WithEventHandler(func(e Event) {
+		events = append(events, e)
+	})) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		deltas = append(deltas, delta)
+	}
+	if strings.Join(deltas, "") != "specialist answer here" {
+		t.Errorf("expected the specialist's text to be streamed, got %q", deltas)
+	}
+	var textEvents int
+	for _, e := range events {
+		if _, ok := e.(TextDeltaEvent); ok {
+			textEvents++
+		}
+	}
+	if textEvents != len(deltas) {
+		t.Errorf("expected one text delta event per delta, got %d for %d deltas", textEvents, len(deltas))
+	}
+
+	// Iter continues with the target's steps
+	triageRaw.queueResponse(toolCallResponse(makeToolCall("call_2", "transfer_to_support", map[string]any{})), nil)
+	supportRaw.queueResponse(textResponse("again"), nil)
+	run := triage.Iter(context.Background(), testDeps{}, WithPrompt("help"))
+	defer run.Close()
+	var nodes int
+	for run.Next() {
+		nodes++
+	}
+	if _, err := run.Result(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Triage request and response, then the specialist's request and response
+	if nodes != 4 {
+		t.Errorf("expected 4 steps across the handoff, got %d", nodes)
+	}
+}
+
+func TestAgent_Run_WithHistoryProcessor(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+
+	// Keeps only the latest message
+	lastOnly := func(ctx context.Context, messages []types.Message) []types.Message {
+		return messages[len(messages)-1:]
+	}
+	redact := func(ctx context.Context, messages []types.Message) []types.Message {
+		for i, m := range messages {
+			if strings.Contains(m.TextContent(), "Ada") {
+				messages[i] = types.NewUserMessage(types.WithText("[redacted]"))
+			}
+		}
+		return messages
+	}
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")),
+		WithHistoryProcessor[testDeps, emptyOutput](lastOnly),
+		WithHistoryProcessor[testDeps, emptyOutput](redact),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, params := range raw.chatParams {
+		if len(params.Messages) != 1 || params.Messages[0].TextContent() != "[redacted]" {
+			t.Errorf("request %d: expected a single redacted message, got %d messages", i, len(params.Messages))
+		}
+	}
+	if len(result.Messages) != 4 || result.Messages[0].TextContent() != "greet Ada" {
+		t.Errorf("expected the full history in the result, got %d messages", len(result.Messages))
+	}
+}
+
+// messageCounter counts one token per message, plus one per untrimmed tool result
+type messageCounter struct{}
+
+func (messageCounter) CountMessages(messages []types.Message) int {
+	tokens := len(messages)
+	for _, m := range messages {
+		if m.Role == types.RoleTool && m.TextContent() != TrimmedToolResultText {
+			tokens++
+		}
+	}
+	return tokens
+}
+
+func contextWindowHistory() []types.Message {
+	callID := "call_1"
+	return []types.Message{
+		types.NewUserMessage(types.WithText("first")),
+		types.NewAssistantMessage(types.WithToolCalls(makeToolCall(callID, "greet", map[string]any{"name": "Ada"}))),
+		types.NewToolMessage(types.WithText("Hi Ada"), types.WithToolCallID(callID)),
+		types.NewAssistantMessage(types.WithText("greeted")),
+		types.NewUserMessage(types.WithText("second")),
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	history := contextWindowHistory()
+
+	// Fits: unchanged
+	if got := ContextWindow(10, ContextDropOldest, messageCounter{})(context.Background(), history); len(got) != 5 {
+		t.Errorf("expected history to be unchanged, got %d messages", len(got))
+	}
+
+	// The tool call and its result are dropped together
+	got := ContextWindow(3, ContextDropOldest, messageCounter{})(context.Background(), history)
+	if len(got) != 2 || got[0].TextContent() != "greeted" {
+		t.Errorf("expected the last two messages, got %d messages", len(got))
+	}
+
+	// Trimming the tool result is enough to fit
+	got = ContextWindow(5, ContextTrimToolResults, messageCounter{})(context.Background(), history)
+	if len(got) != 5 || got[2].TextContent() != TrimmedToolResultText {
+		t.Errorf("expected the tool result to be trimmed, got %d messages", len(got))
+	}
+	if history[2].TextContent() != "Hi Ada" {
+		t.Error("the input history was modified")
+	}
+
+	// The latest message is always kept
+	got = ContextWindow(1, ContextTrimToolResults, messageCounter{})(context.Background(), history)
+	if len(got) != 1 || got[0].TextContent() != "second" {
+		t.Errorf("expected only the latest message, got %d messages", len(got))
+	}
+}
+
+func TestAgent_Run_WithContextWindow(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("Done"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithContextWindow[testDeps, emptyOutput](3, ContextDropOldest),
+		WithTokenCounter[testDeps, emptyOutput](messageCounter{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	history := contextWindowHistory()
+	result, err := agent.Run(context.Background(), testDeps{}, WithMessages(history[:4]), WithPrompt("second"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sent := raw.chatParams[0].Messages; len(sent) != 2 || sent[1].TextContent() != "second" {
+		t.Errorf("expected the oldest turns to be dropped, got %d messages", len(sent))
+	}
+	if len(result.Messages) != 6 {
+		t.Errorf("expected the full history in the result, got %d messages", len(result.Messages))
+	}
+
+	if _, err := New[testDeps, emptyOutput](client, WithContextWindow[testDeps, emptyOutput](0, ContextDropOldest)); err == nil {
+		t.Error("expected an error for a non-positive context window")
+	}
+	if _, err := New[testDeps, emptyOutput](client, WithContextWindow[testDeps, emptyOutput](10, "newest")); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("Greeted Ada."), nil)
+	raw.queueResponse(textResponse("Greeted Ada twice."), nil)
+
+	process := Summarize(client, "cheap-model", 4, messageCounter{})
+	history := contextWindowHistory()
+
+	got := process(context.Background(), history)
+	if len(got) != 3 || got[0].TextContent() != SummaryPrefix+"Greeted Ada." || got[1].TextContent() != "greeted" {
+		t.Fatalf("expected a summary and the last two messages, got %d messages", len(got))
+	}
+	if params := raw.chatParams[0]; params.Model != "cheap-model" || params.SystemPrompt != SummaryPrompt {
+		t.Errorf("unexpected summary request: model %q", params.Model)
+	}
+	if transcript := raw.chatParams[0].Messages[0].TextContent(); !strings.Contains(transcript, "Tool result: Hi Ada") {
+		t.Errorf("tool result missing from transcript: %q", transcript)
+	}
+
+	// Still fits with the cached summary: no new request
+	history = append(history, types.NewAssistantMessage(types.WithText("anything else?")))
+	if got := process(context.Background(), history); len(got) != 4 || raw.chatCalls != 1 {
+		t.Fatalf("expected the cached summary to be reused, got %d messages and %d calls", len(got), raw.chatCalls)
+	}
+
+	// Doesn't fit anymore: the cached summary is extended
+	history = append(history, types.NewUserMessage(types.WithText("third")))
+	got = process(context.Background(), history)
+	if got[0].TextContent() != SummaryPrefix+"Greeted Ada twice." || raw.chatCalls != 2 {
+		t.Fatalf("expected a new summary, got %q after %d calls", got[0].TextContent(), raw.chatCalls)
+	}
+	if transcript := raw.chatParams[1].Messages[0].TextContent(); !strings.Contains(transcript, "Greeted Ada.") || strings.Contains(transcript, "Hi Ada") {
+		t.Errorf("expected the previous summary to be extended, got %q", transcript)
+	}
+}
+
+func TestAgent_Run_WithSummarizer(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("Greeted Ada."), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithSummarizer[testDeps, emptyOutput]("cheap-model", 4),
+		WithTokenCounter[testDeps, emptyOutput](messageCounter{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	history := contextWindowHistory()
+	result, err := agent.Run(context.Background(), testDeps{}, WithMessages(history[:4]), WithPrompt("second"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := raw.chatParams[1].Messages
+	if len(sent) != 3 || !strings.HasPrefix(sent[0].TextContent(), SummaryPrefix) || sent[2].TextContent() != "second" {
+		t.Errorf("expected the older turns to be summarized, got %d messages", len(sent))
+	}
+	if len(result.Messages) != 6 {
+		t.Errorf("expected the full history in the result, got %d messages", len(result.Messages))
+	}
+
+	if _, err := New[testDeps, emptyOutput](client, WithSummarizer[testDeps, emptyOutput]("", 10)); err == nil {
+		t.Error("expected an error for an empty summary model")
+	}
+}
+
+func TestAgent_Run_WithSession(t *testing.T) {
+	fileStore, err := NewFileHistoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stores := map[string]HistoryStore{"memory": NewMemoryHistoryStore(), "file": fileStore}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			raw, client := newTestClient()
+			raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+			raw.queueResponse(textResponse("Greeted Ada"), nil)
+			raw.queueResponse(textResponse("You asked me to greet Ada"), nil)
+
+			agent, err := New[testDeps, emptyOutput](client, WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"), WithSession(store, "s1")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("what did I ask?"), WithSession(store, "s1"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// The second run sees the first one's messages
+			if sent := raw.chatParams[2].Messages; len(sent) != 5 || sent[0].TextContent() != "greet Ada" {
+				t.Errorf("expected the session history in the request, got %d messages", len(sent))
+			}
+
+			stored, err := store.Load(context.Background(), "s1")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(stored) != 6 || len(result.Messages) != 6 {
+				t.Fatalf("expected 6 stored messages, got %d", len(stored))
+			}
+			if stored[1].ToolCalls[0].Function.Name != "greet" || *stored[2].ToolCallID != "call_1" {
+				t.Errorf("tool call not persisted: %+v", stored[1])
+			}
+			if stored[5].TextContent() != "You asked me to greet Ada" {
+				t.Errorf("unexpected last message %q", stored[5].TextContent())
+			}
+
+			if other, _ := store.Load(context.Background(), "s2"); len(other) != 0 {
+				t.Errorf("expected an empty session, got %d messages", len(other))
+			}
+		})
+	}
+
+	if _, err := fileStore.Load(context.Background(), "../escape"); err == nil {
+		t.Error("expected an error for a session ID with a path separator")
+	}
+}
+
+func TestAgent_Run_NameAndMetadata(t *testing.T) {
+	triageRaw, triageClient := newTestClient()
+	triageRaw.queueResponse(toolCallResponse(makeToolCall("call_1", "whoami", map[string]any{"name": "x"})), nil)
+	triageRaw.queueResponse(toolCallResponse(makeToolCall("call_2", "transfer_to_billing", map[string]any{})), nil)
+
+	billingRaw, billingClient := newTestClient()
+	billingRaw.queueResponse(textResponse("refunded"), nil)
+
+	billing, err := New[testDeps, emptyOutput](billingClient,
+		WithName[testDeps, emptyOutput]("billing"),
+		WithMetadata[testDeps, emptyOutput](map[string]string{"team": "payments"}),
+		WithMetadata[testDeps, emptyOutput](map[string]string{"version": "2"}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	toBilling, err := NewHandoff(billing, "transfer_to_billing", "Transfers billing questions", func(d testDeps) testDeps { return d })
+	if err != nil {
+		t.Fatalf("NewHandoff failed: %v", err)
+	}
+
+	var toolAgent string
+	whoami, _ := NewTool[testDeps, testInput, testOutput]("whoami", "Reports the agent",
+		func(ctx context.Context, rc *RunContext[testDeps], in testInput) (testOutput, error) {
+			toolAgent = rc.AgentName
+			return testOutput{}, nil
+		},
+	)
+	triage, err := New[testDeps, emptyOutput](triageClient,
+		WithName[testDeps, emptyOutput]("triage"),
+		WithTools[testDeps, emptyOutput](whoami),
+		WithHandoffs(toBilling),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var started []string
+	result, err := triage.Run(context.Background(), testDeps{}, WithPrompt("refund me"), WithEventHandler(func(e Event) {
+		if s, ok := e.(RunStartedEvent); ok {
+			started = append(started, s.AgentName)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if toolAgent != "triage" {
+		t.Errorf("expected RunContext.AgentName triage, got %q", toolAgent)
+	}
+	if fmt.Sprint(started) != "[triage billing]" {
+		t.Errorf("unexpected started agents: %v", started)
+	}
+	want := map[string]string{"team": "payments", "version": "2"}
+	if result.AgentName != "billing" || !maps.Equal(result.AgentMetadata, want) {
+		t.Errorf("expected the billing agent in the result, got %q %v", result.AgentName, result.AgentMetadata)
+	}
+	if triage.Name() != "triage" || !maps.Equal(billing.Metadata(), want) {
+		t.Errorf("unexpected accessors: %q %v", triage.Name(), billing.Metadata())
+	}
+}
+
+func TestAgent_Run_WithLogger(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	agent, err := New[testDeps, emptyOutput](client,
+		WithName[testDeps, emptyOutput]("greeter"),
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")),
+		WithLogger[testDeps, emptyOutput](logger),
+		WithLogContent[testDeps, emptyOutput](func(text string) string { return strings.ReplaceAll(text, "Ada", "[name]") }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logs := buf.String()
+	for _, want := range []string{`msg="model request"`, `msg="model response"`, `msg="tool start" tool=greet`, `msg="tool end" tool=greet`, "agent=greeter", `Hi [name]`} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("expected %q in logs:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, "Ada") {
+		t.Errorf("expected names to be redacted:\n%s", logs)
+	}
+}
+
+func TestAgent_Run_AbortTool(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call_1", "greet", map[string]any{"name": "Ada"}),
+		makeToolCall("call_2", AbortToolName, map[string]any{"reason": "no such person"}),
+	), nil)
+
+	var executed bool
+	greet := newGreetTool("greet", "Hi ")
+	execute := greet.Execute
+	greet.Execute = func(ctx context.Context, rc *RunContext[testDeps], args map[string]any) (*types.ToolResult, error) {
+		executed = true
+		return execute(ctx, rc, args)
+	}
+	agent, err := New[testDeps, testOutput](client,
+		WithTools[testDeps, testOutput](greet),
+		WithAbortTool[testDeps, testOutput](),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("greet Bob"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Aborted == nil || result.Aborted.Reason != "no such person" {
+		t.Fatalf("expected the run to be aborted, got %+v", result.Aborted)
+	}
+	if executed {
+		t.Error("tools called alongside the abort tool must not run")
+	}
+	if got := toolNames(raw.chatParams[0].Tools); !slices.Contains(got, AbortToolName) {
+		t.Errorf("expected the abort tool to be offered, got %v", got)
+	}
+	// user, assistant, one tool result per call
+	if len(result.Messages) != 4 || result.Messages[3].TextContent() != "Run aborted." {
+		t.Errorf("unexpected messages: %+v", result.Messages)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	decoded, err := UnmarshalRunResult[testOutput](data)
+	if err != nil || decoded.Aborted == nil || decoded.Aborted.Reason != "no such person" {
+		t.Errorf("Aborted did not round-trip: %+v, %v", decoded, err)
+	}
+
+	// Without the option the tool is not offered
+	raw.queueResponse(textResponse("done"), nil)
+	plain, _ := New[testDeps, string](client)
+	if _, err := plain.Run(context.Background(), testDeps{}, WithPrompt("hi")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := toolNames(raw.chatParams[1].Tools); slices.Contains(got, AbortToolName) {
+		t.Errorf("abort tool offered without WithAbortTool: %v", got)
+	}
+}
+
+func TestAgent_Run_ModelSettings(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("done"), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	agent, err := New[testDeps, string](client, WithTools[testDeps, string](newGreetTool("greet", "Hi ")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = agent.Run(context.Background(), testDeps{}, WithPrompt("hi"),
+		WithRunTemperature(0.2),
+		WithRunMaxTokens(100),
+		WithRunTopP(0.9),
+		WithRunStopSequences("END"),
+		WithRunModelSettings(types.WithSeed(7)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Every request of the run carries the settings
+	for i, params := range raw.chatParams {
+		if params.Temperature == nil || *params.Temperature != 0.2 || params.MaxTokens == nil || *params.MaxTokens != 100 ||
+			params.TopP == nil || *params.TopP != 0.9 || !slices.Equal(params.Stop, []string{"END"}) || params.Seed == nil || *params.Seed != 7 {
+			t.Errorf("request %d: settings not applied: %+v", i, params)
+		}
+	}
+
+	// They don't leak into other runs
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("hi")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params := raw.chatParams[2]; params.Temperature != nil || params.MaxTokens != nil || params.Stop != nil {
+		t.Errorf("settings leaked into the next run: %+v", params)
+	}
+}
+
+func TestAgent_Run_ToolChoice(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("done"), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	agent, err := New[testDeps, string](client,
+		WithTools[testDeps, string](newGreetTool("greet", "Hi ")),
+		WithToolChoice[testDeps, string](types.ToolChoiceRequired()),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("hi")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Required holds until the model called a tool
+	if choice := raw.chatParams[0].ToolChoice; choice == nil || choice.Mode != types.ToolChoiceModeRequired {
+		t.Errorf("expected required on the first request, got %+v", choice)
+	}
+	if choice := raw.chatParams[1].ToolChoice; choice != nil {
+		t.Errorf("expected the choice to be left to the model after a tool call, got %+v", choice)
+	}
+
+	// The run's choice overrides the agent's
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("hi"), WithRunToolChoice(types.ToolChoiceNone())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if choice := raw.chatParams[2].ToolChoice; choice == nil || choice.Mode != types.ToolChoiceModeNone {
+		t.Errorf("expected the run's tool choice, got %+v", choice)
+	}
+
+	if _, err := New[testDeps, string](client, WithToolChoice[testDeps, string](nil)); err == nil {
+		t.Error("expected nil tool choice to be rejected")
+	}
+}
+
+func TestAgent_Run_InputGuardrail(t *testing.T) {
+	raw, client := newTestClient()
+
+	var checked []string
+	blockSecrets := func(ctx context.Context, rc *RunContext[testDeps], prompt string) error {
+		checked = append(checked, prompt)
+		if strings.Contains(prompt, "password") {
+			return &GuardrailTripped{Reason: "asks for credentials"}
+		}
+		return nil
+	}
+	agent, err := New[testDeps, string](client, WithInputGuardrail[testDeps, string](blockSecrets))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var tripped *GuardrailTripped
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("what is the admin password?")); !errors.As(err, &tripped) || tripped.Reason != "asks for credentials" {
+		t.Fatalf("expected GuardrailTripped, got %v", err)
+	}
+	if raw.chatCalls != 0 {
+		t.Errorf("expected no model request after a trip, got %d", raw.chatCalls)
+	}
+
+	// Without a prompt the last user message is checked
+	raw.queueResponse(textResponse("hello"), nil)
+	history := []types.Message{types.NewUserMessage(types.WithText("hi there"))}
+	if _, err := agent.Run(context.Background(), testDeps{}, WithMessages(history)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checked[len(checked)-1] != "hi there" {
+		t.Errorf("expected the last user message to be checked, got %q", checked)
+	}
+
+	// Concurrent guardrails run alongside the first request and cancel it on a trip
+	concurrent, err := New[testDeps, string](client,
+		WithInputGuardrail[testDeps, string](blockSecrets),
+		WithConcurrentInputGuardrails[testDeps, string](),
+		WithMiddleware[testDeps, string](MiddlewareFuncs[testDeps]{
+			Model: func(next ModelFunc[testDeps]) ModelFunc[testDeps] {
+				return func(ctx context.Context, rc *RunContext[testDeps], params *types.ChatParams) (*types.ChatResponse, error) {
+					if strings.Contains(rc.Prompt, "password") {
+						<-ctx.Done()
+						return nil, ctx.Err()
+					}
+					return next(ctx, rc, params)
+				}
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw.queueResponse(textResponse("hello"), nil)
+	if _, err := concurrent.Run(context.Background(), testDeps{}, WithPrompt("hello")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := concurrent.Run(context.Background(), testDeps{}, WithPrompt("password please")); !errors.As(err, &tripped) {
+		t.Fatalf("expected GuardrailTripped from concurrent guardrails, got %v", err)
+	}
+
+	if _, err := New[testDeps, string](client, WithInputGuardrail[testDeps, string](nil)); err == nil {
+		t.Error("expected nil guardrail to be rejected")
+	}
+}
+
+func TestAgent_Run_OutputGuardrail(t *testing.T) {
+	noEmails := func(ctx context.Context, rc *RunContext[testDeps], output testOutput) error {
+		if strings.Contains(output.Result, "@") {
+			return &GuardrailTripped{Reason: "the output must not contain email addresses"}
+		}
+		return nil
+	}
+
+	// Retrying guardrails send the reason back to the model
+	raw, client := newTestClient()
+	raw.queueResponse(structuredResponse(`{"result":"mail ada@example.com"}`), nil)
+	raw.queueResponse(structuredResponse(`{"result":"mail Ada"}`), nil)
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithOutputRetries[testDeps, testOutput](1),
+		WithOutputGuardrail[testDeps, testOutput](noEmails, GuardrailActionRetry),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("test"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Output.Result != "mail Ada" {
+		t.Errorf("unexpected output: %+v", result.Output)
+	}
+	feedback := raw.chatParams[1].Messages[len(raw.chatParams[1].Messages)-1].TextContent()
+	if !strings.Contains(feedback, "must not contain email addresses") {
+		t.Errorf("expected the trip reason as feedback, got %q", feedback)
+	}
+
+	// Failing guardrails end the run with the trip
+	raw.queueResponse(structuredResponse(`{"result":"mail ada@example.com"}`), nil)
+	failing, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithOutputRetries[testDeps, testOutput](1),
+		WithOutputGuardrail[testDeps, testOutput](noEmails, GuardrailActionFail),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var tripped *GuardrailTripped
+	if _, err := failing.Run(context.Background(), testDeps{}, WithPrompt("test")); !errors.As(err, &tripped) {
+		t.Errorf("expected GuardrailTripped, got %v", err)
+	}
+
+	if _, err := New[testDeps, testOutput](client, WithOutputGuardrail[testDeps, testOutput](noEmails, "ignore")); err == nil {
+		t.Error("expected an unknown action to be rejected")
+	}
+}
+
+func TestAgent_Run_WithReflection(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(structuredResponse(`{"result":"draft"}`), nil)
+	raw.queueResponse(textResponse("The result is too short."), nil)
+	raw.queueResponse(structuredResponse(`{"result":"final"}`), nil)
+	raw.queueResponse(textResponse(ReflectionApproved), nil)
+
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithSystemPrompt[testDeps, testOutput]("Answer thoroughly."),
+		WithReflection[testDeps, testOutput](2, ""),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("explain"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Output.Result != "final" {
+		t.Errorf("expected the regenerated output, got %+v", result.Output)
+	}
+	if result.Usage.TotalTokens != 60 {
+		t.Errorf("expected critique requests in usage, got %d tokens", result.Usage.TotalTokens)
+	}
+
+	critic := raw.chatParams[1]
+	review := critic.Messages[0].TextContent()
+	if critic.SystemPrompt != DefaultCriticPrompt || !strings.Contains(review, "Answer thoroughly.") ||
+		!strings.Contains(review, "explain") || !strings.Contains(review, `{"result":"draft"}`) {
+		t.Errorf("unexpected critique request: %q / %q", critic.SystemPrompt, review)
+	}
+	regenerate := raw.chatParams[2].Messages
+	if got := regenerate[len(regenerate)-1].TextContent(); !strings.Contains(got, "The result is too short.") {
+		t.Errorf("expected the critique as feedback, got %q", got)
+	}
+
+	// The last output is kept once the reflections are used up
+	raw.queueResponse(textResponse("draft"), nil)
+	raw.queueResponse(textResponse("Not good."), nil)
+	raw.queueResponse(textResponse("still a draft"), nil)
+	once, _ := New[testDeps, string](client, WithReflection[testDeps, string](1, "Be strict."))
+	result2, err := once.Run(context.Background(), testDeps{}, WithPrompt("explain"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := result2.Messages[len(result2.Messages)-1].TextContent(); got != "still a draft" {
+		t.Errorf("unexpected answer: %q", got)
+	}
+	if review := raw.chatParams[5].Messages[0].TextContent(); !strings.HasSuffix(review, "Answer:\ndraft") {
+		t.Errorf("expected the text answer to be reviewed, got %q", review)
+	}
+	if raw.chatParams[5].SystemPrompt != "Be strict." {
+		t.Errorf("expected the custom critic prompt, got %q", raw.chatParams[5].SystemPrompt)
+	}
+
+	if _, err := New[testDeps, string](client, WithReflection[testDeps, string](0, "")); err == nil {
+		t.Error("expected zero reflection retries to be rejected")
+	}
+}
+
+func TestAgent_Run_ReflectionModelPath(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("draft"), nil)
+	raw.queueResponse(textResponse(ReflectionApproved), nil)
+
+	var wrapped, requests, responses int
+	agent, err := New[testDeps, string](client,
+		WithReflection[testDeps, string](1, ""),
+		WithOnModelRequest[testDeps, string](func(ctx context.Context, rc *RunContext[testDeps], params *types.ChatParams) {
+			requests++
+		}),
+		WithOnModelResponse[testDeps, string](func(ctx context.Context, rc *RunContext[testDeps], resp *types.ChatResponse) {
+			responses++
+		}),
+		WithMiddleware[testDeps, string](MiddlewareFuncs[testDeps]{
+			Model: func(next ModelFunc[testDeps]) ModelFunc[testDeps] {
+				return func(ctx context.Context, rc *RunContext[testDeps], params *types.ChatParams) (*types.ChatResponse, error) {
+					wrapped++
+					return next(ctx, rc, params)
+				}
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("explain"), WithRunTemperature(0.2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if wrapped != 2 || requests != 2 || responses != 2 {
+		t.Errorf("expected the critique to pass through middleware and hooks, got %d/%d/%d", wrapped, requests, responses)
+	}
+	if critic := raw.chatParams[1]; critic.Temperature == nil || *critic.Temperature != 0.2 {
+		t.Errorf("expected the run settings on the critique request, got %+v", critic)
+	}
+}
+
+func TestSession_Send(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("Hi Ada"), nil)
+	raw.queueResponse(nil, errors.New("boom"))
+	raw.queueResponse(textResponse("Your name is Ada"), nil)
+
+	agent, err := New[testDeps, string](client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	session := NewSession(agent, testDeps{}, types.NewUserMessage(types.WithText("be nice")))
+
+	if _, err := session.Send(context.Background(), "I am Ada"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A failed turn leaves the conversation unchanged
+	if _, err := session.Send(context.Background(), "ignored"); err == nil {
+		t.Fatal("expected the failed turn to return its error")
+	}
+	if _, err := session.Send(context.Background(), "What is my name?"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Each prompt is sent once, after the earlier turns
+	sent := raw.chatParams[2].Messages
+	var texts []string
+	for _, msg := range sent {
+		texts = append(texts, msg.TextContent())
+	}
+	if !slices.Equal(texts, []string{"be nice", "I am Ada", "Hi Ada", "What is my name?"}) {
+		t.Errorf("unexpected messages sent: %q", texts)
+	}
+	if got := len(session.Messages()); got != 5 {
+		t.Errorf("expected 5 messages in the session, got %d", got)
+	}
+	if session.Usage().TotalTokens != 30 {
+		t.Errorf("expected usage of both turns, got %d", session.Usage().TotalTokens)
+	}
+
+	session.Reset()
+	if len(session.Messages()) != 0 || session.Usage().TotalTokens != 0 {
+		t.Error("expected Reset to clear the session")
+	}
+}
+
+func TestAgent_Override(t *testing.T) {
+	prodRaw, prodClient := newTestClient()
+	prod, err := New[testDeps, string](prodClient,
+		WithModel[testDeps, string]("gpt-prod"),
+		WithTools[testDeps, string](newGreetTool("greet", "Hi "), newGreetTool("wave", "Wave ")),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	var requests int
+	test, err := prod.Override(
+		WithClient[testDeps, string](client),
+		WithModel[testDeps, string]("test"),
+		WithToolOverrides[testDeps, string](newGreetTool("greet", "Stub ")),
+		WithOnModelRequest[testDeps, string](func(context.Context, *RunContext[testDeps], *types.ChatParams) { requests++ }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := test.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw.chatParams[0].Model != "test" || prodRaw.chatCalls != 0 {
+		t.Errorf("expected the overridden client and model, got model %q and %d production calls", raw.chatParams[0].Model, prodRaw.chatCalls)
+	}
+	if got := toolNames(raw.chatParams[0].Tools); !slices.Equal(got, []string{"greet", "wave"}) {
+		t.Errorf("expected overridden tools in place, got %v", got)
+	}
+	if got := result.Messages[2].TextContent(); !strings.Contains(got, "Stub Ada") {
+		t.Errorf("expected the stub tool to run, got %q", got)
+	}
+	if requests != 2 {
+		t.Errorf("expected the hook on the copy, got %d calls", requests)
+	}
+
+	// The production agent is untouched
+	prodRaw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	prodRaw.queueResponse(textResponse("done"), nil)
+	result, err = prod.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prodRaw.chatParams[0].Model != "gpt-prod" || !strings.Contains(result.Messages[2].TextContent(), "Hi Ada") || requests != 2 {
+		t.Errorf("override leaked into the original agent: model %q, tool result %q, hook calls %d",
+			prodRaw.chatParams[0].Model, result.Messages[2].TextContent(), requests)
+	}
+
+	if _, err := prod.Override(WithToolOverrides[testDeps, string](newGreetTool("missing", ""))); err == nil {
+		t.Error("expected overriding an unknown tool to fail")
+	}
+}
+
+func TestAgent_Override_HistoryProcessors(t *testing.T) {
+	prodRaw, prodClient := newTestClient()
+	prod, err := New[testDeps, emptyOutput](prodClient,
+		WithSummarizer[testDeps, emptyOutput]("cheap-model", 4),
+		WithTokenCounter[testDeps, emptyOutput](messageCounter{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	history := contextWindowHistory()
+
+	// Summaries go through the overridden client
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("Greeted Ada."), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+	test, err := prod.Override(WithClient[testDeps, emptyOutput](client))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := test.Run(context.Background(), testDeps{}, WithMessages(history[:4]), WithPrompt("second")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prodRaw.chatCalls != 0 || raw.chatCalls != 2 || raw.chatParams[0].Model != "cheap-model" {
+		t.Errorf("expected the summary request on the overridden client, got %d production and %d test calls", prodRaw.chatCalls, raw.chatCalls)
+	}
+
+	// And are counted with the overridden token counter
+	raw, client = newTestClient()
+	raw.queueResponse(textResponse("Done"), nil)
+	test, err = prod.Override(
+		WithClient[testDeps, emptyOutput](client),
+		WithTokenCounter[testDeps, emptyOutput](zeroCounter{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := test.Run(context.Background(), testDeps{}, WithMessages(history[:4]), WithPrompt("second")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw.chatCalls != 1 || prodRaw.chatCalls != 0 || len(raw.chatParams[0].Messages) != 5 {
+		t.Errorf("expected no summary with the overridden counter, got %d test and %d production calls", raw.chatCalls, prodRaw.chatCalls)
+	}
+}
+
+// zeroCounter counts every history as empty
+type zeroCounter struct{}
+
+func (zeroCounter) CountMessages([]types.Message) int { return 0 }
+
+func TestAgent_RunMany(t *testing.T) {
+	raw, client := newTestClient()
+	for range 4 {
+		raw.queueResponse(textResponse("done"), nil)
+	}
+	raw.queueResponse(nil, errors.New("boom"))
+
+	var mu sync.Mutex
+	var running, peak int
+	limit := MiddlewareFuncs[testDeps]{
+		Model: func(next ModelFunc[testDeps]) ModelFunc[testDeps] {
+			return func(ctx context.Context, rc *RunContext[testDeps], params *types.ChatParams) (*types.ChatResponse, error) {
+				mu.Lock()
+				running++
+				peak = max(peak, running)
+				mu.Unlock()
+				time.Sleep(5 * time.Millisecond)
+				defer func() {
+					mu.Lock()
+					running--
+					mu.Unlock()
+				}()
+				return next(ctx, rc, params)
+			}
+		},
+	}
+
+	agent, err := New[testDeps, string](client, WithMiddleware[testDeps, string](limit))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	prompts := []string{"a", "b", "c", "d", "e"}
+	start := time.Now()
+	batch := agent.RunMany(context.Background(), testDeps{}, prompts,
+		WithConcurrency(2),
+		WithRateLimit(200),
+		WithBatchRunOptions(WithRunInstructions("be brief")),
+	)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("batch took %v, want the rate limit to spread 5 runs over at least 20ms", elapsed)
+	}
+
+	if peak > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak)
+	}
+	var failed int
+	for i, result := range batch.Results {
+		if batch.Errors[i] != nil {
+			failed++
+			if result != nil {
+				t.Errorf("prompt %d has both a result and an error", i)
+			}
+			continue
+		}
+		if got := result.Messages[0].TextContent(); got != prompts[i] {
+			t.Errorf("result %d answers prompt %q, want %q", i, got, prompts[i])
+		}
+	}
+	if failed != 1 || batch.Err() == nil {
+		t.Errorf("failed = %d, Err = %v, want one failure", failed, batch.Err())
+	}
+	if batch.Usage.TotalTokens != 60 {
+		t.Errorf("TotalTokens = %d, want 60", batch.Usage.TotalTokens)
+	}
+	for _, params := range raw.chatParams {
+		if !strings.Contains(params.SystemPrompt, "be brief") {
+			t.Errorf("SystemPrompt = %q, want the batch run options applied", params.SystemPrompt)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	batch = agent.RunMany(ctx, testDeps{}, prompts)
+	for i, err := range batch.Errors {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("prompt %d: err = %v, want context.Canceled", i, err)
+		}
+	}
+}
+
+// =============================================================================
+// Streaming Tests
+// =============================================================================
+
+func TestAgent_RunStream_TextAndTools(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("Hello there Ada"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client, WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	run := agent.RunStream(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	defer run.Close()
+
+	var deltas []string
+	for run.Next() {
+		deltas = append(deltas, run.Delta())
+	}
+	result, err := run.Result()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(deltas, "|") != "Hello |there |Ada" {
+		t.Errorf("unexpected deltas: %q", deltas)
+	}
+	// user, assistant tool call, tool result, final assistant
+	if len(result.Messages) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(result.Messages))
+	}
+	if got := result.Messages[2].TextContent(); !strings.Contains(got, "Hi Ada") {
+		t.Errorf("unexpected tool result: %q", got)
+	}
+	if result.Usage.TotalTokens != 30 {
+		t.Errorf("expected accumulated usage of 30 tokens, got %d", result.Usage.TotalTokens)
+	}
+}
+
+func TestAgent_RunStream_StructuredOutput(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(outputToolResponse(`{"result":"done"}`), nil)
+
+	agent, err := New[testDeps, testOutput](client, WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeTool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.RunStream(context.Background(), testDeps{}, WithPrompt("test")).Result()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Output.Result != "done" {
+		t.Errorf("unexpected output: %+v", result.Output)
+	}
+}
+
+func TestAgent_RunStream_Close(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("one two three"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	run := agent.RunStream(context.Background(), testDeps{}, WithPrompt("test"))
+	if !run.Next() {
+		t.Fatal("expected a delta")
+	}
+	run.Close()
+
+	if _, err := run.Result(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+type articleOutput struct {
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+	Count int      `json:"count"`
+}
+
+func TestAgent_RunStreamOutput(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(structuredResponse(`{"title": "A tale", "tags": ["x", "y"], "count": 42}`), nil)
+
+	agent, err := New[testDeps, articleOutput](client,
+		WithResponseFormat[testDeps, articleOutput](types.ResponseFormatModeNative),
+		WithTools[testDeps, articleOutput](newGreetTool("greet", "Hi ")),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	run := agent.RunStreamOutput(context.Background(), testDeps{}, WithPrompt("write"))
+	defer run.Close()
+
+	var snapshots []string
+	for partial := range run.Partials() {
+		snapshots = append(snapshots, fmt.Sprintf("%q %v %d", partial.Output.Title, partial.Output.Tags, partial.Output.Count))
+	}
+	result, err := run.Result()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		`"" [] 0`,
+		`"A " [] 0`,
+		`"A tale" [] 0`,
+		`"A tale" [x] 0`,
+		`"A tale" [x y] 0`,
+		`"A tale" [x y] 42`,
+	}
+	if !slices.Equal(snapshots, want) {
+		t.Errorf("unexpected partials:\n got %q\nwant %q", snapshots, want)
+	}
+	if result.Output.Title != "A tale" || result.Output.Count != 42 {
+		t.Errorf("unexpected output: %+v", result.Output)
+	}
+}
+
+func TestParsePartial(t *testing.T) {
+	tests := []struct {
+		text string
+		want string // completed JSON, "" if nothing parses yet
+	}{
+		{"Here you go:\n```json\n{\"title\": \"Hi", `{"title": "Hi"}`},
+		{`{"title": "a\`, `{"title": "a"}`},
+		{`{"title": "a\u00`, `{"title": "a"}`},
+		{`{"title": "a", "tags": ["b", "c`, `{"title": "a", "tags": ["b", "c"]}`},
+		{`{"title": "a", "count": 4`, `{"title": "a"}`},
+		{`{"title": "a", "ti`, `{"title": "a"}`},
+		{`{"count": 4} trailing text`, `{"count": 4}`},
+		{`no json`, ""},
+	}
+	for _, tt := range tests {
+		partial, ok := parsePartial[articleOutput](tt.text, nil)
+		if got := partial.JSON; ok != (tt.want != "") || got != tt.want {
+			t.Errorf("parsePartial(%q) = %q, %v; want %q", tt.text, got, ok, tt.want)
+		}
+	}
+}
+
+func TestAgent_StreamText(t *testing.T) {
+	raw, client := newTestClient()
+	withText := toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"}))
+	withText.Choices[0].Message.ContentPart = []types.ContentPart{types.NewContentPartText("Let me check. ")}
+	raw.queueResponse(withText, nil)
+	raw.queueResponse(textResponse("Hello there Ada"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client, WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var text strings.Builder
+	for delta, err := range agent.StreamText(context.Background(), testDeps{}, WithPrompt("greet Ada")) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text.WriteString(delta)
+	}
+	if text.String() != "Let me check. Hello there Ada" {
+		t.Errorf("unexpected text: %q", text.String())
+	}
+
+	// A failed run yields its error last
+	raw.queueResponse(nil, errors.New("boom"))
+	var errs []error
+	for _, err := range agent.StreamText(context.Background(), testDeps{}, WithPrompt("hi")) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || errs[0] == nil || !strings.Contains(errs[0].Error(), "boom") {
+		t.Errorf("expected the run error, got %v", errs)
+	}
+
+	// Breaking out early stops the run
+	raw.queueResponse(textResponse("one two three"), nil)
+	for range agent.StreamText(context.Background(), testDeps{}, WithPrompt("hi")) {
+		break
+	}
+}
+
+func eventNames(events []Event) []string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = strings.TrimPrefix(fmt.Sprintf("%T", e), "agent.")
+	}
+	return names
+}
+
+func TestAgent_Run_WithEventHandler(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client, WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var events []Event
+	_, err = agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"), WithEventHandler(func(e Event) {
+		events = append(events, e)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "RunStartedEvent ModelRequestEvent ToolCallStartedEvent ToolResultEvent ModelRequestEvent OutputValidatedEvent RunFinishedEvent"
+	if got := strings.Join(eventNames(events), " "); got != want {
+		t.Fatalf("unexpected events:\n got %s\nwant %s", got, want)
+	}
+	if step := events[4].(ModelRequestEvent).Step; step != 2 {
+		t.Errorf("expected second request to be step 2, got %d", step)
+	}
+	if res := events[3].(ToolResultEvent); res.ToolCallID != "call_1" || !strings.Contains(res.Result.TextContent(), "Hi Ada") {
+		t.Errorf("unexpected tool result event: %+v", res)
+	}
+}
+
+func TestAgent_Run_RunIDAndTraceContext(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	var headers []http.Header
+	var toolRC *RunContext[testDeps]
+	record := MiddlewareFuncs[testDeps]{
+		Model: func(next ModelFunc[testDeps]) ModelFunc[testDeps] {
+			return func(ctx context.Context, rc *RunContext[testDeps], params *types.ChatParams) (*types.ChatResponse, error) {
+				headers = append(headers, types.RequestHeaders(ctx))
+				return next(ctx, rc, params)
+			}
+		},
+		Tool: func(next ToolFunc[testDeps]) ToolFunc[testDeps] {
+			return func(ctx context.Context, rc *RunContext[testDeps], args map[string]any) (*types.ToolResult, error) {
+				toolRC = rc
+				return next(ctx, rc, args)
+			}
+		},
+	}
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")),
+		WithMiddleware[testDeps, emptyOutput](record),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	trace := map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	var events []Event
+	_, err = agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"),
+		WithRunID("req-42"),
+		WithTraceContext(trace),
+		WithEventHandler(func(event Event) { events = append(events, event) }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if toolRC == nil || toolRC.RunID != "req-42" || toolRC.TraceContext["traceparent"] != trace["traceparent"] {
+		t.Errorf("unexpected run context: %+v", toolRC)
+	}
+	if len(headers) != 2 {
+		t.Fatalf("expected 2 model requests, got %d", len(headers))
+	}
+	for _, header := range headers {
+		if header.Get(RunIDHeader) != "req-42" || header.Get("Traceparent") != trace["traceparent"] {
+			t.Errorf("unexpected request headers: %v", header)
+		}
+	}
+	if len(events) == 0 {
+		t.Fatal("expected events")
+	}
+	for _, event := range events {
+		info := reflect.ValueOf(event).FieldByName("RunInfo").Interface().(RunInfo)
+		if info.RunID != "req-42" || info.TraceContext["traceparent"] != trace["traceparent"] {
+			t.Errorf("%T has RunInfo %+v", event, info)
+		}
+	}
+}
+
+func TestAgent_Run_PromptCaching(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithSystemPrompt[testDeps, emptyOutput]("You greet people."),
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")),
+		WithPromptCaching[testDeps, emptyOutput](types.CacheControl{TTL: "1h"}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, params := range raw.chatParams {
+		if params.SystemCacheControl == nil || params.SystemCacheControl.TTL != "1h" {
+			t.Errorf("request %d: SystemCacheControl = %+v", i, params.SystemCacheControl)
+		}
+		if params.ToolsCacheControl == nil || params.ToolsCacheControl.TTL != "1h" {
+			t.Errorf("request %d: ToolsCacheControl = %+v", i, params.ToolsCacheControl)
+		}
+	}
+}
+
+func TestAgent_Run_ReasoningHistory(t *testing.T) {
+	for _, include := range []bool{true, false} {
+		raw, client := newTestClient()
+		thinking := toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"}))
+		thinking.Choices[0].Message.ContentPart = []types.ContentPart{&types.ContentPartReasoning{Text: "Greet Ada first.", Signature: "sig"}}
+		raw.queueResponse(thinking, nil)
+		raw.queueResponse(textResponse("done"), nil)
+
+		agent, err := New[testDeps, emptyOutput](client,
+			WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")),
+			WithReasoningHistory[testDeps, emptyOutput](include),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		sent := raw.chatParams[1].Messages[1]
+		if got := sent.ReasoningContent() != ""; got != include {
+			t.Errorf("include=%v: reasoning sent = %v, message %+v", include, got, sent)
+		}
+		if len(sent.ToolCalls) != 1 {
+			t.Errorf("include=%v: expected the tool call to be kept, got %+v", include, sent)
+		}
+		if got := result.Messages[1].ReasoningContent(); got != "Greet Ada first." {
+			t.Errorf("include=%v: expected the reasoning to be kept in the result, got %q", include, got)
+		}
+	}
+}
+
+func TestAgent_Run_AdditionalOutputProperties(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(structuredResponse(`{"result":"ok","confidence":0.9}`), nil)
+
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithAdditionalOutputProperties[testDeps, testOutput](true),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("answer"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Output.Result != "ok" {
+		t.Errorf("Output = %+v, want ok", result.Output)
+	}
+	if rf := raw.chatParams[0].ResponseFormat; !rf.AllowAdditionalProperties {
+		t.Errorf("expected the response format to allow additional properties, got %+v", rf)
+	}
+}
+
+func TestAgent_Run_OutputSchemaOptions(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(structuredResponse(`{"answer":"ok"}`), nil)
+
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeNative),
+		WithOutputSchemaOptions[testDeps, testOutput](types.WithSchemaTitle("Answer"), types.WithPropertyName("result", "answer")),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("answer"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Output.Result != "ok" {
+		t.Errorf("expected the renamed property decoded into the output, got %+v", result.Output)
+	}
+	schema := raw.chatParams[0].ResponseFormat.Schema
+	if _, ok := schema["properties"].(map[string]any)["answer"]; !ok || schema["title"] != "Answer" {
+		t.Errorf("expected the customized schema sent to the model, got %v", schema)
+	}
+
+	// Streamed partial outputs are renamed back too
+	if partial, ok := parsePartial[testOutput](`{"answer":"o`, agent.outputSchemaOpts); !ok || partial.Output.Result != "o" {
+		t.Errorf("expected the renamed property in the partial output, got %+v", partial.Output)
+	}
+}
+
+func TestAgent_Run_OutputToolName(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "final_answer", map[string]any{"result": "42"})), nil)
+
+	agent, err := New[testDeps, testOutput](client,
+		WithResponseFormat[testDeps, testOutput](types.ResponseFormatModeTool),
+		WithOutputToolName[testDeps, testOutput]("final_answer"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("answer"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Output.Result != "42" {
+		t.Errorf("Output = %+v, want 42", result.Output)
+	}
+	if names := toolNames(raw.chatParams[0].Tools); !slices.Equal(names, []string{"final_answer"}) {
+		t.Errorf("offered tools %v, want [final_answer]", names)
+	}
+
+	// Tools clashing with the output tool are rejected up front
+	if _, err := New[testDeps, testOutput](client,
+		WithTools[testDeps, testOutput](newGreetTool(types.OutputToolName, "Hi ")),
+	); err == nil || !strings.Contains(err.Error(), "clashes with the output tool") {
+		t.Errorf("expected a clash with the default output tool, got %v", err)
+	}
+	if _, err := New[testDeps, testOutput](client,
+		WithTools[testDeps, testOutput](newGreetTool("final_answer", "Hi ")),
+		WithOutputToolName[testDeps, testOutput]("final_answer"),
+	); err == nil {
+		t.Error("expected a clash with the renamed output tool")
+	}
+	_, err = agent.Run(context.Background(), testDeps{}, WithPrompt("answer"),
+		WithRunTools(newGreetTool("final_answer", "Hi ")))
+	if err == nil || !strings.Contains(err.Error(), "clashes with the output tool") {
+		t.Errorf("expected a clash of the run tool, got %v", err)
+	}
+}
+
+func TestAgent_Run_DepsLifecycle(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "deps", map[string]any{})), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	var toolDeps string
+	depsTool, _ := NewTool[testDeps, struct{}, string]("deps", "Reads the deps",
+		func(ctx context.Context, rc *RunContext[testDeps], in struct{}) (string, error) {
+			toolDeps = rc.Deps.Value
+			return "ok", nil
+		})
+
+	var tornDown []string
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](depsTool),
+		WithDepsSetup[testDeps, emptyOutput](func(ctx context.Context, dep testDeps) (testDeps, error) {
+			if dep.Value == "" {
+				return dep, errors.New("no user")
+			}
+			return testDeps{Value: dep.Value + ":tx"}, nil
+		}),
+		WithDepsTeardown[testDeps, emptyOutput](func(ctx context.Context, dep testDeps) {
+			tornDown = append(tornDown, dep.Value)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := agent.Run(context.Background(), testDeps{Value: "ada"}, WithPrompt("hi")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if toolDeps != "ada:tx" {
+		t.Errorf("tool saw deps %q, want ada:tx", toolDeps)
+	}
+	if !slices.Equal(tornDown, []string{"ada:tx"}) {
+		t.Errorf("torn down %v, want [ada:tx]", tornDown)
+	}
+
+	// Failed runs are torn down too
+	raw.queueResponse(nil, errors.New("boom"))
+	if _, err := agent.Run(context.Background(), testDeps{Value: "bob"}, WithPrompt("hi")); err == nil {
+		t.Fatal("expected an error")
+	}
+	if !slices.Equal(tornDown, []string{"ada:tx", "bob:tx"}) {
+		t.Errorf("torn down %v, want [ada:tx bob:tx]", tornDown)
+	}
+
+	// A failed setup fails the run before any request and is not torn down
+	calls := raw.chatCalls
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("hi")); err == nil || !strings.Contains(err.Error(), "no user") {
+		t.Fatalf("expected setup error, got %v", err)
+	}
+	if raw.chatCalls != calls || len(tornDown) != 2 {
+		t.Errorf("unexpected requests (%d) or teardowns %v after failed setup", raw.chatCalls-calls, tornDown)
+	}
+}
+
+func TestAgent_Run_ToolResultLimits(t *testing.T) {
+	long := strings.Repeat("result line ", 100)
+	newSearchTool := func(name string, opts ...ToolOption[testDeps]) *Tool[testDeps] {
+		tool, _ := NewTool[testDeps, testInput, string](name, "Searches",
+			func(ctx context.Context, rc *RunContext[testDeps], in testInput) (string, error) {
+				return long, nil
+			}, opts...)
+		return tool
+	}
+
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call_1", "search", map[string]any{"name": "a"}),
+		makeToolCall("call_2", "small_search", map[string]any{"name": "b"}),
+	), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newSearchTool("search"), newSearchTool("small_search", ToolMaxResultBytes[testDeps](50))),
+		WithMaxToolResultTokens[testDeps, emptyOutput](40),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("search"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	search, small := result.Messages[2].TextContent(), result.Messages[3].TextContent()
+	if !strings.HasSuffix(search, TruncatedMarker) || countTextTokens(types.HeuristicTokenCounter{}, search) > 40 {
+		t.Errorf("unexpected search result (%d bytes): %q", len(search), search)
+	}
+	if !strings.HasSuffix(small, TruncatedMarker) || len(small) > 50 || !strings.Contains(small, "result line") {
+		t.Errorf("unexpected small_search result (%d bytes): %q", len(small), small)
+	}
+
+	// A summarizer shortens results before they are truncated
+	raw.queueResponse(toolCallResponse(makeToolCall("call_3", "search", map[string]any{"name": "a"})), nil)
+	raw.queueResponse(textResponse("done"), nil)
+	var summarized string
+	summarizing, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newSearchTool("search")),
+		WithMaxToolResultBytes[testDeps, emptyOutput](100),
+		WithToolResultSummarizer[testDeps, emptyOutput](func(ctx context.Context, tool string, text string) (string, error) {
+			summarized = tool
+			return "summary of " + tool, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err = summarizing.Run(context.Background(), testDeps{}, WithPrompt("search"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summarized != "search" || result.Messages[2].TextContent() != "summary of search" {
+		t.Errorf("unexpected summarized result: %q", result.Messages[2].TextContent())
+	}
+
+	if _, err := New[testDeps, emptyOutput](client, WithMaxToolResultBytes[testDeps, emptyOutput](0)); err == nil {
+		t.Error("expected an error for a zero limit")
+	}
+}
+
+func TestLimitToolResultKeepsPartOrder(t *testing.T) {
+	_, client := newTestClient()
+	agent, err := New[testDeps, string](client, WithMaxToolResultBytes[testDeps, string](40))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chart, photo := &types.ContentPartImage{Data: "Y2hhcnQ="}, &types.ContentPartImage{Data: "cGhvdG8="}
+	result := &types.ToolResult{ContentPart: []types.ContentPart{
+		types.NewContentPartText("first chunk "), chart, types.NewContentPartText(strings.Repeat("x", 100)), photo,
+	}}
+
+	tool := &Tool[testDeps]{ToolDefinition: types.ToolDefinition{Name: "render"}}
+	limited := agent.limitToolResult(context.Background(), tool, result)
+	parts := limited.ContentPart
+	if len(parts) != 4 || parts[1] != chart || parts[3] != photo {
+		t.Fatalf("expected the images to keep their place, got %+v", parts)
+	}
+	if first := parts[0].(*types.ContentPartText).Text; first != "first chunk " {
+		t.Errorf("expected the first text part to be kept, got %q", first)
+	}
+	if text := limited.TextContent(); len(text) > 40 || !strings.HasSuffix(parts[2].(*types.ContentPartText).Text, TruncatedMarker) {
+		t.Errorf("expected the second text part to be truncated, got %q", text)
+	}
+
+	// Text parts after the cut are dropped
+	short, _ := New[testDeps, string](client, WithMaxToolResultBytes[testDeps, string](15))
+	parts = short.limitToolResult(context.Background(), tool, result).ContentPart
+	if len(parts) != 3 || parts[1] != chart || parts[2] != photo ||
+		!strings.HasSuffix(parts[0].(*types.ContentPartText).Text, TruncatedMarker) {
+		t.Errorf("unexpected parts: %+v", parts)
+	}
+}
+
+func TestAgent_Run_RunTrace(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(
+		makeToolCall("call_1", "greet", map[string]any{"name": "Ada"}),
+		makeToolCall("call_2", "missing", map[string]any{}),
+	), nil)
+	raw.queueResponse(textResponse("done"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client,
+		WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")),
+		WithUnknownToolRetries[testDeps, emptyOutput](1),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("greet Ada"), WithRunID("run-1"), WithRunTrace(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	trace := result.Trace
+	if trace == nil {
+		t.Fatal("expected a trace")
+	}
+	if trace.RunID != "run-1" || trace.Started.IsZero() || trace.Duration() < 0 || trace.Error != "" {
+		t.Errorf("unexpected trace: %+v", trace)
+	}
+	if len(trace.ModelCalls) != 2 {
+		t.Fatalf("expected 2 model calls, got %d", len(trace.ModelCalls))
+	}
+	if call := trace.ModelCalls[1]; call.Step != 2 || call.Params == nil || call.Response == nil || call.Response.Choices[0].Message.TextContent() != "done" {
+		t.Errorf("unexpected model call: %+v", call)
+	}
+	if len(trace.ToolCalls) != 1 || trace.ToolCalls[0].Name != "greet" || !strings.Contains(trace.ToolCalls[0].Result, "Hi Ada") {
+		t.Errorf("unexpected tool calls: %+v", trace.ToolCalls)
+	}
+	if len(trace.Retries) != 1 || !strings.Contains(trace.Retries[0].Error, "missing") || trace.Retries[0].Transient {
+		t.Errorf("unexpected retries: %+v", trace.Retries)
+	}
+
+	data, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatalf("failed to encode trace: %v", err)
+	}
+	var decoded RunTrace
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode trace: %v", err)
+	}
+	if decoded.RunID != "run-1" || len(decoded.ModelCalls) != 2 || decoded.ToolCalls[0].Arguments["name"] != "Ada" {
+		t.Errorf("unexpected decoded trace: %+v", decoded)
+	}
+
+	// The trace of a failed run is filled in too
+	raw.queueResponse(nil, errors.New("boom"))
+	var failed RunTrace
+	if _, err := agent.Run(context.Background(), testDeps{}, WithPrompt("hi"), WithRunTrace(&failed)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(failed.Error, "boom") || len(failed.ModelCalls) != 1 || failed.ModelCalls[0].Error == "" {
+		t.Errorf("unexpected trace of failed run: %+v", failed)
+	}
+
+	// Untraced runs have no trace
+	raw.queueResponse(textResponse("done"), nil)
+	if result, err := agent.Run(context.Background(), testDeps{}, WithPrompt("hi")); err != nil || result.Trace != nil {
+		t.Errorf("unexpected result: %+v, %v", result, err)
+	}
+}
+
+func TestAgent_RunStream_WithEventHandler(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("Hello there"), nil)
+	raw.queueResponse(nil, errors.New("boom"))
+
+	agent, err := New[testDeps, emptyOutput](client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var events []Event
+	handler := WithEventHandler(func(e Event) { events = append(events, e) })
+	if _, err := agent.RunStream(context.Background(), testDeps{}, WithPrompt("hi"), handler).Result(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "RunStartedEvent ModelRequestEvent TextDeltaEvent TextDeltaEvent OutputValidatedEvent RunFinishedEvent"
+	if got := strings.Join(eventNames(events), " "); got != want {
+		t.Fatalf("unexpected events:\n got %s\nwant %s", got, want)
+	}
+
+	events = nil
+	if _, err := agent.RunStream(context.Background(), testDeps{}, WithPrompt("hi"), handler).Result(); err == nil {
+		t.Fatal("expected error")
+	}
+	last, ok := events[len(events)-1].(RunErrorEvent)
+	if !ok || last.Err.Error() != "boom" {
+		t.Errorf("expected RunErrorEvent, got %#v", events[len(events)-1])
+	}
+}
+
+func TestAgent_Iter(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(toolCallResponse(makeToolCall("call_1", "greet", map[string]any{"name": "Ada"})), nil)
+	raw.queueResponse(textResponse("Done"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client, WithTools[testDeps, emptyOutput](newGreetTool("greet", "Hi ")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	run := agent.Iter(context.Background(), testDeps{}, WithPrompt("greet Ada"))
+	defer run.Close()
+
+	var steps []string
+	for run.Next() {
+		switch node := run.Node().(type) {
+		case *ModelRequestNode[testDeps]:
+			steps = append(steps, "request")
+			node.Params.Model = "override"
+		case *ModelResponseNode[testDeps]:
+			steps = append(steps, "response")
+		case *ToolCallNode[testDeps]:
+			steps = append(steps, "tool:"+node.Call.Function.Name)
+			node.Call.Function.Arguments["name"] = "Grace"
+		}
+	}
+	result, err := run.Result()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := strings.Join(steps, " "); got != "request response tool:greet request response" {
+		t.Errorf("unexpected steps: %s", got)
+	}
+	if raw.chatParams[0].Model != "override" {
+		t.Errorf("expected modified params to be sent, got model %q", raw.chatParams[0].Model)
+	}
+	if got := result.Messages[2].TextContent(); !strings.Contains(got, "Hi Grace") {
+		t.Errorf("expected modified arguments to be used, got %q", got)
+	}
+}
+
+func TestAgent_Iter_Close(t *testing.T) {
+	raw, client := newTestClient()
+	raw.queueResponse(textResponse("Done"), nil)
+
+	agent, err := New[testDeps, emptyOutput](client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	run := agent.Iter(context.Background(), testDeps{}, WithPrompt("test"))
+	if !run.Next() {
+		t.Fatal("expected a step")
+	}
+	run.Close()
+
+	if _, err := run.Result(); err == nil {
+		t.Error("expected error after close")
+	}
+	if raw.chatCalls != 0 {
+		t.Errorf("expected the run to stop before the model request, got %d calls", raw.chatCalls)
+	}
+}
+
 // =============================================================================
 // Integration Tests (Real API Calls)
 // =============================================================================
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// PendingApproval is returned as the error of a run that paused because the model called
+// tools marked with ToolRequiresApproval. None of the turn's tool calls have been executed.
+// It can be stored as JSON and passed to Agent.Resume once the calls have been reviewed:
+//
+//	result, err := a.Run(ctx, deps, agent.WithPrompt("delete the report"))
+//	var pending *agent.PendingApproval
+//	if errors.As(err, &pending) {
+//		// Ask a human, then
+//		result, err = a.Resume(ctx, deps, pending, map[string]agent.ApprovalDecision{
+//			pending.ToolCalls[0].ID: agent.Approve(),
+//		})
+//	}
+type PendingApproval struct {
+	RunID     string           `json:"run_id"`
+	Messages  []types.Message  `json:"messages"`   // History ending with the assistant message holding the tool calls
+	ToolCalls []types.ToolCall `json:"tool_calls"` // Calls awaiting a decision
+	Usage     types.Usage      `json:"usage"`
+}
+
+func (p *PendingApproval) Error() string {
+	return fmt.Sprintf("run paused: %d tool call(s) awaiting approval", len(p.ToolCalls))
+}
+
+// ApprovalDecision is the human decision on a pending tool call.
+type ApprovalDecision struct {
+	Approved bool
+	Reason   string // Sent to the model when the call is denied
+}
+
+// Approve allows a pending tool call to execute.
+func Approve() ApprovalDecision {
+	return ApprovalDecision{Approved: true}
+}
+
+// Deny rejects a pending tool call. The model receives reason as the call's error result.
+func Deny(reason string) ApprovalDecision {
+	return ApprovalDecision{Reason: reason}
+}
+
+// ToolRequiresApproval pauses the run with a PendingApproval whenever the model calls the tool,
+// so destructive actions only execute after a human approved them.
+func ToolRequiresApproval[TDep any]() ToolOption[TDep] {
+	return func(t *Tool[TDep]) {
+		t.RequiresApproval = true
+	}
+}
+
+// Resume continues a run paused with a PendingApproval. decisions holds a decision for every
+// pending tool call, keyed by tool call ID: approved calls are executed, denied ones answered
+// with an error result, and the run then continues as usual. Usage includes the paused run.
+// opts apply as in Run; prompts and messages are taken from state.
+func (a *Agent[TDep, TOut]) Resume(ctx context.Context, dep TDep, state *PendingApproval, decisions map[string]ApprovalDecision, opts ...RunOption) (*RunResult[TOut], error) {
+	if state == nil {
+		return nil, errors.New("pending approval cannot be nil")
+	}
+	if len(state.Messages) == 0 || len(state.Messages[len(state.Messages)-1].ToolCalls) == 0 {
+		return nil, errors.New("pending approval must end with an assistant message holding tool calls")
+	}
+	for _, tc := range state.ToolCalls {
+		if _, ok := decisions[tc.ID]; !ok {
+			return nil, fmt.Errorf("no approval decision for tool call %s", tc.ID)
+		}
+	}
+
+	resume := func(rc *runConfig) {
+		rc.prompt = ""
+		rc.messages = slices.Clone(state.Messages)
+		rc.resumed = state
+		rc.approvals = decisions
+	}
+	return a.run(ctx, dep, nil, append(opts[:len(opts):len(opts)], resume))
+}
+
+// pendingApprovals returns the tool calls of msg that need approval before the turn can execute
+func pendingApprovals[TDep any](msg *types.Message, toolMap map[string]*Tool[TDep]) []types.ToolCall {
+	var pending []types.ToolCall
+	for _, tc := range msg.ToolCalls {
+		if tool := toolMap[tc.Function.Name]; tool != nil && tool.RequiresApproval {
+			pending = append(pending, tc)
+		}
+	}
+	return pending
+}
+
+// deniedResult is the tool result the model receives for a denied tool call
+func deniedResult(reason string) *types.ToolResult {
+	text := "The tool call was not approved."
+	if reason != "" {
+		text += " Reason: " + reason
+	}
+	return &types.ToolResult{
+		ContentPart: []types.ContentPart{types.NewContentPartText(text)},
+		IsError:     true,
+	}
+}
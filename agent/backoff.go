@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff returns how long to wait before a retry. attempt is the 1-based retry number.
+type Backoff func(attempt int) time.Duration
+
+// ExponentialBackoff waits base, doubling on every attempt up to max. jitter in [0, 1]
+// randomly shortens each delay by up to that fraction, so concurrent runs spread out.
+func ExponentialBackoff(base, max time.Duration, jitter float64) Backoff {
+	jitter = min(1, jitter)
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		delay = min(delay, max)
+		if jitter > 0 {
+			delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+		}
+		return delay
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
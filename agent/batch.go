@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// DefaultBatchConcurrency is the number of concurrent runs of RunMany without WithConcurrency.
+const DefaultBatchConcurrency = 4
+
+// BatchResult holds the outcome of RunMany, indexed like its prompts.
+type BatchResult[TOut any] struct {
+	Results []*RunResult[TOut] // nil where the run failed
+	Errors  []error            // nil where the run succeeded
+	Usage   types.Usage        // Summed over the successful runs
+}
+
+// Err joins the errors of the failed runs, nil if all succeeded.
+func (b *BatchResult[TOut]) Err() error {
+	return errors.Join(b.Errors...)
+}
+
+// batchConfig holds the settings of RunMany
+type batchConfig struct {
+	concurrency int
+	interval    time.Duration // Minimum time between run starts (0 = no limit)
+	runOpts     []RunOption
+}
+
+// BatchOption configures RunMany.
+type BatchOption func(*batchConfig)
+
+// WithConcurrency sets how many runs RunMany executes at once (default
+// DefaultBatchConcurrency). Values below 1 are treated as 1.
+func WithConcurrency(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.concurrency = max(n, 1)
+	}
+}
+
+// WithRateLimit starts at most perSecond runs per second, spread evenly, to stay below
+// provider rate limits. Non-positive values disable the limit.
+func WithRateLimit(perSecond float64) BatchOption {
+	return func(c *batchConfig) {
+		c.interval = 0
+		if perSecond > 0 {
+			c.interval = time.Duration(float64(time.Second) / perSecond)
+		}
+	}
+}
+
+// WithBatchRunOptions applies opts to every run of RunMany, after which the prompt is set.
+func WithBatchRunOptions(opts ...RunOption) BatchOption {
+	return func(c *batchConfig) {
+		c.runOpts = append(c.runOpts, opts...)
+	}
+}
+
+// RunMany runs the agent once per prompt on a pool of workers, sharing dep, and returns
+// the results in prompt order. A failed run does not stop the others; once ctx is done,
+// the prompts not started yet fail with its error.
+func (a *Agent[TDep, TOut]) RunMany(ctx context.Context, dep TDep, prompts []string, opts ...BatchOption) *BatchResult[TOut] {
+	cfg := batchConfig{concurrency: DefaultBatchConcurrency}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	batch := &BatchResult[TOut]{
+		Results: make([]*RunResult[TOut], len(prompts)),
+		Errors:  make([]error, len(prompts)),
+	}
+
+	var (
+		mu   sync.Mutex
+		next time.Time // Earliest start of the next run under the rate limit
+	)
+	// wait blocks until the rate limit lets another run start
+	wait := func() error {
+		if cfg.interval == 0 {
+			return ctx.Err()
+		}
+		mu.Lock()
+		now := time.Now()
+		start := next
+		if start.Before(now) {
+			start = now
+		}
+		next = start.Add(cfg.interval)
+		mu.Unlock()
+		return sleep(ctx, start.Sub(now))
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(cfg.concurrency, len(prompts)) {
+		wg.Go(func() {
+			for i := range indexes {
+				if err := wait(); err != nil {
+					batch.Errors[i] = err
+					continue
+				}
+				runOpts := append(cfg.runOpts[:len(cfg.runOpts):len(cfg.runOpts)], WithPrompt(prompts[i]))
+				batch.Results[i], batch.Errors[i] = a.Run(ctx, dep, runOpts...)
+			}
+		})
+	}
+	for i := range prompts {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, result := range batch.Results {
+		if result != nil {
+			batch.Usage.Add(&result.Usage)
+		}
+	}
+	return batch
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"container/list"
+	"encoding/json/v2"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// DefaultToolCacheSize is the capacity of the in-memory cache agents use for tools with ToolCache.
+const DefaultToolCacheSize = 256
+
+// ToolResultCache stores the results of cacheable tools, see ToolCache.
+// Implementations must be safe for concurrent use.
+type ToolResultCache interface {
+	// Get returns the result stored under key, if present and not expired
+	Get(key string) (*types.ToolResult, bool)
+
+	// Set stores result under key. A ttl of zero or less never expires.
+	Set(key string, result *types.ToolResult, ttl time.Duration)
+}
+
+// ToolCache caches the tool's successful results for ttl (zero or less = until evicted),
+// keyed by tool name and arguments. Use it for lookups the model tends to repeat.
+// The agent's cache is an in-memory LRU unless replaced with WithToolResultCache.
+func ToolCache[TDep any](ttl time.Duration) ToolOption[TDep] {
+	return func(t *Tool[TDep]) {
+		t.Cache = true
+		t.CacheTTL = ttl
+	}
+}
+
+// WithToolResultCache replaces the agent's in-memory cache for tools with ToolCache,
+// e.g. with one shared between agents or processes.
+func WithToolResultCache[TDep, TOut any](cache ToolResultCache) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if cache == nil {
+			return errors.New("tool result cache cannot be nil")
+		}
+		a.toolCache = cache
+		return nil
+	}
+}
+
+// toolCacheKey identifies a tool call by tool name and normalized arguments
+func toolCacheKey(name string, args map[string]any) (string, error) {
+	data, err := json.Marshal(args, json.Deterministic(true))
+	if err != nil {
+		return "", err
+	}
+	return name + "\x00" + string(data), nil
+}
+
+// LRUToolCache is an in-memory ToolResultCache evicting the least recently used entry
+// once its capacity is reached.
+type LRUToolCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // Front = most recently used
+	now      func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	result  *types.ToolResult
+	expires time.Time // Zero = never
+}
+
+// NewLRUToolCache creates an LRUToolCache holding at most capacity results.
+// A capacity of zero or less uses DefaultToolCacheSize.
+func NewLRUToolCache(capacity int) *LRUToolCache {
+	if capacity <= 0 {
+		capacity = DefaultToolCacheSize
+	}
+	return &LRUToolCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get implements ToolResultCache.
+func (c *LRUToolCache) Get(key string) (*types.ToolResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.result, true
+}
+
+// Set implements ToolResultCache.
+func (c *LRUToolCache) Set(key string, result *types.ToolResult, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.now().Add(ttl)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &lruEntry{key: key, result: result, expires: expires}
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, result: result, expires: expires})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
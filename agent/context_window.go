@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ContextStrategy decides how ContextWindow shrinks a history that does not fit.
+type ContextStrategy string
+
+const (
+	// ContextDropOldest drops the oldest turns until the history fits.
+	ContextDropOldest ContextStrategy = "drop_oldest"
+
+	// ContextTrimToolResults first replaces the content of the oldest tool results with a
+	// placeholder, then drops the oldest turns if that is not enough.
+	ContextTrimToolResults ContextStrategy = "trim_tool_results"
+)
+
+// TrimmedToolResultText replaces the content of tool results trimmed by ContextTrimToolResults.
+const TrimmedToolResultText = "[tool result removed to save context]"
+
+// ContextWindow returns a HistoryProcessor that keeps the messages sent to the model within
+// maxTokens as counted by counter (nil = types.HeuristicTokenCounter). An assistant message
+// with tool calls and the tool results answering it are kept or dropped together, and the
+// latest turn is always kept. The system prompt and tool definitions are not counted.
+func ContextWindow(maxTokens int, strategy ContextStrategy, counter types.TokenCounter) HistoryProcessor {
+	if counter == nil {
+		counter = types.HeuristicTokenCounter{}
+	}
+
+	return func(ctx context.Context, messages []types.Message) []types.Message {
+		if counter.CountMessages(messages) <= maxTokens {
+			return messages
+		}
+
+		turns := splitTurns(messages)
+		if strategy == ContextTrimToolResults {
+			for i := 0; i < len(turns)-1; i++ {
+				for j := range turns[i] {
+					if turns[i][j].Role == types.RoleTool {
+						trimmed := turns[i][j]
+						trimmed.ContentPart = []types.ContentPart{types.NewContentPartText(TrimmedToolResultText)}
+						turns[i][j] = trimmed
+					}
+				}
+				if counter.CountMessages(joinTurns(turns)) <= maxTokens {
+					return joinTurns(turns)
+				}
+			}
+		}
+
+		for len(turns) > 1 && counter.CountMessages(joinTurns(turns)) > maxTokens {
+			turns = turns[1:]
+		}
+		return joinTurns(turns)
+	}
+}
+
+// WithContextWindow keeps the messages sent on each request within maxTokens, so long-running
+// agents don't fail with context length errors. Tokens are counted with the counter set by
+// WithTokenCounter. See ContextWindow.
+func WithContextWindow[TDep, TOut any](maxTokens int, strategy ContextStrategy) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if maxTokens <= 0 {
+			return fmt.Errorf("context window must be positive, got %d", maxTokens)
+		}
+		if strategy != ContextDropOldest && strategy != ContextTrimToolResults {
+			return fmt.Errorf("unknown context strategy: %q", strategy)
+		}
+		a.historyProcessors = append(a.historyProcessors, func(ctx context.Context, a *Agent[TDep, TOut], messages []types.Message) []types.Message {
+			// Resolved per request so WithTokenCounter may come after this option or in Override
+			return ContextWindow(maxTokens, strategy, a.tokenCounter)(ctx, messages)
+		})
+		return nil
+	}
+}
+
+// WithTokenCounter sets the counter used by WithContextWindow and WithMaxToolResultTokens
+// (default types.HeuristicTokenCounter).
+func WithTokenCounter[TDep, TOut any](counter types.TokenCounter) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if counter == nil {
+			return errors.New("token counter cannot be nil")
+		}
+		a.tokenCounter = counter
+		return nil
+	}
+}
+
+// splitTurns groups messages into units that must be kept together: an assistant message
+// with tool calls and the tool results following it, or a single other message
+func splitTurns(messages []types.Message) [][]types.Message {
+	var turns [][]types.Message
+	for _, m := range messages {
+		if m.Role == types.RoleTool && len(turns) > 0 {
+			last := turns[len(turns)-1]
+			if len(last[0].ToolCalls) > 0 {
+				turns[len(turns)-1] = append(last, m)
+				continue
+			}
+		}
+		turns = append(turns, []types.Message{m})
+	}
+	return turns
+}
+
+func joinTurns(turns [][]types.Message) []types.Message {
+	var messages []types.Message
+	for _, turn := range turns {
+		messages = append(messages, turn...)
+	}
+	return messages
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// DelegateInput is the input of tools created with AsTool.
+type DelegateInput struct {
+	Prompt string `json:"prompt" jsonschema:"The task to delegate, with all context the delegate needs"`
+}
+
+// AsTool wraps child as a tool of a parent agent, so the parent can delegate tasks to it.
+// Each call runs child with the prompt the model passes, deps derived from the parent's
+// deps with depsMapper, the parent's context and trace context, and the parent's RunID as
+// ParentRunID. The child's usage is added to the parent's usage. The tool returns the child's
+// output as JSON when it has a response format, and its final text otherwise. A failed child
+// run is reported to the model as an error result.
+func AsTool[TParentDep, TDep, TOut any](child *Agent[TDep, TOut], name, description string, depsMapper func(TParentDep) TDep, opts ...ToolOption[TParentDep]) (*Tool[TParentDep], error) {
+	if child == nil {
+		return nil, errors.New("child agent cannot be nil")
+	}
+	if depsMapper == nil {
+		return nil, errors.New("deps mapper cannot be nil")
+	}
+
+	inputSchema, err := types.SchemaMapFor[DelegateInput]()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate input schema: %w", err)
+	}
+	rf, err := child.responseFormat()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tool[TParentDep]{
+		ToolDefinition: types.ToolDefinition{
+			Name:        name,
+			Description: description,
+			InputSchema: inputSchema,
+		},
+		Execute: func(ctx context.Context, rc *RunContext[TParentDep], args map[string]any) (*types.ToolResult, error) {
+			in, err := types.UnmarshalToolArgs[DelegateInput](args)
+			if err != nil || in.Prompt == "" {
+				return nil, NewModelRetry("the prompt argument is required")
+			}
+
+			result, err := child.Run(ctx, depsMapper(rc.Deps), WithPrompt(in.Prompt), WithParentRunID(rc.RunID), WithTraceContext(rc.TraceContext))
+			if err != nil {
+				return types.ToolResultFromError(fmt.Errorf("delegate %s failed: %w", name, err)), nil
+			}
+			rc.Usage.Add(&result.Usage)
+
+			var text string
+			if rf.Schema != nil {
+				data, err := json.Marshal(result.Output)
+				if err != nil {
+					return types.ToolResultFromError(fmt.Errorf("failed to marshal delegate output: %w", err)), nil
+				}
+				text = string(data)
+			} else if len(result.Messages) > 0 {
+				text = result.Messages[len(result.Messages)-1].TextContent()
+			}
+
+			return &types.ToolResult{
+				ContentPart:       []types.ContentPart{types.NewContentPartText(text)},
+				StructuredContent: result.Output,
+			}, nil
+		},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DepsSetup prepares the deps of a run, e.g. begins a DB transaction or creates a per-user
+// API client, and returns the deps the run uses.
+type DepsSetup[TDep any] func(ctx context.Context, dep TDep) (TDep, error)
+
+// DepsTeardown releases what DepsSetup acquired once the run ended.
+type DepsTeardown[TDep any] func(ctx context.Context, dep TDep)
+
+// WithDepsSetup runs setup at the start of every run, before the first model request; the
+// run uses the deps it returns. An error fails the run. A run paused for approval is set up
+// again when resumed.
+func WithDepsSetup[TDep, TOut any](setup DepsSetup[TDep]) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if setup == nil {
+			return errors.New("deps setup cannot be nil")
+		}
+		a.depsSetup = setup
+		return nil
+	}
+}
+
+// WithDepsTeardown runs teardown with the run's deps when the run ends, whether it succeeded,
+// failed or paused for approval. Its ctx is not cancelled with the run's.
+func WithDepsTeardown[TDep, TOut any](teardown DepsTeardown[TDep]) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if teardown == nil {
+			return errors.New("deps teardown cannot be nil")
+		}
+		a.depsTeardown = teardown
+		return nil
+	}
+}
+
+// runDeps runs the session with dep set up, tearing it down once the session ended
+func (a *Agent[TDep, TOut]) runDeps(ctx context.Context, dep TDep, runCfg *runConfig, onText func(string) error) (*RunResult[TOut], error) {
+	if a.depsSetup != nil {
+		var err error
+		if dep, err = a.depsSetup(ctx, dep); err != nil {
+			return nil, fmt.Errorf("deps setup failed: %w", err)
+		}
+	}
+	if a.depsTeardown != nil {
+		defer a.depsTeardown(context.WithoutCancel(ctx), dep)
+	}
+	return a.runSession(ctx, dep, runCfg, onText)
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"github.com/KennyKeni/elysia/types"
+)
+
+// Event is emitted to the handler set with WithEventHandler while a run progresses.
+// It is one of RunStartedEvent, ModelRequestEvent, TextDeltaEvent, ToolCallStartedEvent,
+// ToolResultEvent, HandoffEvent, OutputValidatedEvent, RunFinishedEvent or RunErrorEvent.
+type Event interface {
+	isEvent()
+	withRunInfo(info RunInfo) Event
+}
+
+// EventHandler receives the events of a run. It is called synchronously from the run loop.
+type EventHandler func(event Event)
+
+// RunStartedEvent is emitted once before the first model request.
+type RunStartedEvent struct {
+	RunInfo
+
+	Prompt string
+
+	AgentName string            // See WithName
+	Metadata  map[string]string // See WithMetadata
+}
+
+// ModelRequestEvent is emitted before every model request.
+type ModelRequestEvent struct {
+	RunInfo
+
+	Step   int // 1-based number of the request within the run
+	Params *types.ChatParams
+}
+
+// TextDeltaEvent carries assistant text as it streams in. It is only emitted by RunStream
+// and RunStreamOutput.
+type TextDeltaEvent struct {
+	RunInfo
+
+	Text string
+}
+
+// ToolCallStartedEvent is emitted before a tool is executed.
+type ToolCallStartedEvent struct {
+	RunInfo
+
+	ToolCallID string
+	Name       string
+	Arguments  map[string]any
+}
+
+// ToolResultEvent is emitted after a tool returned. Result has IsError set when the
+// tool asked the model to retry.
+type ToolResultEvent struct {
+	RunInfo
+
+	ToolCallID string
+	Name       string
+	Result     *types.ToolResult
+}
+
+// HandoffEvent is emitted when the model handed the conversation off to another agent.
+// The target agent's events follow.
+type HandoffEvent struct {
+	RunInfo
+
+	Name       string // Name of the handoff, see NewHandoff
+	ToolCallID string
+}
+
+// OutputValidatedEvent is emitted when the final output passed validation.
+type OutputValidatedEvent struct {
+	RunInfo
+
+	Output any
+}
+
+// RunFinishedEvent is emitted when the run completed successfully.
+type RunFinishedEvent struct {
+	RunInfo
+
+	AgentName string
+	Output    any
+	Usage     types.Usage
+}
+
+// RunErrorEvent is emitted when the run failed.
+type RunErrorEvent struct {
+	RunInfo
+
+	AgentName string
+	Err       error
+}
+
+func (RunStartedEvent) isEvent()      {}
+func (ModelRequestEvent) isEvent()    {}
+func (TextDeltaEvent) isEvent()       {}
+func (ToolCallStartedEvent) isEvent() {}
+func (ToolResultEvent) isEvent()      {}
+func (HandoffEvent) isEvent()         {}
+func (OutputValidatedEvent) isEvent() {}
+func (RunFinishedEvent) isEvent()     {}
+func (RunErrorEvent) isEvent()        {}
+
+func (e RunStartedEvent) withRunInfo(info RunInfo) Event      { e.RunInfo = info; return e }
+func (e ModelRequestEvent) withRunInfo(info RunInfo) Event    { e.RunInfo = info; return e }
+func (e TextDeltaEvent) withRunInfo(info RunInfo) Event       { e.RunInfo = info; return e }
+func (e ToolCallStartedEvent) withRunInfo(info RunInfo) Event { e.RunInfo = info; return e }
+func (e ToolResultEvent) withRunInfo(info RunInfo) Event      { e.RunInfo = info; return e }
+func (e HandoffEvent) withRunInfo(info RunInfo) Event         { e.RunInfo = info; return e }
+func (e OutputValidatedEvent) withRunInfo(info RunInfo) Event { e.RunInfo = info; return e }
+func (e RunFinishedEvent) withRunInfo(info RunInfo) Event     { e.RunInfo = info; return e }
+func (e RunErrorEvent) withRunInfo(info RunInfo) Event        { e.RunInfo = info; return e }
+
+// WithEventHandler streams the events of a run to handler, e.g. to render live progress.
+func WithEventHandler(handler EventHandler) RunOption {
+	return func(rc *runConfig) {
+		rc.eventHandler = handler
+	}
+}
+
+// emit passes event to the run's event handler, if any, with the run's RunInfo
+func (rc *runConfig) emit(event Event) {
+	if rc.eventHandler != nil {
+		rc.eventHandler(event.withRunInfo(RunInfo{RunID: rc.runID, TraceContext: rc.traceContext}))
+	}
+}
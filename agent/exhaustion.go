@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ErrMaxIterations is returned when a run reaches the agent's maximum iterations without an output.
+var ErrMaxIterations = errors.New("agent exceeded max iterations")
+
+// ExhaustionPolicy decides what a run does when it reaches the maximum iterations.
+type ExhaustionPolicy string
+
+const (
+	// ExhaustionFail returns an error wrapping ErrMaxIterations (default).
+	ExhaustionFail ExhaustionPolicy = "fail"
+
+	// ExhaustionReturnPartial returns the messages and usage so far, with a zero Output and
+	// RunResult.Incomplete set.
+	ExhaustionReturnPartial ExhaustionPolicy = "return_partial"
+
+	// ExhaustionForceFinal asks the model to wrap up with WrapUpPrompt and makes one last
+	// request without tools. The run fails if that answer can't be used as the output.
+	ExhaustionForceFinal ExhaustionPolicy = "force_final"
+)
+
+// WrapUpPrompt is sent before the last request of ExhaustionForceFinal.
+const WrapUpPrompt = "You have reached the maximum number of steps. Do not call any more tools; " +
+	"give your final answer now based on the information you have."
+
+// WithExhaustionPolicy sets what a run does when it reaches the maximum iterations (default ExhaustionFail).
+func WithExhaustionPolicy[TDep, TOut any](policy ExhaustionPolicy) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		switch policy {
+		case ExhaustionFail, ExhaustionReturnPartial, ExhaustionForceFinal:
+			a.exhaustionPolicy = policy
+			return nil
+		default:
+			return fmt.Errorf("unknown exhaustion policy: %q", policy)
+		}
+	}
+}
+
+// forceFinal restricts params of the last ExhaustionForceFinal request: no tools are offered
+// and only the output tool may be called when the output is requested through one
+func forceFinal(params *types.ChatParams) {
+	params.Tools = nil
+	params.ParallelToolCalls = nil
+	if params.ResponseFormat.Schema != nil && params.ResponseFormat.Mode == types.ResponseFormatModeTool {
+		params.ToolChoice = types.ToolChoiceToolWithName(params.ResponseFormat.OutputToolName())
+	} else {
+		params.ToolChoice = types.ToolChoiceNone()
+	}
+}
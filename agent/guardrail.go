@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// GuardrailTripped is returned by guardrails to reject a run. It ends the run and is
+// returned from Run unchanged.
+type GuardrailTripped struct {
+	Reason string
+	Info   any // Optional details for the caller, e.g. the detected categories
+}
+
+func (e *GuardrailTripped) Error() string {
+	return "guardrail tripped: " + e.Reason
+}
+
+// InputGuardrail checks the prompt of a run before the model answers it. Returning a
+// *GuardrailTripped, or any other error, ends the run with that error.
+type InputGuardrail[TDep any] func(ctx context.Context, rc *RunContext[TDep], prompt string) error
+
+// WithInputGuardrail adds a guardrail that checks the prompt before the first model request.
+// Guardrails run in registration order and the first error ends the run.
+func WithInputGuardrail[TDep, TOut any](guardrail InputGuardrail[TDep]) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if guardrail == nil {
+			return errors.New("input guardrail cannot be nil")
+		}
+		a.inputGuardrails = append(a.inputGuardrails, guardrail)
+		return nil
+	}
+}
+
+// WithConcurrentInputGuardrails runs the input guardrails alongside the first model request
+// instead of before it, which hides their latency. A tripped guardrail cancels the request.
+// Text already streamed by RunStream before the guardrail tripped is not taken back.
+func WithConcurrentInputGuardrails[TDep, TOut any]() Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		a.concurrentInputGuardrails = true
+		return nil
+	}
+}
+
+// inputCheck is the result of input guardrails running alongside the first model request
+type inputCheck struct {
+	ctx    context.Context // Context of the first model request, cancelled on a trip
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// wait waits for the guardrails and returns their error
+func (c *inputCheck) wait() error {
+	<-c.done
+	c.cancel()
+	return c.err
+}
+
+// checkInput runs the agent's input guardrails on the prompt of a new run. With concurrent
+// guardrails it returns at once with an inputCheck the first model request must use.
+func (a *Agent[TDep, TOut]) checkInput(ctx context.Context, rc *RunContext[TDep], runCfg *runConfig) (*inputCheck, error) {
+	if len(a.inputGuardrails) == 0 || runCfg.resumed != nil {
+		return nil, nil
+	}
+
+	prompt := runCfg.prompt
+	if prompt == "" {
+		// Runs continuing a conversation check its last user message
+		for i := len(rc.Messages) - 1; i >= 0; i-- {
+			if rc.Messages[i].Role == types.RoleUser {
+				prompt = rc.Messages[i].TextContent()
+				break
+			}
+		}
+	}
+
+	run := func(ctx context.Context) error {
+		for _, guardrail := range a.inputGuardrails {
+			if err := guardrail(ctx, rc, prompt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !a.concurrentInputGuardrails {
+		return nil, run(ctx)
+	}
+
+	check := &inputCheck{done: make(chan struct{})}
+	check.ctx, check.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(check.done)
+		if check.err = run(check.ctx); check.err != nil {
+			check.cancel()
+		}
+	}()
+	return check, nil
+}
+
+// OutputGuardrail checks the output of a run after it was decoded and validated, before Run
+// returns it. Returning a *GuardrailTripped or any other error ends the run, a ModelRetry asks
+// the model for a new output.
+type OutputGuardrail[TDep, TOut any] func(ctx context.Context, rc *RunContext[TDep], output TOut) error
+
+// GuardrailAction decides what a tripped output guardrail does.
+type GuardrailAction string
+
+const (
+	// GuardrailActionFail ends the run with the *GuardrailTripped.
+	GuardrailActionFail GuardrailAction = "fail"
+
+	// GuardrailActionRetry sends the trip's Reason back to the model as output retry feedback.
+	// The run fails once the output retries are exhausted.
+	GuardrailActionRetry GuardrailAction = "retry"
+)
+
+// outputGuardrail is a registered OutputGuardrail with its action on a trip
+type outputGuardrail[TDep, TOut any] struct {
+	check  OutputGuardrail[TDep, TOut]
+	action GuardrailAction
+}
+
+// WithOutputGuardrail adds a guardrail that checks the output, e.g. for PII or policy
+// violations, before it reaches the caller. action decides what a *GuardrailTripped does.
+// Guardrails run in registration order after the output validators.
+func WithOutputGuardrail[TDep, TOut any](guardrail OutputGuardrail[TDep, TOut], action GuardrailAction) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if guardrail == nil {
+			return errors.New("output guardrail cannot be nil")
+		}
+		switch action {
+		case GuardrailActionFail, GuardrailActionRetry:
+		default:
+			return fmt.Errorf("unknown guardrail action: %q", action)
+		}
+		a.outputGuardrails = append(a.outputGuardrails, outputGuardrail[TDep, TOut]{check: guardrail, action: action})
+		return nil
+	}
+}
+
+// checkOutput runs the agent's output guardrails on output. Trips of retrying guardrails
+// are returned as a ModelRetry.
+func (a *Agent[TDep, TOut]) checkOutput(ctx context.Context, rc *RunContext[TDep], output TOut) error {
+	for _, guardrail := range a.outputGuardrails {
+		err := guardrail.check(ctx, rc, output)
+		if err == nil {
+			continue
+		}
+		var tripped *GuardrailTripped
+		if guardrail.action == GuardrailActionRetry && errors.As(err, &tripped) {
+			return NewModelRetry(tripped.Reason)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// Handoff transfers the conversation of a run to another agent, created with NewHandoff.
+type Handoff[TDep, TOut any] struct {
+	definition types.ToolDefinition
+	run        func(ctx context.Context, dep TDep, messages []types.Message, onText func(string) error, opts []RunOption) (*RunResult[TOut], error)
+}
+
+// NewHandoff creates a handoff to target, offered to the model as a tool called name
+// (e.g. "transfer_to_billing") described by description. When the model calls it, target
+// continues the conversation with deps derived by depsMapper, and the run returns target's
+// result. This is the "triage agent routes to a specialist" pattern.
+func NewHandoff[TDep, TTargetDep, TOut any](target *Agent[TTargetDep, TOut], name, description string, depsMapper func(TDep) TTargetDep) (*Handoff[TDep, TOut], error) {
+	if target == nil {
+		return nil, errors.New("handoff target cannot be nil")
+	}
+	if depsMapper == nil {
+		return nil, errors.New("deps mapper cannot be nil")
+	}
+	if name == "" {
+		return nil, errors.New("handoff name cannot be empty")
+	}
+
+	return &Handoff[TDep, TOut]{
+		definition: types.ToolDefinition{
+			Name:        name,
+			Description: description,
+			InputSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+		},
+		run: func(ctx context.Context, dep TDep, messages []types.Message, onText func(string) error, opts []RunOption) (*RunResult[TOut], error) {
+			return target.run(ctx, depsMapper(dep), onText, append(opts, WithMessages(messages)))
+		},
+	}, nil
+}
+
+// WithHandoffs offers handoffs to other agents to the model. Their names must not clash
+// with the agent's tools.
+func WithHandoffs[TDep, TOut any](handoffs ...*Handoff[TDep, TOut]) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		for _, h := range handoffs {
+			if h == nil {
+				return errors.New("handoff cannot be nil")
+			}
+			if _, exists := a.toolMap[h.definition.Name]; exists {
+				return fmt.Errorf("handoff name clashes with tool: %s", h.definition.Name)
+			}
+			a.handoffs = append(a.handoffs, h)
+		}
+		return nil
+	}
+}
+
+// findHandoff returns the first handoff called in msg
+func (a *Agent[TDep, TOut]) findHandoff(msg *types.Message) (*Handoff[TDep, TOut], *types.ToolCall) {
+	for i := range msg.ToolCalls {
+		for _, h := range a.handoffs {
+			if msg.ToolCalls[i].Function.Name == h.definition.Name {
+				return h, &msg.ToolCalls[i]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// handoff answers the tool calls of msg and continues the run with the handoff's target.
+// Other tool calls of the turn are not executed.
+func (a *Agent[TDep, TOut]) handoff(ctx context.Context, rc *RunContext[TDep], runCfg *runConfig, msg *types.Message, h *Handoff[TDep, TOut], call *types.ToolCall) (*RunResult[TOut], error) {
+	for _, tc := range msg.ToolCalls {
+		result := &types.ToolResult{ContentPart: []types.ContentPart{types.NewContentPartText("Transferred to " + h.definition.Name + ".")}}
+		if tc.ID != call.ID {
+			result = &types.ToolResult{
+				ContentPart: []types.ContentPart{types.NewContentPartText("Not executed: the conversation was transferred.")},
+				IsError:     true,
+			}
+		}
+		rc.Messages = append(rc.Messages, types.NewToolResultMessage(tc.ID, result))
+	}
+	runCfg.emit(HandoffEvent{Name: h.definition.Name, ToolCallID: call.ID})
+
+	opts := []RunOption{WithParentRunID(rc.RunID), WithTraceContext(rc.TraceContext)}
+	if runCfg.eventHandler != nil {
+		opts = append(opts, WithEventHandler(runCfg.eventHandler))
+	}
+	if runCfg.trace != nil {
+		opts = append(opts, WithRunTrace(runCfg.trace.trace))
+	}
+	if runCfg.onNode != nil {
+		// Streams and Iter continue with the target's steps
+		opts = append(opts, func(rc *runConfig) { rc.onNode = runCfg.onNode })
+	}
+	result, err := h.run(ctx, rc.Deps, rc.Messages, runCfg.onText, opts)
+	if err != nil {
+		return nil, fmt.Errorf("handoff %s failed: %w", h.definition.Name, err)
+	}
+
+	usage := rc.Usage
+	usage.Add(&result.Usage)
+	result.Usage = usage
+	return result, nil
+}
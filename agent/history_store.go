@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// HistoryStore persists conversation histories keyed by session ID, see WithSession.
+type HistoryStore interface {
+	// Load returns the messages of a session, or none if it doesn't exist yet.
+	Load(ctx context.Context, sessionID string) ([]types.Message, error)
+
+	// Append adds messages to the end of a session.
+	Append(ctx context.Context, sessionID string, messages []types.Message) error
+
+	// Save replaces the messages of a session.
+	Save(ctx context.Context, sessionID string, messages []types.Message) error
+}
+
+// WithSession loads the session's messages from store before the run, ahead of any
+// WithMessages, and appends the messages of the run to it when the run succeeds.
+// A run continued by Agent.Resume saves its full history to the session instead.
+func WithSession(store HistoryStore, sessionID string) RunOption {
+	return func(rc *runConfig) {
+		rc.historyStore = store
+		rc.sessionID = sessionID
+	}
+}
+
+// MemoryHistoryStore is a HistoryStore keeping sessions in memory.
+type MemoryHistoryStore struct {
+	mu       sync.Mutex
+	sessions map[string][]types.Message
+}
+
+// NewMemoryHistoryStore creates an empty in-memory HistoryStore
+func NewMemoryHistoryStore() *MemoryHistoryStore {
+	return &MemoryHistoryStore{sessions: make(map[string][]types.Message)}
+}
+
+// Load implements HistoryStore.
+func (s *MemoryHistoryStore) Load(ctx context.Context, sessionID string) ([]types.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.sessions[sessionID]), nil
+}
+
+// Append implements HistoryStore.
+func (s *MemoryHistoryStore) Append(ctx context.Context, sessionID string, messages []types.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sessionID] = append(s.sessions[sessionID], messages...)
+	return nil
+}
+
+// Save implements HistoryStore.
+func (s *MemoryHistoryStore) Save(ctx context.Context, sessionID string, messages []types.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sessionID] = slices.Clone(messages)
+	return nil
+}
+
+// FileHistoryStore is a HistoryStore keeping each session in a JSON Lines file, one
+// message per line, named after the session ID in a directory.
+type FileHistoryStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileHistoryStore creates a HistoryStore writing sessions to dir, creating it if needed
+func NewFileHistoryStore(dir string) (*FileHistoryStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return &FileHistoryStore{dir: dir}, nil
+}
+
+// Load implements HistoryStore.
+func (s *FileHistoryStore) Load(ctx context.Context, sessionID string) ([]types.Message, error) {
+	path, err := s.path(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	data, err := os.ReadFile(path)
+	s.mu.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", sessionID, err)
+	}
+
+	var messages []types.Message
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var message types.Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return nil, fmt.Errorf("failed to decode session %s: %w", sessionID, err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, scanner.Err()
+}
+
+// Append implements HistoryStore.
+func (s *FileHistoryStore) Append(ctx context.Context, sessionID string, messages []types.Message) error {
+	path, err := s.path(sessionID)
+	if err != nil {
+		return err
+	}
+	data, err := encodeMessageLines(messages)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open session %s: %w", sessionID, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write session %s: %w", sessionID, err)
+	}
+	return f.Close()
+}
+
+// Save implements HistoryStore. The file is replaced atomically.
+func (s *FileHistoryStore) Save(ctx context.Context, sessionID string, messages []types.Message) error {
+	path, err := s.path(sessionID)
+	if err != nil {
+		return err
+	}
+	data, err := encodeMessageLines(messages)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write session %s: %w", sessionID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// path returns the file of a session, rejecting IDs that would escape the directory
+func (s *FileHistoryStore) path(sessionID string) (string, error) {
+	if sessionID == "" || sessionID == "." || sessionID == ".." || strings.ContainsAny(sessionID, `/\`) {
+		return "", fmt.Errorf("invalid session ID: %q", sessionID)
+	}
+	return filepath.Join(s.dir, sessionID+".jsonl"), nil
+}
+
+func encodeMessageLines(messages []types.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	for i := range messages {
+		data, err := json.Marshal(&messages[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// hooks holds the lifecycle callbacks registered on an agent, in registration order
+type hooks[TDep any] struct {
+	modelRequest  []func(ctx context.Context, rc *RunContext[TDep], params *types.ChatParams)
+	modelResponse []func(ctx context.Context, rc *RunContext[TDep], resp *types.ChatResponse)
+	toolStart     []func(ctx context.Context, rc *RunContext[TDep], call types.ToolCall)
+	toolEnd       []func(ctx context.Context, rc *RunContext[TDep], call types.ToolCall, result *types.ToolResult, err error)
+	retry         []func(ctx context.Context, rc *RunContext[TDep], err error)
+}
+
+// WithOnModelRequest calls fn before every model request. fn may adjust params for that request.
+func WithOnModelRequest[TDep, TOut any](fn func(ctx context.Context, rc *RunContext[TDep], params *types.ChatParams)) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("model request hook cannot be nil")
+		}
+		a.hooks.modelRequest = append(a.hooks.modelRequest, fn)
+		return nil
+	}
+}
+
+// WithOnModelResponse calls fn after every successful model response, before its tool calls run.
+func WithOnModelResponse[TDep, TOut any](fn func(ctx context.Context, rc *RunContext[TDep], resp *types.ChatResponse)) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("model response hook cannot be nil")
+		}
+		a.hooks.modelResponse = append(a.hooks.modelResponse, fn)
+		return nil
+	}
+}
+
+// WithOnToolStart calls fn before each tool execution. rc.ToolCallID identifies the call.
+func WithOnToolStart[TDep, TOut any](fn func(ctx context.Context, rc *RunContext[TDep], call types.ToolCall)) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("tool start hook cannot be nil")
+		}
+		a.hooks.toolStart = append(a.hooks.toolStart, fn)
+		return nil
+	}
+}
+
+// WithOnToolEnd calls fn after each tool execution with the tool's result or error.
+func WithOnToolEnd[TDep, TOut any](fn func(ctx context.Context, rc *RunContext[TDep], call types.ToolCall, result *types.ToolResult, err error)) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("tool end hook cannot be nil")
+		}
+		a.hooks.toolEnd = append(a.hooks.toolEnd, fn)
+		return nil
+	}
+}
+
+// WithOnRetry calls fn whenever the run retries: a tool returned ModelRetry or the output
+// failed validation. err is the reason fed back to the model.
+func WithOnRetry[TDep, TOut any](fn func(ctx context.Context, rc *RunContext[TDep], err error)) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("retry hook cannot be nil")
+		}
+		a.hooks.retry = append(a.hooks.retry, fn)
+		return nil
+	}
+}
+
+func (h *hooks[TDep]) onModelRequest(ctx context.Context, rc *RunContext[TDep], params *types.ChatParams) {
+	for _, fn := range h.modelRequest {
+		fn(ctx, rc, params)
+	}
+}
+
+func (h *hooks[TDep]) onModelResponse(ctx context.Context, rc *RunContext[TDep], resp *types.ChatResponse) {
+	for _, fn := range h.modelResponse {
+		fn(ctx, rc, resp)
+	}
+}
+
+func (h *hooks[TDep]) onToolStart(ctx context.Context, rc *RunContext[TDep], call types.ToolCall) {
+	for _, fn := range h.toolStart {
+		fn(ctx, rc, call)
+	}
+}
+
+func (h *hooks[TDep]) onToolEnd(ctx context.Context, rc *RunContext[TDep], call types.ToolCall, result *types.ToolResult, err error) {
+	for _, fn := range h.toolEnd {
+		fn(ctx, rc, call, result, err)
+	}
+}
+
+func (h *hooks[TDep]) onRetry(ctx context.Context, rc *RunContext[TDep], err error) {
+	traceRecorderFrom(ctx).retry(err, false)
+	for _, fn := range h.retry {
+		fn(ctx, rc, err)
+	}
+}
@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// errRunClosed stops a run whose AgentRun was closed before it finished
+var errRunClosed = errors.New("agent run closed")
+
+// Node is a step of a run yielded by AgentRun.Next. It is one of *ModelRequestNode,
+// *ModelResponseNode or *ToolCallNode.
+type Node interface {
+	isNode()
+}
+
+// ModelRequestNode is yielded before a model request. Changes to Params apply to that request.
+type ModelRequestNode[TDep any] struct {
+	RunContext *RunContext[TDep]
+	Params     *types.ChatParams
+}
+
+// ModelResponseNode is yielded after a model response, before a choice is selected from it.
+// Changes to Response are what the run continues with.
+type ModelResponseNode[TDep any] struct {
+	RunContext *RunContext[TDep]
+	Response   *types.ChatResponse
+}
+
+// ToolCallNode is yielded before a tool call is executed. Changes to Call, e.g. its
+// arguments, apply to the execution and are kept in the message history.
+type ToolCallNode[TDep any] struct {
+	RunContext *RunContext[TDep]
+	Call       *types.ToolCall
+}
+
+func (*ModelRequestNode[TDep]) isNode()  {}
+func (*ModelResponseNode[TDep]) isNode() {}
+func (*ToolCallNode[TDep]) isNode()      {}
+
+// step pauses the run at node when it is driven by Agent.Iter
+func (rc *runConfig) step(node Node) error {
+	if rc.onNode == nil {
+		return nil
+	}
+	return rc.onNode(node)
+}
+
+// AgentRun is a run driven step by step, created by Agent.Iter:
+//
+//	run := a.Iter(ctx, deps, agent.WithPrompt("hi"))
+//	defer run.Close()
+//	for run.Next() {
+//		switch node := run.Node().(type) {
+//		case *agent.ToolCallNode[Deps]:
+//			log.Println("calling", node.Call.Function.Name)
+//		}
+//	}
+//	result, err := run.Result()
+type AgentRun[TOut any] struct {
+	next func() (Node, bool)
+	stop func()
+
+	node   Node
+	result *RunResult[TOut]
+	err    error
+}
+
+// Iter starts a run that pauses at every model request, model response and tool call,
+// so callers can inspect or modify the run's state between steps. The run only
+// progresses while Next is called; it must be drained with Result or released with Close.
+func (a *Agent[TDep, TOut]) Iter(ctx context.Context, dep TDep, opts ...RunOption) *AgentRun[TOut] {
+	r := &AgentRun[TOut]{}
+
+	seq := func(yield func(Node) bool) {
+		onNode := func(rc *runConfig) {
+			rc.onNode = func(node Node) error {
+				if !yield(node) {
+					return errRunClosed
+				}
+				return nil
+			}
+		}
+		r.result, r.err = a.run(ctx, dep, nil, append(opts[:len(opts):len(opts)], onNode))
+	}
+	r.next, r.stop = iter.Pull(seq)
+
+	return r
+}
+
+// Next runs until the next step and reports whether there is one. It returns false once
+// the run has finished.
+func (r *AgentRun[TOut]) Next() bool {
+	node, ok := r.next()
+	r.node = node
+	return ok
+}
+
+// Node returns the step reached by the last call to Next.
+func (r *AgentRun[TOut]) Node() Node {
+	return r.node
+}
+
+// Result runs the remaining steps and returns the result of the run.
+func (r *AgentRun[TOut]) Result() (*RunResult[TOut], error) {
+	for r.Next() {
+	}
+	return r.result, r.err
+}
+
+// Close stops the run if it has not finished. Result then reports an error.
+func (r *AgentRun[TOut]) Close() error {
+	r.stop()
+	if r.result == nil && r.err == nil {
+		// Closed before the run started
+		r.err = errRunClosed
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"log/slog"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// WithLogger logs the agent's model requests, responses, retries and tool executions to
+// logger at debug level. Message, argument and result text is only logged with WithLogContent.
+func WithLogger[TDep, TOut any](logger *slog.Logger) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if logger == nil {
+			return errors.New("logger cannot be nil")
+		}
+		l := &runLogger{logger: logger, content: &a.logContent}
+		a.hooks.modelRequest = append(a.hooks.modelRequest, func(ctx context.Context, rc *RunContext[TDep], params *types.ChatParams) {
+			l.log(ctx, rc.RunID, rc.AgentName, "model request",
+				slog.String("model", params.Model),
+				slog.Int("messages", len(params.Messages)),
+				slog.Int("tools", len(params.Tools)),
+			)
+		})
+		a.hooks.modelResponse = append(a.hooks.modelResponse, func(ctx context.Context, rc *RunContext[TDep], resp *types.ChatResponse) {
+			attrs := []slog.Attr{slog.Int64("total_tokens", rc.Usage.TotalTokens)}
+			if len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
+				msg := resp.Choices[0].Message
+				attrs = append(attrs, slog.String("finish_reason", resp.Choices[0].FinishReason), slog.Int("tool_calls", len(msg.ToolCalls)))
+				attrs = append(attrs, l.text("text", msg.TextContent())...)
+			}
+			l.log(ctx, rc.RunID, rc.AgentName, "model response", attrs...)
+		})
+		a.hooks.toolStart = append(a.hooks.toolStart, func(ctx context.Context, rc *RunContext[TDep], call types.ToolCall) {
+			args, _ := json.Marshal(call.Function.Arguments)
+			attrs := []slog.Attr{slog.String("tool", call.Function.Name), slog.String("tool_call_id", call.ID)}
+			l.log(ctx, rc.RunID, rc.AgentName, "tool start", append(attrs, l.text("arguments", string(args))...)...)
+		})
+		a.hooks.toolEnd = append(a.hooks.toolEnd, func(ctx context.Context, rc *RunContext[TDep], call types.ToolCall, result *types.ToolResult, err error) {
+			attrs := []slog.Attr{slog.String("tool", call.Function.Name), slog.String("tool_call_id", call.ID)}
+			if err != nil {
+				attrs = append(attrs, slog.Any("error", err))
+			}
+			if result != nil {
+				attrs = append(attrs, slog.Bool("is_error", result.IsError))
+				attrs = append(attrs, l.text("result", result.TextContent())...)
+			}
+			l.log(ctx, rc.RunID, rc.AgentName, "tool end", attrs...)
+		})
+		a.hooks.retry = append(a.hooks.retry, func(ctx context.Context, rc *RunContext[TDep], err error) {
+			l.log(ctx, rc.RunID, rc.AgentName, "retry", slog.Int("retry", rc.Retry), slog.Any("error", err))
+		})
+		return nil
+	}
+}
+
+// WithLogContent includes message, tool argument and tool result text in the logs of
+// WithLogger, passed through redact first to mask secrets or PII.
+func WithLogContent[TDep, TOut any](redact func(text string) string) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if redact == nil {
+			return errors.New("log content redactor cannot be nil")
+		}
+		a.logContent = redact
+		return nil
+	}
+}
+
+// runLogger writes the debug logs of WithLogger
+type runLogger struct {
+	logger  *slog.Logger
+	content *func(string) string // Read per log so WithLogContent may come after WithLogger
+}
+
+func (l *runLogger) log(ctx context.Context, runID, agentName, msg string, attrs ...slog.Attr) {
+	if !l.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs = append(attrs, slog.String("run_id", runID))
+	if agentName != "" {
+		attrs = append(attrs, slog.String("agent", agentName))
+	}
+	l.logger.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
+
+// text returns the attribute for text if content logging is enabled
+func (l *runLogger) text(key, text string) []slog.Attr {
+	if *l.content == nil {
+		return nil
+	}
+	return []slog.Attr{slog.String(key, (*l.content)(text))}
+}
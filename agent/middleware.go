@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ToolFunc is the signature of Tool.Execute.
+type ToolFunc[TDep any] func(ctx context.Context, rc *RunContext[TDep], args map[string]any) (*types.ToolResult, error)
+
+// ToolMiddleware wraps a tool's execution, e.g. to translate results or add logging.
+type ToolMiddleware[TDep any] func(next ToolFunc[TDep]) ToolFunc[TDep]
+
+// ToolWithMiddleware wraps the tool's Execute with the given middleware.
+// The first middleware is the outermost.
+func ToolWithMiddleware[TDep any](middleware ...ToolMiddleware[TDep]) ToolOption[TDep] {
+	return func(t *Tool[TDep]) {
+		next := ToolFunc[TDep](t.Execute)
+		for i := len(middleware) - 1; i >= 0; i-- {
+			next = middleware[i](next)
+		}
+		t.Execute = next
+	}
+}
+
+// ModelFunc performs a single model request of a run.
+type ModelFunc[TDep any] func(ctx context.Context, rc *RunContext[TDep], params *types.ChatParams) (*types.ChatResponse, error)
+
+// Middleware wraps the model requests and tool executions of every run of an agent, so
+// cross-cutting concerns like logging, caching, rate limiting or redaction compose
+// without changing Run. Register it with WithMiddleware.
+type Middleware[TDep any] interface {
+	// WrapModel wraps the model request made on each iteration.
+	WrapModel(next ModelFunc[TDep]) ModelFunc[TDep]
+
+	// WrapTool wraps the execution of tool. It runs outside of the tool's own ToolMiddleware.
+	WrapTool(tool *Tool[TDep], next ToolFunc[TDep]) ToolFunc[TDep]
+}
+
+// MiddlewareFuncs adapts plain functions to a Middleware. A nil field leaves that
+// step unwrapped.
+type MiddlewareFuncs[TDep any] struct {
+	Model func(next ModelFunc[TDep]) ModelFunc[TDep]
+	Tool  ToolMiddleware[TDep]
+}
+
+// WrapModel implements Middleware.
+func (m MiddlewareFuncs[TDep]) WrapModel(next ModelFunc[TDep]) ModelFunc[TDep] {
+	if m.Model == nil {
+		return next
+	}
+	return m.Model(next)
+}
+
+// WrapTool implements Middleware.
+func (m MiddlewareFuncs[TDep]) WrapTool(_ *Tool[TDep], next ToolFunc[TDep]) ToolFunc[TDep] {
+	if m.Tool == nil {
+		return next
+	}
+	return m.Tool(next)
+}
+
+// WithMiddleware adds middleware to every run of the agent. The first middleware is the outermost.
+func WithMiddleware[TDep, TOut any](middleware ...Middleware[TDep]) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		for _, m := range middleware {
+			if m == nil {
+				return errors.New("middleware cannot be nil")
+			}
+		}
+		a.middleware = append(a.middleware, middleware...)
+		return nil
+	}
+}
+
+// wrapModel applies the agent's middleware to a model request
+func (a *Agent[TDep, TOut]) wrapModel(next ModelFunc[TDep]) ModelFunc[TDep] {
+	for i := len(a.middleware) - 1; i >= 0; i-- {
+		next = a.middleware[i].WrapModel(next)
+	}
+	return next
+}
+
+// wrapTool applies the agent's middleware to the execution of tool
+func (a *Agent[TDep, TOut]) wrapTool(tool *Tool[TDep]) ToolFunc[TDep] {
+	next := ToolFunc[TDep](tool.Execute)
+	for i := len(a.middleware) - 1; i >= 0; i-- {
+		next = a.middleware[i].WrapTool(tool, next)
+	}
+	return next
+}
+
+// DefaultMCPRetryMessages are the error fragments MCPErrorClassifier treats as
+// retryable when no patterns are supplied.
+var DefaultMCPRetryMessages = []string{"rate limit", "temporarily unavailable"}
+
+// MCPErrorClassifier converts tool results with IsError set into a ModelRetry
+// when their text contains one of retryMessages (case-insensitive), so the
+// agent's retry machinery handles transient MCP failures. Other results pass
+// through unchanged. A nil retryMessages uses DefaultMCPRetryMessages.
+func MCPErrorClassifier[TDep any](retryMessages []string) ToolMiddleware[TDep] {
+	if retryMessages == nil {
+		retryMessages = DefaultMCPRetryMessages
+	}
+
+	patterns := make([]string, 0, len(retryMessages))
+	for _, m := range retryMessages {
+		if m != "" {
+			patterns = append(patterns, strings.ToLower(m))
+		}
+	}
+
+	return func(next ToolFunc[TDep]) ToolFunc[TDep] {
+		return func(ctx context.Context, rc *RunContext[TDep], args map[string]any) (*types.ToolResult, error) {
+			result, err := next(ctx, rc, args)
+			if err != nil || result == nil || !result.IsError {
+				return result, err
+			}
+
+			text := result.TextContent()
+			lower := strings.ToLower(text)
+			for _, p := range patterns {
+				if strings.Contains(lower, p) {
+					return nil, NewModelRetry(text)
+				}
+			}
+			return result, nil
+		}
+	}
+}
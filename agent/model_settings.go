@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// WithRunModelSettings applies opts to the ChatParams of every model request of the run,
+// e.g. types.WithSeed or types.WithReasoningEffort. Settings the run manages itself, such
+// as messages, tools and the response format, should not be changed this way.
+func WithRunModelSettings(opts ...types.ChatParamOption) RunOption {
+	return func(rc *runConfig) {
+		rc.modelSettings = append(rc.modelSettings, opts...)
+	}
+}
+
+// WithRunTemperature sets the sampling temperature of the run's model requests.
+func WithRunTemperature(temperature float64) RunOption {
+	return WithRunModelSettings(types.WithTemperature(temperature))
+}
+
+// WithRunMaxTokens limits the tokens generated by each model request of the run.
+func WithRunMaxTokens(maxTokens int) RunOption {
+	return WithRunModelSettings(types.WithMaxTokens(maxTokens))
+}
+
+// WithRunTopP sets the nucleus sampling probability of the run's model requests.
+func WithRunTopP(topP float64) RunOption {
+	return WithRunModelSettings(types.WithTopP(topP))
+}
+
+// WithRunStopSequences stops generation of the run's model requests at any of stop.
+func WithRunStopSequences(stop ...string) RunOption {
+	stop = slices.Clone(stop)
+	return WithRunModelSettings(func(p *types.ChatParams) {
+		p.Stop = slices.Clone(stop)
+	})
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// outputFunc produces the run's output from the arguments the model passes to it
+type outputFunc[TDep, TOut any] struct {
+	description string
+	schema      map[string]any
+	call        func(ctx context.Context, rc *RunContext[TDep], content string) (TOut, error)
+}
+
+// WithOutputFunc makes fn the target of the final answer: the model "calls" it with TArgs
+// as structured output, and fn's return value becomes RunResult.Output. fn may have side
+// effects such as writing to a database, and may return a ModelRetry to make the model
+// correct its arguments, counted against the output retries. Any other error fails the run.
+// description tells the model what the output is for. Without WithResponseFormat the Tool
+// mode is used. It cannot be combined with WithOutputVariants.
+func WithOutputFunc[TDep, TArgs, TOut any](description string, fn func(ctx context.Context, rc *RunContext[TDep], args TArgs) (TOut, error)) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if fn == nil {
+			return errors.New("output function cannot be nil")
+		}
+		schema, err := types.SchemaMapFor[TArgs]()
+		if err != nil {
+			return fmt.Errorf("failed to generate output function schema: %w", err)
+		}
+
+		a.outputFunc = &outputFunc[TDep, TOut]{
+			description: description,
+			schema:      schema,
+			call: func(ctx context.Context, rc *RunContext[TDep], content string) (TOut, error) {
+				var args TArgs
+				if err := json.Unmarshal([]byte(content), &args); err != nil {
+					return *new(TOut), NewModelRetry(fmt.Sprintf("failed to parse output: %v", err))
+				}
+				return fn(ctx, rc, args)
+			},
+		}
+		return nil
+	}
+}
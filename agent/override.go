@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// WithClient sets the client the agent sends its requests to, e.g. a mock with Override.
+func WithClient[TDep, TOut any](client types.Client) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if client == nil {
+			return errors.New("client cannot be nil")
+		}
+		a.client = client
+		return nil
+	}
+}
+
+// WithToolOverrides replaces registered tools with tools of the same name, keeping their
+// position, e.g. to stub out a tool with side effects in tests.
+func WithToolOverrides[TDep, TOut any](tools ...*Tool[TDep]) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		for _, t := range tools {
+			if _, exists := a.toolMap[t.Name]; !exists {
+				return fmt.Errorf("no tool to override: %s", t.Name)
+			}
+			a.toolMap[t.Name] = t
+			i := slices.IndexFunc(a.toolList, func(registered *Tool[TDep]) bool { return registered.Name == t.Name })
+			a.toolList[i] = t
+		}
+		return nil
+	}
+}
+
+// Override returns a copy of the agent with opts applied on top of its configuration, so tests
+// can swap the client, model or tools of an agent constructed by production code:
+//
+//	test, err := prod.Override(
+//		agent.WithClient[Deps, Out](mock),
+//		agent.WithModel[Deps, Out]("test"),
+//	)
+//
+// The original agent is not modified. Options that register something, such as hooks or
+// tools, add to the registrations of the copy. The tool result cache is shared.
+func (a *Agent[TDep, TOut]) Override(opts ...Option[TDep, TOut]) (*Agent[TDep, TOut], error) {
+	c := a.clone()
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// clone copies the agent so options applied to the copy leave the original untouched.
+// Slices are clipped so appending to them reallocates.
+func (a *Agent[TDep, TOut]) clone() *Agent[TDep, TOut] {
+	c := *a
+	c.metadata = maps.Clone(a.metadata)
+	c.toolMap = maps.Clone(a.toolMap)
+	c.toolList = slices.Clone(a.toolList)
+
+	c.instructions = slices.Clip(a.instructions)
+	c.preRunChecks = slices.Clip(a.preRunChecks)
+	c.middleware = slices.Clip(a.middleware)
+	c.outputValidators = slices.Clip(a.outputValidators)
+	c.outputVariants = slices.Clip(a.outputVariants)
+	c.prepareTools = slices.Clip(a.prepareTools)
+	c.handoffs = slices.Clip(a.handoffs)
+	c.inputGuardrails = slices.Clip(a.inputGuardrails)
+	c.outputGuardrails = slices.Clip(a.outputGuardrails)
+	c.historyProcessors = slices.Clip(a.historyProcessors)
+	c.outputSchemaOpts = slices.Clip(a.outputSchemaOpts)
+
+	c.hooks.modelRequest = slices.Clip(a.hooks.modelRequest)
+	c.hooks.modelResponse = slices.Clip(a.hooks.modelResponse)
+	c.hooks.toolStart = slices.Clip(a.hooks.toolStart)
+	c.hooks.toolEnd = slices.Clip(a.hooks.toolEnd)
+	c.hooks.retry = slices.Clip(a.hooks.retry)
+	return &c
+}
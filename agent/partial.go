@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"context"
+	"encoding/json/v2"
+	"strings"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// Partial is a snapshot of the structured output while it streams in. Output holds the
+// fields received so far: a string still being written carries its text so far, other
+// values appear once complete.
+type Partial[TOut any] struct {
+	Output TOut
+	JSON   string // The completed JSON Output was decoded from
+}
+
+// StreamedOutput is an agent run in progress started by RunStreamOutput. Read the partial
+// outputs from Partials, then call Result for the validated final output:
+//
+//	run := a.RunStreamOutput(ctx, deps, agent.WithPrompt("Describe Paris"))
+//	defer run.Close()
+//	for partial := range run.Partials() {
+//		render(partial.Output)
+//	}
+//	result, err := run.Result()
+type StreamedOutput[TOut any] struct {
+	partials chan Partial[TOut]
+	cancel   context.CancelFunc
+
+	result *RunResult[TOut]
+	err    error
+}
+
+// RunStreamOutput runs the agent like RunStream, but incrementally parses the structured
+// output as the model writes it and emits a typed Partial whenever more of it arrived.
+// The full output is still validated at the end and returned by Result.
+//
+// Partials are only emitted in the Native and Prompted response format modes, where the
+// output streams as text; in Tool mode the output only arrives with the result.
+// The returned StreamedOutput must be drained or closed to release the run.
+func (a *Agent[TDep, TOut]) RunStreamOutput(ctx context.Context, dep TDep, opts ...RunOption) *StreamedOutput[TOut] {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &StreamedOutput[TOut]{
+		partials: make(chan Partial[TOut]),
+		cancel:   cancel,
+	}
+
+	var text strings.Builder
+	var last string
+	// Every model request starts a new output, e.g. after tool calls or an output retry
+	resetOnRequest := func(rc *runConfig) {
+		next := rc.eventHandler
+		rc.eventHandler = func(event Event) {
+			if _, ok := event.(ModelRequestEvent); ok {
+				text.Reset()
+				last = ""
+			}
+			if next != nil {
+				next(event)
+			}
+		}
+	}
+
+	go func() {
+		defer close(s.partials)
+		s.result, s.err = a.run(ctx, dep, func(delta string) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			text.WriteString(delta)
+			partial, ok := parsePartial[TOut](text.String(), a.outputSchemaOpts)
+			if !ok || partial.JSON == last {
+				return nil
+			}
+			last = partial.JSON
+			select {
+			case s.partials <- partial:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, append(opts[:len(opts):len(opts)], resetOnRequest))
+	}()
+
+	return s
+}
+
+// Partials returns the partial outputs of the run. The channel is closed once the run has finished.
+func (s *StreamedOutput[TOut]) Partials() <-chan Partial[TOut] {
+	return s.partials
+}
+
+// Result waits for the run to finish, discarding unread partials, and returns its result.
+func (s *StreamedOutput[TOut]) Result() (*RunResult[TOut], error) {
+	for range s.partials {
+	}
+	return s.result, s.err
+}
+
+// Close cancels the run if it is still in progress and waits for it to stop.
+func (s *StreamedOutput[TOut]) Close() error {
+	s.cancel()
+	for range s.partials {
+	}
+	return nil
+}
+
+// parsePartial decodes the JSON text streamed so far into TOut, restoring the property names
+// of the output schema options. Text before the first '{' or '[', such as a Markdown code
+// fence, is skipped.
+func parsePartial[TOut any](text string, opts []types.SchemaOption) (Partial[TOut], bool) {
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return Partial[TOut]{}, false
+	}
+	for _, candidate := range completeJSON(text[start:]) {
+		data, err := types.RestorePropertyNames([]byte(candidate), opts...)
+		if err != nil {
+			continue
+		}
+		var output TOut
+		if err := json.Unmarshal(data, &output); err == nil {
+			return Partial[TOut]{Output: output, JSON: candidate}, true
+		}
+	}
+	return Partial[TOut]{}, false
+}
+
+// completeJSON closes the open strings, arrays and objects of a JSON prefix. It returns the
+// prefix completed as is, which keeps a string value that is still being written, followed by
+// the prefix cut back to its last complete value for when the tail is an unfinished key,
+// number or literal. Text after the top-level value is ignored.
+func completeJSON(prefix string) []string {
+	var (
+		closers  []byte // Closing bracket of every open container, innermost last
+		inString bool
+		escaped  bool
+		unicode  = -1 // Start of the string's last \u escape
+		cut      = -1 // End of the last complete value inside a container
+		cutDepth int
+	)
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+				if c == 'u' {
+					unicode = i - 1
+				}
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			if c == '{' {
+				closers = append(closers, '}')
+			} else {
+				closers = append(closers, ']')
+			}
+			cut, cutDepth = i+1, len(closers)
+		case '}', ']':
+			if len(closers) == 0 {
+				return nil
+			}
+			closers = closers[:len(closers)-1]
+			if len(closers) == 0 {
+				return []string{prefix[:i+1]}
+			}
+			cut, cutDepth = i+1, len(closers)
+		case ',':
+			cut, cutDepth = i, len(closers)
+		}
+	}
+
+	var candidates []string
+	if inString {
+		completed := prefix
+		// Drop an escape sequence that is cut off
+		if escaped {
+			completed = completed[:len(completed)-1]
+		} else if unicode >= 0 && len(completed)-unicode < 6 {
+			completed = completed[:unicode]
+		}
+		candidates = append(candidates, completed+`"`+closing(closers))
+	} else if trimmed := strings.TrimRight(prefix, " \t\r\n"); strings.ContainsRune(`"{}[]`, rune(trimmed[len(trimmed)-1])) {
+		// Otherwise a number or literal at the end may still be growing
+		candidates = append(candidates, trimmed+closing(closers))
+	}
+	if cut >= 0 {
+		candidates = append(candidates, prefix[:cut]+closing(closers[:cutDepth]))
+	}
+	return candidates
+}
+
+// closing returns the brackets that close closers, innermost first
+func closing(closers []byte) string {
+	b := make([]byte, len(closers))
+	for i, c := range closers {
+		b[len(closers)-1-i] = c
+	}
+	return string(b)
+}
@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"github.com/KennyKeni/elysia/types"
+)
+
+// WithPromptCaching marks the system prompt and tool definitions of every model request as
+// cacheable with cc, so providers with explicit prompt caching (Anthropic) bill them at the
+// cached rate from the second request of a run on. Messages can be marked with
+// types.WithCacheControl.
+func WithPromptCaching[TDep, TOut any](cc types.CacheControl) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		a.promptCache = &cc
+		return nil
+	}
+}
+
+// markCacheable sets the agent's prompt caching on params
+func (a *Agent[TDep, TOut]) markCacheable(params *types.ChatParams) {
+	if a.promptCache == nil {
+		return
+	}
+	if params.SystemPrompt != "" {
+		cc := *a.promptCache
+		params.SystemCacheControl = &cc
+	}
+	if len(params.Tools) > 0 {
+		cc := *a.promptCache
+		params.ToolsCacheControl = &cc
+	}
+}
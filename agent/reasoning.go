@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// WithReasoningHistory sets whether the reasoning parts of earlier assistant messages are
+// sent back to the model (default true). Adapters only send reasoning their provider can
+// verify, such as signed Anthropic thinking, which extended thinking with tools requires;
+// disable it to save tokens with providers that accept unsigned reasoning. Reasoning is
+// kept in RunResult messages either way.
+func WithReasoningHistory[TDep, TOut any](include bool) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		a.stripReasoning = !include
+		return nil
+	}
+}
+
+// stripReasoning returns a copy of messages without their reasoning parts, leaving messages
+// unmodified
+func stripReasoning(messages []types.Message) []types.Message {
+	stripped := slices.Clone(messages)
+	for i := range stripped {
+		isReasoning := func(part types.ContentPart) bool {
+			_, ok := part.(*types.ContentPartReasoning)
+			return ok
+		}
+		if slices.ContainsFunc(stripped[i].ContentPart, isReasoning) {
+			stripped[i].ContentPart = slices.DeleteFunc(slices.Clone(stripped[i].ContentPart), isReasoning)
+		}
+	}
+	return stripped
+}
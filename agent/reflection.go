@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ReflectionApproved is the reply with which the critic accepts an output.
+const ReflectionApproved = "APPROVED"
+
+// DefaultCriticPrompt is the critic's system prompt when WithReflection is given none.
+const DefaultCriticPrompt = "You review answers written by an assistant. Check the answer against the instructions, " +
+	"the user's request and the expected schema, if any. If the answer is correct and complete, reply with " +
+	ReflectionApproved + " and nothing else. Otherwise list the problems briefly so the assistant can fix them."
+
+// reflection configures the critique pass of WithReflection
+type reflection struct {
+	maxRetries   int
+	criticPrompt string
+}
+
+// WithReflection adds a critique pass: after the agent produced an output, the model reviews
+// it against the instructions and schema under criticPrompt (empty = DefaultCriticPrompt).
+// Unless the critic replies with ReflectionApproved, its critique is sent back and the output
+// regenerated, at most maxRetries times per run; the last output is kept after that.
+// Critique requests count towards the run's Usage and each regeneration towards its iterations.
+func WithReflection[TDep, TOut any](maxRetries int, criticPrompt string) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if maxRetries < 1 {
+			return errors.New("reflection retries must be at least 1")
+		}
+		if criticPrompt == "" {
+			criticPrompt = DefaultCriticPrompt
+		}
+		a.reflection = &reflection{maxRetries: maxRetries, criticPrompt: criticPrompt}
+		return nil
+	}
+}
+
+// critique asks the model to review answer and returns its critique, empty if it approved.
+// The request sees the run's model settings, model hooks and middleware.
+func (a *Agent[TDep, TOut]) critique(ctx context.Context, rc *RunContext[TDep], runCfg *runConfig, systemPrompt string, schema map[string]any, answer string) (string, error) {
+	var review strings.Builder
+	if systemPrompt != "" {
+		fmt.Fprintf(&review, "Instructions:\n%s\n\n", systemPrompt)
+	}
+	if rc.Prompt != "" {
+		fmt.Fprintf(&review, "Request:\n%s\n\n", rc.Prompt)
+	}
+	if schema != nil {
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode schema for reflection: %w", err)
+		}
+		fmt.Fprintf(&review, "Expected schema:\n%s\n\n", data)
+	}
+	fmt.Fprintf(&review, "Answer:\n%s", answer)
+
+	params := &types.ChatParams{
+		Model:        a.model,
+		SystemPrompt: a.reflection.criticPrompt,
+		Messages:     []types.Message{types.NewUserMessage(types.WithText(review.String()))},
+	}
+	for _, opt := range runCfg.modelSettings {
+		opt(params)
+	}
+	a.hooks.onModelRequest(ctx, rc, params)
+
+	// The critique goes through the model middleware like any other request,
+	// but is never streamed as part of the answer
+	model := a.wrapModel(func(ctx context.Context, rc *RunContext[TDep], params *types.ChatParams) (*types.ChatResponse, error) {
+		return a.chatWithRetry(ctx, params, nil)
+	})
+	resp, err := model(ctx, rc, params)
+	if err != nil {
+		return "", fmt.Errorf("reflection failed: %w", err)
+	}
+	a.hooks.onModelResponse(ctx, rc, resp)
+	if resp.Usage != nil {
+		rc.Usage.Add(resp.Usage)
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+		return "", errors.New("reflection failed: empty response")
+	}
+
+	text := strings.TrimSpace(resp.Choices[0].Message.TextContent())
+	if strings.HasPrefix(text, ReflectionApproved) {
+		return "", nil
+	}
+	return text, nil
+}
+
+// reflectionFeedback asks the model to revise its output after a critique
+func reflectionFeedback(critique string) string {
+	return "A reviewer found problems with your answer:\n" + critique + "\n\nPlease give an improved answer."
+}
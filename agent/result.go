@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"encoding/json/v2"
+	"fmt"
+	"os"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// runResultJSON is the wire representation of a RunResult.
+type runResultJSON[TOut any] struct {
+	Output   TOut            `json:"output"`
+	Messages []types.Message `json:"messages"`
+	Usage    types.Usage     `json:"usage"`
+
+	OutputVariant string `json:"output_variant,omitempty"`
+	Incomplete    bool   `json:"incomplete,omitempty"`
+
+	Aborted *RunAborted `json:"aborted,omitempty"`
+	Trace   *RunTrace   `json:"trace,omitempty"`
+
+	AgentName     string            `json:"agent_name,omitempty"`
+	AgentMetadata map[string]string `json:"agent_metadata,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler so a RunResult can be cached between processes.
+// Output is encoded with the standard JSON encoding of TOut.
+func (r RunResult[TOut]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(runResultJSON[TOut]{
+		Output:   r.Output,
+		Messages: r.Messages,
+		Usage:    r.Usage,
+
+		OutputVariant: r.OutputVariant,
+		Incomplete:    r.Incomplete,
+		Aborted:       r.Aborted,
+		Trace:         r.Trace,
+
+		AgentName:     r.AgentName,
+		AgentMetadata: r.AgentMetadata,
+	})
+}
+
+// UnmarshalRunResult decodes a RunResult previously encoded with MarshalJSON.
+func UnmarshalRunResult[TOut any](data []byte) (*RunResult[TOut], error) {
+	var wire runResultJSON[TOut]
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run result: %w", err)
+	}
+
+	return &RunResult[TOut]{
+		Output:   wire.Output,
+		Messages: wire.Messages,
+		Usage:    wire.Usage,
+
+		OutputVariant: wire.OutputVariant,
+		Incomplete:    wire.Incomplete,
+		Aborted:       wire.Aborted,
+		Trace:         wire.Trace,
+
+		AgentName:     wire.AgentName,
+		AgentMetadata: wire.AgentMetadata,
+	}, nil
+}
+
+// SaveToFile writes the JSON encoding of the RunResult to path.
+func (r *RunResult[TOut]) SaveToFile(path string) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run result: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write run result: %w", err)
+	}
+	return nil
+}
+
+// LoadRunResultFromFile reads a RunResult previously written with SaveToFile.
+func LoadRunResultFromFile[TOut any](path string) (*RunResult[TOut], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run result: %w", err)
+	}
+	return UnmarshalRunResult[TOut](data)
+}
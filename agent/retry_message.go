@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ErrNoStructuredOutput is passed to the OutputRetryMessageBuilder when the model
+// responded without the structured output required by the response format.
+var ErrNoStructuredOutput = errors.New("expected structured output but received none")
+
+// MetadataRetryFeedback is the types.Message metadata key set to true on the messages telling
+// the LLM why its output was rejected, so history stores and UIs can hide them.
+const MetadataRetryFeedback = "retry_feedback"
+
+// OutputRetryMessageBuilder builds the feedback text sent to the LLM when output
+// validation fails. attempt is the 1-based retry number.
+type OutputRetryMessageBuilder func(attempt int, err error, schema map[string]any) string
+
+// OutputRetryPrompt builds the whole feedback message sent to the LLM when output
+// validation fails, see WithOutputRetryPrompt.
+type OutputRetryPrompt func(err error, attempt int) types.Message
+
+// OutputRetryError is passed to an OutputRetryPrompt. It wraps the validation error with
+// the schema the output was checked against.
+type OutputRetryError struct {
+	Err    error
+	Schema map[string]any // nil when no response format is configured
+}
+
+func (e *OutputRetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OutputRetryError) Unwrap() error {
+	return e.Err
+}
+
+// FailedSchema returns the parts of Schema describing the properties that failed validation,
+// keyed by their dotted path, e.g. {"address.zip": {"type": "string"}}. It is empty unless Err
+// is a *types.SchemaValidationError listing the failing fields.
+func (e *OutputRetryError) FailedSchema() map[string]any {
+	excerpt := make(map[string]any)
+	var schemaErr *types.SchemaValidationError
+	if !errors.As(e.Err, &schemaErr) {
+		return excerpt
+	}
+	for _, field := range schemaErr.Fields {
+		schema := e.Schema
+		for name := range strings.SplitSeq(field, ".") {
+			properties, _ := schema["properties"].(map[string]any)
+			schema, _ = properties[name].(map[string]any)
+		}
+		if schema != nil {
+			excerpt[field] = schema
+		}
+	}
+	return excerpt
+}
+
+// DefaultOutputRetryMessage is the default OutputRetryMessageBuilder. It includes the
+// attempt number, the validation error, the failing fields (for SchemaValidationError)
+// and a summary of the expected schema.
+func DefaultOutputRetryMessage(attempt int, err error, schema map[string]any) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Output validation failed (attempt %d): %v.", attempt, err)
+
+	var schemaErr *types.SchemaValidationError
+	if errors.As(err, &schemaErr) && len(schemaErr.Fields) > 0 {
+		fmt.Fprintf(&sb, "\nFields with errors: %s.", strings.Join(schemaErr.Fields, ", "))
+	}
+
+	if summary := summarizeSchema(schema); summary != "" {
+		fmt.Fprintf(&sb, "\nExpected output: %s.", summary)
+	}
+
+	sb.WriteString("\nPlease try again and provide output matching the required format.")
+	return sb.String()
+}
+
+// summarizeSchema renders a one-line description of an object schema's properties,
+// e.g. "object with name (string, required), age (integer)".
+func summarizeSchema(schema map[string]any) string {
+	if schema == nil {
+		return ""
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	if len(properties) == 0 {
+		schemaJSON, err := json.Marshal(schema)
+		if err != nil {
+			return ""
+		}
+		return string(schemaJSON)
+	}
+
+	required := make(map[string]bool)
+	if list, ok := schema["required"].([]any); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	fields := make([]string, 0, len(names))
+	for _, name := range names {
+		var attrs []string
+		if prop, ok := properties[name].(map[string]any); ok {
+			if t := schemaType(prop); t != "" {
+				attrs = append(attrs, t)
+			}
+		}
+		if required[name] {
+			attrs = append(attrs, "required")
+		}
+		if len(attrs) > 0 {
+			fields = append(fields, fmt.Sprintf("%s (%s)", name, strings.Join(attrs, ", ")))
+		} else {
+			fields = append(fields, name)
+		}
+	}
+
+	return "object with " + strings.Join(fields, ", ")
+}
+
+// schemaType returns the "type" keyword of a schema, joining union types with "|".
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, "|")
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// RunTrace is a record of everything a run sent and received, for postmortems and prompt
+// debugging. It is plain data and can be encoded as JSON. See WithRunTrace.
+type RunTrace struct {
+	RunID     string    `json:"run_id"`
+	AgentName string    `json:"agent_name,omitempty"`
+	Started   time.Time `json:"started"`
+	Ended     time.Time `json:"ended"`
+	Error     string    `json:"error,omitempty"` // Why the run failed
+
+	ModelCalls []ModelCallTrace `json:"model_calls"`
+	ToolCalls  []ToolCallTrace  `json:"tool_calls"`
+	Retries    []RetryTrace     `json:"retries"`
+}
+
+// Duration returns how long the run took.
+func (t *RunTrace) Duration() time.Duration {
+	return t.Ended.Sub(t.Started)
+}
+
+// ModelCallTrace records one model request of a run, including the transient retries
+// made for it.
+type ModelCallTrace struct {
+	Step     int                 `json:"step"` // 1-based number of the request within the run
+	Started  time.Time           `json:"started"`
+	Ended    time.Time           `json:"ended"`
+	Params   *types.ChatParams   `json:"params"`
+	Response *types.ChatResponse `json:"response,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// Duration returns how long the request took.
+func (c ModelCallTrace) Duration() time.Duration {
+	return c.Ended.Sub(c.Started)
+}
+
+// ToolCallTrace records one tool execution. Result is the text of the tool's result, and
+// Error the error it returned, e.g. a ModelRetry.
+type ToolCallTrace struct {
+	ToolCallID string         `json:"tool_call_id"`
+	Name       string         `json:"name"`
+	Arguments  map[string]any `json:"arguments"`
+	Started    time.Time      `json:"started"`
+	Ended      time.Time      `json:"ended"`
+	Result     string         `json:"result,omitempty"`
+	IsError    bool           `json:"is_error,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Duration returns how long the tool took.
+func (c ToolCallTrace) Duration() time.Duration {
+	return c.Ended.Sub(c.Started)
+}
+
+// RetryTrace records a retry: an error sent back to the model (tool, output or unknown tool
+// retries), or a model request repeated after a transient error.
+type RetryTrace struct {
+	Time      time.Time `json:"time"`
+	Error     string    `json:"error"`
+	Transient bool      `json:"transient,omitempty"` // The model request was repeated
+}
+
+// WithRunTrace records the run into trace and sets RunResult.Trace to it. trace is filled in
+// even when the run fails, which RunResult can't report; pass nil to only get
+// RunResult.Trace. Handoff targets record into the same trace.
+func WithRunTrace(trace *RunTrace) RunOption {
+	return func(rc *runConfig) {
+		if trace == nil {
+			trace = &RunTrace{}
+		}
+		rc.trace = &traceRecorder{trace: trace}
+	}
+}
+
+// traceRecorder fills in a RunTrace. Its methods do nothing on a nil recorder, so call
+// sites need not check whether the run is traced.
+type traceRecorder struct {
+	mu    sync.Mutex
+	trace *RunTrace
+}
+
+type traceRecorderKey struct{}
+
+// withTraceRecorder makes t reachable from retries deep in the run. A nil t hides the
+// recorder of an enclosing run, e.g. from an agent delegated to as a tool.
+func withTraceRecorder(ctx context.Context, t *traceRecorder) context.Context {
+	return context.WithValue(ctx, traceRecorderKey{}, t)
+}
+
+func traceRecorderFrom(ctx context.Context) *traceRecorder {
+	t, _ := ctx.Value(traceRecorderKey{}).(*traceRecorder)
+	return t
+}
+
+// start records the start of a run; a handoff target keeps the trace of the run it continues
+func (t *traceRecorder) start(runID, agentName string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.trace.RunID == "" {
+		t.trace.RunID = runID
+		t.trace.AgentName = agentName
+		t.trace.Started = time.Now()
+	}
+}
+
+// finish records the end of the run and returns the trace
+func (t *traceRecorder) finish(err error) *RunTrace {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace.Ended = time.Now()
+	if err != nil {
+		t.trace.Error = err.Error()
+	}
+	return t.trace
+}
+
+func (t *traceRecorder) modelCall(step int, started time.Time, params *types.ChatParams, resp *types.ChatResponse, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace.ModelCalls = append(t.trace.ModelCalls, ModelCallTrace{
+		Step:     step,
+		Started:  started,
+		Ended:    time.Now(),
+		Params:   params,
+		Response: resp,
+		Error:    errorString(err),
+	})
+}
+
+func (t *traceRecorder) toolCall(call types.ToolCall, started time.Time, result *types.ToolResult, err error) {
+	if t == nil {
+		return
+	}
+	traced := ToolCallTrace{
+		ToolCallID: call.ID,
+		Name:       call.Function.Name,
+		Arguments:  call.Function.Arguments,
+		Started:    started,
+		Ended:      time.Now(),
+		Error:      errorString(err),
+	}
+	if result != nil {
+		traced.Result = result.TextContent()
+		traced.IsError = result.IsError
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace.ToolCalls = append(t.trace.ToolCalls, traced)
+}
+
+func (t *traceRecorder) retry(err error, transient bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace.Retries = append(t.trace.Retries, RetryTrace{Time: time.Now(), Error: errorString(err), Transient: transient})
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
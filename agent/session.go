@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// Session is an in-memory conversation with an agent. Every Send continues from the messages
+// of the previous turns and records the new ones, so callers don't thread RunResult.Messages
+// through WithMessages themselves. To persist conversations, see WithSession and HistoryStore.
+//
+// A Session is safe for concurrent use; turns are serialized.
+type Session[TDep, TOut any] struct {
+	agent *Agent[TDep, TOut]
+	deps  TDep
+
+	mu       sync.Mutex
+	messages []types.Message
+	usage    types.Usage
+}
+
+// NewSession starts a conversation with agent, optionally continuing from history.
+func NewSession[TDep, TOut any](agent *Agent[TDep, TOut], deps TDep, history ...types.Message) *Session[TDep, TOut] {
+	return &Session[TDep, TOut]{
+		agent:    agent,
+		deps:     deps,
+		messages: slices.Clone(history),
+	}
+}
+
+// Send runs the agent on prompt after the conversation so far. On success the turn's messages
+// are added to the conversation; a failed turn leaves it unchanged. opts apply to this turn only
+// and must not set the prompt or messages.
+func (s *Session[TDep, TOut]) Send(ctx context.Context, prompt string, opts ...RunOption) (*RunResult[TOut], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	opts = append(opts[:len(opts):len(opts)], WithMessages(slices.Clone(s.messages)), WithPrompt(prompt))
+	result, err := s.agent.Run(ctx, s.deps, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.messages = slices.Clone(result.Messages)
+	s.usage.Add(&result.Usage)
+	return result, nil
+}
+
+// Messages returns a copy of the conversation so far.
+func (s *Session[TDep, TOut]) Messages() []types.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.messages)
+}
+
+// Usage returns the usage summed over the session's turns.
+func (s *Session[TDep, TOut]) Usage() types.Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
+// Reset clears the conversation and its usage.
+func (s *Session[TDep, TOut]) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+	s.usage = types.Usage{}
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"iter"
+)
+
+// StreamedRun is an agent run in progress started by RunStream. Iterate it with Next
+// to receive the assistant text deltas, then call Result for the final output:
+//
+//	run := a.RunStream(ctx, deps, agent.WithPrompt("hi"))
+//	defer run.Close()
+//	for run.Next() {
+//		fmt.Print(run.Delta())
+//	}
+//	result, err := run.Result()
+type StreamedRun[TOut any] struct {
+	deltas chan string
+	cancel context.CancelFunc
+
+	delta  string
+	result *RunResult[TOut]
+	err    error
+}
+
+// RunStream runs the agent like Run, but streams every model request and emits the
+// assistant text deltas to the caller. Tool calls are accumulated from the stream and
+// executed between turns exactly as in Run.
+//
+// With WithChoiceSelector only the deltas of the first choice are emitted.
+// The returned StreamedRun must be drained or closed to release the run.
+func (a *Agent[TDep, TOut]) RunStream(ctx context.Context, dep TDep, opts ...RunOption) *StreamedRun[TOut] {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &StreamedRun[TOut]{
+		deltas: make(chan string),
+		cancel: cancel,
+	}
+
+	go func() {
+		defer close(s.deltas)
+		s.result, s.err = a.run(ctx, dep, func(delta string) error {
+			// Checked first so a closed run stops even while Close is draining deltas
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			select {
+			case s.deltas <- delta:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, opts)
+	}()
+
+	return s
+}
+
+// Next advances to the next text delta. It returns false once the run has finished.
+func (s *StreamedRun[TOut]) Next() bool {
+	delta, ok := <-s.deltas
+	if !ok {
+		s.delta = ""
+		return false
+	}
+	s.delta = delta
+	return true
+}
+
+// Delta returns the text delta read by the last call to Next.
+func (s *StreamedRun[TOut]) Delta() string {
+	return s.delta
+}
+
+// Result waits for the run to finish, discarding unread deltas, and returns its result.
+func (s *StreamedRun[TOut]) Result() (*RunResult[TOut], error) {
+	for s.Next() {
+	}
+	return s.result, s.err
+}
+
+// Close cancels the run if it is still in progress and waits for it to stop.
+func (s *StreamedRun[TOut]) Close() error {
+	s.cancel()
+	for s.Next() {
+	}
+	return nil
+}
+
+// StreamText runs the agent like RunStream and yields only the assistant text deltas of
+// the whole run, including the turns around tool calls. A failed run yields its error last:
+//
+//	for delta, err := range a.StreamText(ctx, deps, agent.WithPrompt("hi")) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Print(delta)
+//	}
+//
+// Breaking out of the loop cancels the run.
+func (a *Agent[TDep, TOut]) StreamText(ctx context.Context, dep TDep, opts ...RunOption) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		run := a.RunStream(ctx, dep, opts...)
+		defer run.Close()
+		for run.Next() {
+			if !yield(run.Delta(), nil) {
+				return
+			}
+		}
+		if _, err := run.Result(); err != nil {
+			yield("", err)
+		}
+	}
+}
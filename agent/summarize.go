@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// SummaryPrompt is the system prompt of the requests generating conversation summaries.
+const SummaryPrompt = "Summarize the conversation transcript you are given. Keep every fact, decision, " +
+	"tool result and open question needed to continue the conversation; leave out pleasantries. " +
+	"Reply with the summary only."
+
+// SummaryPrefix starts the message that replaces summarized history.
+const SummaryPrefix = "Summary of the earlier conversation:\n"
+
+// maxCachedSummaries bounds the summaries a summarizer keeps for reuse
+const maxCachedSummaries = 16
+
+// Summarize returns a HistoryProcessor that, once the history exceeds threshold tokens as
+// counted by counter (nil = types.HeuristicTokenCounter), replaces the oldest messages with a
+// summary generated by model. The newest turns fitting in half the threshold are kept verbatim,
+// and tool calls are never separated from their results. Summaries are cached and extended
+// incrementally, so a long conversation is not re-summarized on every request.
+//
+// If generating the summary fails the history is sent unchanged. The usage of summary
+// requests is not counted in the run's usage.
+func Summarize(client types.Client, model string, threshold int, counter types.TokenCounter) HistoryProcessor {
+	if counter == nil {
+		counter = types.HeuristicTokenCounter{}
+	}
+	s := &summarizer{model: model, threshold: threshold}
+	return func(ctx context.Context, messages []types.Message) []types.Message {
+		return s.process(ctx, client, counter, messages)
+	}
+}
+
+// WithSummarizer summarizes older messages once the history exceeds threshold tokens, using
+// model (typically a cheaper one) through the agent's client. Tokens are counted with the
+// counter set by WithTokenCounter. See Summarize.
+func WithSummarizer[TDep, TOut any](model string, threshold int) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if model == "" {
+			return errors.New("summary model cannot be empty")
+		}
+		if threshold <= 0 {
+			return fmt.Errorf("summary threshold must be positive, got %d", threshold)
+		}
+		s := &summarizer{model: model, threshold: threshold}
+		a.historyProcessors = append(a.historyProcessors, func(ctx context.Context, a *Agent[TDep, TOut], messages []types.Message) []types.Message {
+			// Resolved per request so WithClient and WithTokenCounter may come after this
+			// option or in Override
+			var counter types.TokenCounter = types.HeuristicTokenCounter{}
+			if a.tokenCounter != nil {
+				counter = a.tokenCounter
+			}
+			return s.process(ctx, a.client, counter, messages)
+		})
+		return nil
+	}
+}
+
+type summarizer struct {
+	model     string
+	threshold int
+
+	mu        sync.Mutex
+	summaries []summary // Oldest first
+}
+
+// summary replaces the first prefixLen messages of a history whose transcript hashes to key
+type summary struct {
+	prefixLen int
+	key       [sha256.Size]byte
+	text      string
+}
+
+func (s *summarizer) process(ctx context.Context, client types.Client, counter types.TokenCounter, messages []types.Message) []types.Message {
+	if counter.CountMessages(messages) <= s.threshold {
+		return messages
+	}
+
+	// Reuse a summary of this history if the result still fits
+	previous := s.lookup(messages)
+	if previous != nil {
+		candidate := withSummary(previous.text, messages[previous.prefixLen:])
+		if counter.CountMessages(candidate) <= s.threshold {
+			return candidate
+		}
+	}
+
+	// Keep the newest turns fitting in half the threshold, and at least the latest one
+	turns := splitTurns(messages)
+	keep := len(turns) - 1
+	for keep > 0 && counter.CountMessages(joinTurns(turns[keep-1:])) <= s.threshold/2 {
+		keep--
+	}
+	cut := len(messages) - len(joinTurns(turns[keep:]))
+	if cut == 0 {
+		return messages
+	}
+
+	transcript := renderTranscript(messages[:cut])
+	if previous != nil && previous.prefixLen <= cut {
+		transcript = SummaryPrefix + previous.text + "\n\n" + renderTranscript(messages[previous.prefixLen:cut])
+	}
+	text, err := s.summarize(ctx, client, transcript)
+	if err != nil {
+		return messages
+	}
+
+	s.store(summary{prefixLen: cut, key: sha256.Sum256([]byte(renderTranscript(messages[:cut]))), text: text})
+	return withSummary(text, messages[cut:])
+}
+
+// lookup returns the cached summary covering the longest prefix of messages, or nil
+func (s *summarizer) lookup(messages []types.Message) *summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found *summary
+	for i := range s.summaries {
+		sum := &s.summaries[i]
+		if sum.prefixLen >= len(messages) || (found != nil && sum.prefixLen <= found.prefixLen) {
+			continue
+		}
+		if sha256.Sum256([]byte(renderTranscript(messages[:sum.prefixLen]))) == sum.key {
+			found = sum
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	result := *found
+	return &result
+}
+
+func (s *summarizer) store(sum summary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.summaries) == maxCachedSummaries {
+		s.summaries = s.summaries[1:]
+	}
+	s.summaries = append(s.summaries, sum)
+}
+
+func (s *summarizer) summarize(ctx context.Context, client types.Client, transcript string) (string, error) {
+	resp, err := client.Chat(ctx, &types.ChatParams{
+		Model:        s.model,
+		SystemPrompt: SummaryPrompt,
+		Messages:     []types.Message{types.NewUserMessage(types.WithText(transcript))},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+		return "", errors.New("no response from model")
+	}
+	text := strings.TrimSpace(resp.Choices[0].Message.TextContent())
+	if text == "" {
+		return "", errors.New("empty summary")
+	}
+	return text, nil
+}
+
+// withSummary prepends a summary message to the kept messages
+func withSummary(text string, kept []types.Message) []types.Message {
+	messages := make([]types.Message, 0, len(kept)+1)
+	messages = append(messages, types.NewUserMessage(types.WithText(SummaryPrefix+text)))
+	return append(messages, kept...)
+}
+
+// renderTranscript renders messages as plain text for the summary model
+func renderTranscript(messages []types.Message) string {
+	var b strings.Builder
+	for i := range messages {
+		m := &messages[i]
+		switch m.Role {
+		case types.RoleUser:
+			fmt.Fprintf(&b, "User: %s\n", m.TextContent())
+		case types.RoleAssistant:
+			if text := m.TextContent(); text != "" {
+				fmt.Fprintf(&b, "Assistant: %s\n", text)
+			}
+			for _, tc := range m.ToolCalls {
+				args, _ := json.Marshal(tc.Function.Arguments, json.Deterministic(true))
+				fmt.Fprintf(&b, "Assistant called %s with %s\n", tc.Function.Name, args)
+			}
+		case types.RoleTool:
+			fmt.Fprintf(&b, "Tool result: %s\n", m.TextContent())
+		}
+	}
+	return b.String()
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	json "encoding/json/v2"
 	"fmt"
+	"time"
 
 	"github.com/KennyKeni/elysia/types"
 )
@@ -56,6 +57,15 @@ type RunContext[TDep any] struct {
 	// RunID is the unique ID for the entire agent run (useful for tracing)
 	RunID string
 
+	// ParentRunID is the RunID of the run that delegated to this one, see AsTool (empty for top-level runs)
+	ParentRunID string
+
+	// TraceContext is the trace context of the run, see WithTraceContext (nil = none)
+	TraceContext map[string]string
+
+	// AgentName is the name of the agent running, see WithName
+	AgentName string
+
 	// Prompt is the original user prompt that started this run
 	Prompt string
 
@@ -73,6 +83,15 @@ type Tool[TDep any] struct {
 	types.ToolDefinition
 	Execute func(ctx context.Context, rc *RunContext[TDep], args map[string]any) (*types.ToolResult, error)
 	Retries int // Per-tool retry count (0 = use agent default)
+
+	// RequiresApproval pauses the run before the tool executes, see ToolRequiresApproval
+	RequiresApproval bool
+
+	Cache    bool          // Reuse results of identical calls, see ToolCache
+	CacheTTL time.Duration // How long cached results stay valid (0 = until evicted)
+
+	MaxResultBytes  int // Truncate longer results, see ToolMaxResultBytes (0 = agent's limit)
+	MaxResultTokens int // Truncate longer results, see ToolMaxResultTokens (0 = agent's limit)
 }
 
 // ToolOption configures a Tool.
@@ -85,6 +104,33 @@ func ToolRetries[TDep any](retries int) ToolOption[TDep] {
 	}
 }
 
+// ToolWithMetadata attaches metadata to a tool's definition.
+// Entries are merged into any existing metadata (e.g. from MCP annotations).
+func ToolWithMetadata[TDep any](meta map[string]any) ToolOption[TDep] {
+	return func(t *Tool[TDep]) {
+		if len(meta) == 0 {
+			return
+		}
+		merged := make(map[string]any, len(t.Metadata)+len(meta))
+		for k, v := range t.Metadata {
+			merged[k] = v
+		}
+		for k, v := range meta {
+			merged[k] = v
+		}
+		t.Metadata = merged
+	}
+}
+
+// ToolStrict marks the tool strict (see types.ToolDefinition.Strict) and validates arguments
+// against the strict schema, so optional arguments sent as null are accepted.
+func ToolStrict[TDep any]() ToolOption[TDep] {
+	return func(t *Tool[TDep]) {
+		t.Strict = true
+		t.InputSchema = types.StrictSchema(t.InputSchema)
+	}
+}
+
 // WrapTool wraps a types.Tool (MCP, external tools) into an agent.Tool
 func WrapTool[TDep any](tool *types.Tool, opts ...ToolOption[TDep]) *Tool[TDep] {
 	t := &Tool[TDep]{
@@ -184,6 +230,13 @@ func NewTool[TDep, TIn, TOut any](
 		opt(t)
 	}
 
+	if t.Strict {
+		resolvedInputSchema, err = types.ResolveSchemaMap(t.InputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve strict input schema: %w", err)
+		}
+	}
+
 	return t, nil
 }
 
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"errors"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// WithToolChoice sets how the model may use tools, e.g. types.ToolChoiceRequired() to force
+// a retrieval call before the model answers. A required or specific tool choice only holds
+// until the model has called a tool; later requests leave the choice to the model so the run
+// can finish. Use WithRunToolChoice to override it for a run.
+func WithToolChoice[TDep, TOut any](choice *types.ToolChoice) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if choice == nil {
+			return errors.New("tool choice cannot be nil")
+		}
+		a.toolChoice = choice
+		return nil
+	}
+}
+
+// WithRunToolChoice overrides the agent's tool choice for the run, see WithToolChoice.
+func WithRunToolChoice(choice *types.ToolChoice) RunOption {
+	return func(rc *runConfig) {
+		rc.toolChoice = choice
+	}
+}
+
+// resolveToolChoice returns the tool choice of the next request
+func (a *Agent[TDep, TOut]) resolveToolChoice(runCfg *runConfig, tools *toolState) *types.ToolChoice {
+	choice := a.toolChoice
+	if runCfg.toolChoice != nil {
+		choice = runCfg.toolChoice
+	}
+	if choice == nil {
+		return nil
+	}
+	if tools.called && (choice.Mode == types.ToolChoiceModeRequired || choice.Mode == types.ToolChoiceModeTool) {
+		return nil
+	}
+	return &types.ToolChoice{Mode: choice.Mode, Name: choice.Name}
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// TruncatedMarker ends the text of tool results cut to their size limit.
+const TruncatedMarker = "[truncated]"
+
+// ToolResultSummarizer shortens the text of a tool result exceeding its size limit, e.g. by
+// asking a cheap model for the relevant parts. tool is the name of the tool that returned it.
+type ToolResultSummarizer func(ctx context.Context, tool string, text string) (string, error)
+
+// ToolMaxResultBytes truncates the tool's results to maxBytes bytes of text, overriding the
+// agent's limit. See WithMaxToolResultBytes.
+func ToolMaxResultBytes[TDep any](maxBytes int) ToolOption[TDep] {
+	return func(t *Tool[TDep]) {
+		t.MaxResultBytes = maxBytes
+	}
+}
+
+// ToolMaxResultTokens truncates the tool's results to maxTokens tokens of text, overriding
+// the agent's limit. See WithMaxToolResultTokens.
+func ToolMaxResultTokens[TDep any](maxTokens int) ToolOption[TDep] {
+	return func(t *Tool[TDep]) {
+		t.MaxResultTokens = maxTokens
+	}
+}
+
+// WithMaxToolResultBytes truncates the text of tool results longer than maxBytes before they
+// are added to the conversation, ending them with TruncatedMarker, so one verbose tool can't
+// fill the context window. Tools may set their own limit with ToolMaxResultBytes.
+func WithMaxToolResultBytes[TDep, TOut any](maxBytes int) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if maxBytes <= 0 {
+			return fmt.Errorf("max tool result bytes must be positive, got %d", maxBytes)
+		}
+		a.maxToolResultBytes = maxBytes
+		return nil
+	}
+}
+
+// WithMaxToolResultTokens truncates the text of tool results longer than maxTokens, as counted
+// by the counter set with WithTokenCounter, like WithMaxToolResultBytes. Tools may set their
+// own limit with ToolMaxResultTokens.
+func WithMaxToolResultTokens[TDep, TOut any](maxTokens int) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if maxTokens <= 0 {
+			return fmt.Errorf("max tool result tokens must be positive, got %d", maxTokens)
+		}
+		a.maxToolResultTokens = maxTokens
+		return nil
+	}
+}
+
+// WithToolResultSummarizer shortens tool results exceeding their size limit with summarize
+// instead of truncating them. Summaries still exceeding the limit are truncated, and so are
+// results summarize fails on.
+func WithToolResultSummarizer[TDep, TOut any](summarize ToolResultSummarizer) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if summarize == nil {
+			return errors.New("tool result summarizer cannot be nil")
+		}
+		a.toolResultSummarizer = summarize
+		return nil
+	}
+}
+
+// limitToolResult returns result with its text cut to the size limit of tool. Results within
+// the limit are returned as is; non-text parts are kept in their place.
+func (a *Agent[TDep, TOut]) limitToolResult(ctx context.Context, tool *Tool[TDep], result *types.ToolResult) *types.ToolResult {
+	maxBytes := cmp.Or(tool.MaxResultBytes, a.maxToolResultBytes)
+	maxTokens := cmp.Or(tool.MaxResultTokens, a.maxToolResultTokens)
+	if result == nil || maxBytes == 0 && maxTokens == 0 {
+		return result
+	}
+
+	counter := a.tokenCounter
+	if counter == nil {
+		counter = types.HeuristicTokenCounter{}
+	}
+	fits := func(text string) bool {
+		return (maxBytes == 0 || len(text) <= maxBytes) && (maxTokens == 0 || countTextTokens(counter, text) <= maxTokens)
+	}
+
+	text := result.TextContent()
+	if fits(text) {
+		return result
+	}
+	if a.toolResultSummarizer != nil {
+		if summary, err := a.toolResultSummarizer(ctx, tool.Name, text); err == nil {
+			text = summary
+		}
+	}
+	if !fits(text) {
+		text = truncateText(text, fits)
+	}
+
+	limited := *result
+	limited.ContentPart = placeText(result.ContentPart, text)
+	return &limited
+}
+
+// placeText replaces the text parts of parts with text, keeping the order of all parts. Text
+// parts are kept while text still starts with them; the first one that doesn't holds the rest
+// of text, and the ones after it are dropped.
+func placeText(parts []types.ContentPart, text string) []types.ContentPart {
+	placed := make([]types.ContentPart, 0, len(parts))
+	last := -1
+	for _, part := range parts {
+		p, ok := part.(*types.ContentPartText)
+		if !ok {
+			placed = append(placed, part)
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, p.Text) {
+			text = text[len(p.Text):]
+		} else {
+			part, text = types.NewContentPartText(text), ""
+		}
+		last = len(placed)
+		placed = append(placed, part)
+	}
+	if text != "" && last >= 0 {
+		placed[last] = types.NewContentPartText(placed[last].(*types.ContentPartText).Text + text)
+	}
+	return placed
+}
+
+// truncateText returns the longest prefix of text that fits when followed by TruncatedMarker,
+// cut at a rune boundary
+func truncateText(text string, fits func(string) bool) string {
+	suffix := "\n" + TruncatedMarker
+	lo, hi := 0, len(text)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if fits(text[:mid] + suffix) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	for lo > 0 && lo < len(text) && !utf8.RuneStart(text[lo]) {
+		lo--
+	}
+	return strings.TrimRight(text[:lo], " \t\r\n") + suffix
+}
+
+// countTextTokens counts the tokens of text, without the overhead of the message holding it
+func countTextTokens(counter types.TokenCounter, text string) int {
+	messages := []types.Message{{Role: types.RoleTool}}
+	empty := counter.CountMessages(messages)
+	messages[0].ContentPart = []types.ContentPart{types.NewContentPartText(text)}
+	return counter.CountMessages(messages) - empty
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"maps"
+	"net/http"
+)
+
+// RunIDHeader is the header carrying the RunID on the model requests of a run, to correlate
+// provider requests with the run's events and logs.
+const RunIDHeader = "X-Run-Id"
+
+// WithRunID sets the RunID of the run instead of generating a UUID, e.g. to reuse the ID of
+// the request being served. Resumed runs keep the RunID of the paused run.
+func WithRunID(runID string) RunOption {
+	return func(rc *runConfig) {
+		rc.runID = runID
+	}
+}
+
+// WithTraceContext propagates a trace context through the run, e.g. the W3C "traceparent"
+// and "tracestate" of the incoming request. Its entries are sent as headers with every model
+// request, set on every event and exposed as RunContext.TraceContext; runs the model
+// delegates or hands off to inherit it.
+func WithTraceContext(trace map[string]string) RunOption {
+	return func(rc *runConfig) {
+		rc.traceContext = maps.Clone(trace)
+	}
+}
+
+// RunInfo identifies the run that emitted an event. It is set on every event, including the
+// events of handoff targets, which carry their own RunID.
+type RunInfo struct {
+	RunID        string
+	TraceContext map[string]string // See WithTraceContext (nil = none)
+}
+
+// requestHeaders returns the headers sent with the run's model requests
+func (rc *runConfig) requestHeaders() http.Header {
+	header := make(http.Header, len(rc.traceContext)+1)
+	for key, value := range rc.traceContext {
+		header.Set(key, value)
+	}
+	header.Set(RunIDHeader, rc.runID)
+	return header
+}
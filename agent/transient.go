@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// TransientRetryPolicy controls how a run retries a model call that failed with a transient
+// provider error, such as a rate limit (429) or a server error (5xx). The same request is sent
+// again; transient retries count neither against output retries nor the request limit.
+type TransientRetryPolicy struct {
+	MaxRetries int     // Retries per model call (0 = never retry)
+	Backoff    Backoff // Delay before each retry, raised to the provider's Retry-After (nil = DefaultTransientBackoff)
+
+	// IsTransient classifies errors and returns the delay the provider asked for
+	// (nil = types.IsTransient).
+	IsTransient func(err error) (bool, time.Duration)
+}
+
+// DefaultTransientBackoff is used when TransientRetryPolicy.Backoff is nil
+var DefaultTransientBackoff = ExponentialBackoff(time.Second, 30*time.Second, 0.2)
+
+// DefaultTransientRetryPolicy is the policy of agents created without WithTransientRetryPolicy
+var DefaultTransientRetryPolicy = TransientRetryPolicy{MaxRetries: 3}
+
+// WithTransientRetryPolicy sets how model calls failing with transient provider errors are
+// retried. Use TransientRetryPolicy{} to fail the run on the first error.
+func WithTransientRetryPolicy[TDep, TOut any](policy TransientRetryPolicy) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if policy.MaxRetries < 0 {
+			return errors.New("transient max retries cannot be negative")
+		}
+		a.transientRetry = policy
+		return nil
+	}
+}
+
+// chatWithRetry calls the model, resending the request on transient errors per the agent's policy
+func (a *Agent[TDep, TOut]) chatWithRetry(ctx context.Context, params *types.ChatParams, onText func(string) error) (*types.ChatResponse, error) {
+	policy := a.transientRetry
+	isTransient := policy.IsTransient
+	if isTransient == nil {
+		isTransient = types.IsTransient
+	}
+	backoff := policy.Backoff
+	if backoff == nil {
+		backoff = DefaultTransientBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := a.chat(ctx, params, onText)
+		if err == nil || attempt > policy.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+		transient, retryAfter := isTransient(err)
+		if !transient {
+			return nil, err
+		}
+		traceRecorderFrom(ctx).retry(err, true)
+		if err := sleep(ctx, max(backoff(attempt), retryAfter)); err != nil {
+			return nil, err
+		}
+	}
+}
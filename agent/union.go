@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// OutputVariant is one of the types a union output agent can return, see Variant.
+type OutputVariant[TOut any] struct {
+	Name   string
+	schema map[string]any
+	decode func(data []byte) (TOut, error)
+	err    error // Reported by WithOutputVariants
+}
+
+// Variant declares T as an output variant named name. T (or *T) must implement the
+// agent's output type TOut, typically an interface shared by all variants:
+//
+//	type Reply interface{ isReply() }
+//
+//	agent.WithOutputVariants[Deps, Reply](
+//		agent.Variant[Reply, Answer]("answer"),
+//		agent.Variant[Reply, NeedsClarification]("clarification"),
+//	)
+func Variant[TOut, T any](name string) OutputVariant[TOut] {
+	variant := OutputVariant[TOut]{Name: name}
+
+	var zero T
+	_, valueOK := any(zero).(TOut)
+	_, pointerOK := any(&zero).(TOut)
+	if !valueOK && !pointerOK {
+		variant.err = fmt.Errorf("output variant %q: %s does not implement %s", name, reflect.TypeFor[T](), reflect.TypeFor[TOut]())
+		return variant
+	}
+
+	variant.schema, variant.err = types.SchemaMapFor[T]()
+	if variant.err != nil {
+		variant.err = fmt.Errorf("output variant %q: %w", name, variant.err)
+	}
+	variant.decode = func(data []byte) (TOut, error) {
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return *new(TOut), err
+		}
+		if valueOK {
+			return any(v).(TOut), nil
+		}
+		return any(&v).(TOut), nil
+	}
+	return variant
+}
+
+// WithOutputVariants makes the agent return one of several output types. The model
+// answers with {"result": {"kind": <name>, "value": <variant>}}, the variant's Name is
+// reported in RunResult.OutputVariant. Without WithResponseFormat the Tool mode is used.
+func WithOutputVariants[TDep, TOut any](variants ...OutputVariant[TOut]) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if len(variants) == 0 {
+			return errors.New("at least one output variant is required")
+		}
+		names := make(map[string]bool, len(variants))
+		for _, v := range variants {
+			if v.err != nil {
+				return v.err
+			}
+			if v.Name == "" {
+				return errors.New("output variant name cannot be empty")
+			}
+			if names[v.Name] {
+				return fmt.Errorf("duplicate output variant name: %s", v.Name)
+			}
+			names[v.Name] = true
+		}
+		a.outputVariants = variants
+		return nil
+	}
+}
+
+// unionSchema returns the discriminated union schema of the agent's output variants
+func (a *Agent[TDep, TOut]) unionSchema() map[string]any {
+	options := make([]any, len(a.outputVariants))
+	for i, v := range a.outputVariants {
+		options[i] = map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"kind":  map[string]any{"type": "string", "enum": []any{v.Name}},
+				"value": v.schema,
+			},
+			"required":             []any{"kind", "value"},
+			"additionalProperties": false,
+		}
+	}
+
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"result": map[string]any{"anyOf": options},
+		},
+		"required":             []any{"result"},
+		"additionalProperties": false,
+	}
+}
+
+// decodeOutput parses the structured content of a response into the agent's output,
+// returning the name of the variant for union outputs
+func (a *Agent[TDep, TOut]) decodeOutput(content string) (TOut, string, error) {
+	var out TOut
+	if len(a.outputVariants) == 0 {
+		data, err := types.RestorePropertyNames([]byte(content), a.outputSchemaOpts...)
+		if err != nil {
+			return out, "", err
+		}
+		err = json.Unmarshal(data, &out)
+		return out, "", err
+	}
+
+	var wire struct {
+		Result struct {
+			Kind  string         `json:"kind"`
+			Value jsontext.Value `json:"value"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(content), &wire); err != nil {
+		return out, "", err
+	}
+	for _, v := range a.outputVariants {
+		if v.Name == wire.Result.Kind {
+			out, err := v.decode(wire.Result.Value)
+			return out, v.Name, err
+		}
+	}
+	return out, "", fmt.Errorf("unknown output variant %q", wire.Result.Kind)
+}
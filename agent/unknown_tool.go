@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// WithUnknownToolRetries lets the model correct calls of tools that don't exist: instead of
+// failing the run, such a call is answered with an error result naming the available tools.
+// At most retries such calls are answered per run; the next one fails the run.
+func WithUnknownToolRetries[TDep, TOut any](retries int) Option[TDep, TOut] {
+	return func(a *Agent[TDep, TOut]) error {
+		if retries < 1 {
+			return errors.New("unknown tool retries must be at least 1")
+		}
+		a.unknownToolRetries = retries
+		return nil
+	}
+}
+
+// unknownTool answers a call of a tool missing from toolMap, or fails the run once the
+// unknown tool retries are used up.
+func (a *Agent[TDep, TOut]) unknownTool(ctx context.Context, rc *RunContext[TDep], tc types.ToolCall, toolMap map[string]*Tool[TDep], tools *toolState) (*types.ToolResult, error) {
+	name := tc.Function.Name
+	if tools.unknown >= a.unknownToolRetries {
+		if a.unknownToolRetries > 0 {
+			return nil, fmt.Errorf("unknown tool: %s (exceeded max retries (%d))", name, a.unknownToolRetries)
+		}
+		return nil, fmt.Errorf("unknown tool: %s", name)
+	}
+	tools.unknown++
+
+	text := fmt.Sprintf("tool %s does not exist; available: %s", name, strings.Join(slices.Sorted(maps.Keys(toolMap)), ", "))
+	a.hooks.onRetry(ctx, rc, NewModelRetry(text))
+	return &types.ToolResult{
+		ContentPart: []types.ContentPart{types.NewContentPartText(text)},
+		IsError:     true,
+	}, nil
+}
@@ -1,8 +1,12 @@
 package client
 
 import (
+	"context"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/KennyKeni/elysia/types"
 )
 
 // Config holds provider-agnostic client configuration
@@ -14,6 +18,32 @@ type Config struct {
 	PerAttemptTimeout time.Duration
 	TotalTimeout      time.Duration
 	Headers           http.Header
+	HTTP2             bool
+
+	// StrictModelValidation rejects model names the adapter does not recognize before sending a request
+	StrictModelValidation bool
+
+	// TokenProvider supplies a bearer token per request (e.g. a Microsoft Entra ID access token),
+	// taking precedence over APIKey for the Authorization header
+	TokenProvider func(ctx context.Context) (string, error)
+
+	// APIVersion is sent as the api-version query parameter by providers that version their API that way (Azure OpenAI)
+	APIVersion string
+
+	// Deployments maps model names to provider deployment names (Azure OpenAI); unmapped models are used as-is
+	Deployments map[string]string
+
+	// Logger receives debug logs of requests, responses and errors (nil = no logging)
+	Logger *slog.Logger
+
+	// LogContent redacts message and response text before it is logged (nil = text is not logged)
+	LogContent func(text string) string
+
+	// DocumentConverter converts documents the provider can't read natively (nil = rejected)
+	DocumentConverter types.DocumentConverter
+
+	// ChunkTimeout fails streams that receive no chunk for this long (0 = no limit)
+	ChunkTimeout time.Duration
 }
 
 // DefaultConfig returns config with sensible defaults
@@ -26,6 +56,15 @@ func DefaultConfig() Config {
 	}
 }
 
+// NewConfig returns DefaultConfig with opts applied
+func NewConfig(opts ...Option) Config {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
 // Option is a functional option for configuring a client
 type Option func(*Config)
 
@@ -87,3 +126,71 @@ func WithHeaders(headers http.Header) Option {
 		c.Headers = headers
 	}
 }
+
+// WithHTTP2Transport configures the HTTP transport to negotiate HTTP/2 over TLS
+func WithHTTP2Transport() Option {
+	return func(c *Config) {
+		c.HTTP2 = true
+	}
+}
+
+// WithStrictModelValidation rejects unknown model names before making API calls
+func WithStrictModelValidation() Option {
+	return func(c *Config) {
+		c.StrictModelValidation = true
+	}
+}
+
+// WithTokenProvider authenticates each request with a bearer token from fn, e.g. an Entra ID token source
+func WithTokenProvider(fn func(ctx context.Context) (string, error)) Option {
+	return func(c *Config) {
+		c.TokenProvider = fn
+	}
+}
+
+// WithAPIVersion sets the api-version query parameter (Azure OpenAI)
+func WithAPIVersion(version string) Option {
+	return func(c *Config) {
+		c.APIVersion = version
+	}
+}
+
+// WithDeployment routes requests for model to the named deployment (Azure OpenAI)
+func WithDeployment(model, deployment string) Option {
+	return func(c *Config) {
+		if c.Deployments == nil {
+			c.Deployments = make(map[string]string)
+		}
+		c.Deployments[model] = deployment
+	}
+}
+
+// WithLogger logs requests, responses and errors to logger at debug level
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithLogContent includes message and response text in logs, passed through redact first
+func WithLogContent(redact func(text string) string) Option {
+	return func(c *Config) {
+		c.LogContent = redact
+	}
+}
+
+// WithDocumentConverter converts documents the provider can't read natively, e.g. with
+// types.DocumentText
+func WithDocumentConverter(convert types.DocumentConverter) Option {
+	return func(c *Config) {
+		c.DocumentConverter = convert
+	}
+}
+
+// WithChunkTimeout fails streams with a *types.StreamStalledError when no chunk arrives for
+// timeout, so a hung connection can't block forever
+func WithChunkTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.ChunkTimeout = timeout
+	}
+}
@@ -0,0 +1,276 @@
+// Package elysiatest provides scriptable clients for testing code built on elysia without
+// calling a provider.
+package elysiatest
+
+import (
+	"cmp"
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ErrNoResponse is returned when a TestClient receives a request with no response queued.
+var ErrNoResponse = errors.New("elysiatest: no queued response")
+
+// TestClient is a types.Client that answers requests with queued responses, in order, and
+// records every request. Responses pass through types.NewClient, so the ResponseFormat is
+// handled as with a real adapter:
+//
+//	c := elysiatest.NewTestClient()
+//	c.QueueToolCalls(elysiatest.ToolCall("search", map[string]any{"query": "go"})).
+//		QueueOutput(Answer{Text: "Go is a language"})
+//	a, _ := agent.New[Deps, Answer](c)
+//
+// A TestClient is safe for concurrent use.
+type TestClient struct {
+	types.Client
+	raw *rawClient
+
+	mu      sync.Mutex
+	replies []ChatFunc
+}
+
+// Option configures a TestClient or FunctionClient.
+type Option func(*rawClient)
+
+// WithUsage reports usage on every response that has none (default none).
+func WithUsage(usage types.Usage) Option {
+	return func(r *rawClient) {
+		r.usage = &usage
+	}
+}
+
+// NewTestClient creates a TestClient with an empty queue.
+func NewTestClient(opts ...Option) *TestClient {
+	c := &TestClient{}
+	c.raw = newRawClient(c.next, opts)
+	c.Client = types.NewClient(c.raw)
+	return c
+}
+
+func newRawClient(handle func(*types.ChatParams, int) (*types.ChatResponse, error), opts []Option) *rawClient {
+	raw := &rawClient{handle: handle}
+	for _, opt := range opts {
+		opt(raw)
+	}
+	return raw
+}
+
+// ToolCall builds a tool call for QueueToolCalls or ToolCalls. Tool calls without an ID get a
+// unique one when they are served.
+func ToolCall(name string, args map[string]any) types.ToolCall {
+	return types.ToolCall{Function: types.ToolFunction{Name: name, Arguments: args}}
+}
+
+// QueueText queues an assistant reply with text.
+func (c *TestClient) QueueText(text string) *TestClient {
+	return c.Queue(func(*types.ChatParams) (*types.ChatResponse, error) {
+		return Text(text), nil
+	})
+}
+
+// QueueToolCalls queues an assistant reply calling tools.
+func (c *TestClient) QueueToolCalls(calls ...types.ToolCall) *TestClient {
+	return c.Queue(func(*types.ChatParams) (*types.ChatResponse, error) {
+		return ToolCalls(calls...), nil
+	})
+}
+
+// QueueOutput queues a structured output, returned as Output does.
+func (c *TestClient) QueueOutput(output any) *TestClient {
+	return c.Queue(func(params *types.ChatParams) (*types.ChatResponse, error) {
+		return Output(params, output)
+	})
+}
+
+// QueueResponse queues a response. A missing ID, model, usage and tool call IDs are filled in.
+func (c *TestClient) QueueResponse(resp *types.ChatResponse) *TestClient {
+	return c.Queue(func(*types.ChatParams) (*types.ChatResponse, error) {
+		return resp, nil
+	})
+}
+
+// QueueError queues a failed request.
+func (c *TestClient) QueueError(err error) *TestClient {
+	return c.Queue(func(*types.ChatParams) (*types.ChatResponse, error) {
+		return nil, err
+	})
+}
+
+// Queue queues a function answering the next request, for replies that depend on it.
+func (c *TestClient) Queue(fn ChatFunc) *TestClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replies = append(c.replies, fn)
+	return c
+}
+
+// Requests returns the params of every request received so far, as sent to the provider.
+func (c *TestClient) Requests() []*types.ChatParams {
+	return c.raw.recorded()
+}
+
+// LastRequest returns the params of the latest request, nil if there was none.
+func (c *TestClient) LastRequest() *types.ChatParams {
+	return c.raw.last()
+}
+
+// Remaining returns the number of queued responses not served yet.
+func (c *TestClient) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.replies)
+}
+
+// next pops the queued reply for the n-th request
+func (c *TestClient) next(params *types.ChatParams, n int) (*types.ChatResponse, error) {
+	c.mu.Lock()
+	if len(c.replies) == 0 {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%w for request #%d", ErrNoResponse, n)
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	c.mu.Unlock()
+	return reply(params)
+}
+
+// rawClient implements types.RawClient: it records requests, lets handle answer them and
+// completes the responses
+type rawClient struct {
+	handle func(params *types.ChatParams, n int) (*types.ChatResponse, error)
+
+	mu       sync.Mutex
+	requests []*types.ChatParams
+	usage    *types.Usage // Reported on responses without usage (nil = none)
+	ids      int          // Generated tool call IDs
+}
+
+func (r *rawClient) RawChat(ctx context.Context, params *types.ChatParams) (*types.ChatResponse, error) {
+	r.mu.Lock()
+	r.requests = append(r.requests, params)
+	n := len(r.requests)
+	r.mu.Unlock()
+
+	resp, err := r.handle(params, n)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("elysiatest: nil response for request #%d", n)
+	}
+	return r.complete(resp, params, n), nil
+}
+
+// RawChatStream replays the next response as a stream: text word by word, then the tool
+// calls, then the finish reason and usage.
+func (r *rawClient) RawChatStream(ctx context.Context, params *types.ChatParams) (*types.Stream, error) {
+	resp, err := r.RawChat(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := streamChunks(resp)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewStream(func() (*types.StreamChunk, error) {
+		if len(chunks) == 0 {
+			return nil, io.EOF
+		}
+		chunk := chunks[0]
+		chunks = chunks[1:]
+		return chunk, nil
+	}, nil), nil
+}
+
+func (r *rawClient) RawEmbed(ctx context.Context, params *types.EmbeddingParams) (*types.EmbeddingResponse, error) {
+	return nil, errors.New("elysiatest: embeddings are not supported")
+}
+
+// complete fills in what resp leaves out, without modifying it
+func (r *rawClient) complete(resp *types.ChatResponse, params *types.ChatParams, n int) *types.ChatResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := *resp
+	c.ID = cmp.Or(c.ID, fmt.Sprintf("test-response-%d", n))
+	c.Model = cmp.Or(c.Model, params.Model)
+	if c.Usage == nil && r.usage != nil {
+		usage := *r.usage
+		c.Usage = &usage
+	}
+	c.Choices = slices.Clone(c.Choices)
+	for i, choice := range c.Choices {
+		if choice.Message == nil || !slices.ContainsFunc(choice.Message.ToolCalls, func(tc types.ToolCall) bool { return tc.ID == "" }) {
+			continue
+		}
+		message := *choice.Message
+		message.ToolCalls = slices.Clone(message.ToolCalls)
+		for j := range message.ToolCalls {
+			if message.ToolCalls[j].ID == "" {
+				r.ids++
+				message.ToolCalls[j].ID = fmt.Sprintf("call_%d", r.ids)
+			}
+		}
+		c.Choices[i].Message = &message
+	}
+	return &c
+}
+
+func (r *rawClient) recorded() []*types.ChatParams {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.requests)
+}
+
+func (r *rawClient) last() *types.ChatParams {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.requests) == 0 {
+		return nil
+	}
+	return r.requests[len(r.requests)-1]
+}
+
+// streamChunks splits resp into the chunks a provider would stream
+func streamChunks(resp *types.ChatResponse) ([]*types.StreamChunk, error) {
+	var chunks []*types.StreamChunk
+	for _, choice := range resp.Choices {
+		delta := func(d *types.MessageDelta, finishReason string) {
+			chunks = append(chunks, &types.StreamChunk{
+				ID:      resp.ID,
+				Model:   resp.Model,
+				Choices: []types.StreamChoice{{Index: choice.Index, Delta: d, FinishReason: finishReason}},
+			})
+		}
+		delta(&types.MessageDelta{Role: types.RoleAssistant}, "")
+		if choice.Message != nil {
+			for _, word := range strings.SplitAfter(choice.Message.TextContent(), " ") {
+				if word != "" {
+					delta(&types.MessageDelta{Content: word}, "")
+				}
+			}
+			for i, tc := range choice.Message.ToolCalls {
+				args, err := json.Marshal(tc.Function.Arguments)
+				if err != nil {
+					return nil, fmt.Errorf("elysiatest: encode tool call arguments: %w", err)
+				}
+				delta(&types.MessageDelta{ToolCalls: []types.ToolCallDelta{{
+					Index: i, ID: tc.ID, FunctionName: tc.Function.Name, Arguments: string(args),
+				}}}, "")
+			}
+		}
+		delta(&types.MessageDelta{}, choice.FinishReason)
+	}
+	if len(chunks) > 0 {
+		chunks[len(chunks)-1].Usage = resp.Usage
+	}
+	return chunks, nil
+}
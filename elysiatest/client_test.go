@@ -0,0 +1,115 @@
+package elysiatest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KennyKeni/elysia/agent"
+	"github.com/KennyKeni/elysia/elysiatest"
+	"github.com/KennyKeni/elysia/types"
+)
+
+type answer struct {
+	Text string `json:"text"`
+}
+
+type greetInput struct {
+	Name string `json:"name"`
+}
+
+func newGreetTool(t *testing.T) *agent.Tool[struct{}] {
+	t.Helper()
+	tool, err := agent.NewTool("greet", "Greets someone",
+		func(ctx context.Context, rc *agent.RunContext[struct{}], in greetInput) (string, error) {
+			return "Hi " + in.Name, nil
+		})
+	if err != nil {
+		t.Fatalf("NewTool: %v", err)
+	}
+	return tool
+}
+
+func TestTestClient_Agent(t *testing.T) {
+	for _, mode := range []types.ResponseFormatMode{types.ResponseFormatModeTool, types.ResponseFormatModeNative} {
+		t.Run(string(mode), func(t *testing.T) {
+			client := elysiatest.NewTestClient(elysiatest.WithUsage(types.Usage{TotalTokens: 3}))
+			client.QueueToolCalls(elysiatest.ToolCall("greet", map[string]any{"name": "Ada"})).
+				QueueOutput(answer{Text: "greeted"})
+
+			a, err := agent.New[struct{}, answer](client,
+				agent.WithTools[struct{}, answer](newGreetTool(t)),
+				agent.WithResponseFormat[struct{}, answer](mode),
+			)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			result, err := a.Run(context.Background(), struct{}{}, agent.WithPrompt("greet Ada"))
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if result.Output.Text != "greeted" {
+				t.Errorf("Output = %+v, want greeted", result.Output)
+			}
+			if result.Usage.TotalTokens != 6 {
+				t.Errorf("TotalTokens = %d, want 6", result.Usage.TotalTokens)
+			}
+			if client.Remaining() != 0 {
+				t.Errorf("Remaining = %d, want 0", client.Remaining())
+			}
+
+			requests := client.Requests()
+			if len(requests) != 2 {
+				t.Fatalf("got %d requests, want 2", len(requests))
+			}
+			if requests[1] != client.LastRequest() {
+				t.Error("LastRequest is not the last request")
+			}
+			last := requests[1].Messages[len(requests[1].Messages)-1]
+			if last.Role != types.RoleTool || !strings.Contains(last.TextContent(), "Hi Ada") {
+				t.Errorf("last message = %+v, want the greet result", last)
+			}
+		})
+	}
+}
+
+func TestTestClient_Stream(t *testing.T) {
+	client := elysiatest.NewTestClient()
+	client.QueueToolCalls(elysiatest.ToolCall("greet", map[string]any{"name": "Ada"})).
+		QueueText("hello there Ada")
+
+	a, err := agent.New[struct{}, string](client, agent.WithTools[struct{}, string](newGreetTool(t)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var text strings.Builder
+	for chunk, err := range a.StreamText(context.Background(), struct{}{}, agent.WithPrompt("greet Ada")) {
+		if err != nil {
+			t.Fatalf("StreamText: %v", err)
+		}
+		text.WriteString(chunk)
+	}
+	if text.String() != "hello there Ada" {
+		t.Errorf("streamed %q, want %q", text.String(), "hello there Ada")
+	}
+}
+
+func TestTestClient_Errors(t *testing.T) {
+	failure := errors.New("provider down")
+	client := elysiatest.NewTestClient()
+	client.QueueError(failure)
+
+	params := &types.ChatParams{Messages: []types.Message{types.NewUserMessage(types.WithText("hi"))}}
+	if _, err := client.Chat(context.Background(), params); !errors.Is(err, failure) {
+		t.Errorf("err = %v, want %v", err, failure)
+	}
+	if _, err := client.Chat(context.Background(), params); !errors.Is(err, elysiatest.ErrNoResponse) {
+		t.Errorf("err = %v, want ErrNoResponse", err)
+	}
+	if got := len(client.Requests()); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
@@ -0,0 +1,93 @@
+package elysiatest
+
+import (
+	"encoding/json/v2"
+	"fmt"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ChatFunc answers a chat request. It sees the params as sent to the provider, including the
+// tool definitions and the ResponseFormat.
+type ChatFunc func(params *types.ChatParams) (*types.ChatResponse, error)
+
+// FunctionClient is a types.Client whose replies are computed by a ChatFunc, for tests that
+// respond to the actual request rather than a fixed script. For example, to call every
+// advertised tool once and then answer:
+//
+//	c := elysiatest.NewFunctionClient(func(params *types.ChatParams) (*types.ChatResponse, error) {
+//		if last := params.Messages[len(params.Messages)-1]; last.Role == types.RoleUser {
+//			var calls []types.ToolCall
+//			for _, tool := range params.Tools {
+//				if tool.Name != params.ResponseFormat.OutputToolName() {
+//					calls = append(calls, elysiatest.ToolCall(tool.Name, map[string]any{}))
+//				}
+//			}
+//			return elysiatest.ToolCalls(calls...), nil
+//		}
+//		return elysiatest.Output(params, Answer{Text: "done"})
+//	})
+//
+// Missing response IDs, models, usage and tool call IDs are filled in. A FunctionClient is safe
+// for concurrent use if its ChatFunc is.
+type FunctionClient struct {
+	types.Client
+	raw *rawClient
+}
+
+// NewFunctionClient creates a FunctionClient replying with fn.
+func NewFunctionClient(fn ChatFunc, opts ...Option) *FunctionClient {
+	raw := newRawClient(func(params *types.ChatParams, _ int) (*types.ChatResponse, error) {
+		return fn(params)
+	}, opts)
+	return &FunctionClient{Client: types.NewClient(raw), raw: raw}
+}
+
+// Requests returns the params of every request received so far, as sent to the provider.
+func (c *FunctionClient) Requests() []*types.ChatParams {
+	return c.raw.recorded()
+}
+
+// LastRequest returns the params of the latest request, nil if there was none.
+func (c *FunctionClient) LastRequest() *types.ChatParams {
+	return c.raw.last()
+}
+
+// Text returns an assistant reply with text.
+func Text(text string) *types.ChatResponse {
+	return reply(assistant(text, nil), "stop")
+}
+
+// ToolCalls returns an assistant reply calling tools.
+func ToolCalls(calls ...types.ToolCall) *types.ChatResponse {
+	return reply(assistant("", calls), "tool_calls")
+}
+
+// Output returns a reply with output encoded as JSON, the way params asks for structured
+// output: as a call of the output tool in Tool mode, as text otherwise.
+func Output(params *types.ChatParams, output any) (*types.ChatResponse, error) {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("elysiatest: encode output: %w", err)
+	}
+	if params.ResponseFormat.Mode != types.ResponseFormatModeTool {
+		return Text(string(data)), nil
+	}
+	var args map[string]any
+	if err := json.Unmarshal(data, &args); err != nil {
+		return nil, fmt.Errorf("elysiatest: output tool arguments must be a JSON object: %w", err)
+	}
+	return ToolCalls(ToolCall(params.ResponseFormat.OutputToolName(), args)), nil
+}
+
+func reply(message *types.Message, finishReason string) *types.ChatResponse {
+	return &types.ChatResponse{Choices: []types.Choice{{Message: message, FinishReason: finishReason}}}
+}
+
+func assistant(text string, calls []types.ToolCall) *types.Message {
+	message := &types.Message{Role: types.RoleAssistant, ContentPart: []types.ContentPart{}, ToolCalls: calls}
+	if text != "" {
+		message.ContentPart = append(message.ContentPart, types.NewContentPartText(text))
+	}
+	return message
+}
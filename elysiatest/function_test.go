@@ -0,0 +1,79 @@
+package elysiatest_test
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/KennyKeni/elysia/agent"
+	"github.com/KennyKeni/elysia/elysiatest"
+	"github.com/KennyKeni/elysia/types"
+)
+
+func TestFunctionClient_CallsEveryTool(t *testing.T) {
+	var farewells atomic.Int32
+	farewell, err := agent.NewTool("farewell", "Says goodbye",
+		func(ctx context.Context, rc *agent.RunContext[struct{}], in greetInput) (string, error) {
+			farewells.Add(1)
+			return "Bye " + in.Name, nil
+		})
+	if err != nil {
+		t.Fatalf("NewTool: %v", err)
+	}
+
+	client := elysiatest.NewFunctionClient(func(params *types.ChatParams) (*types.ChatResponse, error) {
+		if last := params.Messages[len(params.Messages)-1]; last.Role == types.RoleUser {
+			var calls []types.ToolCall
+			for _, tool := range params.Tools {
+				if tool.Name != types.OutputToolName {
+					calls = append(calls, elysiatest.ToolCall(tool.Name, map[string]any{"name": "Ada"}))
+				}
+			}
+			return elysiatest.ToolCalls(calls...), nil
+		}
+		return elysiatest.Output(params, answer{Text: "done"})
+	})
+
+	a, err := agent.New[struct{}, answer](client,
+		agent.WithTools[struct{}, answer](newGreetTool(t), farewell),
+		agent.WithResponseFormat[struct{}, answer](types.ResponseFormatModeTool),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	result, err := a.Run(context.Background(), struct{}{}, agent.WithPrompt("be polite"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Output.Text != "done" {
+		t.Errorf("Output = %+v, want done", result.Output)
+	}
+	if farewells.Load() != 1 {
+		t.Errorf("farewell called %d times, want 1", farewells.Load())
+	}
+
+	requests := client.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	var results []string
+	var ids []string
+	for _, msg := range client.LastRequest().Messages {
+		switch msg.Role {
+		case types.RoleTool:
+			results = append(results, msg.TextContent())
+		case types.RoleAssistant:
+			for _, tc := range msg.ToolCalls {
+				ids = append(ids, tc.ID)
+			}
+		}
+	}
+	if len(results) != 2 {
+		t.Errorf("tool results = %q, want one per tool", results)
+	}
+	if len(ids) != 2 || slices.Contains(ids, "") || ids[0] == ids[1] {
+		t.Errorf("tool call IDs = %q, want unique generated IDs", ids)
+	}
+}
@@ -0,0 +1,344 @@
+package elysiatest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/KennyKeni/elysia/types"
+)
+
+// ErrNotRecorded is returned by a Recorder replaying a request that has no recording.
+var ErrNotRecorded = errors.New("elysiatest: request not recorded")
+
+// Redacted replaces secrets in recordings.
+const Redacted = "[REDACTED]"
+
+// RecordMode decides when a Recorder calls the wrapped client.
+type RecordMode int
+
+const (
+	// RecordMissing replays recorded requests and records the others.
+	RecordMissing RecordMode = iota
+
+	// ReplayOnly replays recorded requests and fails the others with ErrNotRecorded,
+	// e.g. in CI where no API key is available.
+	ReplayOnly
+
+	// RecordAll calls the client for every request and replaces the recordings.
+	RecordAll
+)
+
+// defaultSecrets matches API keys and bearer tokens
+var defaultSecrets = regexp.MustCompile(`\b(sk|pk|rk|key)-[A-Za-z0-9_\-]{16,}|Bearer [A-Za-z0-9._\-]+`)
+
+// Recorder is a types.Client that records the requests and responses of a real client to a
+// cassette file and replays them on later runs, so tests against a provider run offline:
+//
+//	var client types.Client // nil: replay only
+//	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+//		client = openai.NewClient(openai.WithAPIKey(key))
+//	}
+//	rec, err := elysiatest.NewRecorder("testdata/weather.json", client)
+//
+// Requests are matched by a hash of their ChatParams after redaction. Identical requests are
+// replayed in the order they were recorded. Only completed responses are recorded: errors and
+// streams that were not read to the end are not, and neither is the Extra of requests and
+// responses. Embed is passed through to the client.
+type Recorder struct {
+	client  types.Client
+	path    string
+	mode    RecordMode
+	secrets []*regexp.Regexp
+
+	mu       sync.Mutex
+	cassette cassette
+	replayed map[string]int // Interactions replayed per key
+	recorded map[string]int // Interactions recorded per key by this Recorder (RecordAll)
+}
+
+// RecorderOption configures a Recorder.
+type RecorderOption func(*Recorder)
+
+// WithRecordMode sets when the Recorder calls the client (default RecordMissing). Without a
+// client, the Recorder always replays only.
+func WithRecordMode(mode RecordMode) RecorderOption {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithRedaction replaces the given secrets with Redacted in recordings, in addition to strings
+// that look like API keys or bearer tokens. Empty secrets are ignored.
+func WithRedaction(secrets ...string) RecorderOption {
+	return func(r *Recorder) {
+		for _, secret := range secrets {
+			if secret != "" {
+				r.secrets = append(r.secrets, regexp.MustCompile(regexp.QuoteMeta(secret)))
+			}
+		}
+	}
+}
+
+// cassette is the file format of a Recorder
+type cassette struct {
+	// ResponseFormats are the response format modes supported by the recorded client
+	ResponseFormats map[types.ResponseFormatMode]bool `json:"response_formats,omitempty"`
+	Interactions    []interaction                     `json:"interactions"`
+}
+
+// interaction is one recorded request
+type interaction struct {
+	Key      string               `json:"key"`
+	Request  jsontext.Value       `json:"request"`
+	Response *types.ChatResponse  `json:"response,omitempty"`
+	Stream   []*types.StreamChunk `json:"stream,omitempty"`
+}
+
+// NewRecorder creates a Recorder with the cassette at path, which need not exist yet. client
+// may be nil to only replay.
+func NewRecorder(path string, client types.Client, opts ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{
+		client:   client,
+		path:     path,
+		secrets:  []*regexp.Regexp{defaultSecrets},
+		replayed: make(map[string]int),
+		recorded: make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if client == nil {
+		r.mode = ReplayOnly
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("elysiatest: read cassette: %w", err)
+	default:
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("elysiatest: decode cassette %s: %w", path, err)
+		}
+	}
+	return r, nil
+}
+
+// Chat replays the recorded response to params, or calls the client and records its response.
+func (r *Recorder) Chat(ctx context.Context, params *types.ChatParams) (*types.ChatResponse, error) {
+	key, request, err := r.key(params, false)
+	if err != nil {
+		return nil, err
+	}
+	if recorded, ok := r.replay(key); ok {
+		return recorded.Response, nil
+	}
+	if r.mode == ReplayOnly {
+		return nil, fmt.Errorf("%w: chat request %s", ErrNotRecorded, key[:12])
+	}
+
+	resp, err := r.client.Chat(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.record(interaction{Key: key, Request: request, Response: resp}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ChatStream replays the recorded chunks for params, or streams from the client and records
+// the chunks once the stream is read to the end.
+func (r *Recorder) ChatStream(ctx context.Context, params *types.ChatParams) (*types.Stream, error) {
+	key, request, err := r.key(params, true)
+	if err != nil {
+		return nil, err
+	}
+	if recorded, ok := r.replay(key); ok {
+		chunks := recorded.Stream
+		return types.NewStream(func() (*types.StreamChunk, error) {
+			if len(chunks) == 0 {
+				return nil, io.EOF
+			}
+			chunk := chunks[0]
+			chunks = chunks[1:]
+			return chunk, nil
+		}, nil), nil
+	}
+	if r.mode == ReplayOnly {
+		return nil, fmt.Errorf("%w: chat stream request %s", ErrNotRecorded, key[:12])
+	}
+
+	stream, err := r.client.ChatStream(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []*types.StreamChunk
+	return types.NewStream(func() (*types.StreamChunk, error) {
+		if !stream.Next() {
+			if err := stream.Err(); err != nil {
+				return nil, err
+			}
+			if err := r.record(interaction{Key: key, Request: request, Stream: chunks}); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		chunk := stream.Chunk()
+		chunks = append(chunks, chunk)
+		return chunk, nil
+	}, stream), nil
+}
+
+// Embed passes the request through to the client.
+func (r *Recorder) Embed(ctx context.Context, params *types.EmbeddingParams) (*types.EmbeddingResponse, error) {
+	if r.client == nil {
+		return nil, errors.New("elysiatest: embeddings are not recorded")
+	}
+	return r.client.Embed(ctx, params)
+}
+
+// SupportsResponseFormat reports whether the client supports mode, as recorded when replaying.
+// Streams extract structured output with it, so replays must answer like the recorded client.
+func (r *Recorder) SupportsResponseFormat(mode types.ResponseFormatMode) bool {
+	if r.client != nil {
+		supporter, ok := r.client.(types.ResponseFormatSupporter)
+		return !ok || supporter.SupportsResponseFormat(mode)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	supported, ok := r.cassette.ResponseFormats[mode]
+	return !ok || supported
+}
+
+// key returns the redacted request and its hash. Message metadata is left out, as it is not
+// sent to providers.
+func (r *Recorder) key(params *types.ChatParams, stream bool) (string, jsontext.Value, error) {
+	params = params.Clone()
+	for i := range params.Messages {
+		params.Messages[i].Metadata = nil
+	}
+	data, err := json.Marshal(params, json.Deterministic(true))
+	if err != nil {
+		return "", nil, fmt.Errorf("elysiatest: encode request: %w", err)
+	}
+	data = r.redact(data)
+
+	hash := sha256.New()
+	if stream {
+		hash.Write([]byte("stream:"))
+	}
+	hash.Write(data)
+	return hex.EncodeToString(hash.Sum(nil)), data, nil
+}
+
+func (r *Recorder) redact(data []byte) []byte {
+	for _, secret := range r.secrets {
+		data = secret.ReplaceAll(data, []byte(Redacted))
+	}
+	return data
+}
+
+// replay returns the next recorded interaction for key
+func (r *Recorder) replay(key string) (interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mode == RecordAll {
+		return interaction{}, false
+	}
+	n := r.replayed[key]
+	for _, recorded := range r.cassette.Interactions {
+		if recorded.Key != key {
+			continue
+		}
+		if n == 0 {
+			r.replayed[key]++
+			return recorded, true
+		}
+		n--
+	}
+	return interaction{}, false
+}
+
+// record adds an interaction to the cassette and saves it. Responses are redacted too.
+func (r *Recorder) record(recorded interaction) error {
+	var err error
+	if recorded.Response, err = redactJSON(r, recorded.Response); err != nil {
+		return err
+	}
+	for i, chunk := range recorded.Stream {
+		if recorded.Stream[i], err = redactJSON(r, chunk); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.mode == RecordAll && r.recorded[recorded.Key] == 0 {
+		// Replace the recordings of earlier runs
+		kept := r.cassette.Interactions[:0]
+		for _, old := range r.cassette.Interactions {
+			if old.Key != recorded.Key {
+				kept = append(kept, old)
+			}
+		}
+		r.cassette.Interactions = kept
+	}
+	r.recorded[recorded.Key]++
+	r.cassette.Interactions = append(r.cassette.Interactions, recorded)
+
+	if supporter, ok := r.client.(types.ResponseFormatSupporter); ok {
+		r.cassette.ResponseFormats = make(map[types.ResponseFormatMode]bool)
+		for _, mode := range []types.ResponseFormatMode{types.ResponseFormatModeNative, types.ResponseFormatModeTool, types.ResponseFormatModePrompted, types.ResponseFormatModeJSON} {
+			r.cassette.ResponseFormats[mode] = supporter.SupportsResponseFormat(mode)
+		}
+	}
+	return r.save()
+}
+
+// save writes the cassette atomically; called with r.mu held
+func (r *Recorder) save() error {
+	data, err := json.Marshal(r.cassette, json.Deterministic(true))
+	if err != nil {
+		return fmt.Errorf("elysiatest: encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("elysiatest: save cassette: %w", err)
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("elysiatest: save cassette: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("elysiatest: save cassette: %w", err)
+	}
+	return nil
+}
+
+// redactJSON returns a copy of v with secrets redacted
+func redactJSON[T any](r *Recorder, v *T) (*T, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("elysiatest: encode response: %w", err)
+	}
+	redacted := new(T)
+	if err := json.Unmarshal(r.redact(data), redacted); err != nil {
+		return nil, fmt.Errorf("elysiatest: decode redacted response: %w", err)
+	}
+	return redacted, nil
+}
@@ -0,0 +1,95 @@
+package elysiatest_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KennyKeni/elysia/agent"
+	"github.com/KennyKeni/elysia/elysiatest"
+	"github.com/KennyKeni/elysia/types"
+)
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "greet.json")
+	const secret = "customer-1234"
+
+	run := func(client types.Client, stream bool) (string, error) {
+		t.Helper()
+		rec, err := elysiatest.NewRecorder(path, client, elysiatest.WithRedaction(secret))
+		if err != nil {
+			t.Fatalf("NewRecorder: %v", err)
+		}
+		a, err := agent.New[struct{}, string](rec, agent.WithTools[struct{}, string](newGreetTool(t)))
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		prompt := agent.WithPrompt("greet Ada for " + secret)
+		if !stream {
+			result, err := a.Run(context.Background(), struct{}{}, prompt)
+			if err != nil {
+				return "", err
+			}
+			return result.Messages[len(result.Messages)-1].TextContent(), nil
+		}
+		var text strings.Builder
+		for chunk, err := range a.StreamText(context.Background(), struct{}{}, prompt) {
+			if err != nil {
+				return "", err
+			}
+			text.WriteString(chunk)
+		}
+		return text.String(), nil
+	}
+
+	live := elysiatest.NewTestClient()
+	live.QueueToolCalls(elysiatest.ToolCall("greet", map[string]any{"name": "Ada"})).
+		QueueText("greeted Ada with key sk-abcdefghijklmnopqrstuvwxyz").
+		QueueToolCalls(elysiatest.ToolCall("greet", map[string]any{"name": "Ada"})).
+		QueueText("streamed greeting")
+
+	for _, stream := range []bool{false, true} {
+		if _, err := run(live, stream); err != nil {
+			t.Fatalf("recording (stream=%v): %v", stream, err)
+		}
+	}
+	if live.Remaining() != 0 {
+		t.Fatalf("Remaining = %d, want 0", live.Remaining())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	if strings.Contains(string(data), secret) || strings.Contains(string(data), "sk-abc") {
+		t.Errorf("cassette contains secrets:\n%s", data)
+	}
+
+	// Replay without a client
+	got, err := run(nil, false)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if want := "greeted Ada with key " + elysiatest.Redacted; got != want {
+		t.Errorf("replayed %q, want %q", got, want)
+	}
+	got, err = run(nil, true)
+	if err != nil {
+		t.Fatalf("stream replay: %v", err)
+	}
+	if got != "streamed greeting" {
+		t.Errorf("stream replayed %q, want %q", got, "streamed greeting")
+	}
+
+	rec, err := elysiatest.NewRecorder(path, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	params := &types.ChatParams{Messages: []types.Message{types.NewUserMessage(types.WithText("unknown"))}}
+	if _, err := rec.Chat(context.Background(), params); !errors.Is(err, elysiatest.ErrNotRecorded) {
+		t.Errorf("err = %v, want ErrNotRecorded", err)
+	}
+}
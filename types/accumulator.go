@@ -12,9 +12,16 @@ import (
 // It is safe for single-goroutine use and intended to be reset or recreated
 // per streaming choice.
 type MessageAccumulator struct {
+	finishReason string
+	usage        *Usage
+
 	role      Role
 	content   strings.Builder
 	refusal   strings.Builder
+	reasoning strings.Builder
+	signature string
+	redacted  []string
+	citations []Annotation
 	toolCalls map[int]*toolCallAccumulator
 	err       error
 }
@@ -48,6 +55,16 @@ func (ma *MessageAccumulator) Update(delta *MessageDelta) {
 	if delta.Refusal != "" {
 		ma.refusal.WriteString(delta.Refusal)
 	}
+	if delta.Reasoning != "" {
+		ma.reasoning.WriteString(delta.Reasoning)
+	}
+	if delta.ReasoningSignature != "" {
+		ma.signature = delta.ReasoningSignature
+	}
+	if delta.RedactedReasoning != "" {
+		ma.redacted = append(ma.redacted, delta.RedactedReasoning)
+	}
+	ma.citations = append(ma.citations, delta.Annotations...)
 
 	for i := range delta.ToolCalls {
 		callDelta := &delta.ToolCalls[i]
@@ -73,16 +90,68 @@ func (ma *MessageAccumulator) Update(delta *MessageDelta) {
 	}
 }
 
-// Message materialises the accumulated content into a Message. It returns an
-// error when tool call JSON arguments cannot be parsed.
+// UpdateChoice merges the delta of a streamed choice and records its finish reason.
+func (ma *MessageAccumulator) UpdateChoice(choice *StreamChoice) {
+	if choice == nil {
+		return
+	}
+	if choice.FinishReason != "" {
+		ma.finishReason = choice.FinishReason
+	}
+	ma.Update(choice.Delta)
+}
+
+// UpdateUsage records the token usage reported by a chunk. Providers report running
+// totals, so the latest usage replaces earlier ones.
+func (ma *MessageAccumulator) UpdateUsage(usage *Usage) {
+	if usage != nil {
+		ma.usage = usage
+	}
+}
+
+// FinishReason returns the finish reason of the choice, empty until the stream reports it.
+func (ma *MessageAccumulator) FinishReason() string {
+	return ma.finishReason
+}
+
+// Usage returns the latest token usage recorded with UpdateUsage, nil if none was.
+func (ma *MessageAccumulator) Usage() *Usage {
+	return ma.usage
+}
+
+// Message materialises the accumulated content into a Message. It returns the first error
+// encountered while accumulating deltas. Tool call arguments that can't be parsed, even after
+// RepairJSON, are left nil with the text received in RawArguments, as by the non-streaming
+// adapters; see ToolFunction.ArgumentsError.
 func (ma *MessageAccumulator) Message() (*Message, error) {
 	if ma.err != nil {
 		return nil, ma.err
 	}
+	return ma.message(), nil
+}
+
+// Snapshot returns the message accumulated so far, e.g. to show progress or to keep the
+// partial answer of a cancelled stream. It never fails: incomplete tool call arguments are
+// repaired where possible with RepairJSON, and otherwise left nil with the text received
+// in RawArguments.
+func (ma *MessageAccumulator) Snapshot() *Message {
+	return ma.message()
+}
 
+// message builds the accumulated message
+func (ma *MessageAccumulator) message() *Message {
 	msg := &Message{
 		Role:        ma.role,
 		ContentPart: make([]ContentPart, 0),
+		Annotations: ma.citations,
+	}
+
+	// Reasoning precedes the answer it produced
+	if ma.reasoning.Len() > 0 {
+		msg.ContentPart = append(msg.ContentPart, &ContentPartReasoning{Text: ma.reasoning.String(), Signature: ma.signature})
+	}
+	for _, data := range ma.redacted {
+		msg.ContentPart = append(msg.ContentPart, NewContentPartRedactedReasoning(data))
 	}
 
 	if ma.content.Len() > 0 {
@@ -107,22 +176,16 @@ func (ma *MessageAccumulator) Message() (*Message, error) {
 				continue
 			}
 
-			argsMap, err := tc.argumentsMap(idx)
-			if err != nil {
-				return nil, err
+			argsMap := tc.argumentsMap()
+			function := ToolFunction{Name: tc.name, Arguments: argsMap}
+			if raw := strings.TrimSpace(tc.arguments.String()); raw != "" && (argsMap == nil || !jsontext.Value(raw).IsValid()) {
+				function.RawArguments = raw // Repaired by argumentsMap, or unparsable
 			}
-
-			msg.ToolCalls = append(msg.ToolCalls, ToolCall{
-				ID: tc.id,
-				Function: ToolFunction{
-					Name:      tc.name,
-					Arguments: argsMap,
-				},
-			})
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{ID: tc.id, Function: function})
 		}
 	}
 
-	return msg, nil
+	return msg
 }
 
 // Error returns the first error encountered while accumulating deltas.
@@ -151,19 +214,17 @@ func (tc *toolCallAccumulator) tryParseArguments() error {
 	return nil
 }
 
-func (tc *toolCallAccumulator) argumentsMap(index int) (map[string]any, error) {
+// argumentsMap returns the parsed arguments, repaired if needed, or nil if they can't be parsed
+func (tc *toolCallAccumulator) argumentsMap() map[string]any {
 	if tc.parsed != nil {
-		return tc.parsed, nil
+		return tc.parsed
 	}
 
 	rawArgs := strings.TrimSpace(tc.arguments.String())
 	if rawArgs == "" {
-		return map[string]any{}, nil
+		return map[string]any{}
 	}
 
-	argsMap := map[string]any{}
-	if err := json.Unmarshal([]byte(rawArgs), &argsMap); err != nil {
-		return nil, fmt.Errorf("parse tool call %d arguments: %w", index, err)
-	}
-	return argsMap, nil
+	argsMap, _ := ParseToolArguments(rawArgs)
+	return argsMap
 }
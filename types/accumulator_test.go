@@ -1,6 +1,9 @@
 package types
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestMessageAccumulatorBuildsMessage(t *testing.T) {
 	acc := NewMessageAccumulator()
@@ -82,7 +85,97 @@ func TestMessageAccumulatorInvalidJSON(t *testing.T) {
 		},
 	})
 
-	if _, err := acc.Message(); err == nil {
-		t.Fatalf("expected error for invalid JSON arguments")
+	// Kept for the model to retry, like the non-streaming adapters do
+	msg, err := acc.Message()
+	if err != nil {
+		t.Fatalf("Message() returned error: %v", err)
+	}
+	function := msg.ToolCalls[0].Function
+	if function.Arguments != nil || function.RawArguments != `{"unterminated"` {
+		t.Fatalf("expected nil arguments with the raw text, got %+v", function)
+	}
+	var argsErr *ToolArgumentsError
+	if !errors.As(function.ArgumentsError(), &argsErr) {
+		t.Fatalf("expected a *ToolArgumentsError, got %v", function.ArgumentsError())
+	}
+}
+
+func TestMessageAccumulatorReasoning(t *testing.T) {
+	acc := NewMessageAccumulator()
+	acc.Update(&MessageDelta{Role: RoleAssistant, Reasoning: "Let me "})
+	acc.Update(&MessageDelta{Reasoning: "think."})
+	acc.Update(&MessageDelta{Content: "42"})
+
+	msg, err := acc.Message()
+	if err != nil {
+		t.Fatalf("Message() returned error: %v", err)
+	}
+
+	if len(msg.ContentPart) != 2 {
+		t.Fatalf("expected 2 content parts, got %d", len(msg.ContentPart))
+	}
+	if _, ok := msg.ContentPart[0].(*ContentPartReasoning); !ok {
+		t.Fatalf("expected reasoning first, got %T", msg.ContentPart[0])
+	}
+	if got := msg.ReasoningContent(); got != "Let me think." {
+		t.Errorf("expected reasoning %q, got %q", "Let me think.", got)
+	}
+	if got := msg.TextContent(); got != "42" {
+		t.Errorf("expected text %q, got %q", "42", got)
+	}
+}
+
+func TestMessageAccumulatorFinishReasonAndUsage(t *testing.T) {
+	acc := NewMessageAccumulator()
+	acc.UpdateChoice(&StreamChoice{Delta: &MessageDelta{Role: RoleAssistant, Content: "Hi"}})
+	acc.UpdateUsage(&Usage{PromptTokens: 3})
+	acc.UpdateChoice(&StreamChoice{Delta: &MessageDelta{}, FinishReason: "stop"})
+	acc.UpdateUsage(&Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4})
+	acc.UpdateUsage(nil)
+
+	if acc.FinishReason() != "stop" {
+		t.Errorf("expected finish reason stop, got %q", acc.FinishReason())
+	}
+	if acc.Usage() == nil || acc.Usage().TotalTokens != 4 {
+		t.Errorf("expected the latest usage, got %+v", acc.Usage())
+	}
+	if msg, err := acc.Message(); err != nil || msg.TextContent() != "Hi" {
+		t.Errorf("unexpected message %+v, error %v", msg, err)
+	}
+}
+
+func TestMessageAccumulatorSnapshot(t *testing.T) {
+	acc := NewMessageAccumulator()
+	acc.Update(&MessageDelta{
+		Role:    RoleAssistant,
+		Content: "Looking",
+		ToolCalls: []ToolCallDelta{
+			{Index: 0, ID: "call_1", FunctionName: "search", Arguments: `{"q": "ca`},
+			{Index: 1, ID: "call_2", FunctionName: "fetch", Arguments: `{"url"`},
+		},
+	})
+
+	snapshot := acc.Snapshot()
+	if snapshot.TextContent() != "Looking" || len(snapshot.ToolCalls) != 2 {
+		t.Fatalf("unexpected snapshot: %+v", snapshot)
+	}
+	if got := snapshot.ToolCalls[0].Function.Arguments["q"]; got != "ca" {
+		t.Errorf("expected repaired partial argument %q, got %v", "ca", got)
+	}
+	unparsable := snapshot.ToolCalls[1].Function
+	if unparsable.Arguments != nil || unparsable.RawArguments != `{"url"` {
+		t.Errorf("expected unparsable arguments kept raw, got %+v", unparsable)
+	}
+
+	acc.Update(&MessageDelta{Content: " up", ToolCalls: []ToolCallDelta{{Index: 1, Arguments: `: "x"}`}}})
+	if snapshot.TextContent() != "Looking" {
+		t.Errorf("expected the snapshot unaffected by later deltas, got %q", snapshot.TextContent())
+	}
+	msg, err := acc.Message()
+	if err != nil {
+		t.Fatalf("Message() returned error: %v", err)
+	}
+	if msg.TextContent() != "Looking up" || msg.ToolCalls[1].Function.Arguments["url"] != "x" {
+		t.Errorf("unexpected final message: %+v", msg)
 	}
 }
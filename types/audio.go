@@ -0,0 +1,51 @@
+package types
+
+import (
+	"context"
+	"io"
+)
+
+// Transcriber converts speech to text.
+// Adapters with a transcription endpoint implement it alongside RawClient.
+type Transcriber interface {
+	Transcribe(ctx context.Context, params *TranscriptionParams) (*TranscriptionResponse, error)
+}
+
+// Speaker converts text to speech.
+// Adapters with a speech endpoint implement it alongside RawClient.
+type Speaker interface {
+	Speak(ctx context.Context, params *SpeechParams) (*SpeechResponse, error)
+}
+
+type TranscriptionParams struct {
+	Model    string
+	Audio    io.Reader
+	Filename string // Its extension tells the provider the audio encoding, e.g. "question.mp3"
+	Language string // ISO-639-1 code of the spoken language (empty = detect)
+	Prompt   string // Context that guides spelling and style
+	Extra    map[string]any
+}
+
+type TranscriptionResponse struct {
+	Text     string
+	Language string  // Detected or requested language, if reported
+	Duration float64 // Audio length in seconds, if reported
+	Usage    *Usage
+	Extra    map[string]any
+}
+
+type SpeechParams struct {
+	Model        string
+	Input        string
+	Voice        string
+	Format       AudioFormat // Output encoding (empty = provider default)
+	Instructions string      // Tone and delivery guidance, for models that support it
+	Speed        *float64
+	Extra        map[string]any
+}
+
+// SpeechResponse streams the synthesized audio; the caller must close Audio.
+type SpeechResponse struct {
+	Audio  io.ReadCloser
+	Format AudioFormat
+}
@@ -0,0 +1,40 @@
+package types
+
+// CacheControl marks the end of a prompt prefix the provider should cache, so later requests
+// starting with the same prefix are billed at the cached rate. It is sent as cache_control by
+// Anthropic; providers that cache prefixes implicitly, like OpenAI, ignore it (see
+// ChatParams.PromptCacheKey).
+type CacheControl struct {
+	// TTL is how long the prefix stays cached, e.g. "5m" or "1h" ("" = provider default)
+	TTL string `json:"ttl,omitempty"`
+}
+
+// WithCacheControl marks the message as the end of a cached prefix.
+func WithCacheControl(cc CacheControl) MessageOption {
+	return func(m *Message) {
+		m.CacheControl = &cc
+	}
+}
+
+// WithSystemCacheControl caches the prefix ending with the system prompt.
+func WithSystemCacheControl(cc CacheControl) ChatParamOption {
+	return func(p *ChatParams) {
+		p.SystemCacheControl = &cc
+	}
+}
+
+// WithToolsCacheControl caches the prefix ending with the tool definitions, which come
+// before the system prompt.
+func WithToolsCacheControl(cc CacheControl) ChatParamOption {
+	return func(p *ChatParams) {
+		p.ToolsCacheControl = &cc
+	}
+}
+
+// WithPromptCacheKey routes requests sharing key to the same prompt cache (OpenAI), which
+// improves hit rates when many requests share a long prefix.
+func WithPromptCacheKey(key string) ChatParamOption {
+	return func(p *ChatParams) {
+		p.PromptCacheKey = key
+	}
+}
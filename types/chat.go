@@ -17,20 +17,127 @@ type ChatParams struct {
 	TopP        *float64 `json:"top_p,omitempty"`
 	TopK        *int     `json:"top_k,omitempty"` // Google, Anthropic
 
+	FrequencyPenalty *float64       `json:"frequency_penalty,omitempty"` // OpenAI, Cohere
+	PresencePenalty  *float64       `json:"presence_penalty,omitempty"`  // OpenAI, Cohere
+	LogitBias        map[string]int `json:"logit_bias,omitempty"`        // Token ID -> bias in [-100, 100] (OpenAI)
+
+	// Reasoning parameters
+	ReasoningEffort ReasoningEffort `json:"reasoning_effort,omitempty"` // OpenAI o-series and gpt-5
+
 	// Control parameters
 	Stop []string `json:"stop,omitempty"`
+	N    *int     `json:"n,omitempty"`    // Number of choices to generate (OpenAI)
+	Seed *int64   `json:"seed,omitempty"` // Best-effort deterministic sampling (OpenAI, Cohere)
 
 	// Tool parameters
-	Tools      []ToolDefinition `json:"tools,omitempty"`
-	ToolChoice *ToolChoice      `json:"tool_choice,omitempty"`
+	Tools             []ToolDefinition `json:"tools,omitempty"`
+	ToolChoice        *ToolChoice      `json:"tool_choice,omitempty"`
+	ParallelToolCalls *bool            `json:"parallel_tool_calls,omitempty"` // false = at most one tool call per response
 
 	// Response
 	ResponseFormat ResponseFormat
 
+	// Prompt caching, see CacheControl
+	SystemCacheControl *CacheControl `json:"system_cache_control,omitzero"` // Cache up to the end of the system prompt
+	ToolsCacheControl  *CacheControl `json:"tools_cache_control,omitzero"`  // Cache up to the end of the tool definitions
+	PromptCacheKey     string        `json:"prompt_cache_key,omitempty"`    // Groups requests sharing a prefix (OpenAI)
+
+	// Request handling (OpenAI)
+	Store       *bool             `json:"store,omitempty"`        // Keep the completion for the dashboard, evals and distillation
+	Metadata    map[string]string `json:"metadata,omitempty"`     // Tags for filtering stored completions
+	ServiceTier string            `json:"service_tier,omitempty"` // e.g. "auto", "default", "flex"
+
 	// Provider-specific extras
 	Extra map[string]any `json:"-"`
 }
 
+// Clone returns a deep copy of the params. Pointer fields, slices and maps are
+// copied so the clone can be mutated without affecting the original. Content
+// parts and JSON schema maps are treated as immutable and shared.
+func (p *ChatParams) Clone() *ChatParams {
+	if p == nil {
+		return nil
+	}
+
+	c := *p
+	c.Messages = cloneMessages(p.Messages)
+	c.StreamOptions = clonePtr(p.StreamOptions)
+	c.MaxTokens = clonePtr(p.MaxTokens)
+	c.Temperature = clonePtr(p.Temperature)
+	c.TopP = clonePtr(p.TopP)
+	c.TopK = clonePtr(p.TopK)
+	c.FrequencyPenalty = clonePtr(p.FrequencyPenalty)
+	c.PresencePenalty = clonePtr(p.PresencePenalty)
+	c.LogitBias = cloneMap(p.LogitBias)
+	c.Stop = cloneSlice(p.Stop)
+	c.N = clonePtr(p.N)
+	c.Seed = clonePtr(p.Seed)
+	c.Tools = cloneSlice(p.Tools)
+	c.ToolChoice = clonePtr(p.ToolChoice)
+	c.ParallelToolCalls = clonePtr(p.ParallelToolCalls)
+	c.Store = clonePtr(p.Store)
+	c.SystemCacheControl = clonePtr(p.SystemCacheControl)
+	c.ToolsCacheControl = clonePtr(p.ToolsCacheControl)
+	c.Metadata = cloneMap(p.Metadata)
+	c.Extra = cloneMap(p.Extra)
+
+	return &c
+}
+
+// CloneWithMessages returns a deep copy of the params with Messages replaced by msgs.
+// Useful for forking a conversation from a shared base configuration.
+func (p *ChatParams) CloneWithMessages(msgs []Message) *ChatParams {
+	c := p.Clone()
+	if c == nil {
+		return nil
+	}
+	c.Messages = cloneMessages(msgs)
+	return c
+}
+
+func cloneMessages(messages []Message) []Message {
+	if messages == nil {
+		return nil
+	}
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		out[i] = m
+		out[i].ContentPart = cloneSlice(m.ContentPart)
+		out[i].ToolCalls = cloneSlice(m.ToolCalls)
+		out[i].ToolCallID = clonePtr(m.ToolCallID)
+		out[i].Annotations = cloneSlice(m.Annotations)
+		out[i].Metadata = cloneMap(m.Metadata)
+		out[i].CacheControl = clonePtr(m.CacheControl)
+	}
+	return out
+}
+
+func clonePtr[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	out := make(map[K]V, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
 type ChatParamOption func(*ChatParams)
 
 func WithMessages(messages []Message) ChatParamOption {
@@ -69,6 +176,45 @@ func WithTopK(topK int) ChatParamOption {
 	}
 }
 
+func WithFrequencyPenalty(penalty float64) ChatParamOption {
+	return func(p *ChatParams) {
+		p.FrequencyPenalty = &penalty
+	}
+}
+
+func WithPresencePenalty(penalty float64) ChatParamOption {
+	return func(p *ChatParams) {
+		p.PresencePenalty = &penalty
+	}
+}
+
+// WithLogitBias sets per-token biases keyed by tokenizer token ID
+func WithLogitBias(bias map[string]int) ChatParamOption {
+	return func(p *ChatParams) {
+		p.LogitBias = bias
+	}
+}
+
+// WithN requests n choices per completion from providers that support it
+func WithN(n int) ChatParamOption {
+	return func(p *ChatParams) {
+		p.N = &n
+	}
+}
+
+// WithSeed requests best-effort deterministic sampling from providers that support it
+func WithSeed(seed int64) ChatParamOption {
+	return func(p *ChatParams) {
+		p.Seed = &seed
+	}
+}
+
+func WithReasoningEffort(effort ReasoningEffort) ChatParamOption {
+	return func(p *ChatParams) {
+		p.ReasoningEffort = effort
+	}
+}
+
 func WithResponseFormat(format ResponseFormat) ChatParamOption {
 	return func(p *ChatParams) {
 		p.ResponseFormat = format
@@ -87,6 +233,40 @@ func WithToolChoice(toolChoice ToolChoice) ChatParamOption {
 	}
 }
 
+// WithParallelToolCalls allows or forbids multiple tool calls in a single response
+func WithParallelToolCalls(enabled bool) ChatParamOption {
+	return func(p *ChatParams) {
+		p.ParallelToolCalls = &enabled
+	}
+}
+
+func WithStore(store bool) ChatParamOption {
+	return func(p *ChatParams) {
+		p.Store = &store
+	}
+}
+
+// WithRequestMetadata merges tags into the request metadata
+func WithRequestMetadata(metadata map[string]string) ChatParamOption {
+	return func(p *ChatParams) {
+		if len(metadata) == 0 {
+			return
+		}
+		if p.Metadata == nil {
+			p.Metadata = make(map[string]string, len(metadata))
+		}
+		for k, v := range metadata {
+			p.Metadata[k] = v
+		}
+	}
+}
+
+func WithServiceTier(tier string) ChatParamOption {
+	return func(p *ChatParams) {
+		p.ServiceTier = tier
+	}
+}
+
 func WithExtras(extras map[string]any) ChatParamOption {
 	return func(p *ChatParams) {
 		if len(extras) == 0 {
@@ -118,6 +298,16 @@ func WithStreamIncludeUsage() ChatParamOption {
 	return WithStreamOptions(StreamOptions{IncludeUsage: true})
 }
 
+// ReasoningEffort controls how much a reasoning model thinks before it answers.
+type ReasoningEffort string
+
+const (
+	ReasoningEffortMinimal ReasoningEffort = "minimal"
+	ReasoningEffortLow     ReasoningEffort = "low"
+	ReasoningEffortMedium  ReasoningEffort = "medium"
+	ReasoningEffortHigh    ReasoningEffort = "high"
+)
+
 type ResponseFormatMode string
 
 const (
@@ -132,6 +322,12 @@ const (
 	// ResponseFormatModePrompted adds instructions to return JSON matching the schema.
 	// Broadest compatibility but least reliable.
 	ResponseFormatModePrompted ResponseFormatMode = "prompted"
+
+	// ResponseFormatModeJSON asks for syntactically valid JSON through the provider's JSON mode
+	// (OpenAI response_format json_object), for output of unknown shape. Schema is optional: it
+	// is described in the prompt and validated, but not enforced by the provider.
+	// Falls back to Prompted mode if provider doesn't support it.
+	ResponseFormatModeJSON ResponseFormatMode = "json"
 )
 
 type ResponseFormat struct {
@@ -139,6 +335,18 @@ type ResponseFormat struct {
 	Name        string
 	Description string
 	Schema      map[string]any
+
+	// Strict rewrites Schema with StrictSchema before the request is built and asks the
+	// provider to enforce it exactly (OpenAI strict mode). Optional fields come back as null.
+	Strict bool
+
+	// AllowAdditionalProperties lets objects in the response hold properties the schema doesn't
+	// declare: Schema is rewritten with RelaxedSchema, so they are neither forbidden in the
+	// request nor rejected by validation. Provider strict modes forbid them, so it overrides Strict.
+	AllowAdditionalProperties bool
+
+	// ToolName names the output tool of Tool mode, see OutputToolName ("" = OutputToolName)
+	ToolName string
 }
 
 // ChatResponse represents the response from a chat completion request.
@@ -149,10 +357,20 @@ type ChatResponse struct {
 	Choices []Choice
 	Usage   *Usage
 
-	// Provider-specific extras
+	// SystemFingerprint identifies the backend configuration that served the request (OpenAI).
+	// Together with ChatParams.Seed, a change signals that outputs may differ across runs.
+	SystemFingerprint string
+
+	// Extra holds provider-specific data: the raw response under ExtraRaw, and fields without
+	// a unified counterpart under the provider's field name, such as "service_tier" (OpenAI)
+	// or "stop_sequence" (Anthropic)
 	Extra map[string]any `json:"-"`
 }
 
+// ExtraRaw is the ChatResponse.Extra and StreamChunk.Extra key holding the provider's raw JSON
+// payload as a jsontext.Value, for data the unified types don't carry.
+const ExtraRaw = "raw"
+
 // Choice represents a single completion choice in the response.
 type Choice struct {
 	Index        int
@@ -165,10 +383,38 @@ type Choice struct {
 }
 
 // Usage represents token usage statistics for the request.
+// The details break down PromptTokens and CompletionTokens; they are subsets, not additions.
 type Usage struct {
 	PromptTokens     int64
 	CompletionTokens int64
 	TotalTokens      int64
+
+	PromptTokensDetails     PromptTokensDetails
+	CompletionTokensDetails CompletionTokensDetails
+}
+
+type PromptTokensDetails struct {
+	CachedTokens int64 // Read from the provider's prompt cache
+	AudioTokens  int64
+}
+
+type CompletionTokensDetails struct {
+	ReasoningTokens int64 // Spent thinking, not visible in the output
+	AudioTokens     int64
+}
+
+// Add accumulates other into u, including the details. A nil other is a no-op.
+func (u *Usage) Add(other *Usage) {
+	if other == nil {
+		return
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.PromptTokensDetails.CachedTokens += other.PromptTokensDetails.CachedTokens
+	u.PromptTokensDetails.AudioTokens += other.PromptTokensDetails.AudioTokens
+	u.CompletionTokensDetails.ReasoningTokens += other.CompletionTokensDetails.ReasoningTokens
+	u.CompletionTokensDetails.AudioTokens += other.CompletionTokensDetails.AudioTokens
 }
 
 // ToolChoiceMode represents the mode for tool selection.
@@ -0,0 +1,224 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// echoRawClient returns a fixed text response and reads the params it receives.
+type echoRawClient struct {
+	mu    sync.Mutex
+	tools [][]ToolDefinition
+}
+
+func (c *echoRawClient) RawChat(ctx context.Context, params *ChatParams) (*ChatResponse, error) {
+	c.mu.Lock()
+	c.tools = append(c.tools, params.Tools)
+	c.mu.Unlock()
+
+	msg := &Message{Role: RoleAssistant}
+	if params.ResponseFormat.Mode == ResponseFormatModeTool {
+		msg.ToolCalls = []ToolCall{{ID: "out", Function: ToolFunction{Name: OutputToolName, Arguments: map[string]any{"n": float64(1)}}}}
+	} else {
+		msg.ContentPart = []ContentPart{NewContentPartText(`{"n": 1}`)}
+	}
+	return &ChatResponse{Choices: []Choice{{Message: msg}}}, nil
+}
+
+func (c *echoRawClient) RawChatStream(ctx context.Context, params *ChatParams) (*Stream, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (c *echoRawClient) RawEmbed(ctx context.Context, params *EmbeddingParams) (*EmbeddingResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func baseTestParams() *ChatParams {
+	maxTokens := 100
+	temperature := 0.5
+	toolCallID := "call-1"
+
+	tools := make([]ToolDefinition, 1, 4) // spare capacity exposes shared backing arrays
+	tools[0] = ToolDefinition{Name: "lookup", InputSchema: map[string]any{"type": "object"}}
+
+	return &ChatParams{
+		Model:        "test-model",
+		SystemPrompt: "You are helpful.",
+		Messages: []Message{
+			NewUserMessage(WithText("hi")),
+			NewToolMessage(WithText("result"), WithToolCallID(toolCallID)),
+		},
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+		Stop:        []string{"END"},
+		Tools:       tools,
+		ToolChoice:  ToolChoiceAuto(),
+		LogitBias:   map[string]int{"50256": -100},
+		Extra:       map[string]any{"seed": 1},
+		ResponseFormat: ResponseFormat{
+			Mode: ResponseFormatModeTool,
+			Schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"n": map[string]any{"type": "integer"}},
+			},
+		},
+	}
+}
+
+func TestChatParamsClone(t *testing.T) {
+	original := baseTestParams()
+	clone := original.Clone()
+
+	*clone.MaxTokens = 1
+	*clone.Temperature = 2
+	clone.Stop[0] = "STOP"
+	clone.Tools = append(clone.Tools, ToolDefinition{Name: "extra"})
+	clone.Tools[0].Name = "renamed"
+	clone.ToolChoice.Mode = ToolChoiceModeNone
+	clone.LogitBias["50256"] = 0
+	clone.Extra["seed"] = 2
+	clone.Messages[0].ContentPart = append(clone.Messages[0].ContentPart, NewContentPartText("more"))
+	*clone.Messages[1].ToolCallID = "changed"
+
+	if *original.MaxTokens != 100 || *original.Temperature != 0.5 {
+		t.Errorf("sampling pointers were shared: %d %f", *original.MaxTokens, *original.Temperature)
+	}
+	if original.Stop[0] != "END" {
+		t.Errorf("stop slice was shared")
+	}
+	if len(original.Tools) != 1 || original.Tools[0].Name != "lookup" {
+		t.Errorf("tools slice was shared: %+v", original.Tools)
+	}
+	if original.Tools[:cap(original.Tools)][1].Name == "extra" {
+		t.Errorf("tools backing array was shared")
+	}
+	if original.ToolChoice.Mode != ToolChoiceModeAuto {
+		t.Errorf("tool choice was shared")
+	}
+	if original.LogitBias["50256"] != -100 {
+		t.Errorf("logit bias map was shared")
+	}
+	if original.Extra["seed"] != 1 {
+		t.Errorf("extra map was shared")
+	}
+	if len(original.Messages[0].ContentPart) != 1 || *original.Messages[1].ToolCallID != "call-1" {
+		t.Errorf("messages were shared")
+	}
+
+	if (*ChatParams)(nil).Clone() != nil {
+		t.Errorf("expected nil clone of nil params")
+	}
+}
+
+func TestChatParamsCloneWithMessages(t *testing.T) {
+	original := baseTestParams()
+	fork := []Message{NewUserMessage(WithText("forked"))}
+
+	clone := original.CloneWithMessages(fork)
+	fork[0].Role = RoleAssistant
+
+	if len(clone.Messages) != 1 || clone.Messages[0].Role != RoleUser {
+		t.Errorf("expected cloned fork messages, got %+v", clone.Messages)
+	}
+	if len(original.Messages) != 2 {
+		t.Errorf("original messages changed: %+v", original.Messages)
+	}
+	if clone.Model != original.Model || clone.SystemPrompt != original.SystemPrompt {
+		t.Errorf("expected other fields to be copied")
+	}
+}
+
+func TestClientChatDoesNotMutateParams(t *testing.T) {
+	for _, mode := range []ResponseFormatMode{ResponseFormatModeTool, ResponseFormatModePrompted} {
+		t.Run(string(mode), func(t *testing.T) {
+			raw := &echoRawClient{}
+			client := NewClient(raw)
+
+			params := baseTestParams()
+			params.ResponseFormat.Mode = mode
+
+			if _, err := client.Chat(context.Background(), params); err != nil {
+				t.Fatalf("Chat returned error: %v", err)
+			}
+
+			if len(params.Tools) != 1 || params.Tools[:cap(params.Tools)][1].Name != "" {
+				t.Errorf("caller tools were mutated: %+v", params.Tools[:cap(params.Tools)])
+			}
+			if params.SystemPrompt != "You are helpful." {
+				t.Errorf("caller system prompt was mutated: %q", params.SystemPrompt)
+			}
+		})
+	}
+}
+
+func TestClientStripsMessageMetadata(t *testing.T) {
+	params := &ChatParams{Messages: []Message{NewUserMessage(WithText("hi"), WithMessageMetadata("author", "ada"))}}
+
+	raw := &recordingRawClient{}
+	if _, err := NewClient(raw).Chat(context.Background(), params); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if raw.messages[0].Metadata != nil {
+		t.Errorf("expected metadata to be stripped, got %v", raw.messages[0].Metadata)
+	}
+	if params.Messages[0].Metadata["author"] != "ada" {
+		t.Error("the caller's message metadata was modified")
+	}
+}
+
+func TestClientChatConcurrentSharedParams(t *testing.T) {
+	raw := &echoRawClient{}
+	client := NewClient(raw)
+	base := baseTestParams()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			params := base.CloneWithMessages([]Message{NewUserMessage(WithText(fmt.Sprintf("request %d", i)))})
+			if _, err := client.Chat(context.Background(), params); err != nil {
+				t.Errorf("Chat returned error: %v", err)
+			}
+			// Sharing the base directly must also be safe
+			if _, err := client.Chat(context.Background(), base); err != nil {
+				t.Errorf("Chat returned error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, tools := range raw.tools {
+		if len(tools) != 2 || tools[1].Name != OutputToolName {
+			t.Fatalf("expected lookup and %s tools, got %+v", OutputToolName, tools)
+		}
+	}
+	if len(base.Tools) != 1 {
+		t.Errorf("base tools were mutated: %+v", base.Tools)
+	}
+}
+
+func TestUsageAdd(t *testing.T) {
+	total := Usage{PromptTokens: 10, TotalTokens: 10}
+	total.Add(&Usage{
+		PromptTokens:            5,
+		CompletionTokens:        3,
+		TotalTokens:             8,
+		PromptTokensDetails:     PromptTokensDetails{CachedTokens: 4, AudioTokens: 1},
+		CompletionTokensDetails: CompletionTokensDetails{ReasoningTokens: 2, AudioTokens: 1},
+	})
+	total.Add(nil)
+
+	want := Usage{
+		PromptTokens:            15,
+		CompletionTokens:        3,
+		TotalTokens:             18,
+		PromptTokensDetails:     PromptTokensDetails{CachedTokens: 4, AudioTokens: 1},
+		CompletionTokensDetails: CompletionTokensDetails{ReasoningTokens: 2, AudioTokens: 1},
+	}
+	if total != want {
+		t.Errorf("got %+v, want %+v", total, want)
+	}
+}
@@ -1,6 +1,10 @@
 package types
 
-import "context"
+import (
+	"context"
+	"log/slog"
+	"time"
+)
 
 // RawClient is implemented by adapters - just provider-specific API calls.
 // Adapters should NOT be exported directly; use NewClient(raw) to wrap them.
@@ -18,18 +22,89 @@ type Client interface {
 	Embed(ctx context.Context, params *EmbeddingParams) (*EmbeddingResponse, error)
 }
 
+// ResponseFormatSupporter is implemented by RawClients that can only serve some ResponseFormat modes.
+// NewClient downgrades unsupported modes before the request is built, see EffectiveResponseFormat.
+type ResponseFormatSupporter interface {
+	SupportsResponseFormat(mode ResponseFormatMode) bool
+}
+
+// EffectiveResponseFormat returns rf with its mode downgraded to one c supports, falling back
+// Native -> Tool -> Prompted. Clients that don't implement ResponseFormatSupporter are assumed
+// to support every mode. Prompted is used when nothing else is supported.
+// A Strict format also gets its schema rewritten with StrictSchema, so the request and the
+// validation of the response use the same schema; so does a format allowing additional
+// properties, with RelaxedSchema. JSON mode without a schema gets an empty one, which any JSON
+// value matches.
+func EffectiveResponseFormat(c any, rf ResponseFormat) ResponseFormat {
+	if rf.AllowAdditionalProperties {
+		rf.Strict = false
+		rf.Schema = RelaxedSchema(rf.Schema)
+	}
+	if rf.Strict {
+		rf.Schema = StrictSchema(rf.Schema)
+	}
+	if rf.Mode == ResponseFormatModeJSON && rf.Schema == nil {
+		rf.Schema = map[string]any{}
+	}
+
+	supporter, ok := c.(ResponseFormatSupporter)
+	if !ok || rf.Schema == nil {
+		return rf
+	}
+
+	var candidates []ResponseFormatMode
+	switch rf.Mode {
+	case ResponseFormatModeNative:
+		candidates = []ResponseFormatMode{ResponseFormatModeNative, ResponseFormatModeTool}
+	case ResponseFormatModeTool:
+		candidates = []ResponseFormatMode{ResponseFormatModeTool}
+	case ResponseFormatModeJSON:
+		candidates = []ResponseFormatMode{ResponseFormatModeJSON}
+	default:
+		return rf
+	}
+
+	for _, mode := range candidates {
+		if supporter.SupportsResponseFormat(mode) {
+			rf.Mode = mode
+			return rf
+		}
+	}
+	rf.Mode = ResponseFormatModePrompted
+	return rf
+}
+
 type baseClient struct {
-	raw RawClient
+	raw        RawClient
+	logger     *slog.Logger        // Debug logs of requests and responses (nil = none)
+	logContent func(string) string // Redacts text included in logs (nil = text not logged)
+
+	convertDocument DocumentConverter // Converts documents the raw client can't read (nil = none)
+	chunkTimeout    time.Duration     // Longest wait for a stream chunk (0 = unlimited)
 }
 
-func NewClient(rc RawClient) Client {
-	return &baseClient{raw: rc}
+func NewClient(rc RawClient, opts ...ClientOption) Client {
+	bc := &baseClient{raw: rc}
+	for _, opt := range opts {
+		opt(bc)
+	}
+	return bc
 }
 
 func (bc *baseClient) Chat(ctx context.Context, params *ChatParams) (*ChatResponse, error) {
+	// Work on a copy so ApplyResponseFormat never mutates the caller's params
+	params = params.Clone()
+	params.ResponseFormat = EffectiveResponseFormat(bc.raw, params.ResponseFormat)
 	ApplyResponseFormat(params)
+	stripMetadata(params)
+	if err := bc.convertDocuments(ctx, params); err != nil {
+		return nil, err
+	}
 
+	bc.logRequest(ctx, "chat request", params)
+	start := time.Now()
 	resp, err := bc.raw.RawChat(ctx, params)
+	bc.logResponse(ctx, resp, err, time.Since(start))
 	if err != nil {
 		return nil, err
 	}
@@ -52,11 +127,35 @@ func (bc *baseClient) Chat(ctx context.Context, params *ChatParams) (*ChatRespon
 }
 
 func (bc *baseClient) ChatStream(ctx context.Context, params *ChatParams) (*Stream, error) {
+	params = params.Clone()
+	params.ResponseFormat = EffectiveResponseFormat(bc.raw, params.ResponseFormat)
 	ApplyResponseFormat(params)
-	return bc.raw.RawChatStream(ctx, params)
-	// Note: Streaming extraction happens in Accumulator (separate concern)
+	stripMetadata(params)
+	if err := bc.convertDocuments(ctx, params); err != nil {
+		return nil, err
+	}
+	bc.logRequest(ctx, "chat stream request", params)
+	stream, err := bc.raw.RawChatStream(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return stream.WithChunkTimeout(bc.chunkTimeout), nil
+	// Note: Streaming extraction happens in StreamWithHandler (separate concern)
+}
+
+// SupportsResponseFormat reports whether the wrapped RawClient supports mode
+func (bc *baseClient) SupportsResponseFormat(mode ResponseFormatMode) bool {
+	supporter, ok := bc.raw.(ResponseFormatSupporter)
+	return !ok || supporter.SupportsResponseFormat(mode)
 }
 
 func (bc *baseClient) Embed(ctx context.Context, params *EmbeddingParams) (*EmbeddingResponse, error) {
 	return bc.raw.RawEmbed(ctx, params)
 }
+
+// stripMetadata removes the message metadata from params, which providers never see
+func stripMetadata(params *ChatParams) {
+	for i := range params.Messages {
+		params.Messages[i].Metadata = nil
+	}
+}
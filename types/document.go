@@ -0,0 +1,89 @@
+package types
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// DocumentConverter turns a document the provider can't read into content parts it can, e.g.
+// the text extracted from a PDF. Returning the document itself sends it unchanged.
+type DocumentConverter func(ctx context.Context, document *ContentPartDocument) ([]ContentPart, error)
+
+// DocumentSupporter is implemented by RawClients whose provider reads some documents natively.
+// NewClient converts the other documents with the converter set by WithDocumentConverter.
+type DocumentSupporter interface {
+	SupportsDocument(document *ContentPartDocument) bool
+}
+
+// WithDocumentConverter converts the documents in requests that the provider can't read with
+// convert, before the adapter sees them. Without a converter, such documents are passed on and
+// rejected by the adapter with an UnsupportedContentError. See DocumentText.
+func WithDocumentConverter(convert DocumentConverter) ClientOption {
+	return func(bc *baseClient) {
+		bc.convertDocument = convert
+	}
+}
+
+// DocumentText is a DocumentConverter for text documents such as CSV, Markdown or JSON sent
+// inline: they become a text part holding their content, headed by their title or filename.
+// Other documents are returned unchanged.
+func DocumentText(_ context.Context, document *ContentPartDocument) ([]ContentPart, error) {
+	if document.Data == "" || !isTextDocument(document.MIMEType) {
+		return []ContentPart{document}, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(document.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decode document %q: %w", document.Filename, err)
+	}
+	text := string(data)
+	if name := cmp.Or(document.Title, document.Filename); name != "" {
+		text = name + ":\n\n" + text
+	}
+	return []ContentPart{NewContentPartText(text)}, nil
+}
+
+// isTextDocument reports whether documents of mimeType hold text
+func isTextDocument(mimeType string) bool {
+	switch mimeType {
+	case "application/json", "application/xml":
+		return true
+	}
+	return strings.HasPrefix(mimeType, "text/")
+}
+
+// convertDocuments replaces the documents of params the raw client can't read with the parts
+// returned by the converter. params must be a clone, whose content part slices it replaces.
+func (bc *baseClient) convertDocuments(ctx context.Context, params *ChatParams) error {
+	if bc.convertDocument == nil {
+		return nil
+	}
+	supporter, _ := bc.raw.(DocumentSupporter)
+	for i := range params.Messages {
+		message := &params.Messages[i]
+		var parts []ContentPart
+		for j, part := range message.ContentPart {
+			document, ok := part.(*ContentPartDocument)
+			if !ok || supporter != nil && supporter.SupportsDocument(document) {
+				if parts != nil {
+					parts = append(parts, part)
+				}
+				continue
+			}
+			converted, err := bc.convertDocument(ctx, document)
+			if err != nil {
+				return fmt.Errorf("convert document: %w", err)
+			}
+			if parts == nil {
+				parts = append(make([]ContentPart, 0, len(message.ContentPart)), message.ContentPart[:j]...)
+			}
+			parts = append(parts, converted...)
+		}
+		if parts != nil {
+			message.ContentPart = parts
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+)
+
+// recordingRawClient records the messages it is sent, and reads PDFs natively
+type recordingRawClient struct {
+	echoRawClient
+	messages []Message
+}
+
+func (c *recordingRawClient) RawChat(ctx context.Context, params *ChatParams) (*ChatResponse, error) {
+	c.messages = params.Messages
+	return c.echoRawClient.RawChat(ctx, params)
+}
+
+func (c *recordingRawClient) SupportsDocument(document *ContentPartDocument) bool {
+	return document.MIMEType == "application/pdf"
+}
+
+func TestClientDocumentConverter(t *testing.T) {
+	csv := NewContentPartDocumentData(base64.StdEncoding.EncodeToString([]byte("a,b\n1,2")), "text/csv", "data.csv")
+	pdf := NewContentPartDocumentData("JVBERi0=", "application/pdf", "report.pdf")
+	params := &ChatParams{Messages: []Message{NewUserMessage(WithText("Compare"), WithDocument(csv), WithDocument(pdf))}}
+
+	raw := &recordingRawClient{}
+	if _, err := NewClient(raw, WithDocumentConverter(DocumentText)).Chat(context.Background(), params); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	parts := raw.messages[0].ContentPart
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 content parts, got %d", len(parts))
+	}
+	if text, ok := parts[1].(*ContentPartText); !ok || text.Text != "data.csv:\n\na,b\n1,2" {
+		t.Errorf("expected the CSV as text, got %#v", parts[1])
+	}
+	if parts[2] != pdf {
+		t.Errorf("expected the supported PDF to be sent as is, got %#v", parts[2])
+	}
+	if params.Messages[0].ContentPart[1] != csv {
+		t.Error("the caller's message was modified")
+	}
+
+	failing := func(context.Context, *ContentPartDocument) ([]ContentPart, error) {
+		return nil, fmt.Errorf("no extractor")
+	}
+	if _, err := NewClient(raw, WithDocumentConverter(failing)).Chat(context.Background(), params); err == nil {
+		t.Error("expected the converter error")
+	}
+}
@@ -1,5 +1,22 @@
 package types
 
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	// ErrDimensionMismatch is returned when comparing vectors of different lengths.
+	ErrDimensionMismatch = errors.New("embedding dimensions do not match")
+
+	// ErrZeroVector is returned when a similarity is requested for a zero-magnitude vector.
+	ErrZeroVector = errors.New("embedding vector has zero magnitude")
+
+	// ErrNotEnoughEmbeddings is returned when a pair lookup receives fewer than two embeddings.
+	ErrNotEnoughEmbeddings = errors.New("at least two embeddings are required")
+)
+
 type EmbeddingParams struct {
 	Model          string
 	Input          []string
@@ -73,3 +90,77 @@ func NewEmbeddingParams(options ...EmbeddingParamsOption) *EmbeddingParams {
 	}
 	return e
 }
+
+// CosineSimilarity returns the cosine similarity of two vectors, in [-1, 1].
+func CosineSimilarity(a, b []float64) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("%w: %d != %d", ErrDimensionMismatch, len(a), len(b))
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0, ErrZeroVector
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), nil
+}
+
+// PairwiseSimilarity computes the symmetric NxN cosine similarity matrix of the embeddings.
+func PairwiseSimilarity(embeddings []Embedding) ([][]float64, error) {
+	n := len(embeddings)
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n)
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			score, err := CosineSimilarity(embeddings[i].Vector, embeddings[j].Vector)
+			if err != nil {
+				return nil, fmt.Errorf("embeddings %d and %d: %w", i, j, err)
+			}
+			matrix[i][j] = score
+			matrix[j][i] = score
+		}
+	}
+
+	return matrix, nil
+}
+
+// MostSimilarPair returns the indexes and score of the two most similar embeddings.
+func MostSimilarPair(embeddings []Embedding) (i, j int, score float64, err error) {
+	return extremePair(embeddings, func(candidate, best float64) bool { return candidate > best })
+}
+
+// LeastSimilarPair returns the indexes and score of the two least similar embeddings.
+func LeastSimilarPair(embeddings []Embedding) (i, j int, score float64, err error) {
+	return extremePair(embeddings, func(candidate, best float64) bool { return candidate < best })
+}
+
+// extremePair scans all distinct pairs and keeps the one for which better reports true.
+func extremePair(embeddings []Embedding, better func(candidate, best float64) bool) (int, int, float64, error) {
+	if len(embeddings) < 2 {
+		return 0, 0, 0, ErrNotEnoughEmbeddings
+	}
+
+	matrix, err := PairwiseSimilarity(embeddings)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	bestI, bestJ, best := 0, 1, matrix[0][1]
+	for i := 0; i < len(matrix); i++ {
+		for j := i + 1; j < len(matrix); j++ {
+			if better(matrix[i][j], best) {
+				bestI, bestJ, best = i, j, matrix[i][j]
+			}
+		}
+	}
+
+	return bestI, bestJ, best, nil
+}
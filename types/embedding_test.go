@@ -0,0 +1,83 @@
+package types
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+const similarityTolerance = 1e-9
+
+func testCorpus() []Embedding {
+	return []Embedding{
+		{Index: 0, Vector: []float64{1, 0, 0}},
+		{Index: 1, Vector: []float64{0.9, 0.1, 0}},
+		{Index: 2, Vector: []float64{-1, 0, 0.2}},
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	score, err := CosineSimilarity([]float64{1, 2}, []float64{2, 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(score-1) > similarityTolerance {
+		t.Errorf("expected parallel vectors to score 1, got %f", score)
+	}
+
+	if _, err := CosineSimilarity([]float64{1}, []float64{1, 2}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("expected ErrDimensionMismatch, got %v", err)
+	}
+	if _, err := CosineSimilarity([]float64{0, 0}, []float64{1, 2}); !errors.Is(err, ErrZeroVector) {
+		t.Errorf("expected ErrZeroVector, got %v", err)
+	}
+}
+
+func TestPairwiseSimilarity(t *testing.T) {
+	matrix, err := PairwiseSimilarity(testCorpus())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(matrix) != 3 {
+		t.Fatalf("expected 3x3 matrix, got %d rows", len(matrix))
+	}
+	for i := range matrix {
+		if math.Abs(matrix[i][i]-1) > similarityTolerance {
+			t.Errorf("expected diagonal [%d][%d] to be 1, got %f", i, i, matrix[i][i])
+		}
+		for j := range matrix[i] {
+			if matrix[i][j] != matrix[j][i] {
+				t.Errorf("matrix not symmetric at [%d][%d]: %f != %f", i, j, matrix[i][j], matrix[j][i])
+			}
+		}
+	}
+}
+
+func TestMostAndLeastSimilarPair(t *testing.T) {
+	i, j, score, err := MostSimilarPair(testCorpus())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i != 0 || j != 1 {
+		t.Errorf("expected most similar pair (0, 1), got (%d, %d)", i, j)
+	}
+	if score <= 0.9 {
+		t.Errorf("expected high similarity score, got %f", score)
+	}
+
+	i, j, score, err = LeastSimilarPair(testCorpus())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i != 0 || j != 2 {
+		t.Errorf("expected least similar pair (0, 2), got (%d, %d)", i, j)
+	}
+	if score >= 0 {
+		t.Errorf("expected negative similarity score, got %f", score)
+	}
+
+	if _, _, _, err := MostSimilarPair(testCorpus()[:1]); !errors.Is(err, ErrNotEnoughEmbeddings) {
+		t.Errorf("expected ErrNotEnoughEmbeddings, got %v", err)
+	}
+}
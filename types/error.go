@@ -1,8 +1,13 @@
 package types
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var ErrUnsupportedResponseMode = errors.New("adapter does not support this response format mode")
@@ -10,6 +15,9 @@ var ErrUnsupportedResponseMode = errors.New("adapter does not support this respo
 type SchemaValidationError struct {
 	RawResponse string
 	Err         error
+
+	// Fields lists the dotted paths of properties that failed validation, when known.
+	Fields []string
 }
 
 func (e *SchemaValidationError) Error() string {
@@ -26,13 +34,77 @@ type ToolNotCalledError struct {
 }
 
 type OutputToolMisuseError struct {
+	OutputTool string // Name of the output tool ("" = OutputToolName)
 	OtherTools []string
 }
 
 func (e *OutputToolMisuseError) Error() string {
-	return fmt.Sprintf("_output tool cannot be called alongside other tools: %v", e.OtherTools)
+	return fmt.Sprintf("%s tool cannot be called alongside other tools: %v", cmp.Or(e.OutputTool, OutputToolName), e.OtherTools)
 }
 
 func (e *ToolNotCalledError) Error() string {
 	return fmt.Sprintf("expected tool %q was not called", e.ExpectedTool)
 }
+
+// ErrUnknownModel is returned by ValidateModel when a model is not in an adapter's known model list.
+type ErrUnknownModel struct {
+	Adapter string
+	Model   string
+}
+
+func (e *ErrUnknownModel) Error() string {
+	return fmt.Sprintf("unknown model %q for adapter %q", e.Model, e.Adapter)
+}
+
+// UnsupportedContentError is returned by adapters for a content part the provider cannot accept.
+// It wraps the adapter's sentinel error (e.g. openai.ErrUnsupportedUserContentPart).
+type UnsupportedContentError struct {
+	Part ContentPart
+	Err  error
+}
+
+func (e *UnsupportedContentError) Error() string {
+	return fmt.Sprintf("%v: %T", e.Err, e.Part)
+}
+
+func (e *UnsupportedContentError) Unwrap() error {
+	return e.Err
+}
+
+// TransientError is implemented by provider errors for requests that may succeed if sent
+// again unchanged, such as rate limits (429) and server errors (5xx).
+type TransientError interface {
+	error
+	Transient() bool
+	// RetryAfter is the delay the provider asked for before retrying, zero if none.
+	RetryAfter() time.Duration
+}
+
+// IsTransient reports whether err or an error it wraps is a transient provider error,
+// along with the delay the provider asked for.
+func IsTransient(err error) (bool, time.Duration) {
+	var transient TransientError
+	if !errors.As(err, &transient) || !transient.Transient() {
+		return false, 0
+	}
+	return true, transient.RetryAfter()
+}
+
+// RetryAfter reads the delay a provider asked for from the Retry-After header, given in
+// seconds or as an HTTP date, and its retry-after-ms variant. It returns zero if neither is set.
+func RetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return max(0, time.Duration(seconds*float64(time.Second)))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(date))
+	}
+	return 0
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"context"
+	"fmt"
+)
+
+// HealthCheck verifies that a client can serve requests.
+type HealthCheck func(ctx context.Context, client Client) error
+
+// ProviderHealthCheck returns a HealthCheck that sends a minimal one-token chat
+// request to model and fails if the provider does not answer.
+func ProviderHealthCheck(model string) HealthCheck {
+	return func(ctx context.Context, client Client) error {
+		maxTokens := 1
+		resp, err := client.Chat(ctx, &ChatParams{
+			Model:     model,
+			Messages:  []Message{NewUserMessage(WithText("ping"))},
+			MaxTokens: &maxTokens,
+		})
+		if err != nil {
+			return fmt.Errorf("provider health check failed: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return fmt.Errorf("provider health check failed: response contained no choices")
+		}
+		return nil
+	}
+}
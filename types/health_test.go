@@ -0,0 +1,30 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type failingRawClient struct {
+	echoRawClient
+	err error
+}
+
+func (c *failingRawClient) RawChat(ctx context.Context, params *ChatParams) (*ChatResponse, error) {
+	return nil, c.err
+}
+
+func TestProviderHealthCheck(t *testing.T) {
+	check := ProviderHealthCheck("test-model")
+
+	if err := check(context.Background(), NewClient(&echoRawClient{})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	unavailable := errors.New("service unavailable")
+	err := check(context.Background(), NewClient(&failingRawClient{err: unavailable}))
+	if !errors.Is(err, unavailable) {
+		t.Fatalf("expected wrapped provider error, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package types
+
+import "context"
+
+// ImageGenerator creates images from a text prompt.
+// Adapters with an image generation endpoint implement it alongside RawClient.
+type ImageGenerator interface {
+	GenerateImage(ctx context.Context, params *ImageParams) (*ImageResponse, error)
+}
+
+type ImageParams struct {
+	Model        string
+	Prompt       string
+	N            *int   // Number of images (nil = 1)
+	Size         string // e.g. "1024x1024" (empty = provider default)
+	Quality      string // Provider-defined, e.g. "low", "high"
+	OutputFormat string // "png", "jpeg" or "webp" (empty = provider default)
+	Extra        map[string]any
+}
+
+type ImageResponse struct {
+	Created int64
+	Images  []GeneratedImage
+	Usage   *Usage
+	Extra   map[string]any
+}
+
+// GeneratedImage holds either base64 Data or a URL, depending on the provider and model.
+type GeneratedImage struct {
+	Data          string
+	URL           string
+	MIMEType      string // Media type of Data, e.g. "image/png"
+	RevisedPrompt string // Prompt the provider actually used, if it rewrote it
+}
+
+// ContentPart returns the image as a message content part, so it can be passed back to a model.
+func (g *GeneratedImage) ContentPart() ContentPart {
+	if g.Data == "" {
+		return NewContentPartImageURL(g.URL)
+	}
+	return &ContentPartImage{Data: g.Data, MIMEType: g.MIMEType}
+}
@@ -0,0 +1,15 @@
+package types
+
+import "testing"
+
+func TestGeneratedImageContentPart(t *testing.T) {
+	image := &GeneratedImage{Data: "aGk=", MIMEType: "image/png"}
+	if part, ok := image.ContentPart().(*ContentPartImage); !ok || part.Data != "aGk=" || part.MIMEType != "image/png" {
+		t.Errorf("expected base64 image part, got %#v", image.ContentPart())
+	}
+
+	image = &GeneratedImage{URL: "https://example.com/a.png"}
+	if part, ok := image.ContentPart().(*ContentPartImageURL); !ok || part.URL != image.URL {
+		t.Errorf("expected image URL part, got %#v", image.ContentPart())
+	}
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ClientOption configures the Client returned by NewClient.
+type ClientOption func(*baseClient)
+
+// WithLogger logs requests, responses and errors to logger at debug level. Message and
+// response text is only logged with WithLogContent. A nil logger disables logging.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(bc *baseClient) {
+		bc.logger = logger
+	}
+}
+
+// WithLogContent includes the text of the last message and of responses in logs, passed
+// through redact first to mask secrets or PII. A nil redact leaves text out of logs.
+func WithLogContent(redact func(text string) string) ClientOption {
+	return func(bc *baseClient) {
+		bc.logContent = redact
+	}
+}
+
+// logEnabled reports whether debug logs would be written
+func (bc *baseClient) logEnabled(ctx context.Context) bool {
+	return bc.logger != nil && bc.logger.Enabled(ctx, slog.LevelDebug)
+}
+
+func (bc *baseClient) logRequest(ctx context.Context, msg string, params *ChatParams) {
+	if !bc.logEnabled(ctx) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("model", params.Model),
+		slog.Int("messages", len(params.Messages)),
+		slog.Int("tools", len(params.Tools)),
+	}
+	if params.ResponseFormat.Schema != nil {
+		attrs = append(attrs, slog.String("response_format", string(params.ResponseFormat.Mode)))
+	}
+	if bc.logContent != nil && len(params.Messages) > 0 {
+		last := &params.Messages[len(params.Messages)-1]
+		attrs = append(attrs, slog.String("last_message", bc.logContent(last.TextContent())))
+	}
+	bc.logger.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
+
+func (bc *baseClient) logResponse(ctx context.Context, resp *ChatResponse, err error, elapsed time.Duration) {
+	if !bc.logEnabled(ctx) {
+		return
+	}
+	if err != nil {
+		bc.logger.LogAttrs(ctx, slog.LevelDebug, "chat request failed", slog.Duration("elapsed", elapsed), slog.Any("error", err))
+		return
+	}
+	if resp == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("id", resp.ID),
+		slog.String("model", resp.Model),
+		slog.Duration("elapsed", elapsed),
+	}
+	if resp.Usage != nil {
+		attrs = append(attrs,
+			slog.Int64("prompt_tokens", resp.Usage.PromptTokens),
+			slog.Int64("completion_tokens", resp.Usage.CompletionTokens),
+		)
+	}
+	for _, choice := range resp.Choices {
+		attrs = append(attrs, slog.String("finish_reason", choice.FinishReason))
+		if choice.Message == nil {
+			continue
+		}
+		for _, tc := range choice.Message.ToolCalls {
+			attrs = append(attrs, slog.String("tool_call", tc.Function.Name))
+		}
+		if bc.logContent != nil {
+			attrs = append(attrs, slog.String("text", bc.logContent(choice.Message.TextContent())))
+		}
+	}
+	bc.logger.LogAttrs(ctx, slog.LevelDebug, "chat response", attrs...)
+}
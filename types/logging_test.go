@@ -0,0 +1,38 @@
+package types
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestClientLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	params := &ChatParams{Model: "test-model", Messages: []Message{NewUserMessage(WithText("my password is hunter2"))}}
+
+	c := NewClient(&echoRawClient{}, WithLogger(logger))
+	if _, err := c.Chat(context.Background(), params); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	logs := buf.String()
+	if !strings.Contains(logs, `msg="chat request" model=test-model messages=1`) || !strings.Contains(logs, `msg="chat response"`) {
+		t.Errorf("expected request and response logs, got:\n%s", logs)
+	}
+	if strings.Contains(logs, "hunter2") {
+		t.Errorf("text logged without WithLogContent:\n%s", logs)
+	}
+
+	buf.Reset()
+	redact := func(text string) string { return strings.ReplaceAll(text, "hunter2", "***") }
+	c = NewClient(&echoRawClient{}, WithLogger(logger), WithLogContent(redact))
+	if _, err := c.Chat(context.Background(), params); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if logs := buf.String(); !strings.Contains(logs, `last_message="my password is ***"`) || strings.Contains(logs, "hunter2") {
+		t.Errorf("expected redacted text, got:\n%s", logs)
+	}
+}
@@ -1,6 +1,11 @@
 package types
 
-import "strings"
+import (
+	"encoding/json/v2"
+	"fmt"
+	"html"
+	"strings"
+)
 
 type ContentPart interface {
 	IsContentPart()
@@ -27,6 +32,144 @@ type Message struct {
 	ContentPart []ContentPart `json:"content_part"`
 	ToolCalls   []ToolCall    `json:"tool_calls,omitempty"`
 	ToolCallID  *string       `json:"tool_call_id,omitempty"` // For RoleTool messages - references which call this respond to
+
+	// Annotations are the sources the model cited in an assistant message
+	Annotations []Annotation `json:"annotations,omitempty"`
+
+	// Metadata holds application data about the message, such as timestamps or authorship,
+	// kept in history but never sent to providers. See WithMessageMetadata.
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// CacheControl marks the message as the end of a cached prompt prefix (nil = none)
+	CacheControl *CacheControl `json:"cache_control,omitzero"`
+}
+
+// messageJSON is the wire representation of a Message.
+type messageJSON struct {
+	Role        Role              `json:"role"`
+	ContentPart []contentPartJSON `json:"content_part"`
+	ToolCalls   []ToolCall        `json:"tool_calls,omitempty"`
+	ToolCallID  *string           `json:"tool_call_id,omitempty"`
+	Annotations []Annotation      `json:"annotations,omitempty"`
+	Metadata    map[string]any    `json:"metadata,omitempty"`
+
+	CacheControl *CacheControl `json:"cache_control,omitzero"`
+}
+
+// contentPartJSON is the wire representation of a ContentPart, discriminated by Type.
+type contentPartJSON struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	MIME     string `json:"mime_type,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Refusal  string `json:"refusal,omitempty"`
+	Format   string `json:"format,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Title    string `json:"title,omitempty"`
+
+	Citations bool   `json:"citations,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Redacted  bool   `json:"redacted,omitempty"`
+
+	CacheControl *CacheControl `json:"cache_control,omitzero"`
+}
+
+const (
+	contentPartTypeText      = "text"
+	contentPartTypeImage     = "image"
+	contentPartTypeImageURL  = "image_url"
+	contentPartTypeRefusal   = "refusal"
+	contentPartTypeReasoning = "reasoning"
+	contentPartTypeAudio     = "audio"
+	contentPartTypeFile      = "file"
+	contentPartTypeDocument  = "document"
+)
+
+// MarshalJSON implements json.Marshaler for Message.
+// Content parts are encoded with a "type" discriminator so they can be decoded again.
+func (m Message) MarshalJSON() ([]byte, error) {
+	wire := messageJSON{
+		Role:        m.Role,
+		ContentPart: make([]contentPartJSON, 0, len(m.ContentPart)),
+		ToolCalls:   m.ToolCalls,
+		ToolCallID:  m.ToolCallID,
+		Annotations: m.Annotations,
+		Metadata:    m.Metadata,
+
+		CacheControl: m.CacheControl,
+	}
+
+	for _, part := range m.ContentPart {
+		switch p := part.(type) {
+		case *ContentPartText:
+			wire.ContentPart = append(wire.ContentPart, contentPartJSON{Type: contentPartTypeText, Text: p.Text, CacheControl: p.CacheControl})
+		case *ContentPartImage:
+			wire.ContentPart = append(wire.ContentPart, contentPartJSON{Type: contentPartTypeImage, Data: p.Data, Detail: p.Detail, MIME: p.MIMEType})
+		case *ContentPartImageURL:
+			wire.ContentPart = append(wire.ContentPart, contentPartJSON{Type: contentPartTypeImageURL, URL: p.URL})
+		case *ContentPartRefusal:
+			wire.ContentPart = append(wire.ContentPart, contentPartJSON{Type: contentPartTypeRefusal, Refusal: p.Refusal})
+		case *ContentPartReasoning:
+			wire.ContentPart = append(wire.ContentPart, contentPartJSON{Type: contentPartTypeReasoning, Text: p.Text, Signature: p.Signature, Redacted: p.Redacted})
+		case *ContentPartAudio:
+			wire.ContentPart = append(wire.ContentPart, contentPartJSON{Type: contentPartTypeAudio, Data: p.Data, Format: string(p.Format)})
+		case *ContentPartFile:
+			wire.ContentPart = append(wire.ContentPart, contentPartJSON{Type: contentPartTypeFile, FileID: p.FileID, Data: p.Data, Filename: p.Filename, MIME: p.MIMEType})
+		case *ContentPartDocument:
+			wire.ContentPart = append(wire.ContentPart, contentPartJSON{Type: contentPartTypeDocument, Data: p.Data, URL: p.URL, Filename: p.Filename, Title: p.Title, MIME: p.MIMEType, Citations: p.Citations})
+		default:
+			return nil, fmt.Errorf("cannot marshal content part of type %T", part)
+		}
+	}
+
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler for Message.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var wire messageJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	parts := make([]ContentPart, 0, len(wire.ContentPart))
+	for _, p := range wire.ContentPart {
+		switch p.Type {
+		case contentPartTypeText:
+			parts = append(parts, &ContentPartText{Text: p.Text, CacheControl: p.CacheControl})
+		case contentPartTypeImage:
+			parts = append(parts, &ContentPartImage{Data: p.Data, Detail: p.Detail, MIMEType: p.MIME})
+		case contentPartTypeImageURL:
+			parts = append(parts, &ContentPartImageURL{URL: p.URL})
+		case contentPartTypeRefusal:
+			parts = append(parts, &ContentPartRefusal{Refusal: p.Refusal})
+		case contentPartTypeReasoning:
+			parts = append(parts, &ContentPartReasoning{Text: p.Text, Signature: p.Signature, Redacted: p.Redacted})
+		case contentPartTypeAudio:
+			parts = append(parts, &ContentPartAudio{Data: p.Data, Format: AudioFormat(p.Format)})
+		case contentPartTypeFile:
+			parts = append(parts, &ContentPartFile{FileID: p.FileID, Data: p.Data, Filename: p.Filename, MIMEType: p.MIME})
+		case contentPartTypeDocument:
+			parts = append(parts, &ContentPartDocument{Data: p.Data, URL: p.URL, Filename: p.Filename, Title: p.Title, MIMEType: p.MIME, Citations: p.Citations})
+		default:
+			return fmt.Errorf("unknown content part type %q", p.Type)
+		}
+	}
+
+	*m = Message{
+		Role:        wire.Role,
+		ContentPart: parts,
+		ToolCalls:   wire.ToolCalls,
+		ToolCallID:  wire.ToolCallID,
+		Annotations: wire.Annotations,
+		Metadata:    wire.Metadata,
+
+		CacheControl: wire.CacheControl,
+	}
+	return nil
 }
 
 func (m *Message) TextContent() string {
@@ -41,18 +184,47 @@ func (m *Message) TextContent() string {
 	return strings.Join(parts, "")
 }
 
+// ReasoningContent returns the message's readable reasoning parts concatenated, without
+// redacted reasoning
+func (m *Message) ReasoningContent() string {
+	var parts []string
+
+	for _, part := range m.ContentPart {
+		if r, ok := part.(*ContentPartReasoning); ok && !r.Redacted {
+			parts = append(parts, r.Text)
+		}
+	}
+
+	return strings.Join(parts, "")
+}
+
+// ToHTML returns the message's text parts concatenated and HTML-escaped,
+// safe for embedding in HTML templates.
+func (m *Message) ToHTML() string {
+	return html.EscapeString(m.TextContent())
+}
+
 type ContentPartText struct {
 	Text string `json:"text"`
+
+	// CacheControl marks the text as the end of a cached prompt prefix (nil = none)
+	CacheControl *CacheControl `json:"cache_control,omitzero"`
 }
 
 func (*ContentPartText) IsContentPart() {}
 
+// ToHTML returns the text HTML-escaped.
+func (p *ContentPartText) ToHTML() string { return html.EscapeString(p.Text) }
+
 func NewContentPartText(text string) *ContentPartText { return &ContentPartText{Text: text} }
 
 // ContentPartImage uses Base64 data values
 type ContentPartImage struct {
 	Data   string `json:"data"`
 	Detail string `json:"detail"`
+
+	// MIMEType is the media type of Data (e.g. "image/jpeg"); adapters assume image/png when empty.
+	MIMEType string `json:"mime_type,omitempty"`
 }
 
 func NewContentPartImage(data string) *ContentPartImage { return &ContentPartImage{Data: data} }
@@ -80,6 +252,119 @@ func NewContentPartRefusal(refusal string) *ContentPartRefusal {
 
 func (*ContentPartRefusal) IsContentPart() {}
 
+// ContentPartReasoning holds the model's reasoning (chain of thought) for an assistant message,
+// kept apart from the final answer. Reasoning with a Signature, or Redacted, is sent back to the
+// provider that produced it, which for Anthropic extended thinking is required while the model
+// uses tools; adapters drop other reasoning.
+type ContentPartReasoning struct {
+	Text      string `json:"text"`
+	Signature string `json:"signature,omitempty"` // Proves to the provider that Text is unmodified
+	Redacted  bool   `json:"redacted,omitempty"`  // Text is encrypted reasoning, hidden by the provider
+}
+
+func NewContentPartReasoning(text string) *ContentPartReasoning {
+	return &ContentPartReasoning{Text: text}
+}
+
+// NewContentPartRedactedReasoning returns reasoning the provider encrypted, e.g. Anthropic
+// redacted_thinking, which is only sent back.
+func NewContentPartRedactedReasoning(data string) *ContentPartReasoning {
+	return &ContentPartReasoning{Text: data, Redacted: true}
+}
+
+func (*ContentPartReasoning) IsContentPart() {}
+
+// AudioFormat is the encoding of ContentPartAudio data.
+type AudioFormat string
+
+const (
+	AudioFormatWAV AudioFormat = "wav"
+	AudioFormatMP3 AudioFormat = "mp3"
+)
+
+// ContentPartAudio is base64-encoded audio input for audio-capable models (e.g. gpt-4o-audio-preview)
+type ContentPartAudio struct {
+	Data   string      `json:"data"`
+	Format AudioFormat `json:"format"`
+}
+
+func NewContentPartAudio(data string, format AudioFormat) *ContentPartAudio {
+	return &ContentPartAudio{Data: data, Format: format}
+}
+
+func (*ContentPartAudio) IsContentPart() {}
+
+// ContentPartFile is a document such as a PDF, referenced by an uploaded FileID or sent inline
+// as base64 Data with a Filename.
+type ContentPartFile struct {
+	FileID   string `json:"file_id,omitempty"`
+	Data     string `json:"data,omitempty"`
+	Filename string `json:"filename,omitempty"`
+
+	// MIMEType is the media type of Data; adapters assume application/pdf when empty.
+	MIMEType string `json:"mime_type,omitempty"`
+}
+
+func NewContentPartFileID(fileID string) *ContentPartFile { return &ContentPartFile{FileID: fileID} }
+func NewContentPartFileData(data, filename string) *ContentPartFile {
+	return &ContentPartFile{Data: data, Filename: filename}
+}
+
+func (*ContentPartFile) IsContentPart() {}
+
+// ContentPartDocument is a document such as a PDF or CSV, sent inline as base64 Data or by URL,
+// for models that read documents natively (Anthropic). For other providers, documents are
+// converted with WithDocumentConverter.
+type ContentPartDocument struct {
+	Data     string `json:"data,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Title    string `json:"title,omitempty"` // Shown to the model with the document
+
+	// Citations asks the model to cite the passages of the document it uses, reported as
+	// Message.Annotations
+	Citations bool `json:"citations,omitempty"`
+
+	// MIMEType is the media type of the document; adapters assume application/pdf when empty.
+	MIMEType string `json:"mime_type,omitempty"`
+}
+
+func NewContentPartDocumentData(data, mimeType, filename string) *ContentPartDocument {
+	return &ContentPartDocument{Data: data, MIMEType: mimeType, Filename: filename}
+}
+func NewContentPartDocumentURL(url string) *ContentPartDocument {
+	return &ContentPartDocument{URL: url}
+}
+
+func (*ContentPartDocument) IsContentPart() {}
+
+// AnnotationType is the kind of source an Annotation cites.
+type AnnotationType string
+
+const (
+	AnnotationURLCitation      AnnotationType = "url_citation"      // A web page, e.g. found by web search
+	AnnotationFileCitation     AnnotationType = "file_citation"     // An uploaded file, e.g. found by file search
+	AnnotationDocumentCitation AnnotationType = "document_citation" // A document of the request, see ContentPartDocument
+)
+
+// Annotation is a source the model cited for a span of its answer, so applications can show
+// where an answer came from. Only the fields relevant to Type are set.
+type Annotation struct {
+	Type AnnotationType `json:"type"`
+
+	// StartIndex and EndIndex delimit the cited span of the message's TextContent, in
+	// characters; they are equal for citations of a position
+	StartIndex int `json:"start_index"`
+	EndIndex   int `json:"end_index"`
+
+	URL           string `json:"url,omitempty"`
+	Title         string `json:"title,omitempty"` // Of the web page or document
+	FileID        string `json:"file_id,omitempty"`
+	Filename      string `json:"filename,omitempty"`
+	DocumentIndex int    `json:"document_index,omitzero"` // Position of the document among the request's documents
+	CitedText     string `json:"cited_text,omitempty"`    // The passage of the source that is cited
+}
+
 type ToolCall struct {
 	ID       string       `json:"id"`
 	Function ToolFunction `json:"function"`
@@ -88,6 +373,20 @@ type ToolCall struct {
 type ToolFunction struct {
 	Name      string         `json:"name"`
 	Arguments map[string]any `json:"arguments"`
+
+	// RawArguments holds the arguments as sent by the model when they were not valid JSON.
+	// Arguments then holds their repair, or nil if they could not be repaired.
+	RawArguments string `json:"raw_arguments,omitempty"`
+}
+
+// ArgumentsError returns the *ToolArgumentsError of arguments that could not be repaired,
+// nil if Arguments holds them.
+func (f ToolFunction) ArgumentsError() error {
+	if f.Arguments != nil || f.RawArguments == "" {
+		return nil
+	}
+	_, err := ParseToolArguments(f.RawArguments)
+	return err
 }
 
 type MessageOption func(*Message)
@@ -98,12 +397,49 @@ func WithText(text string) MessageOption {
 	}
 }
 
+// MessageContentOption transforms text before it is stored in a content part.
+type MessageContentOption func(text string) string
+
+// EscapeHTML is a MessageContentOption that HTML-escapes <, >, &, ' and ".
+func EscapeHTML() MessageContentOption {
+	return html.EscapeString
+}
+
+// WithTextContent appends a ContentPartText after applying the content options in order.
+func WithTextContent(text string, opts ...MessageContentOption) MessageOption {
+	for _, opt := range opts {
+		text = opt(text)
+	}
+	return WithText(text)
+}
+
 func WithImage(data string) MessageOption {
 	return func(m *Message) {
 		m.ContentPart = append(m.ContentPart, &ContentPartImage{Data: data})
 	}
 }
 
+// WithAudio appends base64-encoded audio in the given format
+func WithAudio(data string, format AudioFormat) MessageOption {
+	return func(m *Message) {
+		m.ContentPart = append(m.ContentPart, NewContentPartAudio(data, format))
+	}
+}
+
+// WithFile appends a file content part
+func WithFile(file *ContentPartFile) MessageOption {
+	return func(m *Message) {
+		m.ContentPart = append(m.ContentPart, file)
+	}
+}
+
+// WithDocument appends a document content part
+func WithDocument(document *ContentPartDocument) MessageOption {
+	return func(m *Message) {
+		m.ContentPart = append(m.ContentPart, document)
+	}
+}
+
 func WithToolCalls(toolCalls ...ToolCall) MessageOption {
 	return func(m *Message) {
 		m.ToolCalls = append(m.ToolCalls, toolCalls...)
@@ -116,6 +452,16 @@ func WithToolCallID(toolCallID string) MessageOption {
 	}
 }
 
+// WithMessageMetadata sets the metadata entry key to value.
+func WithMessageMetadata(key string, value any) MessageOption {
+	return func(m *Message) {
+		if m.Metadata == nil {
+			m.Metadata = make(map[string]any)
+		}
+		m.Metadata[key] = value
+	}
+}
+
 func NewUserMessage(opts ...MessageOption) Message {
 	m := Message{Role: RoleUser, ContentPart: make([]ContentPart, 0)}
 	for _, opt := range opts {
@@ -124,6 +470,12 @@ func NewUserMessage(opts ...MessageOption) Message {
 	return m
 }
 
+// NewUserMessageSafe creates a user message whose text is HTML-escaped,
+// for input that originates from a web frontend.
+func NewUserMessageSafe(text string) Message {
+	return NewUserMessage(WithTextContent(text, EscapeHTML()))
+}
+
 func NewAssistantMessage(opts ...MessageOption) Message {
 	m := Message{Role: RoleAssistant, ContentPart: make([]ContentPart, 0)}
 	for _, opt := range opts {
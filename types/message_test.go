@@ -0,0 +1,89 @@
+package types
+
+import (
+	"encoding/json/v2"
+	"reflect"
+	"testing"
+)
+
+func TestMessageJSONRoundTrip(t *testing.T) {
+	callID := "call_1"
+	messages := []Message{
+		NewUserMessage(
+			WithText("Describe these"),
+			func(m *Message) {
+				m.ContentPart = append(m.ContentPart,
+					NewContentPartImageWithDetail("aGVsbG8=", ImageDetailHigh),
+					&ContentPartImage{Data: "aGk=", MIMEType: "image/jpeg"},
+					NewContentPartImageURL("https://example.com/cat.png"),
+				)
+			},
+		),
+		NewAssistantMessage(
+			WithText("Looking up"),
+			WithToolCalls(ToolCall{
+				ID:       callID,
+				Function: ToolFunction{Name: "lookup", Arguments: map[string]any{"q": "cats"}},
+			}),
+		),
+		NewToolMessage(WithText(`{"found":true}`), WithToolCallID(callID)),
+		{Role: RoleAssistant, ContentPart: []ContentPart{NewContentPartRefusal("no")}},
+		{Role: RoleAssistant, ContentPart: []ContentPart{NewContentPartText("Sunny.")}, Annotations: []Annotation{
+			{Type: AnnotationURLCitation, StartIndex: 0, EndIndex: 6, URL: "https://weather.example", Title: "Forecast"},
+			{Type: AnnotationDocumentCitation, EndIndex: 6, DocumentIndex: 2, CitedText: "Sunny all week"},
+		}},
+		{Role: RoleAssistant, ContentPart: []ContentPart{NewContentPartReasoning("think"), NewContentPartText("answer")}},
+		{Role: RoleAssistant, ContentPart: []ContentPart{&ContentPartReasoning{Text: "think", Signature: "sig"}, NewContentPartRedactedReasoning("secret")}},
+		NewUserMessage(WithText("hi"), WithMessageMetadata("author", "ada"), WithMessageMetadata("pinned", true)),
+		NewUserMessage(WithAudio("UklGRg==", AudioFormatMP3)),
+		NewUserMessage(WithFile(NewContentPartFileData("JVBERi0=", "report.pdf")), WithFile(NewContentPartFileID("file-1"))),
+		NewUserMessage(WithDocument(&ContentPartDocument{Data: "JVBERi0=", MIMEType: "application/pdf", Filename: "report.pdf", Title: "Q3 report"}), WithDocument(NewContentPartDocumentURL("https://example.com/a.pdf"))),
+		NewUserMessage(WithText("long document"), WithCacheControl(CacheControl{TTL: "1h"})),
+		{Role: RoleUser, ContentPart: []ContentPart{&ContentPartText{Text: "cached", CacheControl: &CacheControl{}}}},
+	}
+
+	data, err := json.Marshal(messages)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var decoded []Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded, messages) {
+		t.Fatalf("round trip mismatch:\n got: %#v\nwant: %#v", decoded, messages)
+	}
+}
+
+func TestMessageUnmarshalUnknownContentPart(t *testing.T) {
+	var m Message
+	err := json.Unmarshal([]byte(`{"role":"user","content_part":[{"type":"hologram"}]}`), &m)
+	if err == nil {
+		t.Fatal("expected error for unknown content part type")
+	}
+}
+
+func TestHTMLEscaping(t *testing.T) {
+	const raw = `<script>alert("x" & 'y')</script>`
+	const escaped = `&lt;script&gt;alert(&#34;x&#34; &amp; &#39;y&#39;)&lt;/script&gt;`
+
+	msg := NewUserMessageSafe(raw)
+	if got := msg.TextContent(); got != escaped {
+		t.Errorf("NewUserMessageSafe text = %q, want %q", got, escaped)
+	}
+
+	part := NewContentPartText(raw)
+	if got := part.ToHTML(); got != escaped {
+		t.Errorf("ContentPartText.ToHTML() = %q, want %q", got, escaped)
+	}
+	if part.Text != raw {
+		t.Errorf("ToHTML modified the part: %q", part.Text)
+	}
+
+	assistant := NewAssistantMessage(WithText("a < b"), WithText(" & c > d"))
+	if got := assistant.ToHTML(); got != "a &lt; b &amp; c &gt; d" {
+		t.Errorf("Message.ToHTML() = %q", got)
+	}
+}
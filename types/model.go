@@ -0,0 +1,38 @@
+package types
+
+import (
+	"slices"
+	"sync"
+)
+
+var (
+	modelRegistryMu sync.RWMutex
+	modelRegistry   = map[string][]string{}
+)
+
+// RegisterModels records the known model names for an adapter, replacing any previous list.
+// Adapters call this from init so ValidateModel can check model names before a request is sent.
+func RegisterModels(adapter string, models []string) {
+	modelRegistryMu.Lock()
+	defer modelRegistryMu.Unlock()
+	modelRegistry[adapter] = slices.Clone(models)
+}
+
+// KnownModels returns the registered model names for an adapter, or nil if none are registered.
+func KnownModels(adapter string) []string {
+	modelRegistryMu.RLock()
+	defer modelRegistryMu.RUnlock()
+	return slices.Clone(modelRegistry[adapter])
+}
+
+// ValidateModel reports an *ErrUnknownModel if model is not in the adapter's registered list.
+// Adapters without a registered list accept any model.
+func ValidateModel(adapter string, model string) error {
+	modelRegistryMu.RLock()
+	models, ok := modelRegistry[adapter]
+	modelRegistryMu.RUnlock()
+	if !ok || slices.Contains(models, model) {
+		return nil
+	}
+	return &ErrUnknownModel{Adapter: adapter, Model: model}
+}
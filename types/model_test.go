@@ -0,0 +1,27 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateModel(t *testing.T) {
+	RegisterModels("test-adapter", []string{"model-a", "model-b"})
+
+	if err := ValidateModel("test-adapter", "model-a"); err != nil {
+		t.Fatalf("unexpected error for known model: %v", err)
+	}
+
+	err := ValidateModel("test-adapter", "model-z")
+	var unknown *ErrUnknownModel
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected *ErrUnknownModel, got %v", err)
+	}
+	if unknown.Adapter != "test-adapter" || unknown.Model != "model-z" {
+		t.Errorf("unexpected error fields: %+v", unknown)
+	}
+
+	if err := ValidateModel("unregistered", "anything"); err != nil {
+		t.Errorf("expected unregistered adapter to accept any model, got %v", err)
+	}
+}
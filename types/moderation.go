@@ -0,0 +1,59 @@
+package types
+
+import (
+	"context"
+	"slices"
+)
+
+// Moderator classifies content against a provider's usage policies.
+// Adapters with a moderation endpoint implement it alongside RawClient.
+type Moderator interface {
+	Moderate(ctx context.Context, params *ModerationParams) (*ModerationResponse, error)
+}
+
+type ModerationParams struct {
+	Model string   // Empty = provider default
+	Input []string // Each entry is classified separately
+	Extra map[string]any
+}
+
+type ModerationResponse struct {
+	ID      string
+	Model   string
+	Results []ModerationResult // One per input, in input order
+	Extra   map[string]any
+}
+
+// ModerationResult is the classification of one input. Category names are provider-defined,
+// e.g. "hate" or "self-harm/intent" for OpenAI.
+type ModerationResult struct {
+	Flagged        bool
+	Categories     map[string]bool
+	CategoryScores map[string]float64
+}
+
+// Flagged reports whether any input was flagged.
+func (r *ModerationResponse) Flagged() bool {
+	for _, result := range r.Results {
+		if result.Flagged {
+			return true
+		}
+	}
+	return false
+}
+
+// FlaggedCategories returns the sorted names of the categories flagged for any input.
+func (r *ModerationResponse) FlaggedCategories() []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, result := range r.Results {
+		for category, flagged := range result.Categories {
+			if flagged && !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+	slices.Sort(categories)
+	return categories
+}
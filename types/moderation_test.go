@@ -0,0 +1,24 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestModerationResponseFlaggedCategories(t *testing.T) {
+	resp := &ModerationResponse{Results: []ModerationResult{
+		{Flagged: true, Categories: map[string]bool{"violence": true, "hate": false}},
+		{Flagged: true, Categories: map[string]bool{"violence": true, "harassment": true}},
+	}}
+
+	if !resp.Flagged() {
+		t.Error("expected response to be flagged")
+	}
+	if got := resp.FlaggedCategories(); !slices.Equal(got, []string{"harassment", "violence"}) {
+		t.Errorf("unexpected categories: %v", got)
+	}
+
+	if (&ModerationResponse{Results: []ModerationResult{{}}}).Flagged() {
+		t.Error("expected clean response not to be flagged")
+	}
+}
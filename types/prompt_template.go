@@ -0,0 +1,189 @@
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// PromptTemplate renders prompts from text/template syntax. Variables are referenced as
+// {{.name}} and partials, added with WithPartial, are included with {{template "name" .}}.
+// Rendering fails on missing variables instead of printing "<no value>".
+type PromptTemplate struct {
+	tmpl      *template.Template
+	variables []string
+}
+
+// PromptTemplateOption configures a PromptTemplate.
+type PromptTemplateOption func(*template.Template) error
+
+// WithPartial adds a named template the prompt can include with {{template "name" .}}.
+func WithPartial(name, text string) PromptTemplateOption {
+	return func(t *template.Template) error {
+		_, err := t.New(name).Parse(text)
+		return err
+	}
+}
+
+// NewPromptTemplate parses text and its partials.
+func NewPromptTemplate(text string, opts ...PromptTemplateOption) (*PromptTemplate, error) {
+	tmpl := template.New("prompt").Option("missingkey=error")
+	for _, opt := range opts {
+		if err := opt(tmpl); err != nil {
+			return nil, fmt.Errorf("prompt template: %w", err)
+		}
+	}
+	if _, err := tmpl.Parse(text); err != nil {
+		return nil, fmt.Errorf("prompt template: %w", err)
+	}
+
+	collector := variableCollector{tmpl: tmpl, seen: make(map[string]bool), visited: make(map[string]bool)}
+	collector.walkTemplate(tmpl.Name())
+	return &PromptTemplate{tmpl: tmpl, variables: collector.variables}, nil
+}
+
+// MustPromptTemplate is like NewPromptTemplate but panics if the template can't be parsed.
+func MustPromptTemplate(text string, opts ...PromptTemplateOption) *PromptTemplate {
+	t, err := NewPromptTemplate(text, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Variables returns the names of the top-level variables the template references, including
+// those of its partials, in order of first use.
+func (t *PromptTemplate) Variables() []string {
+	return slices.Clone(t.variables)
+}
+
+// Validate checks that data, a map with string keys or a struct, provides every variable.
+func (t *PromptTemplate) Validate(data any) error {
+	var missing []string
+	for _, name := range t.variables {
+		if !hasVariable(data, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("prompt template: missing variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Render executes the template with data.
+func (t *PromptTemplate) Render(data any) (string, error) {
+	if err := t.Validate(data); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("prompt template: %w", err)
+	}
+	return b.String(), nil
+}
+
+func hasVariable(data any, name string) bool {
+	v := reflect.ValueOf(data)
+	if !v.IsValid() {
+		return false
+	}
+	if v.MethodByName(name).IsValid() {
+		return true
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return false
+		}
+		return v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())).IsValid()
+	case reflect.Struct:
+		field, ok := v.Type().FieldByName(name)
+		return ok && field.IsExported()
+	default:
+		return false
+	}
+}
+
+// variableCollector finds the fields referenced on the template's root data. Fields inside
+// range and with blocks refer to another value and are not collected.
+type variableCollector struct {
+	tmpl      *template.Template
+	variables []string
+	seen      map[string]bool
+	visited   map[string]bool
+}
+
+func (c *variableCollector) walkTemplate(name string) {
+	if c.visited[name] {
+		return
+	}
+	c.visited[name] = true
+	if t := c.tmpl.Lookup(name); t != nil && t.Tree != nil {
+		c.walk(t.Tree.Root)
+	}
+}
+
+func (c *variableCollector) add(name string) {
+	if !c.seen[name] {
+		c.seen[name] = true
+		c.variables = append(c.variables, name)
+	}
+}
+
+func (c *variableCollector) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.walk(child)
+		}
+	case *parse.ActionNode:
+		c.walk(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				c.walk(arg)
+			}
+		}
+	case *parse.FieldNode:
+		c.add(n.Ident[0])
+	case *parse.VariableNode:
+		// $.name refers to the root data
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			c.add(n.Ident[1])
+		}
+	case *parse.IfNode:
+		c.walk(n.Pipe)
+		c.walk(n.List)
+		c.walk(n.ElseList)
+	case *parse.RangeNode:
+		c.walk(n.Pipe)
+		c.walk(n.ElseList)
+	case *parse.WithNode:
+		c.walk(n.Pipe)
+		c.walk(n.ElseList)
+	case *parse.TemplateNode:
+		// Only partials rendered with the root data share its variables
+		if n.Pipe != nil && len(n.Pipe.Cmds) == 1 && len(n.Pipe.Cmds[0].Args) == 1 {
+			if _, ok := n.Pipe.Cmds[0].Args[0].(*parse.DotNode); ok {
+				c.walkTemplate(n.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPromptTemplate(t *testing.T) {
+	tmpl, err := NewPromptTemplate(
+		`Hello {{.name}}. {{range .items}}{{.title}} {{end}}{{if .urgent}}Hurry!{{end}}{{template "signature" .}}`,
+		WithPartial("signature", " -- {{.team}}"),
+	)
+	if err != nil {
+		t.Fatalf("NewPromptTemplate failed: %v", err)
+	}
+
+	// Fields inside range refer to the items, not the root data
+	if got, want := tmpl.Variables(), []string{"name", "items", "urgent", "team"}; !slices.Equal(got, want) {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+
+	got, err := tmpl.Render(map[string]any{
+		"name":   "Ada",
+		"items":  []map[string]any{{"title": "a"}, {"title": "b"}},
+		"urgent": false,
+		"team":   "Support",
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if want := "Hello Ada. a b  -- Support"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	err = tmpl.Validate(map[string]any{"name": "Ada"})
+	if err == nil || !strings.Contains(err.Error(), "items, urgent, team") {
+		t.Errorf("expected missing variables error, got %v", err)
+	}
+}
+
+func TestPromptTemplateStructData(t *testing.T) {
+	type data struct {
+		Name   string
+		secret string
+	}
+	tmpl := MustPromptTemplate("Hi {{.Name}}")
+	if got, err := tmpl.Render(&data{Name: "Ada"}); err != nil || got != "Hi Ada" {
+		t.Errorf("Render() = %q, %v", got, err)
+	}
+
+	private := MustPromptTemplate("{{.secret}}")
+	if err := private.Validate(data{secret: "x"}); err == nil {
+		t.Error("expected unexported fields to be missing")
+	}
+
+	if _, err := NewPromptTemplate("{{.Name"); err == nil {
+		t.Error("expected a parse error")
+	}
+}
@@ -0,0 +1,31 @@
+package types
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// requestHeadersKey is the context key of WithRequestHeaders
+type requestHeadersKey struct{}
+
+// WithRequestHeaders returns a copy of ctx whose provider requests carry header in addition
+// to the client's configured headers, e.g. to correlate them with a trace. Headers set on ctx
+// before are kept unless header replaces them.
+func WithRequestHeaders(ctx context.Context, header http.Header) context.Context {
+	merged := RequestHeaders(ctx).Clone()
+	if merged == nil {
+		merged = make(http.Header, len(header))
+	}
+	for key, values := range header {
+		merged[http.CanonicalHeaderKey(key)] = slices.Clone(values)
+	}
+	return context.WithValue(ctx, requestHeadersKey{}, merged)
+}
+
+// RequestHeaders returns the headers set on ctx with WithRequestHeaders, nil if none.
+// Adapters add them to every request they send; callers must not modify them.
+func RequestHeaders(ctx context.Context) http.Header {
+	header, _ := ctx.Value(requestHeadersKey{}).(http.Header)
+	return header
+}
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestWithRequestHeaders(t *testing.T) {
+	if RequestHeaders(context.Background()) != nil {
+		t.Error("expected no headers on a plain context")
+	}
+
+	outer := WithRequestHeaders(context.Background(), http.Header{"traceparent": {"00-a"}, "X-Run-Id": {"parent"}})
+	inner := WithRequestHeaders(outer, http.Header{"X-Run-Id": {"child"}})
+
+	header := RequestHeaders(inner)
+	if header.Get("Traceparent") != "00-a" || header.Get("X-Run-Id") != "child" {
+		t.Errorf("unexpected merged headers: %v", header)
+	}
+	if RequestHeaders(outer).Get("X-Run-Id") != "parent" {
+		t.Error("expected the outer context to be unchanged")
+	}
+}
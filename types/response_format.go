@@ -1,6 +1,7 @@
 package types
 
 import (
+	"cmp"
 	"encoding/json/v2"
 	"errors"
 	"fmt"
@@ -18,7 +19,8 @@ func ApplyResponseFormat(params *ChatParams) {
 	case ResponseFormatModeTool:
 		outputTool := BuildOutputToolDefinition(rf)
 		params.Tools = append(params.Tools, outputTool)
-	case ResponseFormatModePrompted:
+	case ResponseFormatModePrompted, ResponseFormatModeJSON:
+		// JSON mode providers also need to be told to answer in JSON (OpenAI requires it)
 		params.SystemPrompt += BuildPromptedSuffix(rf)
 	}
 }
@@ -32,28 +34,28 @@ func ExtractStructuredContent(rf ResponseFormat, msg *Message) (string, error) {
 	var err error
 
 	switch rf.Mode {
-	case ResponseFormatModeNative:
+	case ResponseFormatModeNative, ResponseFormatModeJSON:
 		content = msg.TextContent()
 
 	case ResponseFormatModeTool:
 		var outputCall *ToolCall
 		for i := range msg.ToolCalls {
-			if msg.ToolCalls[i].Function.Name == OutputToolName {
+			if msg.ToolCalls[i].Function.Name == rf.OutputToolName() {
 				outputCall = &msg.ToolCalls[i]
 				break
 			}
 		}
 
 		if outputCall != nil {
-			// Error if _output called alongside other tools
+			// Error if the output tool is called alongside other tools
 			if len(msg.ToolCalls) > 1 {
 				var otherTools []string
 				for _, tc := range msg.ToolCalls {
-					if tc.Function.Name != OutputToolName {
+					if tc.Function.Name != rf.OutputToolName() {
 						otherTools = append(otherTools, tc.Function.Name)
 					}
 				}
-				return "", &OutputToolMisuseError{OtherTools: otherTools}
+				return "", &OutputToolMisuseError{OutputTool: rf.OutputToolName(), OtherTools: otherTools}
 			}
 
 			// Extract content
@@ -63,12 +65,12 @@ func ExtractStructuredContent(rf ResponseFormat, msg *Message) (string, error) {
 			}
 			content = string(b)
 
-			// Transform: remove _output, add as text
+			// Transform: remove the output tool call, add as text
 			msg.ToolCalls = nil
 			msg.ContentPart = append(msg.ContentPart, &ContentPartText{Text: content})
 		} else if len(msg.ToolCalls) == 0 {
-			// _output not called and no other tools
-			return "", &ToolNotCalledError{ExpectedTool: OutputToolName, Response: msg}
+			// Output tool not called and no other tools
+			return "", &ToolNotCalledError{ExpectedTool: rf.OutputToolName(), Response: msg}
 		}
 		// else: other tools called, content stays empty, agent loop continues
 
@@ -90,16 +92,24 @@ func ExtractStructuredContent(rf ResponseFormat, msg *Message) (string, error) {
 	// Validate content against schema (for all modes)
 	if content != "" {
 		if err := ValidateJSONString(content, rf.Schema); err != nil {
-			return "", &SchemaValidationError{RawResponse: content, Err: err}
+			return "", &SchemaValidationError{RawResponse: content, Err: err, Fields: SchemaErrorFields(err)}
 		}
 	}
 
 	return content, nil
 }
 
+// OutputToolName is the default name of the hidden tool the model calls with its output in
+// Tool mode. User tools must not use it.
 const OutputToolName = "_output"
 
-// BuildOutputToolDefinition creates the hidden _output tool for Tool mode
+// OutputToolName returns the name of the output tool: ToolName, or the OutputToolName
+// constant when unset.
+func (rf ResponseFormat) OutputToolName() string {
+	return cmp.Or(rf.ToolName, OutputToolName)
+}
+
+// BuildOutputToolDefinition creates the hidden output tool for Tool mode
 func BuildOutputToolDefinition(rf ResponseFormat) ToolDefinition {
 	description := rf.Description
 	if description == "" {
@@ -111,21 +121,26 @@ func BuildOutputToolDefinition(rf ResponseFormat) ToolDefinition {
 	}
 
 	return ToolDefinition{
-		Name:        OutputToolName,
+		Name:        rf.OutputToolName(),
 		Description: description,
 		InputSchema: rf.Schema,
+		Strict:      rf.Strict,
 	}
 }
 
-// BuildPromptedSuffix creates the instruction suffix for Prompted mode
+// BuildPromptedSuffix creates the instruction suffix for Prompted and JSON mode. An empty
+// schema asks for JSON of any shape.
 func BuildPromptedSuffix(rf ResponseFormat) string {
+	if len(rf.Schema) == 0 {
+		return "\n\nYou must respond with valid JSON. Do not include any other text, only the JSON value."
+	}
 	schemaJSON, _ := json.Marshal(rf.Schema)
 	return fmt.Sprintf("\n\nYou must respond with valid JSON matching this schema. Do not include any other text, only the JSON object.\n\nSchema:\n%s", schemaJSON)
 }
 
-// ResponseFormatFor creates a ResponseFormat from a Go type
-func ResponseFormatFor[T any](mode ResponseFormatMode, name, description string) (ResponseFormat, error) {
-	schema, err := SchemaMapFor[T]()
+// ResponseFormatFor creates a ResponseFormat from a Go type, its schema customized by opts
+func ResponseFormatFor[T any](mode ResponseFormatMode, name, description string, opts ...SchemaOption) (ResponseFormat, error) {
+	schema, err := SchemaMapFor[T](opts...)
 	if err != nil {
 		return ResponseFormat{}, fmt.Errorf("failed to generate schema: %w", err)
 	}
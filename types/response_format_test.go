@@ -2,6 +2,7 @@ package types
 
 import (
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -467,6 +468,37 @@ func TestExtractStructuredContent_ToolMode_Success(t *testing.T) {
 	}
 }
 
+func TestExtractStructuredContent_ToolMode_CustomToolName(t *testing.T) {
+	rf := ResponseFormat{
+		Mode:     ResponseFormatModeTool,
+		Schema:   testSchema(),
+		ToolName: "final_answer",
+	}
+	if def := BuildOutputToolDefinition(rf); def.Name != "final_answer" {
+		t.Errorf("output tool name = %q, want final_answer", def.Name)
+	}
+
+	msg := &Message{
+		Role: RoleAssistant,
+		ToolCalls: []ToolCall{{
+			ID:       "call_123",
+			Function: ToolFunction{Name: OutputToolName, Arguments: map[string]any{"city": "NYC", "temp": float64(72)}},
+		}},
+	}
+	if content, err := ExtractStructuredContent(rf, msg); err != nil || content != "" {
+		t.Errorf("default output tool name was taken for the output: %q, %v", content, err)
+	}
+
+	msg.ToolCalls[0].Function.Name = "final_answer"
+	content, err := ExtractStructuredContent(rf, msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !contains(content, "NYC") || len(msg.ToolCalls) != 0 {
+		t.Errorf("unexpected extraction: %q, %d tool calls left", content, len(msg.ToolCalls))
+	}
+}
+
 func TestExtractStructuredContent_ToolMode_OutputWithOtherTools(t *testing.T) {
 	rf := ResponseFormat{
 		Mode:   ResponseFormatModeTool,
@@ -832,3 +864,141 @@ func TestExtractStructuredContent_ToolMode_MultipleOutputCalls(t *testing.T) {
 		t.Errorf("expected OutputToolMisuseError, got %T: %v", err, err)
 	}
 }
+
+// modeLimitedClient supports only the listed response format modes.
+type modeLimitedClient struct {
+	echoRawClient
+	modes []ResponseFormatMode
+}
+
+func (c *modeLimitedClient) SupportsResponseFormat(mode ResponseFormatMode) bool {
+	for _, m := range c.modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEffectiveResponseFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		supported []ResponseFormatMode
+		mode      ResponseFormatMode
+		want      ResponseFormatMode
+	}{
+		{"native supported", []ResponseFormatMode{ResponseFormatModeNative}, ResponseFormatModeNative, ResponseFormatModeNative},
+		{"native falls back to tool", []ResponseFormatMode{ResponseFormatModeTool}, ResponseFormatModeNative, ResponseFormatModeTool},
+		{"native falls back to prompted", nil, ResponseFormatModeNative, ResponseFormatModePrompted},
+		{"tool falls back to prompted", []ResponseFormatMode{ResponseFormatModeNative}, ResponseFormatModeTool, ResponseFormatModePrompted},
+		{"prompted unchanged", nil, ResponseFormatModePrompted, ResponseFormatModePrompted},
+		{"json supported", []ResponseFormatMode{ResponseFormatModeJSON}, ResponseFormatModeJSON, ResponseFormatModeJSON},
+		{"json falls back to prompted", []ResponseFormatMode{ResponseFormatModeNative}, ResponseFormatModeJSON, ResponseFormatModePrompted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := &modeLimitedClient{modes: tt.supported}
+			rf := ResponseFormat{Mode: tt.mode, Schema: testSchema()}
+
+			if got := EffectiveResponseFormat(raw, rf).Mode; got != tt.want {
+				t.Errorf("raw client: got %q, want %q", got, tt.want)
+			}
+			if got := EffectiveResponseFormat(NewClient(raw), rf).Mode; got != tt.want {
+				t.Errorf("wrapped client: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unsupported interface keeps mode", func(t *testing.T) {
+		rf := ResponseFormat{Mode: ResponseFormatModeNative, Schema: testSchema()}
+		if got := EffectiveResponseFormat(NewClient(&echoRawClient{}), rf).Mode; got != ResponseFormatModeNative {
+			t.Errorf("got %q, want native", got)
+		}
+	})
+}
+
+func TestClientChatDowngradesResponseFormat(t *testing.T) {
+	raw := &modeLimitedClient{}
+	params := &ChatParams{
+		Model:          "test-model",
+		ResponseFormat: ResponseFormat{Mode: ResponseFormatModeNative, Schema: map[string]any{"type": "object"}},
+	}
+
+	resp, err := NewClient(raw).Chat(t.Context(), params)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Choices[0].StructuredContent != `{"n": 1}` {
+		t.Errorf("unexpected structured content %q", resp.Choices[0].StructuredContent)
+	}
+	if params.ResponseFormat.Mode != ResponseFormatModeNative {
+		t.Errorf("caller params mutated: %q", params.ResponseFormat.Mode)
+	}
+}
+
+func TestClientChatJSONMode(t *testing.T) {
+	for _, supported := range []bool{true, false} {
+		raw := &modeLimitedClient{}
+		want := ResponseFormatModePrompted
+		if supported {
+			raw.modes = []ResponseFormatMode{ResponseFormatModeJSON}
+			want = ResponseFormatModeJSON
+		}
+		params := &ChatParams{Model: "test-model", ResponseFormat: ResponseFormat{Mode: ResponseFormatModeJSON}}
+
+		rf := EffectiveResponseFormat(raw, params.ResponseFormat)
+		if rf.Mode != want || rf.Schema == nil {
+			t.Errorf("supported=%v: expected mode %q with an empty schema, got %+v", supported, want, rf)
+		}
+		applied := &ChatParams{ResponseFormat: rf}
+		ApplyResponseFormat(applied)
+		if !strings.Contains(applied.SystemPrompt, "valid JSON") || strings.Contains(applied.SystemPrompt, "Schema:") {
+			t.Errorf("supported=%v: expected JSON instructions without a schema, got %q", supported, applied.SystemPrompt)
+		}
+
+		resp, err := NewClient(raw).Chat(t.Context(), params)
+		if err != nil {
+			t.Fatalf("supported=%v: Chat failed: %v", supported, err)
+		}
+		if resp.Choices[0].StructuredContent != `{"n": 1}` {
+			t.Errorf("supported=%v: unexpected structured content %q", supported, resp.Choices[0].StructuredContent)
+		}
+	}
+}
+
+func TestExtractStructuredContent_JSONMode_InvalidJSON(t *testing.T) {
+	rf := ResponseFormat{Mode: ResponseFormatModeJSON, Schema: map[string]any{}}
+	msg := &Message{Role: RoleAssistant, ContentPart: []ContentPart{&ContentPartText{Text: `{"n": 1`}}}
+
+	var validationErr *SchemaValidationError
+	if _, err := ExtractStructuredContent(rf, msg); !errors.As(err, &validationErr) {
+		t.Errorf("expected SchemaValidationError, got %v", err)
+	}
+}
+
+func TestEffectiveResponseFormat_AllowAdditionalProperties(t *testing.T) {
+	rf := ResponseFormat{
+		Mode:   ResponseFormatModeNative,
+		Schema: map[string]any{"type": "object", "properties": map[string]any{"m": map[string]any{"type": "number"}}, "additionalProperties": false},
+		Strict: true,
+	}
+	params := &ChatParams{Model: "test-model", ResponseFormat: rf}
+	var validationErr *SchemaValidationError
+	if _, err := NewClient(&echoRawClient{}).Chat(t.Context(), params); !errors.As(err, &validationErr) {
+		t.Fatalf("expected the undeclared property to fail validation, got %v", err)
+	}
+
+	params.ResponseFormat.AllowAdditionalProperties = true
+	effective := EffectiveResponseFormat(&echoRawClient{}, params.ResponseFormat)
+	if effective.Strict || effective.Schema["additionalProperties"] != nil {
+		t.Errorf("expected a relaxed, non-strict format, got %+v", effective)
+	}
+	resp, err := NewClient(&echoRawClient{}).Chat(t.Context(), params)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Choices[0].StructuredContent != `{"n": 1}` {
+		t.Errorf("unexpected structured content %q", resp.Choices[0].StructuredContent)
+	}
+}
@@ -3,6 +3,10 @@ package types
 import (
 	"encoding/json/v2"
 	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/google/jsonschema-go/jsonschema"
 )
@@ -12,24 +16,28 @@ func isValidJSON(s string) bool {
 	return json.Unmarshal([]byte(s), &js) == nil
 }
 
-// ResolveSchemaFor generates and resolves a JSON schema from a Go type
-func ResolveSchemaFor[T any]() (*jsonschema.Resolved, error) {
-	schema, err := jsonschema.For[T](nil)
+// ResolveSchemaFor generates and resolves a JSON schema from a Go type, customized by opts.
+// See SchemaMapFor.
+func ResolveSchemaFor[T any](opts ...SchemaOption) (*jsonschema.Resolved, error) {
+	schema, err := SchemaMapFor[T](opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate schema: %w", err)
-	}
-
-	resolved, err := schema.Resolve(nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve schema: %w", err)
+		return nil, err
 	}
-
-	return resolved, nil
+	return ResolveSchemaMap(schema)
 }
 
-// SchemaMapFor generates a JSON schema map from a Go type
-func SchemaMapFor[T any]() (map[string]any, error) {
-	schema, err := jsonschema.For[T](nil)
+// SchemaMapFor generates a JSON schema map from a Go type, customized by opts.
+// A jsonschema tag of the form `jsonschema:"example:San Francisco, CA"` adds the
+// value to the property's "examples" keyword instead of its description. A tag of
+// keyword=value pairs adds validation keywords, e.g.
+// `jsonschema:"minimum=0,maximum=10"` or `jsonschema:"enum=low|medium|high"`; the
+// supported keywords are minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// multipleOf, minLength, maxLength, minItems, maxItems, pattern, format, title,
+// description and enum. Recursive types are not supported; describe them with
+// WithTypeSchema.
+func SchemaMapFor[T any](opts ...SchemaOption) (map[string]any, error) {
+	cfg := newSchemaConfig(opts)
+	schema, err := cfg.generate(reflect.TypeFor[T]())
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate schema: %w", err)
 	}
@@ -44,9 +52,254 @@ func SchemaMapFor[T any]() (map[string]any, error) {
 		return nil, fmt.Errorf("failed to convert schema to map: %w", err)
 	}
 
+	if err := applyFieldTags(reflect.TypeFor[T](), schemaMap); err != nil {
+		return nil, fmt.Errorf("failed to apply struct tags: %w", err)
+	}
+	if err := cfg.apply(schemaMap); err != nil {
+		return nil, fmt.Errorf("failed to customize schema: %w", err)
+	}
+
 	return schemaMap, nil
 }
 
+const exampleTagPrefix = "example:"
+
+// applyFieldTags walks a Go type alongside its generated schema map and applies
+// keywords that jsonschema-go does not understand from struct tags.
+func applyFieldTags(t reflect.Type, schema map[string]any) error {
+	if schema == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if items, ok := schema["items"].(map[string]any); ok {
+			return applyFieldTags(t.Elem(), items)
+		}
+	case reflect.Map:
+		if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+			return applyFieldTags(t.Elem(), additional)
+		}
+	case reflect.Struct:
+		properties, _ := schema["properties"].(map[string]any)
+		for _, field := range reflect.VisibleFields(t) {
+			if field.Anonymous || !field.IsExported() {
+				continue
+			}
+			name := jsonFieldName(field)
+			if name == "" {
+				continue
+			}
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				continue
+			}
+			tag, _ := field.Tag.Lookup("jsonschema")
+			switch {
+			case strings.HasPrefix(tag, exampleTagPrefix):
+				// jsonschema-go treats the whole tag as a description
+				delete(property, "description")
+				property["examples"] = []any{strings.TrimPrefix(tag, exampleTagPrefix)}
+			case constraintTag.MatchString(tag):
+				if err := applyConstraintTag(tag, property); err != nil {
+					return fmt.Errorf("field %s.%s: %w", t, field.Name, err)
+				}
+			}
+			if err := applyFieldTags(field.Type, property); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFieldName returns the JSON property name for a struct field,
+// or "" if the field is omitted from JSON.
+func jsonFieldName(field reflect.StructField) string {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return field.Name
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	switch name {
+	case "-":
+		if tag == "-" {
+			return ""
+		}
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// StrictSchema returns a copy of schema rewritten for OpenAI strict mode: every object lists
+// all of its properties as required, with the previously optional ones made nullable, and
+// rejects additional properties. The input is not modified. Map types cannot be expressed
+// in strict mode; their additionalProperties schema is kept and the provider will reject it.
+func StrictSchema(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+
+	out := make(map[string]any, len(schema)+2)
+	for k, v := range schema {
+		out[k] = v
+	}
+
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		if sub, ok := out[key].(map[string]any); ok {
+			out[key] = StrictSchema(sub)
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf", "prefixItems"} {
+		if subs, ok := out[key].([]any); ok {
+			strict := make([]any, len(subs))
+			for i, sub := range subs {
+				if m, ok := sub.(map[string]any); ok {
+					sub = StrictSchema(m)
+				}
+				strict[i] = sub
+			}
+			out[key] = strict
+		}
+	}
+	for _, key := range []string{"$defs", "definitions"} {
+		if defs, ok := out[key].(map[string]any); ok {
+			strict := make(map[string]any, len(defs))
+			for name, def := range defs {
+				if m, ok := def.(map[string]any); ok {
+					def = StrictSchema(m)
+				}
+				strict[name] = def
+			}
+			out[key] = strict
+		}
+	}
+
+	properties, ok := out["properties"].(map[string]any)
+	if !ok {
+		return out
+	}
+
+	required := make(map[string]bool)
+	switch names := out["required"].(type) {
+	case []any:
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	case []string:
+		for _, name := range names {
+			required[name] = true
+		}
+	}
+
+	strict := make(map[string]any, len(properties))
+	names := make([]string, 0, len(properties))
+	for name, property := range properties {
+		if m, ok := property.(map[string]any); ok {
+			m = StrictSchema(m)
+			if !required[name] {
+				m = nullableSchema(m)
+			}
+			property = m
+		}
+		strict[name] = property
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	allRequired := make([]any, len(names))
+	for i, name := range names {
+		allRequired[i] = name
+	}
+	out["properties"] = strict
+	out["required"] = allRequired
+	out["additionalProperties"] = false
+	return out
+}
+
+// RelaxedSchema returns a copy of schema whose objects accept properties they don't declare:
+// "additionalProperties": false, which schemas generated from structs set, is removed. The
+// input is not modified.
+func RelaxedSchema(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+
+	out := make(map[string]any, len(schema))
+	for k, v := range schema {
+		out[k] = v
+	}
+	if out["additionalProperties"] == false {
+		delete(out, "additionalProperties")
+	}
+
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		if sub, ok := out[key].(map[string]any); ok {
+			out[key] = RelaxedSchema(sub)
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf", "prefixItems"} {
+		if subs, ok := out[key].([]any); ok {
+			relaxed := make([]any, len(subs))
+			for i, sub := range subs {
+				if m, ok := sub.(map[string]any); ok {
+					sub = RelaxedSchema(m)
+				}
+				relaxed[i] = sub
+			}
+			out[key] = relaxed
+		}
+	}
+	for _, key := range []string{"properties", "$defs", "definitions"} {
+		if subs, ok := out[key].(map[string]any); ok {
+			relaxed := make(map[string]any, len(subs))
+			for name, sub := range subs {
+				if m, ok := sub.(map[string]any); ok {
+					sub = RelaxedSchema(m)
+				}
+				relaxed[name] = sub
+			}
+			out[key] = relaxed
+		}
+	}
+	return out
+}
+
+// nullableSchema makes schema also accept null; schema must be a copy owned by the caller
+func nullableSchema(schema map[string]any) map[string]any {
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, nil) {
+		schema["enum"] = append(slices.Clone(enum), nil)
+	}
+
+	switch t := schema["type"].(type) {
+	case string:
+		if t != "null" {
+			schema["type"] = []any{t, "null"}
+		}
+	case []any:
+		if !slices.Contains(t, any("null")) {
+			schema["type"] = append(slices.Clone(t), "null")
+		}
+	case []string:
+		if !slices.Contains(t, "null") {
+			types := make([]any, 0, len(t)+1)
+			for _, s := range t {
+				types = append(types, s)
+			}
+			schema["type"] = append(types, "null")
+		}
+	default:
+		return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+	}
+	return schema
+}
+
 // ValidateStruct validates a Go struct against a resolved schema.
 // It marshals the struct to JSON and unmarshals to map[string]any before validating,
 // since jsonschema-go cannot validate Go structs directly.
@@ -64,28 +317,37 @@ func ValidateStruct(resolved *jsonschema.Resolved, value any) error {
 	return resolved.Validate(jsonValue)
 }
 
-// ValidateJSONString parses a JSON string and validates it against a schema map
-func ValidateJSONString(content string, schema map[string]any) error {
-	// Parse the content as JSON
-	var parsed any
-	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
-		return fmt.Errorf("invalid JSON: %w", err)
-	}
-
-	// Convert schema map to jsonschema and resolve
+// ResolveSchemaMap converts a schema map to jsonschema and resolves it
+func ResolveSchemaMap(schema map[string]any) (*jsonschema.Resolved, error) {
 	schemaBytes, err := json.Marshal(schema)
 	if err != nil {
-		return fmt.Errorf("failed to marshal schema: %w", err)
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
 	}
 
 	var schemaObj jsonschema.Schema
 	if err := json.Unmarshal(schemaBytes, &schemaObj); err != nil {
-		return fmt.Errorf("failed to parse schema: %w", err)
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
 	}
 
 	resolved, err := schemaObj.Resolve(nil)
 	if err != nil {
-		return fmt.Errorf("failed to resolve schema: %w", err)
+		return nil, fmt.Errorf("failed to resolve schema: %w", err)
+	}
+
+	return resolved, nil
+}
+
+// ValidateJSONString parses a JSON string and validates it against a schema map
+func ValidateJSONString(content string, schema map[string]any) error {
+	// Parse the content as JSON
+	var parsed any
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	resolved, err := ResolveSchemaMap(schema)
+	if err != nil {
+		return err
 	}
 
 	// Validate
@@ -95,3 +357,43 @@ func ValidateJSONString(content string, schema map[string]any) error {
 
 	return nil
 }
+
+var (
+	propertyPathRe      = regexp.MustCompile(`validating (/properties/[^:]+):`)
+	missingPropertiesRe = regexp.MustCompile(`missing properties: \[([^\]]*)\]`)
+	quotedNameRe        = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+)
+
+// SchemaErrorFields extracts the dotted paths of the properties that caused a
+// jsonschema-go validation error (e.g. "address.city"). Missing required
+// properties are reported relative to their parent object.
+func SchemaErrorFields(err error) []string {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+
+	var parent string
+	if matches := propertyPathRe.FindAllStringSubmatch(msg, -1); len(matches) > 0 {
+		path := matches[len(matches)-1][1]
+		parent = strings.Join(strings.Split(strings.TrimPrefix(path, "/properties/"), "/properties/"), ".")
+	}
+
+	missing := missingPropertiesRe.FindStringSubmatch(msg)
+	if missing == nil {
+		if parent == "" {
+			return nil
+		}
+		return []string{parent}
+	}
+
+	var fields []string
+	for _, m := range quotedNameRe.FindAllStringSubmatch(missing[1], -1) {
+		if parent != "" {
+			fields = append(fields, parent+"."+m[1])
+		} else {
+			fields = append(fields, m[1])
+		}
+	}
+	return fields
+}
@@ -0,0 +1,257 @@
+package types
+
+import (
+	"cmp"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// SchemaOption customizes the schemas generated by SchemaMapFor and ResolveSchemaFor, e.g.
+// for providers rejecting parts of the default reflection output.
+type SchemaOption func(*schemaConfig)
+
+// schemaConfig holds the settings of schema generation
+type schemaConfig struct {
+	title       string
+	description string
+	typeSchemas map[reflect.Type]*jsonschema.Schema
+	omit        []string          // Dotted paths of properties to remove
+	rename      map[string]string // Dotted path -> new property name
+}
+
+// WithSchemaTitle sets the title of the generated schema.
+func WithSchemaTitle(title string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.title = title
+	}
+}
+
+// WithSchemaDescription sets the description of the generated schema.
+func WithSchemaDescription(description string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.description = description
+	}
+}
+
+// WithTypeSchema describes values of type T with schema wherever they occur, instead of the
+// reflection output, e.g. a UUID type as a string:
+//
+//	types.WithTypeSchema[uuid.UUID](&jsonschema.Schema{Type: "string", Format: "uuid"})
+func WithTypeSchema[T any](schema *jsonschema.Schema) SchemaOption {
+	return func(c *schemaConfig) {
+		if c.typeSchemas == nil {
+			c.typeSchemas = make(map[reflect.Type]*jsonschema.Schema)
+		}
+		c.typeSchemas[reflect.TypeFor[T]()] = schema
+	}
+}
+
+// WithoutProperties removes properties from the schema, given as dotted paths of JSON names
+// such as "address.zip"; array items are stepped into implicitly. The model then never fills
+// them in, so they decode to their zero value.
+func WithoutProperties(paths ...string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.omit = append(c.omit, paths...)
+	}
+}
+
+// WithPropertyName renames the property at path, a dotted path of the original JSON names as
+// for WithoutProperties, to name. Only the schema changes: values must be renamed back with
+// RestorePropertyNames before they are decoded into the Go type.
+func WithPropertyName(path, name string) SchemaOption {
+	return func(c *schemaConfig) {
+		if c.rename == nil {
+			c.rename = make(map[string]string)
+		}
+		c.rename[path] = name
+	}
+}
+
+// RestorePropertyNames renames the properties of data, a JSON value matching a schema generated
+// with opts, back to the JSON names of the Go type, undoing WithPropertyName so data can be
+// decoded. data is returned unchanged when opts rename nothing.
+func RestorePropertyNames(data []byte, opts ...SchemaOption) ([]byte, error) {
+	c := newSchemaConfig(opts)
+	if len(c.rename) == 0 {
+		return data, nil
+	}
+
+	// Properties by their name in data, holding their renamed descendants
+	root := &renamedProperty{}
+	for path, name := range c.rename {
+		node := root
+		names := strings.Split(path, ".")
+		for i := range names {
+			key := names[i]
+			if i == len(names)-1 {
+				key = name
+			} else if renamed, ok := c.rename[strings.Join(names[:i+1], ".")]; ok {
+				key = renamed
+			}
+			if node.children == nil {
+				node.children = make(map[string]*renamedProperty)
+			}
+			child, ok := node.children[key]
+			if !ok {
+				child = &renamedProperty{name: names[i]}
+				node.children[key] = child
+			}
+			node = child
+		}
+	}
+
+	restored, err := root.restore(jsontext.Value(data))
+	if err != nil {
+		return nil, fmt.Errorf("restore property names: %w", err)
+	}
+	return restored, nil
+}
+
+// renamedProperty is a property renamed by WithPropertyName, or the parent of one
+type renamedProperty struct {
+	name     string                      // Name in the Go type
+	children map[string]*renamedProperty // By their name in the data
+}
+
+// restore renames the properties of value below p; arrays are stepped into as in schemaParent
+func (p *renamedProperty) restore(value jsontext.Value) (jsontext.Value, error) {
+	if len(p.children) == 0 {
+		return value, nil
+	}
+	switch value.Kind() {
+	case '[':
+		var items []jsontext.Value
+		if err := json.Unmarshal(value, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			restored, err := p.restore(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = restored
+		}
+		return json.Marshal(items)
+	case '{':
+		var object map[string]jsontext.Value
+		if err := json.Unmarshal(value, &object); err != nil {
+			return nil, err
+		}
+		restored := make(map[string]jsontext.Value, len(object))
+		for key, v := range object {
+			if child, ok := p.children[key]; ok {
+				var err error
+				if v, err = child.restore(v); err != nil {
+					return nil, err
+				}
+				key = child.name
+			}
+			restored[key] = v
+		}
+		return json.Marshal(restored, json.Deterministic(true))
+	default:
+		return value, nil
+	}
+}
+
+func newSchemaConfig(opts []SchemaOption) *schemaConfig {
+	c := &schemaConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// generate reflects the schema of t with jsonschema-go
+func (c *schemaConfig) generate(t reflect.Type) (*jsonschema.Schema, error) {
+	stripped, err := reflectionType(t, c.typeSchemas, map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.ForType(stripped, &jsonschema.ForOptions{TypeSchemas: c.typeSchemas})
+}
+
+// apply makes the changes to a generated schema map that reflection can't
+func (c *schemaConfig) apply(schema map[string]any) error {
+	if c.title != "" {
+		schema["title"] = c.title
+	}
+	if c.description != "" {
+		schema["description"] = c.description
+	}
+	for _, path := range c.omit {
+		parent, name, err := schemaParent(schema, path)
+		if err != nil {
+			return err
+		}
+		delete(parent["properties"].(map[string]any), name)
+		setRequired(parent, name, "")
+	}
+	// Deepest first, so paths name properties before their parents are renamed
+	paths := slices.Collect(maps.Keys(c.rename))
+	slices.SortFunc(paths, func(a, b string) int {
+		return cmp.Or(strings.Count(b, ".")-strings.Count(a, "."), strings.Compare(a, b))
+	})
+	for _, path := range paths {
+		parent, name, err := schemaParent(schema, path)
+		if err != nil {
+			return err
+		}
+		properties := parent["properties"].(map[string]any)
+		properties[c.rename[path]] = properties[name]
+		delete(properties, name)
+		setRequired(parent, name, c.rename[path])
+	}
+	return nil
+}
+
+// schemaParent returns the object schema holding the property at the dotted path, and the
+// property's name
+func schemaParent(schema map[string]any, path string) (map[string]any, string, error) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		for {
+			items, ok := schema["items"].(map[string]any)
+			if !ok {
+				break
+			}
+			schema = items
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			return nil, "", fmt.Errorf("schema has no property %q", strings.Join(names[:i+1], "."))
+		}
+		if i == len(names)-1 {
+			return schema, name, nil
+		}
+		schema = property
+	}
+	return nil, "", fmt.Errorf("schema has no property %q", path)
+}
+
+// setRequired replaces name in the required list of schema with newName, or removes it when
+// newName is empty
+func setRequired(schema map[string]any, name, newName string) {
+	required, ok := schema["required"].([]any)
+	if !ok {
+		return
+	}
+	i := slices.Index(required, any(name))
+	switch {
+	case i < 0:
+	case newName == "":
+		schema["required"] = slices.Delete(slices.Clone(required), i, i+1)
+	default:
+		required = slices.Clone(required)
+		required[i] = newName
+		schema["required"] = required
+	}
+}
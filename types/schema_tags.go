@@ -0,0 +1,175 @@
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// constraintTag matches jsonschema tags holding constraints, such as
+// `jsonschema:"minimum=0,maximum=10"`. jsonschema-go rejects such tags, so they are removed
+// before reflection and applied to the schema map afterwards.
+var constraintTag = regexp.MustCompile("^[^ \t\n]*=")
+
+// constraintKinds maps the keywords allowed in constraint tags to the kind of their value
+var constraintKinds = map[string]string{
+	"minimum":          "number",
+	"maximum":          "number",
+	"exclusiveMinimum": "number",
+	"exclusiveMaximum": "number",
+	"multipleOf":       "number",
+	"minLength":        "integer",
+	"maxLength":        "integer",
+	"minItems":         "integer",
+	"maxItems":         "integer",
+	"pattern":          "string",
+	"format":           "string",
+	"title":            "string",
+	"description":      "string",
+	"enum":             "enum",
+}
+
+// applyConstraintTag adds the keywords of a constraint tag, comma-separated keyword=value pairs
+// with enum values separated by |, to the property schema. Values cannot contain commas.
+func applyConstraintTag(tag string, property map[string]any) error {
+	for pair := range strings.SplitSeq(tag, ",") {
+		keyword, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return fmt.Errorf("jsonschema tag %q: %q is not keyword=value", tag, pair)
+		}
+		switch constraintKinds[keyword] {
+		case "number":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("jsonschema tag %q: %s must be a number", tag, keyword)
+			}
+			property[keyword] = n
+		case "integer":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("jsonschema tag %q: %s must be a non-negative integer", tag, keyword)
+			}
+			property[keyword] = n
+		case "string":
+			property[keyword] = value
+		case "enum":
+			values, err := enumValues(strings.Split(value, "|"), property)
+			if err != nil {
+				return fmt.Errorf("jsonschema tag %q: %w", tag, err)
+			}
+			property[keyword] = values
+		default:
+			return fmt.Errorf("jsonschema tag %q: unknown keyword %q", tag, keyword)
+		}
+	}
+	return nil
+}
+
+// enumValues converts the enum values of a tag to the JSON type of the property
+func enumValues(values []string, property map[string]any) ([]any, error) {
+	converted := make([]any, len(values))
+	for i, value := range values {
+		switch propertyType(property) {
+		case "integer", "number":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("enum value %q is not a number", value)
+			}
+			converted[i] = n
+		case "boolean":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("enum value %q is not a boolean", value)
+			}
+			converted[i] = b
+		default:
+			converted[i] = value
+		}
+	}
+	return converted, nil
+}
+
+// propertyType returns the JSON type of a property schema, ignoring "null"
+func propertyType(property map[string]any) string {
+	switch t := property["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, name := range t {
+			if name != "null" {
+				s, _ := name.(string)
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// reflectionType returns t with the constraint tags removed from its structs, so jsonschema-go
+// accepts it; applyFieldTags adds the constraints back from the tags of t. Types without
+// constraint tags and those in typeSchemas are returned unchanged. Recursive types are
+// rejected, as by jsonschema-go: they must be described with WithTypeSchema.
+func reflectionType(t reflect.Type, typeSchemas map[reflect.Type]*jsonschema.Schema, seen map[reflect.Type]bool) (reflect.Type, error) {
+	if _, ok := typeSchemas[t]; ok {
+		return t, nil
+	}
+	if t.Name() != "" {
+		if seen[t] {
+			return nil, fmt.Errorf("recursive type %v is not supported, describe it with WithTypeSchema", t)
+		}
+		seen[t] = true
+		defer delete(seen, t)
+	}
+
+	var elem reflect.Type
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		var err error
+		if elem, err = reflectionType(t.Elem(), typeSchemas, seen); err != nil || elem == t.Elem() {
+			return t, err
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return reflect.PointerTo(elem), nil
+	case reflect.Slice:
+		return reflect.SliceOf(elem), nil
+	case reflect.Array:
+		return reflect.ArrayOf(t.Len(), elem), nil
+	case reflect.Map:
+		return reflect.MapOf(t.Key(), elem), nil
+	case reflect.Struct:
+		changed := false
+		var fields []reflect.StructField
+		for _, field := range reflect.VisibleFields(t) {
+			if field.Anonymous || !field.IsExported() {
+				continue // Not properties of their own, see jsonschema.For
+			}
+			fieldType := field.Type
+			if field.Tag.Get("json") != "-" { // Skipped fields may refer back to t
+				var err error
+				if fieldType, err = reflectionType(field.Type, typeSchemas, seen); err != nil {
+					return nil, err
+				}
+			}
+			stripped := reflect.StructField{Name: field.Name, Type: fieldType, Tag: field.Tag}
+			if tag, ok := field.Tag.Lookup("jsonschema"); ok && constraintTag.MatchString(tag) {
+				stripped.Tag = ""
+				if name, ok := field.Tag.Lookup("json"); ok {
+					stripped.Tag = reflect.StructTag("json:" + strconv.Quote(name))
+				}
+			}
+			changed = changed || stripped.Type != field.Type || stripped.Tag != field.Tag
+			fields = append(fields, stripped)
+		}
+		if changed {
+			return reflect.StructOf(fields), nil
+		}
+	}
+	return t, nil
+}
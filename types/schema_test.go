@@ -0,0 +1,373 @@
+package types
+
+import (
+	"context"
+	"encoding/json/v2"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+type exampleLocation struct {
+	City string `json:"city" jsonschema:"example:San Francisco, CA"`
+	Zip  string `json:"zip" jsonschema:"Postal code"`
+}
+
+type exampleInput struct {
+	Location  exampleLocation   `json:"location"`
+	Stops     []exampleLocation `json:"stops"`
+	Units     string            `json:"units" jsonschema:"example:celsius"`
+	Untagged  int               `json:"untagged"`
+	Internal  string            `json:"-"`
+	Unchanged string
+}
+
+func schemaProperty(t *testing.T, schema map[string]any, path ...string) map[string]any {
+	t.Helper()
+	current := schema
+	for _, key := range path {
+		next, ok := current[key].(map[string]any)
+		if !ok {
+			t.Fatalf("schema missing %q in path %v: %#v", key, path, current)
+		}
+		current = next
+	}
+	return current
+}
+
+func TestSchemaMapForExamples(t *testing.T) {
+	schema, err := SchemaMapFor[exampleInput]()
+	if err != nil {
+		t.Fatalf("SchemaMapFor returned error: %v", err)
+	}
+
+	units := schemaProperty(t, schema, "properties", "units")
+	if !reflect.DeepEqual(units["examples"], []any{"celsius"}) {
+		t.Errorf("expected units examples [celsius], got %#v", units["examples"])
+	}
+	if _, ok := units["description"]; ok {
+		t.Errorf("expected example tag not to become a description, got %v", units["description"])
+	}
+
+	city := schemaProperty(t, schema, "properties", "location", "properties", "city")
+	if !reflect.DeepEqual(city["examples"], []any{"San Francisco, CA"}) {
+		t.Errorf("expected nested city examples, got %#v", city["examples"])
+	}
+
+	stopCity := schemaProperty(t, schema, "properties", "stops", "items", "properties", "city")
+	if !reflect.DeepEqual(stopCity["examples"], []any{"San Francisco, CA"}) {
+		t.Errorf("expected slice item city examples, got %#v", stopCity["examples"])
+	}
+}
+
+func TestSchemaMapForNoExamplesWithoutTag(t *testing.T) {
+	schema, err := SchemaMapFor[exampleInput]()
+	if err != nil {
+		t.Fatalf("SchemaMapFor returned error: %v", err)
+	}
+
+	for _, path := range [][]string{
+		{"properties", "untagged"},
+		{"properties", "Unchanged"},
+		{"properties", "location", "properties", "zip"},
+	} {
+		property := schemaProperty(t, schema, path...)
+		if _, ok := property["examples"]; ok {
+			t.Errorf("expected no examples for %v, got %#v", path, property["examples"])
+		}
+	}
+
+	zip := schemaProperty(t, schema, "properties", "location", "properties", "zip")
+	if zip["description"] != "Postal code" {
+		t.Errorf("expected description to be preserved, got %v", zip["description"])
+	}
+}
+
+func TestSchemaErrorFields(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name": map[string]any{"type": "string"},
+			"address": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"city": map[string]any{"type": "string"},
+				},
+				"required": []any{"city"},
+			},
+		},
+		"required": []any{"name", "address"},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "wrong type", content: `{"name": 1, "address": {"city": "x"}}`, want: []string{"name"}},
+		{name: "nested wrong type", content: `{"name": "a", "address": {"city": 2}}`, want: []string{"address.city"}},
+		{name: "missing top-level", content: `{"address": {"city": "x"}}`, want: []string{"name"}},
+		{name: "missing nested", content: `{"name": "a", "address": {}}`, want: []string{"address.city"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJSONString(tt.content, schema)
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			if got := SchemaErrorFields(err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SchemaErrorFields() = %v, want %v (err: %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+type strictInput struct {
+	Name     string            `json:"name"`
+	Nickname string            `json:"nickname,omitempty"`
+	Stops    []exampleLocation `json:"stops,omitempty"`
+}
+
+func TestStrictSchema(t *testing.T) {
+	schema, err := SchemaMapFor[strictInput]()
+	if err != nil {
+		t.Fatalf("SchemaMapFor failed: %v", err)
+	}
+
+	strict := StrictSchema(schema)
+
+	if !reflect.DeepEqual(strict["required"], []any{"name", "nickname", "stops"}) {
+		t.Errorf("expected all properties required, got %v", strict["required"])
+	}
+	if strict["additionalProperties"] != false {
+		t.Errorf("expected additionalProperties false, got %v", strict["additionalProperties"])
+	}
+	if got := schemaProperty(t, strict, "properties", "nickname")["type"]; !reflect.DeepEqual(got, []any{"string", "null"}) {
+		t.Errorf("expected optional property to be nullable, got %v", got)
+	}
+	if got := schemaProperty(t, strict, "properties", "name")["type"]; got != "string" {
+		t.Errorf("expected required property unchanged, got %v", got)
+	}
+	if got := schemaProperty(t, strict, "properties", "stops", "items")["required"]; !reflect.DeepEqual(got, []any{"city", "zip"}) {
+		t.Errorf("expected nested objects to be rewritten, got %v", got)
+	}
+
+	// The input is left untouched and the transformation is idempotent
+	if _, ok := schemaProperty(t, schema, "properties", "nickname")["type"].(string); !ok {
+		t.Errorf("input schema was modified")
+	}
+	if !reflect.DeepEqual(StrictSchema(strict), strict) {
+		t.Errorf("expected StrictSchema to be idempotent")
+	}
+
+	if err := ValidateJSONString(`{"name": "a", "nickname": null, "stops": null}`, strict); err != nil {
+		t.Errorf("expected nulls for optional properties to validate: %v", err)
+	}
+}
+
+func TestRelaxedSchema(t *testing.T) {
+	schema, err := SchemaMapFor[strictInput]()
+	if err != nil {
+		t.Fatalf("SchemaMapFor failed: %v", err)
+	}
+	extra := `{"name": "a", "extra": 1, "stops": [{"city": "b", "zip": "c", "extra": true}]}`
+	if err := ValidateJSONString(extra, schema); err == nil {
+		t.Fatal("expected additional properties to fail the generated schema")
+	}
+
+	relaxed := RelaxedSchema(schema)
+	if err := ValidateJSONString(extra, relaxed); err != nil {
+		t.Errorf("expected additional properties to validate: %v", err)
+	}
+	if err := ValidateJSONString(`{"extra": 1}`, relaxed); err == nil {
+		t.Error("expected required properties to still be checked")
+	}
+	if schema["additionalProperties"] != false {
+		t.Error("input schema was modified")
+	}
+}
+
+type schemaOptionsInput struct {
+	ID      string            `json:"id"`
+	Created time.Time         `json:"created"`
+	Stops   []exampleLocation `json:"stops"`
+}
+
+func TestSchemaMapForOptions(t *testing.T) {
+	schema, err := SchemaMapFor[schemaOptionsInput](
+		WithSchemaTitle("Trip"),
+		WithSchemaDescription("A planned trip"),
+		WithTypeSchema[time.Time](&jsonschema.Schema{Type: "string", Format: "date-time"}),
+		WithoutProperties("id"),
+		WithPropertyName("stops", "waypoints"),
+		WithPropertyName("stops.zip", "postcode"),
+	)
+	if err != nil {
+		t.Fatalf("SchemaMapFor failed: %v", err)
+	}
+
+	if schema["title"] != "Trip" || schema["description"] != "A planned trip" {
+		t.Errorf("expected title and description, got %v, %v", schema["title"], schema["description"])
+	}
+	if got := schemaProperty(t, schema, "properties", "created")["format"]; got != "date-time" {
+		t.Errorf("expected the custom time schema, got format %v", got)
+	}
+	if _, ok := schema["properties"].(map[string]any)["id"]; ok {
+		t.Error("expected id to be omitted")
+	}
+	if !reflect.DeepEqual(schema["required"], []any{"created", "waypoints"}) {
+		t.Errorf("expected required to follow omissions and renames, got %v", schema["required"])
+	}
+	stop := schemaProperty(t, schema, "properties", "waypoints", "items")
+	if _, ok := stop["properties"].(map[string]any)["postcode"]; !ok {
+		t.Errorf("expected the nested property renamed, got %v", stop["properties"])
+	}
+
+	if _, err := SchemaMapFor[schemaOptionsInput](WithoutProperties("stops.country")); err == nil {
+		t.Error("expected an error for an unknown property")
+	}
+
+	resolved, err := ResolveSchemaFor[schemaOptionsInput](WithoutProperties("id"))
+	if err != nil {
+		t.Fatalf("ResolveSchemaFor failed: %v", err)
+	}
+	if err := resolved.Validate(map[string]any{"created": "2026-01-01T00:00:00Z", "stops": []any{}}); err != nil {
+		t.Errorf("expected a value without the omitted property to validate: %v", err)
+	}
+}
+
+func TestRestorePropertyNames(t *testing.T) {
+	opts := []SchemaOption{WithPropertyName("stops", "waypoints"), WithPropertyName("stops.zip", "postcode")}
+	data, err := RestorePropertyNames([]byte(`{"id":"t1","waypoints":[{"city":"Oslo","postcode":"0150"}]}`), opts...)
+	if err != nil {
+		t.Fatalf("RestorePropertyNames failed: %v", err)
+	}
+	var trip struct {
+		ID    string            `json:"id"`
+		Stops []exampleLocation `json:"stops"`
+	}
+	if err := json.Unmarshal(data, &trip); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if trip.ID != "t1" || len(trip.Stops) != 1 || trip.Stops[0] != (exampleLocation{City: "Oslo", Zip: "0150"}) {
+		t.Errorf("expected the original names restored, got %s", data)
+	}
+
+	if got, err := RestorePropertyNames([]byte(`{"a":1}`), WithSchemaTitle("T")); err != nil || string(got) != `{"a":1}` {
+		t.Errorf("expected data unchanged without renames, got %s, %v", got, err)
+	}
+	if _, err := RestorePropertyNames([]byte(`{"waypoints":`), opts...); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+type constrainedInput struct {
+	Rating   int      `json:"rating" jsonschema:"minimum=0,maximum=10"`
+	Priority string   `json:"priority" jsonschema:"enum=low|medium|high"`
+	Due      string   `json:"due,omitempty" jsonschema:"format=date-time,description=When it is due"`
+	Tags     []string `json:"tags,omitempty" jsonschema:"maxItems=3"`
+	Note     string   `json:"note,omitempty" jsonschema:"Free text"`
+	Level    *int     `json:"level,omitempty" jsonschema:"enum=1|2|3"`
+
+	Stops []constrainedStop `json:"stops,omitempty"`
+}
+
+type constrainedStop struct {
+	Name string `json:"name" jsonschema:"minLength=1"`
+}
+
+func TestSchemaMapForConstraintTags(t *testing.T) {
+	schema, err := SchemaMapFor[constrainedInput]()
+	if err != nil {
+		t.Fatalf("SchemaMapFor failed: %v", err)
+	}
+
+	rating := schemaProperty(t, schema, "properties", "rating")
+	if rating["minimum"] != 0.0 || rating["maximum"] != 10.0 || rating["description"] != nil {
+		t.Errorf("expected range constraints without a description, got %v", rating)
+	}
+	if got := schemaProperty(t, schema, "properties", "priority")["enum"]; !reflect.DeepEqual(got, []any{"low", "medium", "high"}) {
+		t.Errorf("expected string enum, got %v", got)
+	}
+	if got := schemaProperty(t, schema, "properties", "level")["enum"]; !reflect.DeepEqual(got, []any{1.0, 2.0, 3.0}) {
+		t.Errorf("expected numeric enum, got %v", got)
+	}
+	due := schemaProperty(t, schema, "properties", "due")
+	if due["format"] != "date-time" || due["description"] != "When it is due" {
+		t.Errorf("expected format and description, got %v", due)
+	}
+	if got := schemaProperty(t, schema, "properties", "note")["description"]; got != "Free text" {
+		t.Errorf("expected plain tags to stay descriptions, got %v", got)
+	}
+	if got := schemaProperty(t, schema, "properties", "stops", "items", "properties", "name")["minLength"]; got != 1 {
+		t.Errorf("expected nested constraints, got %v", got)
+	}
+
+	resolved, err := ResolveSchemaFor[constrainedInput]()
+	if err != nil {
+		t.Fatalf("ResolveSchemaFor failed: %v", err)
+	}
+	if err := resolved.Validate(map[string]any{"rating": 5.0, "priority": "low"}); err != nil {
+		t.Errorf("expected valid input to pass: %v", err)
+	}
+	for _, input := range []map[string]any{
+		{"rating": 11.0, "priority": "low"},
+		{"rating": 5.0, "priority": "urgent"},
+		{"rating": 5.0, "priority": "low", "tags": []any{"a", "b", "c", "d"}},
+		{"rating": 5.0, "priority": "low", "stops": []any{map[string]any{"name": ""}}},
+	} {
+		if err := resolved.Validate(input); err == nil {
+			t.Errorf("expected %v to fail validation", input)
+		}
+	}
+}
+
+type treeNode struct {
+	Value    int        `json:"value" jsonschema:"minimum=0"`
+	Children []treeNode `json:"children,omitempty"`
+	Parent   *treeNode  `json:"-"`
+}
+
+type treeInput struct {
+	Root   treeNode  `json:"root"`
+	Parent *treeNode `json:"-"`
+}
+
+func TestSchemaMapForRecursiveTypes(t *testing.T) {
+	if _, err := SchemaMapFor[treeNode](); err == nil || !strings.Contains(err.Error(), "recursive type") {
+		t.Errorf("expected a recursive type error, got %v", err)
+	}
+	if _, err := NewTool("walk", "Walks a tree", func(ctx context.Context, in treeNode) (string, error) { return "", nil }); err == nil {
+		t.Error("expected NewTool to reject a recursive input type")
+	}
+
+	// Described with WithTypeSchema, the type can be used
+	node := &jsonschema.Schema{Type: "object", Properties: map[string]*jsonschema.Schema{"value": {Type: "integer"}}}
+	schema, err := SchemaMapFor[treeInput](WithTypeSchema[treeNode](node))
+	if err != nil {
+		t.Fatalf("SchemaMapFor failed: %v", err)
+	}
+	if got := schemaProperty(t, schema, "properties", "root", "properties", "value")["type"]; got != "integer" {
+		t.Errorf("expected the custom node schema, got %v", got)
+	}
+}
+
+func TestSchemaMapForInvalidConstraintTag(t *testing.T) {
+	type unknownKeyword struct {
+		N int `json:"n" jsonschema:"min=0"`
+	}
+	if _, err := SchemaMapFor[unknownKeyword](); err == nil {
+		t.Error("expected an error for an unknown keyword")
+	}
+
+	type badNumber struct {
+		N int `json:"n" jsonschema:"maximum=ten"`
+	}
+	if _, err := SchemaMapFor[badNumber](); err == nil {
+		t.Error("expected an error for a non-numeric maximum")
+	}
+}
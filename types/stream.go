@@ -89,6 +89,12 @@ type StreamChunk struct {
 	Model   string
 	Choices []StreamChoice
 	Usage   *Usage
+
+	SystemFingerprint string
+
+	// Extra holds provider-specific data of the chunk, like ChatResponse.Extra: the raw event
+	// under ExtraRaw
+	Extra map[string]any `json:"-"`
 }
 
 // StreamChoice holds incremental content for one choice index.
@@ -104,6 +110,13 @@ type MessageDelta struct {
 	Content   string
 	ToolCalls []ToolCallDelta
 	Refusal   string
+	Reasoning string
+
+	ReasoningSignature string // Signature of the reasoning streamed so far, see ContentPartReasoning
+	RedactedReasoning  string // A complete block of encrypted reasoning
+
+	// Annotations are complete citations, with spans counted over the whole message
+	Annotations []Annotation
 }
 
 // ToolCallDelta represents partial tool call information for a choice.
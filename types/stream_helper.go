@@ -0,0 +1,99 @@
+package types
+
+import (
+	"context"
+	"sort"
+)
+
+// StreamHandler is invoked for every chunk received by StreamWithHandler.
+// Returning an error stops consumption and is returned to the caller.
+type StreamHandler func(chunk *StreamChunk) error
+
+// StreamWithHandler performs a streaming chat request, passes every chunk to
+// handler (which may be nil) and accumulates the deltas into a ChatResponse.
+//
+// When params.ResponseFormat has a schema, the structured content is extracted
+// from the assembled message and stored on Choices[0].StructuredContent, so the
+// result is equivalent to a non-streaming Chat call.
+func StreamWithHandler(ctx context.Context, c Client, params *ChatParams, handler StreamHandler) (*ChatResponse, error) {
+	stream, err := c.ChatStream(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	resp := &ChatResponse{Extra: make(map[string]any)}
+	accumulators := make(map[int]*MessageAccumulator)
+
+	for stream.Next() {
+		chunk := stream.Chunk()
+
+		if handler != nil {
+			if err := handler(chunk); err != nil {
+				return nil, err
+			}
+		}
+
+		if resp.ID == "" {
+			resp.ID = chunk.ID
+			resp.Created = chunk.Created
+			resp.Model = chunk.Model
+		}
+		if chunk.SystemFingerprint != "" {
+			resp.SystemFingerprint = chunk.SystemFingerprint
+		}
+		if chunk.Usage != nil {
+			resp.Usage = chunk.Usage
+		}
+		for key, value := range chunk.Extra {
+			if key != ExtraRaw { // Raw events don't add up to a raw response
+				resp.Extra[key] = value
+			}
+		}
+
+		for _, choice := range chunk.Choices {
+			acc := accumulators[choice.Index]
+			if acc == nil {
+				acc = NewMessageAccumulator()
+				accumulators[choice.Index] = acc
+			}
+			acc.UpdateChoice(&choice)
+			acc.UpdateUsage(chunk.Usage)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int, 0, len(accumulators))
+	for idx := range accumulators {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	resp.Choices = make([]Choice, 0, len(indexes))
+	for _, idx := range indexes {
+		msg, err := accumulators[idx].Message()
+		if err != nil {
+			return nil, err
+		}
+		if msg.Role == "" {
+			msg.Role = RoleAssistant
+		}
+		resp.Choices = append(resp.Choices, Choice{
+			Index:        idx,
+			Message:      msg,
+			FinishReason: accumulators[idx].FinishReason(),
+		})
+	}
+
+	if rf := EffectiveResponseFormat(c, params.ResponseFormat); rf.Schema != nil && len(resp.Choices) > 0 {
+		content, err := ExtractStructuredContent(rf, resp.Choices[0].Message)
+		if err != nil {
+			return nil, err
+		}
+		resp.Choices[0].StructuredContent = content
+	}
+
+	return resp, nil
+}
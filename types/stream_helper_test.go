@@ -0,0 +1,290 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// streamRawClient is a RawClient whose streaming calls replay a fixed set of chunks.
+type streamRawClient struct {
+	chunks []*StreamChunk
+}
+
+func (c *streamRawClient) RawChat(ctx context.Context, params *ChatParams) (*ChatResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *streamRawClient) RawChatStream(ctx context.Context, params *ChatParams) (*Stream, error) {
+	i := 0
+	return NewStream(func() (*StreamChunk, error) {
+		if i >= len(c.chunks) {
+			return nil, io.EOF
+		}
+		chunk := c.chunks[i]
+		i++
+		return chunk, nil
+	}, nil), nil
+}
+
+func (c *streamRawClient) RawEmbed(ctx context.Context, params *EmbeddingParams) (*EmbeddingResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func contentChunks(fragments ...string) []*StreamChunk {
+	chunks := make([]*StreamChunk, 0, len(fragments)+1)
+	for i, fragment := range fragments {
+		delta := &MessageDelta{Content: fragment}
+		if i == 0 {
+			delta.Role = RoleAssistant
+		}
+		chunks = append(chunks, &StreamChunk{
+			ID:      "chunk",
+			Model:   "test-model",
+			Choices: []StreamChoice{{Index: 0, Delta: delta}},
+		})
+	}
+	chunks = append(chunks, &StreamChunk{
+		ID:      "chunk",
+		Choices: []StreamChoice{{Index: 0, Delta: &MessageDelta{}, FinishReason: "stop"}},
+		Usage:   &Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7},
+	})
+	return chunks
+}
+
+func TestStreamWithHandlerStructuredContent(t *testing.T) {
+	raw := &streamRawClient{chunks: contentChunks(`{"ci`, `ty": "NY`, `C", "temp"`, `: 72}`)}
+	client := NewClient(raw)
+
+	params := &ChatParams{
+		Model: "test-model",
+		ResponseFormat: ResponseFormat{
+			Mode: ResponseFormatModeNative,
+			Schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"city": map[string]any{"type": "string"},
+					"temp": map[string]any{"type": "integer"},
+				},
+				"required": []any{"city", "temp"},
+			},
+		},
+	}
+
+	var seen int
+	resp, err := StreamWithHandler(context.Background(), client, params, func(chunk *StreamChunk) error {
+		seen++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamWithHandler returned error: %v", err)
+	}
+
+	if seen != 5 {
+		t.Errorf("expected handler to see 5 chunks, got %d", seen)
+	}
+	if len(resp.Choices) != 1 {
+		t.Fatalf("expected 1 choice, got %d", len(resp.Choices))
+	}
+
+	want := `{"city": "NYC", "temp": 72}`
+	if got := resp.Choices[0].StructuredContent; got != want {
+		t.Errorf("expected structured content %q, got %q", want, got)
+	}
+	if resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("expected finish reason stop, got %q", resp.Choices[0].FinishReason)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 7 {
+		t.Errorf("expected usage total 7, got %#v", resp.Usage)
+	}
+}
+
+func TestStreamWithHandlerWithoutSchema(t *testing.T) {
+	raw := &streamRawClient{chunks: contentChunks("Hel", "lo")}
+	raw.chunks[0].SystemFingerprint = "fp_1"
+
+	resp, err := StreamWithHandler(context.Background(), NewClient(raw), &ChatParams{}, nil)
+	if err != nil {
+		t.Fatalf("StreamWithHandler returned error: %v", err)
+	}
+
+	if got := resp.Choices[0].Message.TextContent(); got != "Hello" {
+		t.Errorf("expected text %q, got %q", "Hello", got)
+	}
+	if resp.Choices[0].StructuredContent != "" {
+		t.Errorf("expected no structured content, got %q", resp.Choices[0].StructuredContent)
+	}
+	if resp.SystemFingerprint != "fp_1" {
+		t.Errorf("expected system fingerprint %q, got %q", "fp_1", resp.SystemFingerprint)
+	}
+}
+
+func TestStreamWithHandlerInvalidStructuredContent(t *testing.T) {
+	raw := &streamRawClient{chunks: contentChunks(`{"city": `, `1}`)}
+
+	params := &ChatParams{
+		ResponseFormat: ResponseFormat{
+			Mode: ResponseFormatModeNative,
+			Schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string"}},
+			},
+		},
+	}
+
+	_, err := StreamWithHandler(context.Background(), NewClient(raw), params, nil)
+	var schemaErr *SchemaValidationError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected SchemaValidationError, got %v", err)
+	}
+}
+
+func TestStreamWithHandlerHandlerError(t *testing.T) {
+	raw := &streamRawClient{chunks: contentChunks("a", "b")}
+	stop := errors.New("stop")
+
+	_, err := StreamWithHandler(context.Background(), NewClient(raw), &ChatParams{}, func(chunk *StreamChunk) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+}
+
+func TestStreamWithHandlerExtra(t *testing.T) {
+	raw := &streamRawClient{chunks: contentChunks("Hel", "lo")}
+	raw.chunks[0].Extra = map[string]any{ExtraRaw: "event 1"}
+	raw.chunks[2].Extra = map[string]any{ExtraRaw: "event 3", "service_tier": "flex"}
+
+	resp, err := StreamWithHandler(context.Background(), NewClient(raw), &ChatParams{}, nil)
+	if err != nil {
+		t.Fatalf("StreamWithHandler returned error: %v", err)
+	}
+
+	if resp.Extra["service_tier"] != "flex" {
+		t.Errorf("expected service tier flex, got %v", resp.Extra["service_tier"])
+	}
+	if _, ok := resp.Extra[ExtraRaw]; ok {
+		t.Errorf("expected no raw response for a stream, got %v", resp.Extra[ExtraRaw])
+	}
+}
+
+// stalledRawClient streams one chunk, then blocks until the stream is closed.
+type stalledRawClient struct {
+	streamRawClient
+	closed chan struct{}
+}
+
+func (c *stalledRawClient) RawChatStream(ctx context.Context, params *ChatParams) (*Stream, error) {
+	sent := false
+	return NewStream(func() (*StreamChunk, error) {
+		if !sent {
+			sent = true
+			return contentChunks("Hel")[0], nil
+		}
+		<-c.closed
+		return nil, errors.New("connection closed")
+	}, closerFunc(func() error {
+		close(c.closed)
+		return nil
+	})), nil
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestStreamWithHandlerChunkTimeout(t *testing.T) {
+	raw := &stalledRawClient{closed: make(chan struct{})}
+	var chunks int
+	_, err := StreamWithHandler(context.Background(), NewClient(raw, WithChunkTimeout(20*time.Millisecond)), &ChatParams{}, func(chunk *StreamChunk) error {
+		chunks++
+		return nil
+	})
+
+	var stalled *StreamStalledError
+	if !errors.As(err, &stalled) || stalled.Timeout != 20*time.Millisecond {
+		t.Fatalf("expected StreamStalledError, got %v", err)
+	}
+	if chunks != 1 {
+		t.Errorf("expected the chunk before the stall to be handled, got %d chunks", chunks)
+	}
+	select {
+	case <-raw.closed:
+	default:
+		t.Error("expected the stalled stream to be closed")
+	}
+}
+
+func TestStreamWithChunkTimeoutPassesChunks(t *testing.T) {
+	raw := &streamRawClient{chunks: contentChunks("Hel", "lo")}
+	resp, err := StreamWithHandler(context.Background(), NewClient(raw, WithChunkTimeout(time.Second)), &ChatParams{}, nil)
+	if err != nil {
+		t.Fatalf("StreamWithHandler returned error: %v", err)
+	}
+	if got := resp.Choices[0].Message.TextContent(); got != "Hello" {
+		t.Errorf("expected text %q, got %q", "Hello", got)
+	}
+}
+
+func TestTeeStream(t *testing.T) {
+	raw := &streamRawClient{chunks: contentChunks("Hel", "lo")}
+	source, _ := raw.RawChatStream(context.Background(), &ChatParams{})
+	closed := false
+	source.closer = closerFunc(func() error {
+		closed = true
+		return nil
+	})
+
+	streams := TeeStream(source, 2)
+	if len(streams) != 2 {
+		t.Fatalf("expected 2 streams, got %d", len(streams))
+	}
+
+	var wg sync.WaitGroup
+	counts := make([]int, len(streams))
+	for i, stream := range streams {
+		wg.Go(func() {
+			for stream.Next() {
+				counts[i]++
+			}
+			if err := stream.Err(); err != nil {
+				t.Errorf("stream %d: unexpected error: %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	for i, count := range counts {
+		if count != len(raw.chunks) {
+			t.Errorf("stream %d: expected %d chunks, got %d", i, len(raw.chunks), count)
+		}
+	}
+	streams[0].Close()
+	if closed {
+		t.Error("expected the source to stay open until every stream is closed")
+	}
+	streams[1].Close()
+	if !closed {
+		t.Error("expected the source to be closed with the last stream")
+	}
+}
+
+func TestTeeStreamError(t *testing.T) {
+	failure := errors.New("connection reset")
+	source := NewStream(func() (*StreamChunk, error) {
+		return nil, failure
+	}, nil)
+
+	for i, stream := range TeeStream(source, 2) {
+		if stream.Next() {
+			t.Fatalf("stream %d: expected no chunks", i)
+		}
+		if !errors.Is(stream.Err(), failure) {
+			t.Errorf("stream %d: expected the source error, got %v", i, stream.Err())
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package types
+
+import (
+	"io"
+	"sync"
+)
+
+// TeeStream splits s into n streams that each yield every chunk of s and its error, so one
+// provider stream can feed several consumers, e.g. a UI and a StreamWithHandler accumulator,
+// without requesting it twice. The streams may be read from different goroutines at their
+// own pace; chunks not read yet by a stream are buffered. s is closed once all n streams are.
+// Chunks are shared between the streams and must not be modified.
+func TeeStream(s *Stream, n int) []*Stream {
+	if n < 1 {
+		return nil
+	}
+	t := &tee{source: s, queues: make([][]*StreamChunk, n), closed: make([]bool, n), open: n}
+	t.cond = sync.NewCond(&t.mu)
+
+	streams := make([]*Stream, n)
+	for i := range streams {
+		streams[i] = NewStream(func() (*StreamChunk, error) {
+			return t.next(i)
+		}, teeCloser{t, i})
+	}
+	return streams
+}
+
+// tee reads the source stream on behalf of the streams of TeeStream
+type tee struct {
+	source *Stream
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  [][]*StreamChunk // Chunks read from the source but not yet by each stream
+	closed  []bool
+	open    int  // Streams not closed yet
+	reading bool // A stream is waiting on the source
+	done    bool
+	err     error
+}
+
+// next returns the next chunk of stream i, reading from the source when i has none buffered
+func (t *tee) next(i int) (*StreamChunk, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for len(t.queues[i]) == 0 && !t.done {
+		if t.reading {
+			t.cond.Wait() // Another stream is reading; its chunk is queued for i too
+			continue
+		}
+		t.reading = true
+		t.mu.Unlock()
+		ok := t.source.Next()
+		t.mu.Lock()
+		t.reading = false
+
+		if ok {
+			chunk := t.source.Chunk()
+			for j := range t.queues {
+				if !t.closed[j] {
+					t.queues[j] = append(t.queues[j], chunk)
+				}
+			}
+		} else {
+			t.done = true
+			t.err = t.source.Err()
+		}
+		t.cond.Broadcast()
+	}
+
+	if len(t.queues[i]) > 0 {
+		chunk := t.queues[i][0]
+		t.queues[i] = t.queues[i][1:]
+		return chunk, nil
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+	return nil, io.EOF
+}
+
+// close drops the buffer of stream i, closing the source after the last stream
+func (t *tee) close(i int) error {
+	t.mu.Lock()
+	if t.closed[i] {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed[i] = true
+	t.queues[i] = nil
+	t.open--
+	last := t.open == 0
+	t.mu.Unlock()
+
+	if last {
+		return t.source.Close()
+	}
+	return nil
+}
+
+type teeCloser struct {
+	tee   *tee
+	index int
+}
+
+func (c teeCloser) Close() error {
+	return c.tee.close(c.index)
+}
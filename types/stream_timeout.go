@@ -0,0 +1,73 @@
+package types
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// StreamStalledError is returned by a stream that received no chunk for longer than its
+// chunk timeout. See WithChunkTimeout.
+type StreamStalledError struct {
+	Timeout time.Duration
+}
+
+func (e *StreamStalledError) Error() string {
+	return fmt.Sprintf("stream stalled: no chunk received for %s", e.Timeout)
+}
+
+// WithChunkTimeout fails the streams of the client with a *StreamStalledError when no chunk
+// arrives for timeout, so a hung connection can't block a caller without a context deadline.
+// Non-positive timeouts disable the check. See Stream.WithChunkTimeout.
+func WithChunkTimeout(timeout time.Duration) ClientOption {
+	return func(bc *baseClient) {
+		bc.chunkTimeout = timeout
+	}
+}
+
+// WithChunkTimeout returns a stream reading from s that fails with a *StreamStalledError when
+// the next chunk takes longer than timeout, closing s to release the connection. Non-positive
+// timeouts return s unchanged. Close the returned stream instead of s.
+func (s *Stream) WithChunkTimeout(timeout time.Duration) *Stream {
+	if s == nil || s.next == nil || timeout <= 0 {
+		return s
+	}
+	type result struct {
+		chunk *StreamChunk
+		err   error
+	}
+	next := s.next
+	closer := &onceCloser{closer: s}
+	return NewStream(func() (*StreamChunk, error) {
+		done := make(chan result, 1) // Buffered so a stalled read can finish after the timeout
+		go func() {
+			chunk, err := next()
+			done <- result{chunk, err}
+		}()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case r := <-done:
+			return r.chunk, r.err
+		case <-timer.C:
+			closer.Close() // Unblocks the stalled read
+			return nil, &StreamStalledError{Timeout: timeout}
+		}
+	}, closer)
+}
+
+// onceCloser closes a stream closed both on a stall and by the caller only once
+type onceCloser struct {
+	once   sync.Once
+	closer io.Closer
+	err    error
+}
+
+func (c *onceCloser) Close() error {
+	c.once.Do(func() {
+		c.err = c.closer.Close()
+	})
+	return c.err
+}
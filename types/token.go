@@ -0,0 +1,52 @@
+package types
+
+import "encoding/json/v2"
+
+// TokenCounter estimates how many tokens messages take up in a model's context window.
+type TokenCounter interface {
+	CountMessages(messages []Message) int
+}
+
+const (
+	// heuristicCharsPerToken is the average number of characters per token of English text
+	heuristicCharsPerToken = 4
+
+	// heuristicMessageOverhead covers the role and separators every message is wrapped in
+	heuristicMessageOverhead = 4
+
+	// heuristicNonTextPartTokens is charged for images, audio and files, whose cost depends on the provider
+	heuristicNonTextPartTokens = 85
+)
+
+// HeuristicTokenCounter estimates tokens from text length, about four characters per token
+// plus a small overhead per message. Use it when no tokenizer for the model is available.
+type HeuristicTokenCounter struct{}
+
+// CountMessages implements TokenCounter.
+func (HeuristicTokenCounter) CountMessages(messages []Message) int {
+	tokens := 0
+	for i := range messages {
+		chars := 0
+		tokens += heuristicMessageOverhead
+		for _, part := range messages[i].ContentPart {
+			switch p := part.(type) {
+			case *ContentPartText:
+				chars += len(p.Text)
+			case *ContentPartRefusal:
+				chars += len(p.Refusal)
+			case *ContentPartReasoning:
+				chars += len(p.Text)
+			default:
+				tokens += heuristicNonTextPartTokens
+			}
+		}
+		for _, tc := range messages[i].ToolCalls {
+			chars += len(tc.ID) + len(tc.Function.Name)
+			if args, err := json.Marshal(tc.Function.Arguments); err == nil {
+				chars += len(args)
+			}
+		}
+		tokens += (chars + heuristicCharsPerToken - 1) / heuristicCharsPerToken
+	}
+	return tokens
+}
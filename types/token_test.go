@@ -0,0 +1,28 @@
+package types
+
+import "testing"
+
+func TestHeuristicTokenCounter(t *testing.T) {
+	var counter HeuristicTokenCounter
+
+	if got := counter.CountMessages(nil); got != 0 {
+		t.Errorf("empty history = %d tokens, want 0", got)
+	}
+
+	short := []Message{NewUserMessage(WithText("abcd"))}
+	long := []Message{NewUserMessage(WithText("abcdabcdabcdabcd"))}
+	if got := counter.CountMessages(short); got != 5 {
+		t.Errorf("short message = %d tokens, want 5", got)
+	}
+	if counter.CountMessages(long) <= counter.CountMessages(short) {
+		t.Error("longer text should count more tokens")
+	}
+
+	withCall := []Message{NewAssistantMessage(WithToolCalls(ToolCall{
+		ID:       "call_1",
+		Function: ToolFunction{Name: "search", Arguments: map[string]any{"query": "weather in Paris"}},
+	}))}
+	if got := counter.CountMessages(withCall); got <= heuristicMessageOverhead {
+		t.Errorf("tool call arguments not counted: %d tokens", got)
+	}
+}
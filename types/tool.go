@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json/v2"
 	"fmt"
+	"strings"
 )
 
 // ToolDefinition is metadata describing a tool for the LLM
@@ -14,6 +15,14 @@ type ToolDefinition struct {
 	Description  string
 	InputSchema  map[string]any
 	OutputSchema map[string]any
+
+	// Strict asks the provider to enforce InputSchema exactly (OpenAI strict mode). Adapters
+	// send StrictSchema(InputSchema); optional arguments then arrive as null.
+	Strict bool
+
+	// Metadata carries extra annotations about the tool (e.g. MCP hints).
+	// It is never sent to the LLM; hooks and loggers may inspect it.
+	Metadata map[string]any
 }
 
 type Execute func(ctx context.Context, args map[string]any) (*ToolResult, error)
@@ -102,6 +111,39 @@ type ToolResult struct {
 	IsError           bool
 }
 
+// TextContent returns the concatenated text parts of the result.
+func (t *ToolResult) TextContent() string {
+	var parts []string
+	for _, part := range t.ContentPart {
+		if p, ok := part.(*ContentPartText); ok {
+			parts = append(parts, p.Text)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// Images returns the base64 image parts of the result.
+func (t *ToolResult) Images() []*ContentPartImage {
+	var images []*ContentPartImage
+	for _, part := range t.ContentPart {
+		if p, ok := part.(*ContentPartImage); ok {
+			images = append(images, p)
+		}
+	}
+	return images
+}
+
+// ImageURLs returns the image URL parts of the result.
+func (t *ToolResult) ImageURLs() []*ContentPartImageURL {
+	var urls []*ContentPartImageURL
+	for _, part := range t.ContentPart {
+		if p, ok := part.(*ContentPartImageURL); ok {
+			urls = append(urls, p)
+		}
+	}
+	return urls
+}
+
 type ToolResultOption func(*ToolResult)
 
 // WithToolText Appends ContentPartText to tool
@@ -131,6 +173,20 @@ func NewToolResult(opts ...ToolResultOption) *ToolResult {
 	return t
 }
 
+// NewImageToolResult creates a result holding a single base64-encoded image.
+func NewImageToolResult(data, mimeType string) *ToolResult {
+	return &ToolResult{
+		ContentPart: []ContentPart{&ContentPartImage{Data: data, MIMEType: mimeType}},
+	}
+}
+
+// NewImageURLToolResult creates a result holding a single image URL.
+func NewImageURLToolResult(url string) *ToolResult {
+	return &ToolResult{
+		ContentPart: []ContentPart{NewContentPartImageURL(url)},
+	}
+}
+
 // NewToolResultMessage converts a ToolResult to a tool Message
 // This is a convenience helper for creating tool response messages from tool execution results
 func NewToolResultMessage(toolCallID string, result *ToolResult) Message {
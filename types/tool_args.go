@@ -0,0 +1,159 @@
+package types
+
+import (
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"strings"
+)
+
+// ToolArgumentsError reports tool call arguments that are not a JSON object, even after
+// RepairJSON. The agent sends it back to the model as a retry.
+type ToolArgumentsError struct {
+	Raw string // The arguments as sent by the model
+	Err error
+}
+
+func (e *ToolArgumentsError) Error() string {
+	return "invalid tool arguments: " + e.Err.Error()
+}
+
+func (e *ToolArgumentsError) Unwrap() error {
+	return e.Err
+}
+
+// ParseToolArguments decodes the JSON object arguments of a tool call. Invalid JSON is
+// repaired with RepairJSON first; if it still can't be decoded, a *ToolArgumentsError is
+// returned. Empty arguments decode to an empty map.
+func ParseToolArguments(raw string) (map[string]any, error) {
+	if strings.TrimSpace(raw) == "" {
+		return map[string]any{}, nil
+	}
+	args, err := decodeArguments(raw)
+	if err == nil {
+		return args, nil
+	}
+	if repaired, repairErr := decodeArguments(RepairJSON(raw)); repairErr == nil {
+		return repaired, nil
+	}
+	return nil, &ToolArgumentsError{Raw: raw, Err: err}
+}
+
+// NewToolFunction builds the function of a tool call from the raw JSON arguments sent by a
+// provider. Arguments that needed repair keep their original text in RawArguments, and
+// arguments that could not be repaired are left nil, see ToolFunction.ArgumentsError.
+func NewToolFunction(name, raw string) ToolFunction {
+	f := ToolFunction{Name: name}
+	f.Arguments, _ = ParseToolArguments(raw)
+	if f.Arguments == nil || !jsontext.Value(raw).IsValid() && strings.TrimSpace(raw) != "" {
+		f.RawArguments = raw
+	}
+	return f
+}
+
+func decodeArguments(raw string) (map[string]any, error) {
+	var args map[string]any
+	if err := json.Unmarshal([]byte(raw), &args); err != nil {
+		return nil, err
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	return args, nil
+}
+
+// RepairJSON fixes mistakes models commonly make when writing JSON: markdown code fences,
+// trailing commas, unescaped quotes and control characters inside strings, and strings or
+// brackets left open by truncated output. Valid JSON is returned unchanged; the result is not
+// guaranteed to be valid.
+func RepairJSON(s string) string {
+	s = strings.TrimSpace(s)
+	if jsontext.Value(s).IsValid() {
+		return s
+	}
+	s = stripCodeFence(s)
+
+	var (
+		b        strings.Builder
+		closers  []byte // Closing brackets of the open objects and arrays
+		inString bool
+		escaped  bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				if !closesString(s[i+1:]) {
+					b.WriteString(`\"`)
+					continue
+				}
+				inString = false
+			case c == '\n':
+				b.WriteString(`\n`)
+				continue
+			case c == '\r':
+				b.WriteString(`\r`)
+				continue
+			case c == '\t':
+				b.WriteString(`\t`)
+				continue
+			}
+			b.WriteByte(c)
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			closers = append(closers, '}')
+		case '[':
+			closers = append(closers, ']')
+		case '}', ']':
+			if len(closers) > 0 && closers[len(closers)-1] == c {
+				closers = closers[:len(closers)-1]
+			}
+		case ',':
+			if rest := strings.TrimLeft(s[i+1:], " \t\r\n"); rest == "" || rest[0] == '}' || rest[0] == ']' {
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+
+	out := b.String()
+	if inString {
+		out = strings.TrimSuffix(out, `\`) + `"`
+	}
+	out = strings.TrimRight(out, " \t\r\n,")
+	if strings.HasSuffix(out, ":") {
+		out += "null"
+	}
+	for i := len(closers) - 1; i >= 0; i-- {
+		out += string(closers[i])
+	}
+	return out
+}
+
+// closesString reports whether a quote followed by rest ends a string rather than being an
+// unescaped quote inside it
+func closesString(rest string) bool {
+	rest = strings.TrimLeft(rest, " \t\r\n")
+	return rest == "" || strings.ContainsRune(",:}]", rune(rest[0]))
+}
+
+// stripCodeFence removes a markdown code fence around s
+func stripCodeFence(s string) string {
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if newline := strings.IndexByte(s, '\n'); newline >= 0 {
+		s = s[newline+1:] // Language tag
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
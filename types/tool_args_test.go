@@ -0,0 +1,64 @@
+package types
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"valid", `{"a": 1}`, `{"a": 1}`},
+		{"trailing comma", `{"a": 1, "b": [1, 2,],}`, `{"a": 1, "b": [1, 2]}`},
+		{"code fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"unescaped quotes", `{"q": "say "hi" now"}`, `{"q": "say \"hi\" now"}`},
+		{"newline in string", "{\"q\": \"two\nlines\"}", `{"q": "two\nlines"}`},
+		{"truncated string", `{"q": "unfinish`, `{"q": "unfinish"}`},
+		{"truncated after key", `{"a": [1, {"b":`, `{"a": [1, {"b":null}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RepairJSON(tt.in); got != tt.want {
+				t.Errorf("RepairJSON(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseToolArguments(t *testing.T) {
+	args, err := ParseToolArguments(`{"city": "Paris",}`)
+	if err != nil || !reflect.DeepEqual(args, map[string]any{"city": "Paris"}) {
+		t.Errorf("ParseToolArguments = %v, %v", args, err)
+	}
+	if args, err := ParseToolArguments(" "); err != nil || args == nil || len(args) != 0 {
+		t.Errorf("expected empty arguments to decode to an empty map, got %v, %v", args, err)
+	}
+
+	_, err = ParseToolArguments(`{"city" "Paris"}`)
+	var argsErr *ToolArgumentsError
+	if !errors.As(err, &argsErr) || argsErr.Raw != `{"city" "Paris"}` {
+		t.Errorf("expected a *ToolArgumentsError, got %v", err)
+	}
+}
+
+func TestNewToolFunction(t *testing.T) {
+	valid := NewToolFunction("weather", `{"city": "Paris"}`)
+	if valid.RawArguments != "" || valid.Arguments["city"] != "Paris" || valid.ArgumentsError() != nil {
+		t.Errorf("unexpected function for valid arguments: %+v", valid)
+	}
+
+	repaired := NewToolFunction("weather", `{"city": "Paris",}`)
+	if repaired.RawArguments != `{"city": "Paris",}` || repaired.Arguments["city"] != "Paris" || repaired.ArgumentsError() != nil {
+		t.Errorf("unexpected function for repaired arguments: %+v", repaired)
+	}
+
+	broken := NewToolFunction("weather", `{"city" "Paris"}`)
+	var argsErr *ToolArgumentsError
+	if broken.Arguments != nil || broken.RawArguments != `{"city" "Paris"}` || !errors.As(broken.ArgumentsError(), &argsErr) {
+		t.Errorf("unexpected function for broken arguments: %+v", broken)
+	}
+}
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestImageToolResults(t *testing.T) {
+	result := NewImageToolResult("aGVsbG8=", "image/png")
+	images := result.Images()
+	if len(images) != 1 || images[0].Data != "aGVsbG8=" || images[0].MIMEType != "image/png" {
+		t.Fatalf("unexpected images: %+v", images)
+	}
+	if result.TextContent() != "" {
+		t.Errorf("expected no text content, got %q", result.TextContent())
+	}
+
+	urlResult := NewImageURLToolResult("https://example.com/chart.png")
+	if urls := urlResult.ImageURLs(); len(urls) != 1 || urls[0].URL != "https://example.com/chart.png" {
+		t.Fatalf("unexpected image URLs: %+v", urls)
+	}
+	if len(urlResult.Images()) != 0 {
+		t.Errorf("expected URL result to have no base64 images")
+	}
+}
+
+func TestToolResultImagesPartition(t *testing.T) {
+	result := NewToolResult(
+		WithToolText("Here is the chart: "),
+		WithToolImage("Y2hhcnQ="),
+		WithToolText("and a screenshot."),
+		WithToolImage("c2NyZWVu"),
+	)
+
+	images := result.Images()
+	if len(images) != 2 || images[0].Data != "Y2hhcnQ=" || images[1].Data != "c2NyZWVu" {
+		t.Fatalf("unexpected images: %+v", images)
+	}
+	if got := result.TextContent(); got != "Here is the chart: and a screenshot." {
+		t.Errorf("unexpected text content: %q", got)
+	}
+
+	var textParts int
+	for _, part := range result.ContentPart {
+		if _, ok := part.(*ContentPartText); ok {
+			textParts++
+		}
+	}
+	if textParts+len(images) != len(result.ContentPart) {
+		t.Errorf("text and image parts do not cover all %d content parts", len(result.ContentPart))
+	}
+}
+
+func TestNewToolRejectsConstraintViolations(t *testing.T) {
+	type rateInput struct {
+		Score int `json:"score" jsonschema:"minimum=1,maximum=5"`
+	}
+	called := false
+	tool, err := NewTool("rate", "Rates a thing", func(ctx context.Context, in rateInput) (string, error) {
+		called = true
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("NewTool failed: %v", err)
+	}
+	if tool.InputSchema["properties"].(map[string]any)["score"].(map[string]any)["maximum"] != 5.0 {
+		t.Errorf("expected the constraint in the input schema, got %v", tool.InputSchema)
+	}
+
+	result, err := tool.Execute(context.Background(), map[string]any{"score": 9.0})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.TextContent(), "input validation error") || called {
+		t.Errorf("expected the handler not to run for out-of-range input, got %+v", result)
+	}
+}